@@ -0,0 +1,61 @@
+package main
+
+import (
+	"crypto/tls"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/Adi-ty/go-loadbalancer/internal/config"
+	"github.com/Adi-ty/go-loadbalancer/pkg/middleware"
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// acmeRenewBefore is how long before expiry ACME certificates are renewed.
+const acmeRenewBefore = 30 * 24 * time.Hour
+
+// newACMEManager obtains and renews certificates for cfg.ACMEDomains,
+// caching them in cfg.ACMECacheDir so restarts don't request new ones.
+func newACMEManager(cfg config.Config) *autocert.Manager {
+    m := &autocert.Manager{
+        Prompt:      autocert.AcceptTOS,
+        Cache:       autocert.DirCache(cfg.ACMECacheDir),
+        HostPolicy:  autocert.HostWhitelist(cfg.ACMEDomains...),
+        RenewBefore: acmeRenewBefore,
+        Email:       cfg.ACMEEmail,
+    }
+    if cfg.ACMEDirectoryURL != "" {
+        m.Client = &acme.Client{DirectoryURL: cfg.ACMEDirectoryURL}
+    }
+    return m
+}
+
+// useACME makes tlsConfig fetch certificates from m, answering TLS-ALPN-01
+// challenges as well as normal handshakes.
+func useACME(tlsConfig *tls.Config, m *autocert.Manager) {
+    tlsConfig.GetCertificate = m.GetCertificate
+    // net/http adds h2 and http/1.1 to NextProtos itself.
+    tlsConfig.NextProtos = append(tlsConfig.NextProtos, acme.ALPNProto)
+}
+
+// startACMEChallengeServer answers HTTP-01 challenges on cfg.ACMEHTTPPort and
+// redirects all other requests to HTTPS.
+func startACMEChallengeServer(cfg config.Config, m *autocert.Manager) *http.Server {
+    srv := &http.Server{
+        Addr:         net.JoinHostPort(cfg.BindAddr, cfg.ACMEHTTPPort),
+        Handler:      m.HTTPHandler(middleware.NewHTTPRedirectHandler(httpsRedirectHost(cfg))),
+        ReadTimeout:  cfg.ReadTimeout,
+        WriteTimeout: cfg.WriteTimeout,
+        IdleTimeout:  cfg.IdleTimeout,
+    }
+
+    go func() {
+        log.Printf("Answering ACME HTTP-01 challenges on :%s", cfg.ACMEHTTPPort)
+        if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+            log.Fatalf("ACME challenge server failed: %v", err)
+        }
+    }()
+    return srv
+}
@@ -0,0 +1,368 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Adi-ty/go-loadbalancer/internal/config"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// fakeACME is a minimal ACME (RFC 8555) CA for one order at a time. It does
+// not check request signatures, and validates HTTP-01 challenges by fetching
+// them from challengeAddr.
+type fakeACME struct {
+    *httptest.Server
+
+    caCert *x509.Certificate
+    caKey  *ecdsa.PrivateKey
+    roots  *x509.CertPool
+
+    challengeAddr string
+
+    mu         sync.Mutex
+    domain     string
+    token      string
+    authzState string
+    orderState string
+    certPEM    []byte
+    issued     int
+}
+
+func newFakeACME(t *testing.T) *fakeACME {
+    t.Helper()
+
+    key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+    if err != nil {
+        t.Fatal(err)
+    }
+    tmpl := &x509.Certificate{
+        SerialNumber:          big.NewInt(1),
+        Subject:               pkix.Name{CommonName: "fake ACME CA"},
+        NotBefore:             time.Now().Add(-time.Hour),
+        NotAfter:              time.Now().Add(24 * time.Hour),
+        IsCA:                  true,
+        KeyUsage:              x509.KeyUsageCertSign,
+        BasicConstraintsValid: true,
+    }
+    der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+    if err != nil {
+        t.Fatal(err)
+    }
+    caCert, err := x509.ParseCertificate(der)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    ca := &fakeACME{caCert: caCert, caKey: key, roots: x509.NewCertPool()}
+    ca.roots.AddCert(caCert)
+
+    mux := http.NewServeMux()
+    mux.HandleFunc("GET /directory", ca.directory)
+    mux.HandleFunc("/nonce", func(w http.ResponseWriter, r *http.Request) {})
+    mux.HandleFunc("POST /account", ca.newAccount)
+    mux.HandleFunc("POST /order", ca.newOrder)
+    mux.HandleFunc("POST /order/1", ca.order)
+    mux.HandleFunc("POST /authz/1", ca.authz)
+    mux.HandleFunc("POST /authz/1/http-01", ca.challenge)
+    mux.HandleFunc("POST /finalize/1", ca.finalize)
+    mux.HandleFunc("POST /cert/1", ca.cert)
+    ca.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        w.Header().Set("Replay-Nonce", base64.RawURLEncoding.EncodeToString([]byte(time.Now().String())))
+        mux.ServeHTTP(w, r)
+    }))
+    t.Cleanup(ca.Close)
+    return ca
+}
+
+// payload decodes the payload of the JWS in r's body into v.
+func payload(r *http.Request, v any) error {
+    var jws struct {
+        Payload string `json:"payload"`
+    }
+    if err := json.NewDecoder(r.Body).Decode(&jws); err != nil {
+        return err
+    }
+    data, err := base64.RawURLEncoding.DecodeString(jws.Payload)
+    if err != nil {
+        return err
+    }
+    return json.Unmarshal(data, v)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+    w.Header().Set("Content-Type", "application/json")
+    w.WriteHeader(status)
+    json.NewEncoder(w).Encode(v)
+}
+
+func (ca *fakeACME) directory(w http.ResponseWriter, r *http.Request) {
+    writeJSON(w, http.StatusOK, map[string]string{
+        "newNonce":   ca.URL + "/nonce",
+        "newAccount": ca.URL + "/account",
+        "newOrder":   ca.URL + "/order",
+        "revokeCert": ca.URL + "/revoke",
+        "keyChange":  ca.URL + "/key-change",
+    })
+}
+
+func (ca *fakeACME) newAccount(w http.ResponseWriter, r *http.Request) {
+    w.Header().Set("Location", ca.URL+"/account/1")
+    writeJSON(w, http.StatusCreated, map[string]string{"status": "valid"})
+}
+
+func (ca *fakeACME) newOrder(w http.ResponseWriter, r *http.Request) {
+    var req struct {
+        Identifiers []struct{ Value string } `json:"identifiers"`
+    }
+    if err := payload(r, &req); err != nil || len(req.Identifiers) != 1 {
+        http.Error(w, "want one identifier", http.StatusBadRequest)
+        return
+    }
+
+    ca.mu.Lock()
+    ca.domain = req.Identifiers[0].Value
+    ca.token = fmt.Sprintf("token%d", time.Now().UnixNano())
+    ca.authzState = "pending"
+    ca.orderState = "pending"
+    ca.mu.Unlock()
+
+    ca.writeOrder(w, http.StatusCreated)
+}
+
+func (ca *fakeACME) order(w http.ResponseWriter, r *http.Request) {
+    ca.writeOrder(w, http.StatusOK)
+}
+
+func (ca *fakeACME) writeOrder(w http.ResponseWriter, status int) {
+    ca.mu.Lock()
+    defer ca.mu.Unlock()
+
+    order := map[string]any{
+        "status":         ca.orderState,
+        "identifiers":    []map[string]string{{"type": "dns", "value": ca.domain}},
+        "authorizations": []string{ca.URL + "/authz/1"},
+        "finalize":       ca.URL + "/finalize/1",
+    }
+    if ca.orderState == "valid" {
+        order["certificate"] = ca.URL + "/cert/1"
+    }
+    w.Header().Set("Location", ca.URL+"/order/1")
+    writeJSON(w, status, order)
+}
+
+func (ca *fakeACME) authz(w http.ResponseWriter, r *http.Request) {
+    ca.mu.Lock()
+    defer ca.mu.Unlock()
+
+    writeJSON(w, http.StatusOK, map[string]any{
+        "status":     ca.authzState,
+        "identifier": map[string]string{"type": "dns", "value": ca.domain},
+        "challenges": []map[string]string{{
+            "type":   "http-01",
+            "url":    ca.URL + "/authz/1/http-01",
+            "token":  ca.token,
+            "status": ca.authzState,
+        }},
+    })
+}
+
+// challenge validates the HTTP-01 challenge by fetching the token from the
+// challenge server, as the CA would from port 80 of the domain.
+func (ca *fakeACME) challenge(w http.ResponseWriter, r *http.Request) {
+    ca.mu.Lock()
+    domain, token := ca.domain, ca.token
+    ca.mu.Unlock()
+
+    state := "invalid"
+    req, _ := http.NewRequest(http.MethodGet, "http://"+ca.challengeAddr+"/.well-known/acme-challenge/"+token, nil)
+    req.Host = domain
+    deadline := time.Now().Add(5 * time.Second)
+    for time.Now().Before(deadline) {
+        resp, err := http.DefaultClient.Do(req)
+        if err != nil {
+            time.Sleep(20 * time.Millisecond)
+            continue
+        }
+        body, _ := io.ReadAll(resp.Body)
+        resp.Body.Close()
+        if resp.StatusCode == http.StatusOK && strings.HasPrefix(string(body), token+".") {
+            state = "valid"
+        }
+        break
+    }
+
+    ca.mu.Lock()
+    ca.authzState = state
+    if state == "valid" {
+        ca.orderState = "ready"
+    }
+    ca.mu.Unlock()
+
+    writeJSON(w, http.StatusOK, map[string]string{
+        "type":   "http-01",
+        "url":    ca.URL + "/authz/1/http-01",
+        "token":  token,
+        "status": state,
+    })
+}
+
+func (ca *fakeACME) finalize(w http.ResponseWriter, r *http.Request) {
+    var req struct {
+        CSR string `json:"csr"`
+    }
+    if err := payload(r, &req); err != nil {
+        http.Error(w, err.Error(), http.StatusBadRequest)
+        return
+    }
+    der, err := base64.RawURLEncoding.DecodeString(req.CSR)
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusBadRequest)
+        return
+    }
+    csr, err := x509.ParseCertificateRequest(der)
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusBadRequest)
+        return
+    }
+
+    tmpl := &x509.Certificate{
+        SerialNumber: big.NewInt(time.Now().UnixNano()),
+        Subject:      pkix.Name{CommonName: csr.DNSNames[0]},
+        DNSNames:     csr.DNSNames,
+        NotBefore:    time.Now().Add(-time.Hour),
+        NotAfter:     time.Now().Add(90 * 24 * time.Hour),
+        KeyUsage:     x509.KeyUsageDigitalSignature,
+        ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+    }
+    leaf, err := x509.CreateCertificate(rand.Reader, tmpl, ca.caCert, csr.PublicKey, ca.caKey)
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+        return
+    }
+
+    ca.mu.Lock()
+    ca.certPEM = append(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: leaf}),
+        pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.caCert.Raw})...)
+    ca.orderState = "valid"
+    ca.issued++
+    ca.mu.Unlock()
+
+    ca.writeOrder(w, http.StatusOK)
+}
+
+func (ca *fakeACME) cert(w http.ResponseWriter, r *http.Request) {
+    ca.mu.Lock()
+    defer ca.mu.Unlock()
+
+    w.Header().Set("Content-Type", "application/pem-certificate-chain")
+    w.Write(ca.certPEM)
+}
+
+func (ca *fakeACME) issuedCount() int {
+    ca.mu.Lock()
+    defer ca.mu.Unlock()
+    return ca.issued
+}
+
+// serveACMETLS serves TLS handshakes with certificates from m and returns
+// the listener's address.
+func serveACMETLS(t *testing.T, cfg config.Config, m *autocert.Manager) string {
+    t.Helper()
+
+    tlsConfig, err := cfg.TLSConfig()
+    if err != nil {
+        t.Fatal(err)
+    }
+    useACME(tlsConfig, m)
+
+    ln, err := tls.Listen("tcp", "127.0.0.1:0", tlsConfig)
+    if err != nil {
+        t.Fatal(err)
+    }
+    t.Cleanup(func() { ln.Close() })
+    go func() {
+        for {
+            conn, err := ln.Accept()
+            if err != nil {
+                return
+            }
+            conn.(*tls.Conn).Handshake()
+            conn.Close()
+        }
+    }()
+    return ln.Addr().String()
+}
+
+func TestACMEIssuesCertificate(t *testing.T) {
+    ca := newFakeACME(t)
+
+    ln, err := net.Listen("tcp", "127.0.0.1:0")
+    if err != nil {
+        t.Fatal(err)
+    }
+    ca.challengeAddr = ln.Addr().String()
+    _, port, _ := net.SplitHostPort(ca.challengeAddr)
+    ln.Close()
+
+    cfg := config.Default()
+    cfg.BindAddr = "127.0.0.1"
+    cfg.ACMEDomains = []string{"lb.example.com"}
+    cfg.ACMECacheDir = t.TempDir()
+    cfg.ACMEHTTPPort = port
+    cfg.ACMEDirectoryURL = ca.URL + "/directory"
+
+    m := newACMEManager(cfg)
+    if m.RenewBefore != 30*24*time.Hour {
+        t.Errorf("RenewBefore = %v, want 30 days", m.RenewBefore)
+    }
+    challengeSrv := startACMEChallengeServer(cfg, m)
+    defer challengeSrv.Close()
+
+    dial := func(addr string) *x509.Certificate {
+        t.Helper()
+        conn, err := tls.DialWithDialer(&net.Dialer{Timeout: 30 * time.Second}, "tcp", addr, &tls.Config{
+            ServerName: "lb.example.com",
+            RootCAs:    ca.roots,
+        })
+        if err != nil {
+            t.Fatalf("TLS handshake: %v", err)
+        }
+        defer conn.Close()
+        return conn.ConnectionState().PeerCertificates[0]
+    }
+
+    leaf := dial(serveACMETLS(t, cfg, m))
+    if leaf.Issuer.CommonName != "fake ACME CA" {
+        t.Errorf("certificate issued by %q, want the fake ACME CA", leaf.Issuer.CommonName)
+    }
+    if _, err := os.Stat(filepath.Join(cfg.ACMECacheDir, "lb.example.com")); err != nil {
+        t.Errorf("certificate not cached: %v", err)
+    }
+
+    // A restarted balancer loads the certificate from the cache instead of
+    // ordering another.
+    dial(serveACMETLS(t, cfg, newACMEManager(cfg)))
+    if got := ca.issuedCount(); got != 1 {
+        t.Errorf("CA issued %d certificates, want 1", got)
+    }
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Adi-ty/go-loadbalancer/internal/config"
+)
+
+// Exit codes of the check subcommand.
+const (
+    checkOK          = 0
+    checkInvalid     = 1
+    checkUnreachable = 2
+)
+
+// runCheck validates a config file and dials every backend it lists:
+//
+//	go-loadbalancer check --config config.yaml
+func runCheck(args []string) int {
+    fs := flag.NewFlagSet("check", flag.ContinueOnError)
+    configPath := fs.String("config", "", "Path to the YAML config file to check")
+    dialTimeout := fs.Duration("timeout", 3*time.Second, "TCP dial timeout per backend")
+    if err := fs.Parse(args); err != nil {
+        return checkInvalid
+    }
+
+    if *configPath == "" {
+        fmt.Fprintln(os.Stderr, "check: --config is required")
+        return checkInvalid
+    }
+
+    cfg := config.Default()
+    if err := config.LoadFile(*configPath, &cfg); err != nil {
+        fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+        return checkInvalid
+    }
+    if err := cfg.Validate(); err != nil {
+        fmt.Fprintf(os.Stderr, "❌ Configuration error: %v\n", err)
+        return checkInvalid
+    }
+    if len(cfg.Backends) == 0 {
+        fmt.Fprintln(os.Stderr, "❌ Configuration error: no backends configured")
+        return checkInvalid
+    }
+
+    exitCode := checkOK
+    tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+    fmt.Fprintln(tw, "BACKEND\tWEIGHT\tRESULT\tDETAIL")
+
+    for _, backend := range cfg.Backends {
+        addr, err := backendDialAddr(backend.URL)
+        if err != nil {
+            fmt.Fprintf(tw, "%s\t%d\tFAIL\t%v\n", backend.URL, backend.Weight, err)
+            exitCode = checkInvalid
+            continue
+        }
+
+        start := time.Now()
+        conn, err := net.DialTimeout("tcp", addr, *dialTimeout)
+        if err != nil {
+            fmt.Fprintf(tw, "%s\t%d\tUNREACHABLE\t%v\n", backend.URL, backend.Weight, err)
+            if exitCode == checkOK {
+                exitCode = checkUnreachable
+            }
+            continue
+        }
+        conn.Close()
+        fmt.Fprintf(tw, "%s\t%d\tPASS\tconnected in %s\n", backend.URL, backend.Weight, time.Since(start).Round(time.Millisecond))
+    }
+    tw.Flush()
+
+    return exitCode
+}
+
+// backendDialAddr returns the host:port to dial for a configured backend URL.
+func backendDialAddr(rawURL string) (string, error) {
+    u, err := url.Parse(normalizeBackendURL(rawURL))
+    if err != nil {
+        return "", fmt.Errorf("invalid URL: %w", err)
+    }
+    if u.Hostname() == "" {
+        return "", fmt.Errorf("invalid URL: missing host")
+    }
+
+    port := u.Port()
+    if port == "" {
+        port = "80"
+        if u.Scheme == "https" {
+            port = "443"
+        }
+    }
+    return net.JoinHostPort(u.Hostname(), port), nil
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeCheckConfig writes a config listing backends and returns its path.
+func writeCheckConfig(t *testing.T, backends ...string) string {
+    t.Helper()
+
+    var b strings.Builder
+    b.WriteString("backends:\n")
+    for _, backend := range backends {
+        fmt.Fprintf(&b, "  - url: %s\n    weight: 1\n", backend)
+    }
+    path := filepath.Join(t.TempDir(), "config.yaml")
+    if err := os.WriteFile(path, []byte(b.String()), 0o644); err != nil {
+        t.Fatal(err)
+    }
+    return path
+}
+
+func TestCheckSubcommand(t *testing.T) {
+    reachable := httptest.NewServer(http.NotFoundHandler())
+    t.Cleanup(reachable.Close)
+    closed := httptest.NewServer(http.NotFoundHandler())
+    closed.Close()
+
+    tests := []struct {
+        name     string
+        backends []string
+        want     int
+    }{
+        {"all reachable", []string{reachable.URL}, checkOK},
+        {"one unreachable", []string{reachable.URL, closed.URL}, checkUnreachable},
+        {"invalid URL", []string{reachable.URL, "http://"}, checkInvalid},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            code, out := runCommand(t, "check", "--config", writeCheckConfig(t, tt.backends...), "--timeout", "1s")
+            if code != tt.want {
+                t.Errorf("exit code %d, want %d; output:\n%s", code, tt.want, out)
+            }
+        })
+    }
+}
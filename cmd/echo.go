@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"time"
+	"unicode/utf8"
+)
+
+// maxEchoBodyBytes caps how much of a request body the echo server returns.
+const maxEchoBodyBytes = 1 << 20
+
+// echoResponse describes the request the echo server received.
+type echoResponse struct {
+    Method     string              `json:"method"`
+    Path       string              `json:"path"`
+    Host       string              `json:"host"`
+    Headers    map[string][]string `json:"headers"`
+    Body       string              `json:"body"`
+    BodyBase64 bool                `json:"body_base64,omitempty"` // Set when the body is not valid UTF-8
+    RemoteAddr string              `json:"remote_addr"`
+}
+
+// runEcho serves an HTTP echo backend for smoke-testing the proxy pipeline:
+//
+//	go-loadbalancer echo --port 9000
+//
+// Every request, including health checks, gets 200 with a JSON description
+// of what arrived, so headers injected by the balancer can be inspected.
+func runEcho(args []string) int {
+    fs := flag.NewFlagSet("echo", flag.ContinueOnError)
+    port := fs.String("port", "9000", "Port to listen on")
+    if err := fs.Parse(args); err != nil {
+        return 1
+    }
+
+    srv := &http.Server{
+        Addr:         ":" + *port,
+        Handler:      http.HandlerFunc(handleEcho),
+        ReadTimeout:  15 * time.Second,
+        WriteTimeout: 15 * time.Second,
+    }
+
+    log.Printf("Echo server listening on :%s", *port)
+    if err := srv.ListenAndServe(); err != nil {
+        fmt.Fprintf(os.Stderr, "❌ Echo server failed: %v\n", err)
+        return 1
+    }
+    return 0
+}
+
+func handleEcho(w http.ResponseWriter, r *http.Request) {
+    body, err := io.ReadAll(io.LimitReader(r.Body, maxEchoBodyBytes))
+    if err != nil {
+        http.Error(w, "failed to read body: "+err.Error(), http.StatusBadRequest)
+        return
+    }
+
+    resp := echoResponse{
+        Method:     r.Method,
+        Path:       r.URL.RequestURI(),
+        Host:       r.Host,
+        Headers:    r.Header,
+        Body:       string(body),
+        RemoteAddr: r.RemoteAddr,
+    }
+    if !utf8.Valid(body) {
+        resp.Body = base64.StdEncoding.EncodeToString(body)
+        resp.BodyBase64 = true
+    }
+
+    w.Header().Set("Content-Type", "application/json")
+    json.NewEncoder(w).Encode(resp)
+}
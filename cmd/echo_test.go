@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// startEcho runs the echo subcommand in a subprocess and returns its address
+// once it accepts connections.
+func startEcho(t *testing.T) string {
+    t.Helper()
+
+    ln, err := net.Listen("tcp", "127.0.0.1:0")
+    if err != nil {
+        t.Fatal(err)
+    }
+    addr := ln.Addr().String()
+    _, port, _ := net.SplitHostPort(addr)
+    ln.Close()
+
+    cmd := command("echo", "--port", port)
+    if err := cmd.Start(); err != nil {
+        t.Fatal(err)
+    }
+    t.Cleanup(func() {
+        cmd.Process.Kill()
+        cmd.Wait()
+    })
+
+    deadline := time.Now().Add(10 * time.Second)
+    for {
+        conn, err := net.Dial("tcp", addr)
+        if err == nil {
+            conn.Close()
+            return addr
+        }
+        if time.Now().After(deadline) {
+            t.Fatalf("echo server did not start listening on %s", addr)
+        }
+        time.Sleep(20 * time.Millisecond)
+    }
+}
+
+func TestEchoThroughBalancer(t *testing.T) {
+    echoAddr := startEcho(t)
+    addr := startBalancer(t, []string{"http://" + echoAddr})
+
+    resp, err := http.Post(fmt.Sprintf("http://%s/orders?id=7", addr), "text/plain", strings.NewReader("hello echo"))
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK {
+        t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+    }
+
+    var echo echoResponse
+    if err := json.NewDecoder(resp.Body).Decode(&echo); err != nil {
+        t.Fatal(err)
+    }
+    if echo.Method != http.MethodPost || echo.Path != "/orders?id=7" || echo.Body != "hello echo" {
+        t.Errorf("echo = %s %s %q, want POST /orders?id=7 \"hello echo\"", echo.Method, echo.Path, echo.Body)
+    }
+    if echo.Host != echoAddr {
+        t.Errorf("Host = %q, want the backend's %q", echo.Host, echoAddr)
+    }
+    header := http.Header(echo.Headers)
+    for name, want := range map[string]string{
+        "X-Forwarded-For":   "127.0.0.1",
+        "X-Forwarded-By":    "go-loadbalancer",
+        "X-Forwarded-Proto": "http",
+    } {
+        if got := header.Get(name); got != want {
+            t.Errorf("%s = %q, want %q", name, got, want)
+        }
+    }
+}
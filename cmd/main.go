@@ -3,55 +3,51 @@ package main
 import (
 	"bufio"
 	"context"
+	"flag"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
+	"net/netip"
 	"os"
 	"os/signal"
+	"regexp"
 	"strconv"
 	"strings"
 	"syscall"
 	"time"
 
+	"github.com/Adi-ty/go-loadbalancer/internal/admin"
 	"github.com/Adi-ty/go-loadbalancer/internal/balancer"
+	"github.com/Adi-ty/go-loadbalancer/internal/config"
+	"github.com/Adi-ty/go-loadbalancer/internal/discovery"
+	"github.com/Adi-ty/go-loadbalancer/internal/listener"
+	"github.com/Adi-ty/go-loadbalancer/internal/weightsync"
+	"github.com/Adi-ty/go-loadbalancer/pkg/middleware"
 )
 
-const listenPort = "8080"
+// normalizeBackendURL defaults backend addresses without a scheme to http.
+func normalizeBackendURL(rawURL string) string {
+    if !strings.HasPrefix(rawURL, "http://") && !strings.HasPrefix(rawURL, "https://") {
+        return "http://" + rawURL
+    }
+    return rawURL
+}
 
-func parseServerInput(input string) ([]*balancer.Server, error) {
-    input = strings.TrimSpace(input)
-    parts := strings.Split(input, ",")
+// buildServers creates backends from config entries.
+func buildServers(backends []config.BackendConfig) ([]*balancer.Server, error) {
     var servers []*balancer.Server
-
-    for _, part := range parts {
-        part = strings.TrimSpace(part)
-        if part == "" {
-            continue
-        }
-
-        addrWeight := strings.Split(part, "/")
-        if len(addrWeight) != 2 {
-            return nil, fmt.Errorf("invalid format for server %s. Expected: host:port/weight", part)
-        }
-
-        rawURL := addrWeight[0]
-        weightStr := addrWeight[1]
-
-        if !strings.HasPrefix(rawURL, "http://") && !strings.HasPrefix(rawURL, "https://") {
-            rawURL = "http://" + rawURL
-        }
-
-        weight, err := strconv.Atoi(weightStr)
-        if err != nil || weight < 1 {
-            return nil, fmt.Errorf("invalid weight for server %s. Must be an integer >= 1", rawURL)
-        }
-
-        server, err := balancer.NewServer(rawURL, weight)
+    for _, backend := range backends {
+        server, err := balancer.NewServer(normalizeBackendURL(backend.URL), backend.Weight)
         if err != nil {
             return nil, err
         }
+        server.Priority = backend.Priority
+        server.PreserveHost = backend.PreserveHost
+        server.UseSNIFromHost = backend.UseSNIFromHost
+        server.SLOTarget = backend.SLOTarget
         servers = append(servers, server)
-        log.Printf("Added backend: %s (Weight: %d)", server.URL.String(), weight)
+        log.Printf("Added backend: %s (Weight: %d)", server.URL.String(), backend.Weight)
     }
 
     if len(servers) == 0 {
@@ -60,7 +56,28 @@ func parseServerInput(input string) ([]*balancer.Server, error) {
     return servers, nil
 }
 
-func main() {
+// splitList splits a comma-separated flag value, dropping empty entries.
+func splitList(value string) []string {
+    var items []string
+    for _, item := range strings.Split(value, ",") {
+        if item = strings.TrimSpace(item); item != "" {
+            items = append(items, item)
+        }
+    }
+    return items
+}
+
+// httpsRedirectHost is where plain HTTP requests are redirected. Clients keep
+// the hostname they used; only the port changes.
+func httpsRedirectHost(cfg config.Config) string {
+    if cfg.ListenPort == "443" {
+        return ""
+    }
+    return ":" + cfg.ListenPort
+}
+
+// readServersFromStdin prompts for the backend list interactively.
+func readServersFromStdin() ([]*balancer.Server, error) {
     reader := bufio.NewReader(os.Stdin)
     fmt.Println("--- Weighted Least Connection Load Balancer ---")
     fmt.Println("Enter backend servers with weights separated by commas.")
@@ -70,34 +87,531 @@ func main() {
 
     input, err := reader.ReadString('\n')
     if err != nil {
-        log.Fatalf("Error reading backend servers: %v", err)
+        return nil, fmt.Errorf("error reading backend servers: %w", err)
+    }
+
+    servers, err := balancer.ParseServerList(input)
+    if err != nil {
+        return nil, err
+    }
+    for _, server := range servers {
+        log.Printf("Added backend: %s (Weight: %d)", server.URL.String(), server.Weight)
+    }
+    return servers, nil
+}
+
+func main() {
+    if len(os.Args) > 1 {
+        switch os.Args[1] {
+        case "check":
+            os.Exit(runCheck(os.Args[2:]))
+        case "echo":
+            os.Exit(runEcho(os.Args[2:]))
+        }
+    }
+
+    cfg := config.Default()
+
+    configPath := flag.String("config", "", "Path to a YAML config file")
+    flag.StringVar(&cfg.ListenPort, "port", cfg.ListenPort, "Port the load balancer listens on")
+    flag.StringVar(&cfg.BindAddr, "bind-addr", cfg.BindAddr, "IP address of the interface to listen on (default all interfaces)")
+    flag.StringVar(&cfg.AdminAddr, "admin-addr", cfg.AdminAddr, "Address of the admin API (empty disables it)")
+    flag.StringVar(&cfg.AdminToken, "admin-token", cfg.AdminToken, "Bearer token required by the admin API (empty disables token auth)")
+    flag.Func("admin-allowed-cidrs", "Comma-separated networks allowed to reach the admin API, e.g. 10.0.0.0/8,127.0.0.1", func(value string) error {
+        cfg.AdminAllowedCIDRs = splitList(value)
+        return nil
+    })
+    flag.IntVar(&cfg.ListenBacklog, "listen-backlog", cfg.ListenBacklog, "Length of the kernel TCP accept queue (0 = system default)")
+    flag.IntVar(&cfg.ReusePortListeners, "reuseport-listeners", cfg.ReusePortListeners, "Number of SO_REUSEPORT listeners accepting connections on the listen port")
+    flag.IntVar(&cfg.MaxHeaderBytes, "max-header-bytes", cfg.MaxHeaderBytes, "Maximum size of incoming request headers in bytes")
+    flag.IntVar(&cfg.MaxURLBytes, "max-url-bytes", cfg.MaxURLBytes, "Maximum length of the request URI in bytes (0 = unlimited)")
+    flag.BoolVar(&cfg.PropagateDeadline, "propagate-deadline", cfg.PropagateDeadline, "Forward the time left before the client's X-Timeout-Ms or Grpc-Timeout deadline to backends as X-Timeout-Ms")
+    flag.Int64Var(&cfg.MaxRequestBody, "max-request-body", cfg.MaxRequestBody, "Maximum request body size in bytes (0 = unlimited)")
+    flag.BoolVar(&cfg.BufferUnknownLengthBodies, "buffer-unknown-length-bodies", cfg.BufferUnknownLengthBodies, "Buffer request bodies without Content-Length, up to --max-request-body, and forward them with one")
+    flag.BoolVar(&cfg.ForwardTrailers, "forward-trailers", cfg.ForwardTrailers, "Relay HTTP trailers sent by backends to clients")
+    flag.BoolVar(&cfg.LatencyBudget, "latency-budget", cfg.LatencyBudget, "Deduct time spent in the balancer from X-Request-Deadline-Ms or Grpc-Timeout and forward the rest as X-Remaining-Deadline-Ms")
+    flag.IntVar(&cfg.MinRemainingMs, "min-remaining-ms", cfg.MinRemainingMs, "Answer 504 instead of forwarding when less than this much latency budget is left")
+    flag.StringVar(&cfg.AccessLogFile, "access-log-file", cfg.AccessLogFile, "Write access logs to this file (reopened on SIGHUP)")
+    flag.StringVar(&cfg.AccessLogFormat, "access-log-format", cfg.AccessLogFormat, "Access log format: json, clf or combined")
+    flag.Float64Var(&cfg.LogSamplingRate, "log-sampling-rate", cfg.LogSamplingRate, "Fraction (0-1) of 2xx and 3xx requests written to the access log, 0 for none; errors are always logged")
+    flag.IntVar(&cfg.LogRequestBody, "log-request-body", cfg.LogRequestBody, "Log up to N bytes of each request body in the access log (0 = off)")
+    flag.DurationVar(&cfg.BackendIdleConnTimeout, "backend-idle-conn-timeout", cfg.BackendIdleConnTimeout, "Close idle backend connections after this long (0 = never)")
+    flag.IntVar(&cfg.BackendMaxIdleConns, "backend-max-idle-conns", cfg.BackendMaxIdleConns, "Maximum idle connections kept per backend transport (0 = unlimited)")
+    flag.IntVar(&cfg.BackendMaxIdleConnsPerHost, "backend-max-idle-conns-per-host", cfg.BackendMaxIdleConnsPerHost, "Maximum idle connections kept per backend host")
+    flag.DurationVar(&cfg.BackendKeepAliveInterval, "backend-keepalive-interval", cfg.BackendKeepAliveInterval, "Interval between TCP keepalive probes on backend connections")
+    flag.IntVar(&cfg.BackendKeepAliveCount, "backend-keepalive-count", cfg.BackendKeepAliveCount, "Unanswered TCP keepalive probes before a backend connection is dropped")
+    flag.DurationVar(&cfg.BackendConnectJitter, "backend-connect-jitter", cfg.BackendConnectJitter, "Random delay of up to this long before the first connections to a backend that has just become healthy again (0 = none)")
+    flag.StringVar(&cfg.DebugBackendHeader, "debug-backend-header", cfg.DebugBackendHeader, "Response header that reports the serving backend, e.g. X-Debug-Backend (empty disables it)")
+    flag.StringVar(&cfg.TLSCertFile, "tls-cert-file", cfg.TLSCertFile, "TLS certificate file; enables HTTPS together with --tls-key-file")
+    flag.StringVar(&cfg.TLSKeyFile, "tls-key-file", cfg.TLSKeyFile, "TLS private key file")
+    flag.Func("acme-domain", "Comma-separated domains to obtain Let's Encrypt certificates for, instead of --tls-cert-file", func(value string) error {
+        cfg.ACMEDomains = splitList(value)
+        return nil
+    })
+    flag.StringVar(&cfg.ACMECacheDir, "acme-cache-dir", cfg.ACMECacheDir, "Directory where ACME account keys and certificates are cached")
+    flag.StringVar(&cfg.ACMEHTTPPort, "acme-http-port", cfg.ACMEHTTPPort, "Port answering ACME HTTP-01 challenges; other requests are redirected to HTTPS")
+    flag.StringVar(&cfg.ACMEEmail, "acme-email", cfg.ACMEEmail, "Contact email registered with the ACME CA")
+    flag.StringVar(&cfg.ACMEDirectoryURL, "acme-directory-url", cfg.ACMEDirectoryURL, "ACME directory URL (default Let's Encrypt production)")
+    flag.StringVar(&cfg.TLSMinVersion, "tls-min-version", cfg.TLSMinVersion, "Minimum TLS version: tls12 or tls13")
+    flag.Func("tls-cipher-suites", "Comma-separated TLS 1.2 cipher suites, e.g. TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256 (default Go's)", func(value string) error {
+        cfg.TLSCipherSuites = splitList(value)
+        return nil
+    })
+    flag.StringVar(&cfg.HTTPRedirectPort, "http-redirect-port", cfg.HTTPRedirectPort, "Serve plain HTTP on this port and redirect every request to HTTPS (requires TLS)")
+    flag.IntVar(&cfg.HSTSMaxAge, "hsts-max-age", cfg.HSTSMaxAge, "Strict-Transport-Security max-age in seconds when TLS is enabled")
+    flag.BoolVar(&cfg.HSTSIncludeSubdomains, "hsts-include-subdomains", cfg.HSTSIncludeSubdomains, "Add includeSubDomains to the Strict-Transport-Security header")
+    flag.BoolVar(&cfg.HSTSPreload, "hsts-preload", cfg.HSTSPreload, "Add preload to the Strict-Transport-Security header")
+    flag.BoolVar(&cfg.SecurityHeaders, "security-headers", cfg.SecurityHeaders, "Add Content-Security-Policy, X-Frame-Options and other security headers to responses")
+    flag.StringVar(&cfg.ContentSecurityPolicy, "content-security-policy", cfg.ContentSecurityPolicy, "Content-Security-Policy sent with --security-headers (empty omits it)")
+    flag.StringVar(&cfg.XFrameOptions, "x-frame-options", cfg.XFrameOptions, "X-Frame-Options sent with --security-headers (empty omits it)")
+    flag.StringVar(&cfg.BackendLoadHeader, "backend-load-header", cfg.BackendLoadHeader, "Response header in which backends report their load from 0.0 to 1.0, e.g. X-Backend-Load (empty disables it)")
+    flag.BoolVar(&cfg.DiagnosticHeaders, "diagnostic-headers", cfg.DiagnosticHeaders, "Add X-LB-Server, X-LB-Algorithm and, with --request-id, X-LB-Request-ID to responses (not for production)")
+    flag.BoolVar(&cfg.RequestID, "request-id", cfg.RequestID, "Give every request an X-Request-ID, keeping one sent by the client, and echo it on the response")
+    flag.Float64Var(&cfg.RateLimitRPS, "rate-limit-rps", cfg.RateLimitRPS, "Requests per second allowed from each client IP (0 disables rate limiting)")
+    flag.IntVar(&cfg.RateLimitBurst, "rate-limit-burst", cfg.RateLimitBurst, "Requests a client IP may send in a burst above --rate-limit-rps")
+    flag.Func("cors-allowed-origins", "Comma-separated origins allowed to make cross-origin requests, or * for any (empty disables CORS)", func(value string) error {
+        cfg.CORSAllowedOrigins = splitList(value)
+        return nil
+    })
+    flag.Func("cors-allowed-methods", "Comma-separated methods allowed in CORS preflight responses (default GET, HEAD, POST)", func(value string) error {
+        cfg.CORSAllowedMethods = splitList(value)
+        return nil
+    })
+    flag.Func("cors-allowed-headers", "Comma-separated request headers allowed in CORS preflight responses", func(value string) error {
+        cfg.CORSAllowedHeaders = splitList(value)
+        return nil
+    })
+    flag.BoolVar(&cfg.CORSAllowCredentials, "cors-allow-credentials", cfg.CORSAllowCredentials, "Allow cross-origin requests with credentials")
+    flag.IntVar(&cfg.CORSMaxAge, "cors-max-age", cfg.CORSMaxAge, "Seconds browsers may cache CORS preflight responses (0 omits the header)")
+    flag.BoolVar(&cfg.Compression, "compression", cfg.Compression, "Gzip responses for clients that accept it")
+    flag.IntVar(&cfg.MaxTrackedPaths, "max-tracked-paths", cfg.MaxTrackedPaths, "Maximum request paths with latency stats in /admin/stats/paths (0 disables path stats)")
+    flag.StringVar(&cfg.PathIDPattern, "path-id-pattern", cfg.PathIDPattern, "Regex matching path segments to replace with {id} in path stats (default UUIDs and integers)")
+    flag.StringVar(&cfg.MaintenanceDir, "maintenance-dir", cfg.MaintenanceDir, "Serve static files from this directory with status 503 while no backend is healthy")
+    flag.StringVar(&cfg.GlobalOptionsMethods, "global-options-methods", cfg.GlobalOptionsMethods, "Comma-separated methods listed in the Allow header for OPTIONS *")
+    flag.IntVar(&cfg.MaxRetries, "max-retries", cfg.MaxRetries, "Retry requests on up to N other backends when a backend cannot be reached")
+    flag.DurationVar(&cfg.MaxRetryDuration, "max-retry-duration", cfg.MaxRetryDuration, "Maximum total time a retried request waits for response headers across all attempts (0 = no limit)")
+    flag.DurationVar(&cfg.HealthCheckInterval, "health-check-interval", cfg.HealthCheckInterval, "How often backends are health checked")
+    flag.DurationVar(&cfg.MaxHealthCheckInterval, "max-health-check-interval", cfg.MaxHealthCheckInterval, "Longest interval between checks of a backend that keeps failing (0 = 5x --health-check-interval)")
+    flag.IntVar(&cfg.HealthConsecutiveSuccesses, "health-consecutive-successes", cfg.HealthConsecutiveSuccesses, "Health checks an unhealthy backend must pass in a row to be marked healthy")
+    flag.BoolVar(&cfg.HealthCheckPing, "health-check-ping", cfg.HealthCheckPing, "Ping backends over ICMP before each HTTP health check (needs CAP_NET_RAW)")
+    flag.BoolVar(&cfg.ErrorRateRampDown, "error-rate-ramp-down", cfg.ErrorRateRampDown, "Reduce the weight of backends whose error rate exceeds --ramp-down-threshold")
+    flag.Float64Var(&cfg.RampDownThreshold, "ramp-down-threshold", cfg.RampDownThreshold, "Error rate (0-1) above which a backend's weight is reduced")
+    flag.DurationVar(&cfg.RampDownRecoveryPeriod, "ramp-down-recovery-period", cfg.RampDownRecoveryPeriod, "How long a recovered backend takes to return to full weight")
+    flag.BoolVar(&cfg.PoolCircuitBreaker, "pool-circuit-breaker", cfg.PoolCircuitBreaker, "Reject all requests with 503 while the pool's error rate exceeds --pool-error-threshold")
+    flag.Float64Var(&cfg.PoolErrorThreshold, "pool-error-threshold", cfg.PoolErrorThreshold, "Pool error rate (0-1) above which the pool circuit opens")
+    flag.DurationVar(&cfg.PoolErrorWindow, "pool-error-window", cfg.PoolErrorWindow, "Rolling window the pool error rate is measured over")
+    flag.DurationVar(&cfg.PoolOpenDuration, "pool-open-duration", cfg.PoolOpenDuration, "How long the pool circuit stays open before a probe request")
+    flag.Float64Var(&cfg.SLOTarget, "slo-target", cfg.SLOTarget, "Share of requests (e.g. 0.999) backends must serve successfully; exports each backend's remaining error budget (0 = off)")
+    flag.IntVar(&cfg.BackendMaxConnections, "backend-max-connections", cfg.BackendMaxConnections, "Maximum concurrent requests per backend (0 = unlimited)")
+    flag.BoolVar(&cfg.FailFast, "fail-fast", cfg.FailFast, "Return 503 immediately when every backend is at --backend-max-connections instead of queueing")
+    flag.DurationVar(&cfg.MaxQueueWait, "max-queue-wait", cfg.MaxQueueWait, "How long a request waits for a backend below --backend-max-connections before failing with 503")
+    flag.BoolVar(&cfg.RequestBufferPool, "request-buffer-pool", cfg.RequestBufferPool, "Reuse pooled buffers for request bodies held in memory or streamed to backends")
+    flag.IntVar(&cfg.RequestBufferSize, "request-buffer-size", cfg.RequestBufferSize, "Size in bytes of pooled request body buffers")
+    flag.BoolVar(&cfg.ResponseBufferPool, "response-buffer-pool", cfg.ResponseBufferPool, "Reuse pooled buffers when copying response bodies")
+    flag.IntVar(&cfg.ResponseBufferSize, "response-buffer-size", cfg.ResponseBufferSize, "Size in bytes of pooled response copy buffers")
+    flag.DurationVar(&cfg.ReadTimeout, "read-timeout", cfg.ReadTimeout, "Maximum duration for reading an entire client request")
+    flag.DurationVar(&cfg.WriteTimeout, "write-timeout", cfg.WriteTimeout, "Maximum duration before timing out writes of a response")
+    flag.DurationVar(&cfg.IdleTimeout, "idle-timeout", cfg.IdleTimeout, "How long to keep idle client keep-alive connections open (must be >= read-timeout)")
+    flag.DurationVar(&cfg.ShutdownDelay, "shutdown-delay", cfg.ShutdownDelay, "After SIGTERM, answer 503 for this long before draining so upstream health checks notice")
+    flag.DurationVar(&cfg.ShutdownGracePeriod, "shutdown-grace-period", cfg.ShutdownGracePeriod, "Maximum time to wait for in-flight requests before closing the listeners")
+    flag.Func("etcd-endpoints", "Comma-separated etcd endpoints to discover backends from", func(value string) error {
+        cfg.EtcdEndpoints = splitList(value)
+        return nil
+    })
+    flag.StringVar(&cfg.EtcdPrefix, "etcd-prefix", cfg.EtcdPrefix, "etcd key prefix holding backends as {\"url\": ..., \"weight\": ...}")
+    flag.StringVar(&cfg.EtcdUsername, "etcd-username", cfg.EtcdUsername, "etcd username")
+    flag.StringVar(&cfg.EtcdPassword, "etcd-password", cfg.EtcdPassword, "etcd password")
+    flag.StringVar(&cfg.DiscoverySRV, "discovery-srv", cfg.DiscoverySRV, "DNS SRV record to discover backends from, e.g. _http._tcp.service.consul")
+    flag.DurationVar(&cfg.DiscoverySRVInterval, "discovery-srv-interval", cfg.DiscoverySRVInterval, "How often the --discovery-srv record is re-resolved")
+    flag.StringVar(&cfg.RedisAddr, "redis-addr", cfg.RedisAddr, "Redis address (host:port) to share backend weights with other instances through")
+    flag.StringVar(&cfg.RedisPassword, "redis-password", cfg.RedisPassword, "Redis password")
+    flag.StringVar(&cfg.RedisWeightsKey, "redis-weights-key", cfg.RedisWeightsKey, "Redis hash mapping backend host to weight")
+    flag.DurationVar(&cfg.RedisSyncInterval, "redis-sync-interval", cfg.RedisSyncInterval, "How often backend weights are read from --redis-addr")
+    flag.IntVar(&cfg.TrustedForwardedProto, "trusted-forwarded-proto", cfg.TrustedForwardedProto, "Number of upstream proxies trusted to set X-Forwarded-Proto (0 = use the incoming connection's scheme)")
+    flag.StringVar(&cfg.PoolName, "pool-name", cfg.PoolName, "Name added as the pool_name label to every lb_* metric")
+    flag.IntVar(&cfg.PrewarmRequests, "prewarm-requests", cfg.PrewarmRequests, "Warmup requests sent to each healthy backend before accepting traffic (0 = none)")
+    flag.BoolVar(&cfg.CollapseRequests, "collapse-requests", cfg.CollapseRequests, "Serve concurrent identical GET and HEAD requests from a single backend request")
+    flag.StringVar(&cfg.DiscoverCIDR, "discover-cidr", cfg.DiscoverCIDR, "Scan this CIDR range, e.g. 10.0.1.0/24, and add addresses answering on --discover-port as backends")
+    flag.IntVar(&cfg.DiscoverPort, "discover-port", cfg.DiscoverPort, "TCP port probed on each address of --discover-cidr")
+    flag.DurationVar(&cfg.DiscoverScanInterval, "discover-scan-interval", cfg.DiscoverScanInterval, "How often the --discover-cidr range is scanned")
+    flag.DurationVar(&cfg.DiscoverDialTimeout, "discover-dial-timeout", cfg.DiscoverDialTimeout, "Timeout for each --discover-cidr connection probe")
+    flag.IntVar(&cfg.DiscoverRemoveAfter, "discover-remove-after", cfg.DiscoverRemoveAfter, "Consecutive --discover-cidr scans a backend must miss before it is removed")
+    flag.Func("retry-methods", "Comma-separated methods that may be retried (default GET,HEAD,OPTIONS,PUT,DELETE)", func(value string) error {
+        cfg.RetryMethods = splitList(value)
+        return nil
+    })
+    flag.Func("connect-allowed-ports", "Comma-separated target ports CONNECT requests may tunnel to (empty disables CONNECT)", func(value string) error {
+        cfg.ConnectAllowedPorts = nil
+        for _, item := range splitList(value) {
+            port, err := strconv.Atoi(item)
+            if err != nil {
+                return fmt.Errorf("invalid port %q", item)
+            }
+            cfg.ConnectAllowedPorts = append(cfg.ConnectAllowedPorts, port)
+        }
+        return nil
+    })
+    flag.Parse()
+
+    if *configPath != "" {
+        if err := config.LoadFile(*configPath, &cfg); err != nil {
+            log.Fatalf("Configuration error: %v", err)
+        }
+        // Parse again so flags given on the command line override the file.
+        flag.Parse()
+    }
+
+    if err := cfg.Validate(); err != nil {
+        log.Fatalf("Configuration error: %v", err)
     }
 
-    servers, err := parseServerInput(input)
+    var servers []*balancer.Server
+    var err error
+    if len(cfg.Backends) > 0 {
+        servers, err = buildServers(cfg.Backends)
+    } else if len(cfg.EtcdEndpoints) == 0 && cfg.DiscoverySRV == "" && cfg.DiscoverCIDR == "" {
+        servers, err = readServersFromStdin()
+    }
     if err != nil {
         log.Fatalf("Configuration error: %v", err)
     }
 
-    loadBalancer := balancer.NewWeightedLeastConnection(servers)
+    configureBackend := func(server *balancer.Server) {
+        server.HealthCheckExpectedStatuses = cfg.HealthExpectedStatuses
+        server.HealthCheckBodyContains = cfg.HealthBodyContains
+        server.HealthCheckBodyNotContains = cfg.HealthBodyNotContains
+        server.HealthFlappingThreshold = cfg.HealthFlappingThreshold
+        server.HealthCheckConsecutiveSuccessesRequired = uint32(cfg.HealthConsecutiveSuccesses)
+        server.HealthCheckPingFirst = cfg.HealthCheckPing
+        server.Transport.IdleConnTimeout = cfg.BackendIdleConnTimeout
+        server.Transport.MaxIdleConns = cfg.BackendMaxIdleConns
+        server.Transport.MaxIdleConnsPerHost = cfg.BackendMaxIdleConnsPerHost
+        server.SetKeepAlive(cfg.BackendKeepAliveInterval, cfg.BackendKeepAliveCount)
+        server.ConnectJitter = cfg.BackendConnectJitter
+        server.DebugHeader = cfg.DebugBackendHeader
+        server.TrustedForwardedProtoHops = cfg.TrustedForwardedProto
+        server.ProxyHideHeaders = cfg.ProxyHideHeaders
+        server.ProxyPassHeaders = cfg.ProxyPassHeaders
+        server.BackendLoadHeader = cfg.BackendLoadHeader
+        server.MaxConnections = int32(cfg.BackendMaxConnections)
+        if server.SLOTarget == 0 {
+            server.SLOTarget = cfg.SLOTarget
+        }
+    }
+    for _, server := range servers {
+        configureBackend(server)
+    }
+
+    opts := []balancer.Option{
+        balancer.WithMaxURLBytes(cfg.MaxURLBytes),
+        balancer.WithDeadlinePropagation(cfg.PropagateDeadline),
+        balancer.WithMaxRequestBody(cfg.MaxRequestBody),
+        balancer.WithPoolName(cfg.PoolName),
+        balancer.WithBufferUnknownLengthBodies(cfg.BufferUnknownLengthBodies),
+        balancer.WithTrailerForwarding(cfg.ForwardTrailers),
+        balancer.WithGlobalOptionsMethods(cfg.GlobalOptions()),
+        balancer.WithRetries(cfg.MaxRetries, cfg.RetryMethods),
+        balancer.WithMaxRetryDuration(cfg.MaxRetryDuration),
+        balancer.WithFailFast(cfg.FailFast),
+        balancer.WithMaxQueueWait(cfg.MaxQueueWait),
+        balancer.WithDiagnosticHeaders(cfg.DiagnosticHeaders),
+        balancer.WithHealthCheckInterval(cfg.HealthCheckInterval),
+        balancer.WithMaxHealthCheckInterval(cfg.MaxHealthCheckInterval),
+    }
+    if cfg.LatencyBudget {
+        opts = append(opts, balancer.WithLatencyBudget(time.Duration(cfg.MinRemainingMs)*time.Millisecond))
+    }
+    if cfg.ErrorRateRampDown {
+        opts = append(opts, balancer.WithErrorRateRampDown(balancer.RampDownConfig{
+            Threshold:      cfg.RampDownThreshold,
+            RecoveryPeriod: cfg.RampDownRecoveryPeriod,
+        }))
+    }
+    if cfg.MaintenanceDir != "" {
+        opts = append(opts, balancer.WithMaintenanceDir(cfg.MaintenanceDir))
+    }
+    if len(cfg.ConnectAllowedPorts) > 0 {
+        opts = append(opts, balancer.WithConnectTunneling(cfg.ConnectAllowedPorts))
+    }
+    if cfg.RequestBufferPool {
+        opts = append(opts, balancer.WithRequestBufferPool(cfg.RequestBufferSize))
+    }
+    if cfg.ResponseBufferPool {
+        opts = append(opts, balancer.WithResponseBufferPool(cfg.ResponseBufferSize))
+    }
+
+    loadBalancer := balancer.NewWeightedLeastConnection(servers, opts...)
 
     ctx, cancel := context.WithCancel(context.Background())
     defer cancel()
+
+    if cfg.PrewarmRequests > 0 {
+        // Check health first so only backends that are up get warmed.
+        loadBalancer.BulkHealthCheck()
+        if err := loadBalancer.PreWarm(ctx, cfg.PrewarmRequests, nil); err != nil {
+            log.Printf("[WARN] Prewarming backends: %v", err)
+        }
+    }
     go loadBalancer.StartHealthChecks(ctx)
 
+    if len(cfg.EtcdEndpoints) > 0 {
+        etcd, err := discovery.NewEtcdDiscovery(discovery.EtcdConfig{
+            Endpoints: cfg.EtcdEndpoints,
+            Prefix:    cfg.EtcdPrefix,
+            Username:  cfg.EtcdUsername,
+            Password:  cfg.EtcdPassword,
+            Configure: configureBackend,
+        }, loadBalancer)
+        if err != nil {
+            log.Fatalf("Failed to start etcd discovery: %v", err)
+        }
+        log.Printf("Discovering backends under %s in etcd", cfg.EtcdPrefix)
+        go etcd.Run(ctx)
+    }
+
+    if cfg.DiscoverySRV != "" {
+        srvDiscovery := discovery.NewSRVDiscovery(discovery.SRVConfig{
+            Name:            cfg.DiscoverySRV,
+            RefreshInterval: cfg.DiscoverySRVInterval,
+            Configure:       configureBackend,
+        }, loadBalancer)
+        log.Printf("Discovering backends from SRV record %s", cfg.DiscoverySRV)
+        go srvDiscovery.Run(ctx)
+    }
+
+    if cfg.DiscoverCIDR != "" {
+        cidrDiscovery, err := discovery.NewCIDRDiscovery(discovery.CIDRConfig{
+            Prefix:       netip.MustParsePrefix(cfg.DiscoverCIDR),
+            Port:         cfg.DiscoverPort,
+            ScanInterval: cfg.DiscoverScanInterval,
+            DialTimeout:  cfg.DiscoverDialTimeout,
+            RemoveAfter:  cfg.DiscoverRemoveAfter,
+            Configure:    configureBackend,
+        }, loadBalancer)
+        if err != nil {
+            log.Fatalf("Failed to start CIDR discovery: %v", err)
+        }
+        log.Printf("Discovering backends in %s on port %d", cfg.DiscoverCIDR, cfg.DiscoverPort)
+        go cidrDiscovery.Run(ctx)
+    }
+
+    var weightSync *weightsync.RedisWeightSync
+    if cfg.RedisAddr != "" {
+        weightSync = weightsync.NewRedisWeightSync(weightsync.RedisWeightSyncConfig{
+            Addr:         cfg.RedisAddr,
+            Password:     cfg.RedisPassword,
+            Key:          cfg.RedisWeightsKey,
+            SyncInterval: cfg.RedisSyncInterval,
+        }, loadBalancer)
+        log.Printf("Syncing backend weights with %s in Redis at %s", cfg.RedisWeightsKey, cfg.RedisAddr)
+        go weightSync.Run(ctx)
+    }
+
+    var handler http.Handler = loadBalancer
+    if cfg.PoolCircuitBreaker {
+        breaker := balancer.NewPoolCircuitBreaker(loadBalancer, balancer.PoolCircuitBreakerConfig{
+            PoolErrorThreshold: cfg.PoolErrorThreshold,
+            Window:             cfg.PoolErrorWindow,
+            PoolOpenDuration:   cfg.PoolOpenDuration,
+        })
+        loadBalancer.AddMetricsCollector(breaker)
+        handler = breaker
+    }
+    if cfg.CollapseRequests {
+        collapser := middleware.NewRequestCollapser(handler, middleware.RequestCollapserConfig{
+            PoolName: cfg.PoolName,
+        })
+        loadBalancer.AddMetricsCollector(collapser)
+        handler = collapser
+    }
+
+    var pathStats *middleware.PathStatsMiddleware
+    if cfg.MaxTrackedPaths > 0 {
+        pathStatsCfg := middleware.PathStatsConfig{MaxPaths: cfg.MaxTrackedPaths}
+        if cfg.PathIDPattern != "" {
+            // Validate has already checked the pattern.
+            pathStatsCfg.IDPattern = regexp.MustCompile(cfg.PathIDPattern)
+        }
+        pathStats = middleware.NewPathStatsMiddleware(handler, pathStatsCfg)
+        handler = pathStats
+    }
+    if cfg.AccessLogFile != "" {
+        logFile, err := middleware.OpenLogFile(cfg.AccessLogFile)
+        if err != nil {
+            log.Fatalf("Failed to open access log: %v", err)
+        }
+        defer logFile.Close()
+
+        if cfg.LogRequestBody > 0 {
+            log.Printf("[WARN] Logging up to %d bytes of every request body. Bodies may contain credentials or personal data, and buffering them adds overhead.", cfg.LogRequestBody)
+        }
+
+        handler = middleware.NewAccessLogMiddleware(handler, middleware.AccessLogConfig{
+            Output:           logFile,
+            Format:           cfg.AccessLogFormat,
+            BodyPreviewBytes: cfg.LogRequestBody,
+            SampleRate:       cfg.LogSamplingRate,
+        })
+
+        // Reopen the access log on SIGHUP for log rotation.
+        hupChan := make(chan os.Signal, 1)
+        signal.Notify(hupChan, syscall.SIGHUP)
+        go func() {
+            for range hupChan {
+                if err := logFile.Reopen(); err != nil {
+                    log.Printf("Failed to reopen access log: %v", err)
+                    continue
+                }
+                log.Printf("Reopened access log %s", cfg.AccessLogFile)
+            }
+        }()
+    }
+
+    // The outermost middlewares, listed outermost first. The request ID is
+    // assigned before the access log and balancer see the request.
+    var middlewares []func(http.Handler) http.Handler
+    if cfg.TLSEnabled() {
+        middlewares = append(middlewares, middleware.HSTS(middleware.HSTSConfig{
+            MaxAge:            cfg.HSTSMaxAge,
+            IncludeSubdomains: cfg.HSTSIncludeSubdomains,
+            Preload:           cfg.HSTSPreload,
+        }))
+    }
+    if cfg.SecurityHeaders {
+        middlewares = append(middlewares, middleware.SecurityHeaders(middleware.SecurityHeadersConfig{
+            ContentSecurityPolicy: cfg.ContentSecurityPolicy,
+            XContentTypeOptions:   cfg.XContentTypeOptions,
+            XFrameOptions:         cfg.XFrameOptions,
+            PermissionsPolicy:     cfg.PermissionsPolicy,
+            ReferrerPolicy:        cfg.ReferrerPolicy,
+        }))
+    }
+    if cfg.RequestID {
+        middlewares = append(middlewares, middleware.RequestID())
+    }
+    if len(cfg.CORSAllowedOrigins) > 0 {
+        middlewares = append(middlewares, middleware.CORS(middleware.CORSConfig{
+            AllowedOrigins:   cfg.CORSAllowedOrigins,
+            AllowedMethods:   cfg.CORSAllowedMethods,
+            AllowedHeaders:   cfg.CORSAllowedHeaders,
+            AllowCredentials: cfg.CORSAllowCredentials,
+            MaxAge:           cfg.CORSMaxAge,
+        }))
+    }
+    if cfg.RateLimitRPS > 0 {
+        middlewares = append(middlewares, middleware.RateLimit(cfg.RateLimitRPS, cfg.RateLimitBurst))
+    }
+    if cfg.Compression {
+        middlewares = append(middlewares, middleware.Compression())
+    }
+    handler = middleware.Chain(middlewares...)(handler)
+
     srv := &http.Server{
-        Addr:         ":" + listenPort,
-        Handler:      loadBalancer,
-        ReadTimeout:  15 * time.Second,
-        WriteTimeout: 15 * time.Second,
-        IdleTimeout:  60 * time.Second,
+        Addr:         net.JoinHostPort(cfg.BindAddr, cfg.ListenPort),
+        Handler:      handler,
+        ReadTimeout:  cfg.ReadTimeout,
+        WriteTimeout: cfg.WriteTimeout,
+        IdleTimeout:  cfg.IdleTimeout,
+        // Oversized headers are rejected by net/http with 431.
+        MaxHeaderBytes: cfg.MaxHeaderBytes,
+        // Let the balancer answer OPTIONS * with its own Allow list.
+        DisableGeneralOptionsHandler: true,
+    }
+    if cfg.TLSEnabled() {
+        // Validate has already checked the TLS settings.
+        srv.TLSConfig, _ = cfg.TLSConfig()
+    }
+
+    var acmeSrv *http.Server
+    if cfg.ACMEEnabled() {
+        acmeManager := newACMEManager(cfg)
+        useACME(srv.TLSConfig, acmeManager)
+        acmeSrv = startACMEChallengeServer(cfg, acmeManager)
+        log.Printf("Obtaining certificates for %s via ACME", strings.Join(cfg.ACMEDomains, ", "))
+    }
+
+    listeners, err := listener.ListenMany(ctx, srv.Addr, cfg.ReusePortListeners, listener.Config{Backlog: cfg.ListenBacklog})
+    if err != nil {
+        log.Fatalf("Failed to listen on %s: %v", srv.Addr, err)
+    }
+
+    scheme := "http"
+    if cfg.TLSEnabled() {
+        scheme = "https"
+    }
+    host := "localhost"
+    if cfg.BindAddr != "" {
+        host = cfg.BindAddr
+    }
+    fmt.Printf("\n🚀 Starting Load Balancer on %s://%s\n", scheme, net.JoinHostPort(host, cfg.ListenPort))
+    if len(listeners) > 1 {
+        log.Printf("Accepting connections on %d SO_REUSEPORT listeners", len(listeners))
+    }
+
+    // Shutdown closes every listener passed to Serve.
+    for _, ln := range listeners {
+        go func(ln net.Listener) {
+            var err error
+            if cfg.TLSEnabled() {
+                // Both are empty with ACME, which supplies certificates
+                // through TLSConfig.GetCertificate instead.
+                err = srv.ServeTLS(ln, cfg.TLSCertFile, cfg.TLSKeyFile)
+            } else {
+                err = srv.Serve(ln)
+            }
+            if err != nil && err != http.ErrServerClosed {
+                log.Fatalf("Server failed: %v", err)
+            }
+        }(ln)
+    }
+
+    var redirectSrv *http.Server
+    if cfg.HTTPRedirectPort != "" {
+        redirectSrv = &http.Server{
+            Addr:         net.JoinHostPort(cfg.BindAddr, cfg.HTTPRedirectPort),
+            Handler:      middleware.NewHTTPRedirectHandler(httpsRedirectHost(cfg)),
+            ReadTimeout:  cfg.ReadTimeout,
+            WriteTimeout: cfg.WriteTimeout,
+            IdleTimeout:  cfg.IdleTimeout,
+        }
+
+        go func() {
+            log.Printf("Redirecting HTTP on :%s to HTTPS", cfg.HTTPRedirectPort)
+            if err := redirectSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+                log.Fatalf("HTTP redirect server failed: %v", err)
+            }
+        }()
     }
 
-    go func() {
-        fmt.Printf("\n🚀 Starting Load Balancer on http://localhost:%s\n", listenPort)
-        if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-            log.Fatalf("Server failed: %v", err)
+    var adminSrv *http.Server
+    if cfg.AdminAddr != "" {
+        adminHandler := admin.NewServer(loadBalancer, cfg)
+        adminHandler.ConfigureBackend = configureBackend
+        adminHandler.PathStats = pathStats
+        if weightSync != nil {
+            adminHandler.UpdateWeight = weightSync.UpdateWeight
+        }
+        // Validate has already checked the CIDRs.
+        allowedCIDRs, _ := cfg.AdminAllowedPrefixes()
+        adminAuth := admin.AdminAuth(admin.AuthConfig{
+            Token:        cfg.AdminToken,
+            AllowedCIDRs: allowedCIDRs,
+        })
+
+        adminSrv = &http.Server{
+            Addr:         cfg.AdminAddr,
+            Handler:      adminAuth(adminHandler),
+            ReadTimeout:  15 * time.Second,
+            WriteTimeout: 15 * time.Second,
         }
-    }()
+
+        go func() {
+            log.Printf("Admin API listening on %s", cfg.AdminAddr)
+            if err := adminSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+                log.Fatalf("Admin server failed: %v", err)
+            }
+        }()
+    }
 
     // Graceful shutdown
     sigChan := make(chan os.Signal, 1)
@@ -107,12 +621,46 @@ func main() {
     log.Println("\n🛑 Shutting down gracefully...")
     cancel() // Stop health checks
 
-    shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 30*time.Second)
-    defer shutdownCancel()
+    // Phase one: keep the listeners open but turn new requests away with
+    // 503, giving upstream balancers time to take us out of rotation.
+    loadBalancer.StartDraining()
+    if cfg.ShutdownDelay > 0 {
+        log.Printf("Rejecting new requests for %s before draining", cfg.ShutdownDelay)
+        time.Sleep(cfg.ShutdownDelay)
+    }
+
+    // Phase two: wait for in-flight requests, then close the listeners.
+    drainCtx, drainCancel := context.WithTimeout(context.Background(), cfg.ShutdownGracePeriod)
+    defer drainCancel()
+
+    if err := loadBalancer.DrainUntilIdle(drainCtx); err != nil {
+        log.Printf("Drain incomplete: %v", err)
+    }
 
-    if err := srv.Shutdown(shutdownCtx); err != nil {
+    if err := srv.Shutdown(drainCtx); err != nil {
         log.Printf("Server shutdown error: %v", err)
     }
 
+    shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
+    defer shutdownCancel()
+
+    if redirectSrv != nil {
+        if err := redirectSrv.Shutdown(shutdownCtx); err != nil {
+            log.Printf("HTTP redirect server shutdown error: %v", err)
+        }
+    }
+
+    if acmeSrv != nil {
+        if err := acmeSrv.Shutdown(shutdownCtx); err != nil {
+            log.Printf("ACME challenge server shutdown error: %v", err)
+        }
+    }
+
+    if adminSrv != nil {
+        if err := adminSrv.Shutdown(shutdownCtx); err != nil {
+            log.Printf("Admin server shutdown error: %v", err)
+        }
+    }
+
     log.Println("✅ Shutdown complete")
 }
\ No newline at end of file
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestMain runs main itself when the test binary is re-executed as the
+// go-loadbalancer command by command.
+func TestMain(m *testing.M) {
+    if os.Getenv("GO_LOADBALANCER_RUN_MAIN") == "1" {
+        main()
+        os.Exit(0)
+    }
+    os.Exit(m.Run())
+}
+
+// command returns a go-loadbalancer command with args, run by re-executing
+// the test binary.
+func command(args ...string) *exec.Cmd {
+    cmd := exec.Command(os.Args[0], args...)
+    cmd.Env = append(os.Environ(), "GO_LOADBALANCER_RUN_MAIN=1")
+    return cmd
+}
+
+// runCommand runs go-loadbalancer with args in a subprocess and returns its
+// exit code and combined output.
+func runCommand(t *testing.T, args ...string) (int, string) {
+    t.Helper()
+
+    out, err := command(args...).CombinedOutput()
+
+    var exitErr *exec.ExitError
+    switch {
+    case err == nil:
+        return 0, string(out)
+    case errors.As(err, &exitErr):
+        return exitErr.ExitCode(), string(out)
+    default:
+        t.Fatalf("running %v: %v", args, err)
+        return 0, ""
+    }
+}
+
+// startBalancer runs the load balancer in a subprocess, proxying to the
+// backends, and returns its address once it accepts connections.
+func startBalancer(t *testing.T, backends []string, args ...string) string {
+    t.Helper()
+
+    ln, err := net.Listen("tcp", "127.0.0.1:0")
+    if err != nil {
+        t.Fatal(err)
+    }
+    addr := ln.Addr().String()
+    _, port, _ := net.SplitHostPort(addr)
+    ln.Close()
+
+    args = append([]string{
+        "--config", writeCheckConfig(t, backends...),
+        "--bind-addr", "127.0.0.1",
+        "--port", port,
+        "--admin-addr", "",
+    }, args...)
+    cmd := command(args...)
+    logFile, err := os.Create(filepath.Join(t.TempDir(), "balancer.log"))
+    if err != nil {
+        t.Fatal(err)
+    }
+    cmd.Stdout, cmd.Stderr = logFile, logFile
+    if err := cmd.Start(); err != nil {
+        t.Fatal(err)
+    }
+    t.Cleanup(func() {
+        cmd.Process.Kill()
+        cmd.Wait()
+        logFile.Close()
+    })
+
+    deadline := time.Now().Add(10 * time.Second)
+    for {
+        conn, err := net.Dial("tcp", addr)
+        if err == nil {
+            conn.Close()
+            return addr
+        }
+        if time.Now().After(deadline) {
+            out, _ := os.ReadFile(logFile.Name())
+            t.Fatalf("load balancer did not start listening on %s:\n%s", addr, out)
+        }
+        time.Sleep(20 * time.Millisecond)
+    }
+}
+
+func TestWriteTimeout(t *testing.T) {
+    backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if r.URL.Path == "/slow" {
+            time.Sleep(200 * time.Millisecond)
+        }
+        w.Write([]byte("ok"))
+    }))
+    t.Cleanup(backend.Close)
+
+    addr := startBalancer(t, []string{backend.URL}, "--write-timeout", "100ms")
+
+    resp, err := http.Get(fmt.Sprintf("http://%s/", addr))
+    if err != nil {
+        t.Fatalf("fast request: %v", err)
+    }
+    io.Copy(io.Discard, resp.Body)
+    resp.Body.Close()
+
+    resp, err = http.Get(fmt.Sprintf("http://%s/slow", addr))
+    if err == nil {
+        resp.Body.Close()
+        t.Fatalf("request to a backend slower than --write-timeout got status %d, want a connection error", resp.StatusCode)
+    }
+}
+
+func TestBindAddr(t *testing.T) {
+    // Make sure IPv6 loopback works here, so a refused dial to ::1 means the
+    // balancer is not listening there.
+    ln, err := net.Listen("tcp", "[::1]:0")
+    if err != nil {
+        t.Skipf("no IPv6 loopback: %v", err)
+    }
+    ln.Close()
+
+    backend := httptest.NewServer(http.NotFoundHandler())
+    t.Cleanup(backend.Close)
+
+    // startBalancer binds to 127.0.0.1.
+    addr := startBalancer(t, []string{backend.URL})
+    _, port, _ := net.SplitHostPort(addr)
+
+    conn, err := net.Dial("tcp", net.JoinHostPort("127.0.0.1", port))
+    if err != nil {
+        t.Fatalf("dial 127.0.0.1: %v", err)
+    }
+    conn.Close()
+
+    if conn, err := net.Dial("tcp", net.JoinHostPort("::1", port)); err == nil {
+        conn.Close()
+        t.Errorf("dial ::1 succeeded with --bind-addr 127.0.0.1")
+    }
+}
@@ -0,0 +1,386 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Adi-ty/go-loadbalancer/internal/balancer"
+	"github.com/Adi-ty/go-loadbalancer/internal/config"
+	"github.com/Adi-ty/go-loadbalancer/pkg/middleware"
+	"golang.org/x/time/rate"
+)
+
+// Server exposes the admin API for inspecting and changing a running
+// balancer. It is meant to be served on its own listener, separate from
+// proxied traffic.
+type Server struct {
+    lb  *balancer.WeightedLeastConnection
+    cfg config.Config
+    mux *http.ServeMux
+
+    healthCheckMu       sync.Mutex
+    healthCheckLimiters map[string]*rate.Limiter // Keyed by backend host
+
+    // ConfigureBackend, when set, is applied to backends added through the
+    // API so they get the same settings as those configured at startup.
+    ConfigureBackend func(*balancer.Server)
+
+    // UpdateWeight, when set, is used instead of the balancer's to change a
+    // backend's weight, e.g. to share the change with other instances.
+    UpdateWeight func(ctx context.Context, host string, weight int) error
+
+    // PathStats, when set, is reported by GET /admin/stats/paths.
+    PathStats *middleware.PathStatsMiddleware
+}
+
+func NewServer(lb *balancer.WeightedLeastConnection, cfg config.Config) *Server {
+    s := &Server{
+        lb:  lb,
+        cfg: cfg,
+        mux: http.NewServeMux(),
+
+        healthCheckLimiters: make(map[string]*rate.Limiter),
+    }
+
+    s.mux.HandleFunc("GET /admin/config", s.handleConfig)
+    s.mux.HandleFunc("GET /admin/backends", s.handleListBackends)
+    s.mux.HandleFunc("POST /admin/backends", s.handleAddBackend)
+    s.mux.HandleFunc("DELETE /admin/backends/{host}", s.handleRemoveBackend)
+    s.mux.HandleFunc("PUT /admin/backends/{host}/weight", s.handleUpdateWeight)
+    s.mux.HandleFunc("POST /admin/backends/{host}/healthcheck", s.handleHealthCheck)
+    s.mux.HandleFunc("GET /admin/healthcheck/all", s.handleBulkHealthCheck)
+    s.mux.HandleFunc("POST /admin/healthcheck/dry-run", s.handleHealthCheckDryRun)
+    s.mux.HandleFunc("POST /admin/reset-stats", s.handleResetStats)
+    s.mux.HandleFunc("GET /admin/metrics", s.handleMetrics)
+    s.mux.HandleFunc("GET /admin/stats/paths", s.handlePathStats)
+
+    return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+    s.mux.ServeHTTP(w, r)
+}
+
+type backendInfo struct {
+    URL               string `json:"url"`
+    Host              string `json:"host"`
+    Weight            int    `json:"weight"`
+    Priority          int    `json:"priority"`
+    Status            string `json:"status"`
+    ActiveConnections int32  `json:"active_connections"`
+    PeakConnections   int32  `json:"peak_connections"`
+    TotalRequests     uint64 `json:"total_requests"`
+    FailureCount      uint32 `json:"failure_count"`
+}
+
+func newBackendInfo(server *balancer.Server) backendInfo {
+    return backendInfo{
+        URL:               server.URL.String(),
+        Host:              server.URL.Host,
+        Weight:            server.Weight,
+        Priority:          server.Priority,
+        Status:            server.Status(),
+        ActiveConnections: server.ActiveConnections.Load(),
+        PeakConnections:   server.PeakConnections.Load(),
+        TotalRequests:     server.RequestCount.Load(),
+        FailureCount:      server.FailureCount.Load(),
+    }
+}
+
+func (s *Server) handleListBackends(w http.ResponseWriter, r *http.Request) {
+    servers := s.lb.All()
+    backends := make([]backendInfo, 0, len(servers))
+    for _, server := range servers {
+        backends = append(backends, newBackendInfo(server))
+    }
+
+    writeJSON(w, http.StatusOK, backends)
+}
+
+func (s *Server) handleAddBackend(w http.ResponseWriter, r *http.Request) {
+    var body struct {
+        URL      string `json:"url"`
+        Weight   int    `json:"weight"`
+        Priority int    `json:"priority"`
+    }
+    if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+        writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+        return
+    }
+    if body.URL == "" {
+        writeError(w, http.StatusBadRequest, "url must be set")
+        return
+    }
+    if body.Weight == 0 {
+        body.Weight = 1
+    }
+    if body.Priority < 0 {
+        writeError(w, http.StatusBadRequest, "priority must be >= 0")
+        return
+    }
+
+    server, err := balancer.NewServer(body.URL, body.Weight)
+    if err != nil {
+        writeBalancerError(w, err)
+        return
+    }
+    if s.lb.Find(server.URL.Host) != nil {
+        writeBalancerError(w, &balancer.ErrDuplicateServer{URL: server.URL.Host})
+        return
+    }
+    server.Priority = body.Priority
+    if s.ConfigureBackend != nil {
+        s.ConfigureBackend(server)
+    }
+
+    s.lb.Add(server)
+    log.Printf("[ADMIN] Added backend %s (Weight: %d)", server.URL, server.Weight)
+
+    writeJSON(w, http.StatusCreated, newBackendInfo(server))
+}
+
+func (s *Server) handleRemoveBackend(w http.ResponseWriter, r *http.Request) {
+    host := r.PathValue("host")
+    server := s.lb.Find(host)
+    if server == nil {
+        writeBalancerError(w, &balancer.ErrServerNotFound{URL: host})
+        return
+    }
+
+    s.lb.Remove(server.URL.String())
+    log.Printf("[ADMIN] Removed backend %s", server.URL)
+
+    w.WriteHeader(http.StatusNoContent)
+}
+
+// handleConfig returns the running configuration with secrets redacted. The
+// backend list reflects the live pool so changes made through the admin API
+// show up here.
+func (s *Server) handleConfig(w http.ResponseWriter, r *http.Request) {
+    cfg := s.cfg.Redacted()
+
+    servers := s.lb.All()
+    cfg.Backends = make([]config.BackendConfig, 0, len(servers))
+    for _, server := range servers {
+        cfg.Backends = append(cfg.Backends, config.BackendConfig{
+            URL:            server.URL.String(),
+            Weight:         server.Weight,
+            Priority:       server.Priority,
+            PreserveHost:   server.PreserveHost,
+            UseSNIFromHost: server.UseSNIFromHost,
+            SLOTarget:      server.SLOTarget,
+        })
+    }
+
+    writeJSON(w, http.StatusOK, cfg)
+}
+
+func (s *Server) handleUpdateWeight(w http.ResponseWriter, r *http.Request) {
+    host := r.PathValue("host")
+
+    var body struct {
+        Weight int `json:"weight"`
+    }
+    if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+        writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+        return
+    }
+    // Look the host up first so an unknown host is a 404 whatever the
+    // weight, including when UpdateWeight is replaced.
+    if s.lb.Find(host) == nil {
+        writeBalancerError(w, &balancer.ErrServerNotFound{URL: host})
+        return
+    }
+
+    updateWeight := func(ctx context.Context, host string, weight int) error {
+        return s.lb.UpdateWeight(host, weight)
+    }
+    if s.UpdateWeight != nil {
+        updateWeight = s.UpdateWeight
+    }
+    if err := updateWeight(r.Context(), host, body.Weight); err != nil {
+        writeBalancerError(w, err)
+        return
+    }
+
+    w.WriteHeader(http.StatusNoContent)
+}
+
+type healthCheckResult struct {
+    URL       string `json:"url"`
+    Healthy   bool   `json:"healthy"`
+    LatencyMs int64  `json:"latency_ms"`
+    Error     string `json:"error,omitempty"`
+}
+
+// handleHealthCheck runs a health check on one backend immediately and
+// records the result. Each backend may be checked at most once per second.
+func (s *Server) handleHealthCheck(w http.ResponseWriter, r *http.Request) {
+    host := r.PathValue("host")
+    server := s.lb.Find(host)
+    if server == nil {
+        writeBalancerError(w, &balancer.ErrServerNotFound{URL: host})
+        return
+    }
+
+    if !s.healthCheckLimiter(host).Allow() {
+        w.Header().Set("Retry-After", "1")
+        writeError(w, http.StatusTooManyRequests, "health check for "+host+" was requested less than a second ago")
+        return
+    }
+
+    start := time.Now()
+    err := server.RunHealthCheck()
+
+    result := healthCheckResult{
+        URL:       server.URL.String(),
+        Healthy:   err == nil,
+        LatencyMs: time.Since(start).Milliseconds(),
+    }
+    if err != nil {
+        result.Error = err.Error()
+    }
+
+    writeJSON(w, http.StatusOK, result)
+}
+
+// handleBulkHealthCheck health checks every backend now and reports the
+// results: 200 if all are healthy, 207 if only some are and 503 if none are.
+// Unlike /readyz it does not rely on the cached health state.
+func (s *Server) handleBulkHealthCheck(w http.ResponseWriter, r *http.Request) {
+    start := time.Now()
+    checks := s.lb.BulkHealthCheck()
+
+    var body struct {
+        DurationMs int64               `json:"duration_ms"`
+        Results    []healthCheckResult `json:"results"`
+    }
+    body.DurationMs = time.Since(start).Milliseconds()
+    body.Results = make([]healthCheckResult, 0, len(checks))
+
+    healthy := 0
+    for _, check := range checks {
+        result := healthCheckResult{
+            URL:       check.Server.URL.String(),
+            Healthy:   check.Err == nil,
+            LatencyMs: check.Latency.Milliseconds(),
+        }
+        if check.Err != nil {
+            result.Error = check.Err.Error()
+        } else {
+            healthy++
+        }
+        body.Results = append(body.Results, result)
+    }
+
+    status := http.StatusOK
+    switch {
+    case healthy == 0 && len(checks) > 0:
+        status = http.StatusServiceUnavailable
+    case healthy < len(checks):
+        status = http.StatusMultiStatus
+    }
+    writeJSON(w, status, body)
+}
+
+type dryRunResult struct {
+    URL            string `json:"url"`
+    WouldBeHealthy bool   `json:"would_be_healthy"`
+    LatencyMs      int64  `json:"latency_ms"`
+    Error          string `json:"error,omitempty"`
+}
+
+// handleHealthCheckDryRun health checks every backend and reports the
+// outcome without changing any backend's recorded health.
+func (s *Server) handleHealthCheckDryRun(w http.ResponseWriter, r *http.Request) {
+    checks := s.lb.HealthCheckDryRun(r.Context())
+
+    results := make([]dryRunResult, 0, len(checks))
+    for _, check := range checks {
+        result := dryRunResult{
+            URL:            check.URL,
+            WouldBeHealthy: check.WouldBeHealthy,
+            LatencyMs:      check.Latency.Milliseconds(),
+        }
+        if check.Error != nil {
+            result.Error = check.Error.Error()
+        }
+        results = append(results, result)
+    }
+
+    writeJSON(w, http.StatusOK, results)
+}
+
+func (s *Server) healthCheckLimiter(host string) *rate.Limiter {
+    s.healthCheckMu.Lock()
+    defer s.healthCheckMu.Unlock()
+
+    limiter, ok := s.healthCheckLimiters[host]
+    if !ok {
+        limiter = rate.NewLimiter(rate.Every(time.Second), 1)
+        s.healthCheckLimiters[host] = limiter
+    }
+    return limiter
+}
+
+// handleResetStats zeros the runtime counters, including peak connections, of
+// every backend.
+func (s *Server) handleResetStats(w http.ResponseWriter, r *http.Request) {
+    for _, server := range s.lb.All() {
+        server.Reset()
+    }
+    w.WriteHeader(http.StatusNoContent)
+}
+
+// handleMetrics returns the pool's performance report.
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+    writeJSON(w, http.StatusOK, s.lb.PerformanceReport())
+}
+
+// handlePathStats returns the latency histogram of each tracked path.
+func (s *Server) handlePathStats(w http.ResponseWriter, r *http.Request) {
+    if s.PathStats == nil {
+        writeError(w, http.StatusNotFound, "path stats are disabled")
+        return
+    }
+    writeJSON(w, http.StatusOK, s.PathStats.Snapshot())
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+    w.Header().Set("Content-Type", "application/json")
+    w.WriteHeader(status)
+    json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+    writeJSON(w, status, map[string]string{"error": msg})
+}
+
+// writeBalancerError maps errors returned by the balancer package to HTTP
+// statuses.
+func writeBalancerError(w http.ResponseWriter, err error) {
+    var (
+        notFound      *balancer.ErrServerNotFound
+        duplicate     *balancer.ErrDuplicateServer
+        invalidWeight *balancer.ErrInvalidWeight
+        invalidURL    *balancer.ErrInvalidURL
+    )
+
+    status := http.StatusInternalServerError
+    switch {
+    case errors.As(err, &notFound):
+        status = http.StatusNotFound
+    case errors.As(err, &duplicate):
+        status = http.StatusConflict
+    case errors.As(err, &invalidWeight), errors.As(err, &invalidURL):
+        status = http.StatusBadRequest
+    case errors.Is(err, balancer.ErrNoHealthyBackend):
+        status = http.StatusServiceUnavailable
+    }
+    writeError(w, status, err.Error())
+}
@@ -0,0 +1,281 @@
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Adi-ty/go-loadbalancer/internal/balancer"
+	"github.com/Adi-ty/go-loadbalancer/internal/config"
+	"github.com/Adi-ty/go-loadbalancer/pkg/middleware"
+	lbtesting "github.com/Adi-ty/go-loadbalancer/pkg/testing"
+)
+
+func newTestAdmin(t *testing.T) *Server {
+    t.Helper()
+
+    server, err := balancer.NewServer("http://localhost:8081", 1)
+    if err != nil {
+        t.Fatalf("NewServer: %v", err)
+    }
+    return NewServer(balancer.NewWeightedLeastConnection([]*balancer.Server{server}), config.Default())
+}
+
+func TestErrorStatuses(t *testing.T) {
+    tests := []struct {
+        name   string
+        method string
+        path   string
+        body   string
+        want   int
+    }{
+        {name: "invalid weight", method: http.MethodPut, path: "/admin/backends/localhost:8081/weight", body: `{"weight":0}`, want: http.StatusBadRequest},
+        {name: "unknown host", method: http.MethodPut, path: "/admin/backends/localhost:9999/weight", body: `{"weight":5}`, want: http.StatusNotFound},
+        {name: "unknown host with invalid weight", method: http.MethodPut, path: "/admin/backends/localhost:9999/weight", body: `{"weight":0}`, want: http.StatusNotFound},
+        {name: "weight updated", method: http.MethodPut, path: "/admin/backends/localhost:8081/weight", body: `{"weight":5}`, want: http.StatusNoContent},
+        {name: "invalid URL", method: http.MethodPost, path: "/admin/backends", body: `{"url":"localhost"}`, want: http.StatusBadRequest},
+        {name: "duplicate backend", method: http.MethodPost, path: "/admin/backends", body: `{"url":"http://localhost:8081"}`, want: http.StatusConflict},
+        {name: "remove unknown backend", method: http.MethodDelete, path: "/admin/backends/localhost:9999", want: http.StatusNotFound},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            rec := httptest.NewRecorder()
+            newTestAdmin(t).ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body)))
+            if rec.Code != tt.want {
+                t.Errorf("%s %s = %d, want %d: %s", tt.method, tt.path, rec.Code, tt.want, rec.Body)
+            }
+        })
+    }
+}
+
+func TestWriteBalancerError(t *testing.T) {
+    tests := []struct {
+        err  error
+        want int
+    }{
+        {err: &balancer.ErrServerNotFound{URL: "localhost:9999"}, want: http.StatusNotFound},
+        {err: &balancer.ErrDuplicateServer{URL: "localhost:8081"}, want: http.StatusConflict},
+        {err: &balancer.ErrInvalidWeight{URL: "localhost:8081", Weight: 0}, want: http.StatusBadRequest},
+        {err: &balancer.ErrInvalidURL{Raw: "localhost"}, want: http.StatusBadRequest},
+        {err: balancer.ErrNoHealthyBackend, want: http.StatusServiceUnavailable},
+        {err: fmt.Errorf("selecting backend: %w", balancer.ErrNoHealthyBackend), want: http.StatusServiceUnavailable},
+    }
+
+    for _, tt := range tests {
+        rec := httptest.NewRecorder()
+        writeBalancerError(rec, tt.err)
+        if rec.Code != tt.want {
+            t.Errorf("writeBalancerError(%v) = %d, want %d", tt.err, rec.Code, tt.want)
+        }
+    }
+}
+
+func TestConfigReflectsWeightChanges(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "config.yaml")
+    yaml := `
+admin_token: s3cret
+redis_password: hunter2
+backends:
+  - url: http://localhost:8081
+    weight: 1
+    slo_target: 0.99
+  - url: http://localhost:8082
+    weight: 2
+`
+    if err := os.WriteFile(path, []byte(yaml), 0o600); err != nil {
+        t.Fatal(err)
+    }
+    cfg := config.Default()
+    if err := config.LoadFile(path, &cfg); err != nil {
+        t.Fatalf("LoadFile: %v", err)
+    }
+
+    var servers []*balancer.Server
+    for _, backend := range cfg.Backends {
+        server, err := balancer.NewServer(backend.URL, backend.Weight)
+        if err != nil {
+            t.Fatalf("NewServer: %v", err)
+        }
+        server.SLOTarget = backend.SLOTarget
+        servers = append(servers, server)
+    }
+    admin := NewServer(balancer.NewWeightedLeastConnection(servers), cfg)
+
+    rec := httptest.NewRecorder()
+    admin.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/admin/backends/localhost:8082/weight", strings.NewReader(`{"weight":7}`)))
+    if rec.Code != http.StatusNoContent {
+        t.Fatalf("weight update = %d: %s", rec.Code, rec.Body)
+    }
+
+    rec = httptest.NewRecorder()
+    admin.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/config", nil))
+    var got config.Config
+    if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+        t.Fatalf("GET /admin/config: %v: %s", err, rec.Body)
+    }
+
+    weights := make(map[string]int)
+    sloTargets := make(map[string]float64)
+    for _, backend := range got.Backends {
+        weights[backend.URL] = backend.Weight
+        sloTargets[backend.URL] = backend.SLOTarget
+    }
+    if weights["http://localhost:8081"] != 1 || weights["http://localhost:8082"] != 7 {
+        t.Errorf("backend weights = %v, want localhost:8082 updated to 7", weights)
+    }
+    if sloTargets["http://localhost:8081"] != 0.99 || sloTargets["http://localhost:8082"] != 0 {
+        t.Errorf("backend SLO targets = %v, want 0.99 kept for localhost:8081", sloTargets)
+    }
+    if got.AdminToken != "[REDACTED]" || got.RedisPassword != "[REDACTED]" {
+        t.Errorf("secrets served as admin_token %q, redis_password %q, want them redacted", got.AdminToken, got.RedisPassword)
+    }
+}
+
+// newBackendAdmin returns an admin server for a pool holding one server
+// proxying to backend.
+func newBackendAdmin(t *testing.T, backend *lbtesting.FakeBackend) (*Server, *balancer.Server) {
+    t.Helper()
+
+    server, err := balancer.NewServer(backend.URL, 1)
+    if err != nil {
+        t.Fatalf("NewServer: %v", err)
+    }
+    return NewServer(balancer.NewWeightedLeastConnection([]*balancer.Server{server}), config.Default()), server
+}
+
+func TestHealthCheckEndpoint(t *testing.T) {
+    backend := lbtesting.NewFakeBackend(t)
+    admin, server := newBackendAdmin(t, backend)
+    path := "/admin/backends/" + server.URL.Host + "/healthcheck"
+
+    backend.SetHealthy(false)
+    server.RunHealthCheck()
+    if server.IsHealthy.Load() {
+        t.Fatal("backend still healthy after a failed check")
+    }
+    backend.SetHealthy(true)
+
+    rec := httptest.NewRecorder()
+    admin.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, nil))
+    var result healthCheckResult
+    if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+        t.Fatalf("POST %s = %d: %v: %s", path, rec.Code, err, rec.Body)
+    }
+    if !result.Healthy || result.URL != server.URL.String() {
+        t.Errorf("result = %+v, want %s healthy", result, server.URL)
+    }
+    if !server.IsHealthy.Load() {
+        t.Errorf("on-demand check did not record the backend as healthy")
+    }
+
+    rec = httptest.NewRecorder()
+    admin.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, nil))
+    if rec.Code != http.StatusTooManyRequests {
+        t.Errorf("second check within a second = %d, want %d", rec.Code, http.StatusTooManyRequests)
+    }
+}
+
+func TestPathStatsEndpoint(t *testing.T) {
+    admin := newTestAdmin(t)
+    rec := httptest.NewRecorder()
+    admin.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/stats/paths", nil))
+    if rec.Code != http.StatusNotFound {
+        t.Errorf("GET /admin/stats/paths without path stats = %d, want %d", rec.Code, http.StatusNotFound)
+    }
+
+    admin.PathStats = middleware.NewPathStatsMiddleware(http.NotFoundHandler(), middleware.PathStatsConfig{})
+    paths := []string{"/", "/users", "/users/1", "/orders", "/health?full=1"}
+    for _, path := range paths {
+        admin.PathStats.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+    }
+
+    rec = httptest.NewRecorder()
+    admin.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/stats/paths", nil))
+    var stats []middleware.PathStat
+    if err := json.NewDecoder(rec.Body).Decode(&stats); err != nil {
+        t.Fatal(err)
+    }
+    var got []string
+    for _, stat := range stats {
+        got = append(got, stat.Path)
+    }
+    want := []string{"/", "/health", "/orders", "/users", "/users/{id}"}
+    if strings.Join(got, " ") != strings.Join(want, " ") {
+        t.Errorf("paths = %q, want %q", got, want)
+    }
+}
+
+func TestBulkHealthCheckEndpoint(t *testing.T) {
+    tests := []struct {
+        name    string
+        healthy []bool
+        want    int
+    }{
+        {"all healthy", []bool{true, true}, http.StatusOK},
+        {"mixed", []bool{true, false}, http.StatusMultiStatus},
+        {"none healthy", []bool{false, false}, http.StatusServiceUnavailable},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            var servers []*balancer.Server
+            for _, healthy := range tt.healthy {
+                backend := lbtesting.NewFakeBackend(t)
+                backend.SetHealthy(healthy)
+                server, err := balancer.NewServer(backend.URL, 1)
+                if err != nil {
+                    t.Fatalf("NewServer: %v", err)
+                }
+                servers = append(servers, server)
+            }
+            admin := NewServer(balancer.NewWeightedLeastConnection(servers), config.Default())
+
+            rec := httptest.NewRecorder()
+            admin.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/healthcheck/all", nil))
+            if rec.Code != tt.want {
+                t.Errorf("status = %d, want %d", rec.Code, tt.want)
+            }
+
+            var body struct {
+                Results []healthCheckResult `json:"results"`
+            }
+            if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+                t.Fatalf("%v: %s", err, rec.Body)
+            }
+            if len(body.Results) != len(servers) {
+                t.Fatalf("results = %+v, want one per backend", body.Results)
+            }
+            for i, result := range body.Results {
+                if result.URL != servers[i].URL.String() || result.Healthy != tt.healthy[i] || (result.Error == "") != tt.healthy[i] {
+                    t.Errorf("result %d = %+v, want %s healthy %v", i, result, servers[i].URL, tt.healthy[i])
+                }
+            }
+        })
+    }
+}
+
+func TestHealthCheckDryRunEndpoint(t *testing.T) {
+    backend := lbtesting.NewFakeBackend(t)
+    admin, server := newBackendAdmin(t, backend)
+    backend.SetHealthy(false)
+
+    rec := httptest.NewRecorder()
+    admin.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/admin/healthcheck/dry-run", nil))
+    var results []dryRunResult
+    if err := json.Unmarshal(rec.Body.Bytes(), &results); err != nil {
+        t.Fatalf("POST /admin/healthcheck/dry-run = %d: %v: %s", rec.Code, err, rec.Body)
+    }
+    if len(results) != 1 || results[0].WouldBeHealthy || results[0].Error == "" {
+        t.Errorf("results = %+v, want the failing backend reported as would be unhealthy", results)
+    }
+    if !server.IsHealthy.Load() || server.FailureCount.Load() != 0 {
+        t.Errorf("healthy = %v, failures = %d after a dry run; want the backend's state untouched",
+            server.IsHealthy.Load(), server.FailureCount.Load())
+    }
+}
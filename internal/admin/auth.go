@@ -0,0 +1,69 @@
+package admin
+
+import (
+	"crypto/subtle"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// AuthConfig restricts access to the admin API. Empty fields disable the
+// corresponding check; when both are set a request must pass both.
+type AuthConfig struct {
+    // Token is the bearer token clients must send as
+    // "Authorization: Bearer <token>".
+    Token string
+
+    // AllowedCIDRs lists the networks clients may connect from.
+    AllowedCIDRs []netip.Prefix
+}
+
+// AdminAuth rejects requests from addresses outside cfg.AllowedCIDRs with 403
+// and requests without the expected bearer token with 401. The client
+// address is taken from the connection, not from forwarding headers.
+func AdminAuth(cfg AuthConfig) func(http.Handler) http.Handler {
+    return func(next http.Handler) http.Handler {
+        return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+            if len(cfg.AllowedCIDRs) > 0 && !remoteAddrAllowed(r.RemoteAddr, cfg.AllowedCIDRs) {
+                writeError(w, http.StatusForbidden, "client address not allowed")
+                return
+            }
+            if cfg.Token != "" && !validBearerToken(r.Header.Get("Authorization"), cfg.Token) {
+                w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+                writeError(w, http.StatusUnauthorized, "missing or invalid bearer token")
+                return
+            }
+            next.ServeHTTP(w, r)
+        })
+    }
+}
+
+func remoteAddrAllowed(remoteAddr string, allowed []netip.Prefix) bool {
+    host, _, err := net.SplitHostPort(remoteAddr)
+    if err != nil {
+        host = remoteAddr
+    }
+    addr, err := netip.ParseAddr(host)
+    if err != nil {
+        return false
+    }
+    addr = addr.Unmap()
+
+    for _, prefix := range allowed {
+        if prefix.Contains(addr) {
+            return true
+        }
+    }
+    return false
+}
+
+// validBearerToken compares the token in an Authorization header against
+// want in constant time.
+func validBearerToken(header, want string) bool {
+    scheme, token, ok := strings.Cut(header, " ")
+    if !ok || !strings.EqualFold(scheme, "Bearer") {
+        return false
+    }
+    return subtle.ConstantTimeCompare([]byte(strings.TrimSpace(token)), []byte(want)) == 1
+}
@@ -0,0 +1,59 @@
+package admin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func TestAdminAuth(t *testing.T) {
+    const token = "s3cret"
+    allowed := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("::1/128")}
+
+    tests := []struct {
+        name          string
+        cfg           AuthConfig
+        remoteAddr    string
+        authorization string
+        want          int
+    }{
+        {"no checks", AuthConfig{}, "192.0.2.1:1234", "", http.StatusOK},
+
+        {"token only, valid", AuthConfig{Token: token}, "192.0.2.1:1234", "Bearer s3cret", http.StatusOK},
+        {"token only, lower-case scheme", AuthConfig{Token: token}, "192.0.2.1:1234", "bearer s3cret", http.StatusOK},
+        {"token only, missing", AuthConfig{Token: token}, "192.0.2.1:1234", "", http.StatusUnauthorized},
+        {"token only, wrong", AuthConfig{Token: token}, "192.0.2.1:1234", "Bearer s3cre", http.StatusUnauthorized},
+        {"token only, basic auth", AuthConfig{Token: token}, "192.0.2.1:1234", "Basic s3cret", http.StatusUnauthorized},
+
+        {"CIDRs only, inside", AuthConfig{AllowedCIDRs: allowed}, "10.1.2.3:1234", "", http.StatusOK},
+        {"CIDRs only, IPv6 inside", AuthConfig{AllowedCIDRs: allowed}, "[::1]:1234", "", http.StatusOK},
+        {"CIDRs only, IPv4-mapped inside", AuthConfig{AllowedCIDRs: allowed}, "[::ffff:10.1.2.3]:1234", "", http.StatusOK},
+        {"CIDRs only, outside", AuthConfig{AllowedCIDRs: allowed}, "192.0.2.1:1234", "", http.StatusForbidden},
+
+        {"both, passing both", AuthConfig{Token: token, AllowedCIDRs: allowed}, "10.1.2.3:1234", "Bearer s3cret", http.StatusOK},
+        {"both, wrong token", AuthConfig{Token: token, AllowedCIDRs: allowed}, "10.1.2.3:1234", "Bearer nope", http.StatusUnauthorized},
+        {"both, outside", AuthConfig{Token: token, AllowedCIDRs: allowed}, "192.0.2.1:1234", "Bearer s3cret", http.StatusForbidden},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            handler := AdminAuth(tt.cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+            req := httptest.NewRequest(http.MethodGet, "/admin/backends", nil)
+            req.RemoteAddr = tt.remoteAddr
+            if tt.authorization != "" {
+                req.Header.Set("Authorization", tt.authorization)
+            }
+            rec := httptest.NewRecorder()
+            handler.ServeHTTP(rec, req)
+
+            if rec.Code != tt.want {
+                t.Errorf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+            }
+            if rec.Code == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") == "" {
+                t.Errorf("401 without a WWW-Authenticate header")
+            }
+        })
+    }
+}
@@ -0,0 +1,118 @@
+package balancer
+
+import (
+	"fmt"
+	"hash/fnv"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// AttributeHash pins requests to backends by hashing a request attribute, so
+// every request carrying the same user ID, session cookie, etc. lands on the
+// same server while the healthy set is stable.
+type AttributeHash struct {
+    *BackendPool
+
+    hashOn string
+}
+
+// AttributeHashOption configures an AttributeHash.
+type AttributeHashOption func(*AttributeHash)
+
+// WithHashOn selects the request attribute to hash: "ip", "header:<name>",
+// "cookie:<name>" or "query:<name>". Requests missing the attribute fall
+// back to hashing the client IP.
+func WithHashOn(attribute string) AttributeHashOption {
+    return func(ah *AttributeHash) {
+        ah.hashOn = attribute
+    }
+}
+
+func NewAttributeHash(servers []*Server, opts ...AttributeHashOption) (*AttributeHash, error) {
+    ah := &AttributeHash{
+        BackendPool: NewBackendPool(servers),
+        hashOn:      "ip",
+    }
+    for _, opt := range opts {
+        opt(ah)
+    }
+
+    if err := validateHashOn(ah.hashOn); err != nil {
+        return nil, err
+    }
+    return ah, nil
+}
+
+func validateHashOn(hashOn string) error {
+    if hashOn == "ip" {
+        return nil
+    }
+
+    kind, name, ok := strings.Cut(hashOn, ":")
+    if !ok || name == "" {
+        return fmt.Errorf("invalid hash attribute %q. Expected: ip, header:<name>, cookie:<name> or query:<name>", hashOn)
+    }
+    switch kind {
+    case "header", "cookie", "query":
+        return nil
+    }
+    return fmt.Errorf("invalid hash attribute %q. Expected: ip, header:<name>, cookie:<name> or query:<name>", hashOn)
+}
+
+// hashKey extracts the configured attribute from r, falling back to the
+// client IP when it is absent.
+func (ah *AttributeHash) hashKey(r *http.Request) string {
+    kind, name, _ := strings.Cut(ah.hashOn, ":")
+
+    var value string
+    switch kind {
+    case "header":
+        value = r.Header.Get(name)
+    case "cookie":
+        if cookie, err := r.Cookie(name); err == nil {
+            value = cookie.Value
+        }
+    case "query":
+        value = r.URL.Query().Get(name)
+    }
+
+    if value != "" {
+        return value
+    }
+    return remoteIP(r)
+}
+
+// remoteIP returns the client IP of r without the port.
+func remoteIP(r *http.Request) string {
+    host, _, err := net.SplitHostPort(r.RemoteAddr)
+    if err != nil {
+        return r.RemoteAddr
+    }
+    return host
+}
+
+// NextServer returns the healthy backend key hashes to, or nil if none is
+// available.
+func (ah *AttributeHash) NextServer(key string) *Server {
+    healthy := ah.Healthy()
+    if len(healthy) == 0 {
+        return nil
+    }
+
+    h := fnv.New32a()
+    h.Write([]byte(key))
+    return healthy[h.Sum32()%uint32(len(healthy))]
+}
+
+func (ah *AttributeHash) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+    server := ah.NextServer(ah.hashKey(r))
+    if server == nil {
+        log.Printf("[ERROR] No healthy backend available for request %s %s", r.Method, r.URL.Path)
+        http.Error(w, "Service Unavailable: No healthy backend servers available.", http.StatusServiceUnavailable)
+        return
+    }
+
+    server.serve(w, r)
+}
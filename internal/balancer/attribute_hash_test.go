@@ -0,0 +1,93 @@
+package balancer
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// hitBackends returns the indexes of the backends in ab that received
+// requests.
+func (ab *algorithmBackends) hitBackends() []int {
+    var hit []int
+    for i := range ab.hits {
+        if ab.hits[i].Load() > 0 {
+            hit = append(hit, i)
+        }
+    }
+    return hit
+}
+
+func TestAttributeHashSameValueSameBackend(t *testing.T) {
+    tests := []struct {
+        hashOn string
+        set    func(r *http.Request, value string)
+    }{
+        {hashOn: "header:X-User-ID", set: func(r *http.Request, value string) { r.Header.Set("X-User-ID", value) }},
+        {hashOn: "cookie:session", set: func(r *http.Request, value string) { r.AddCookie(&http.Cookie{Name: "session", Value: value}) }},
+        {hashOn: "query:user_id", set: func(r *http.Request, value string) { r.URL.RawQuery = "user_id=" + value }},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.hashOn, func(t *testing.T) {
+            ab := newAlgorithmBackends(t, []int{1, 1, 1, 1}, false)
+            ah, err := NewAttributeHash(ab.servers, WithHashOn(tt.hashOn))
+            if err != nil {
+                t.Fatalf("NewAttributeHash: %v", err)
+            }
+
+            // algorithmRequest varies the client IP with every request.
+            for i := 0; i < 1000; i++ {
+                r := algorithmRequest(i)
+                tt.set(r, "user-42")
+                serveRequest(ah, r)
+            }
+            if hit := ab.hitBackends(); len(hit) != 1 {
+                t.Errorf("1000 requests for one user reached backends %v, want exactly one", hit)
+            }
+        })
+    }
+}
+
+func TestAttributeHashSpreadsValues(t *testing.T) {
+    ab := newAlgorithmBackends(t, []int{1, 1, 1, 1}, false)
+    ah, err := NewAttributeHash(ab.servers, WithHashOn("header:X-User-ID"))
+    if err != nil {
+        t.Fatalf("NewAttributeHash: %v", err)
+    }
+
+    for i := 0; i < 200; i++ {
+        r := httptest.NewRequest(http.MethodGet, "/", nil)
+        r.Header.Set("X-User-ID", fmt.Sprintf("user-%d", i))
+        serveRequest(ah, r)
+    }
+    if hit := ab.hitBackends(); len(hit) != 4 {
+        t.Errorf("200 users reached backends %v, want all 4", hit)
+    }
+}
+
+func TestAttributeHashFallsBackToIP(t *testing.T) {
+    ab := newAlgorithmBackends(t, []int{1, 1, 1, 1}, false)
+    ah, err := NewAttributeHash(ab.servers, WithHashOn("header:X-User-ID"))
+    if err != nil {
+        t.Fatalf("NewAttributeHash: %v", err)
+    }
+
+    for i := 0; i < 100; i++ {
+        r := httptest.NewRequest(http.MethodGet, "/", nil)
+        r.RemoteAddr = fmt.Sprintf("192.0.2.7:%d", 40000+i)
+        serveRequest(ah, r)
+    }
+    if hit := ab.hitBackends(); len(hit) != 1 {
+        t.Errorf("requests without the header from one IP reached backends %v, want exactly one", hit)
+    }
+}
+
+func TestAttributeHashInvalidAttribute(t *testing.T) {
+    for _, hashOn := range []string{"", "header:", "body:x", "cookie"} {
+        if _, err := NewAttributeHash(nil, WithHashOn(hashOn)); err == nil {
+            t.Errorf("NewAttributeHash accepted hash attribute %q", hashOn)
+        }
+    }
+}
@@ -0,0 +1,38 @@
+package balancer
+
+import "time"
+
+// healthBackoffThreshold is the number of consecutive failures after which a
+// backend is probed less often.
+const healthBackoffThreshold = 2
+
+// healthCheckDue reports whether server should be probed in the cycle that
+// started at now. Half an interval of slack absorbs ticker jitter.
+func (wlc *WeightedLeastConnection) healthCheckDue(server *Server, now time.Time) bool {
+    next := server.nextCheckTime.Load()
+    return next == 0 || now.Add(wlc.healthCheckInterval/2).UnixNano() >= next
+}
+
+// scheduleNextHealthCheck backs off probing of a backend that keeps failing,
+// doubling the interval for each failure past healthBackoffThreshold up to
+// maxHealthCheckInterval. A passing check restores the base interval.
+func (wlc *WeightedLeastConnection) scheduleNextHealthCheck(server *Server, now time.Time, healthy bool) {
+    failures := int(server.FailureCount.Load())
+    if healthy || failures <= healthBackoffThreshold {
+        server.nextCheckTime.Store(0)
+        return
+    }
+
+    maxInterval := wlc.maxHealthCheckInterval
+    if maxInterval <= 0 {
+        maxInterval = 5 * wlc.healthCheckInterval
+    }
+
+    interval := wlc.healthCheckInterval
+    for i := healthBackoffThreshold; i < failures && interval < maxInterval; i++ {
+        interval *= 2
+    }
+    interval = min(interval, maxInterval)
+
+    server.nextCheckTime.Store(now.Add(interval).UnixNano())
+}
@@ -0,0 +1,61 @@
+package balancer
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHealthCheckBackoff(t *testing.T) {
+    var failing atomic.Bool
+    failing.Store(true)
+    backend := newHealthBackend(t, func(w http.ResponseWriter, r *http.Request) {
+        if failing.Load() {
+            w.WriteHeader(http.StatusServiceUnavailable)
+        }
+    })
+
+    const interval = 100 * time.Millisecond
+    server := newTestServer(t, backend.URL, 1)
+    wlc := NewWeightedLeastConnection([]*Server{server},
+        WithHealthCheckInterval(interval),
+        WithMaxHealthCheckInterval(800*time.Millisecond),
+    )
+
+    // probe runs a cycle every interval on a simulated clock until the
+    // backend has been probed n times, returning the gaps between probes.
+    now := time.Now()
+    probe := func(n int) []time.Duration {
+        var gaps []time.Duration
+        last := now
+        for probes := 0; probes < n; now = now.Add(interval) {
+            if !wlc.healthCheckDue(server, now) {
+                continue
+            }
+            if probes > 0 {
+                gaps = append(gaps, now.Sub(last))
+            }
+            wlc.checkServer(context.Background(), server, now)
+            last = now
+            probes++
+        }
+        return gaps
+    }
+
+    want := []time.Duration{100, 100, 200, 400, 800, 800, 800}
+    for i := range want {
+        want[i] *= time.Millisecond
+    }
+    if gaps := probe(8); !slices.Equal(gaps, want) {
+        t.Errorf("gaps between probes of a failing backend = %v, want %v", gaps, want)
+    }
+
+    // The next probe passes and restores the base interval.
+    failing.Store(false)
+    if gaps := probe(4); !slices.Equal(gaps, []time.Duration{interval, interval, interval}) {
+        t.Errorf("gaps between probes after recovery = %v, want the base interval once a check passes", gaps)
+    }
+}
@@ -2,11 +2,19 @@ package balancer
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"runtime"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/Adi-ty/go-loadbalancer/internal/events"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/time/rate"
 )
 
 type LoadBalancer interface {
@@ -14,20 +22,223 @@ type LoadBalancer interface {
     StartHealthChecks(ctx context.Context)
 }
 
+// DefaultGlobalOptionsMethods is the Allow header sent in response to
+// OPTIONS *.
+const DefaultGlobalOptionsMethods = "GET, HEAD, POST, PUT, DELETE, OPTIONS, PATCH"
+
+// DefaultHealthCheckInterval is how often every backend is health checked.
+const DefaultHealthCheckInterval = 10 * time.Second
+
+// DefaultMaxQueueWait is how long a request waits for a backend below
+// MaxConnections before failing with 503.
+const DefaultMaxQueueWait = 5 * time.Second
+
+// healthyPollInterval is how often a request parked by WaitForHealthy checks
+// for a recovered backend.
+const healthyPollInterval = 200 * time.Millisecond
+
+// BackendRecorder is implemented by response writers that want to know which
+// backend served the request, e.g. access loggers.
+type BackendRecorder interface {
+    RecordBackend(host string)
+}
+
 type WeightedLeastConnection struct {
-    servers       []*Server
-    mu            sync.RWMutex
+    *BackendPool
+
     totalRequests uint64
     mu2           sync.Mutex // For totalRequests
+    startTime     time.Time
+
+    // HealthCheckRateLimiter, when set, is waited on before each health
+    // check request. nil means unlimited.
+    HealthCheckRateLimiter *rate.Limiter
+
+    // WaitForHealthy is how long a request waits for a backend to recover
+    // when none is healthy before failing with 503. Zero fails immediately.
+    WaitForHealthy time.Duration
+
+    // WatchdogInterval is how long the health check loop may go without
+    // completing a cycle before it is considered stalled and restarted.
+    // Zero means three health check intervals; negative disables the
+    // watchdog.
+    WatchdogInterval time.Duration
+    lastHealthCycle  atomic.Int64  // Unix nanoseconds
+    healthLoopGen    atomic.Uint64 // Generation of the current health check loop
+
+    healthCheckInterval    time.Duration
+    maxHealthCheckInterval time.Duration // Backoff cap for failing backends; zero means 5x the interval
+    healthCheckConcurrency int
+    healthCycleDuration    atomic.Int64 // Nanoseconds taken by the last complete cycle
+
+    // FailFast answers 503 with Retry-After when every backend is at
+    // MaxConnections instead of queueing the request for a free slot.
+    FailFast bool
+
+    // MaxQueueWait is how long a request queued for a free slot waits
+    // before failing with 503.
+    MaxQueueWait time.Duration
+
+    maxURLBytes       int
+    propagateDeadline bool
+    stripTrailers     bool
+
+    // maxRequestBody limits request bodies (zero means unlimited).
+    // bufferUnknownLength reads bodies sent without Content-Length into
+    // memory so they are forwarded with one.
+    maxRequestBody      int64
+    bufferUnknownLength bool
+
+    // latencyBudget charges time spent queueing and selecting a backend
+    // against the client's X-Request-Deadline-Ms or Grpc-Timeout budget.
+    latencyBudget      bool
+    minRemainingBudget time.Duration
+
+    trafficShaping []TrafficShapingRule
+
+    events      *events.EventBus
+    maintenance http.Handler
+
+    failover         LoadBalancer
+    failoverRequests atomic.Uint64
+
+    globalOptionsAllow string
+
+    // connectAllowedPorts are the target ports CONNECT requests may tunnel
+    // to. Empty disables CONNECT.
+    connectAllowedPorts []int
+
+    maxRetries       int
+    maxRetryDuration time.Duration // Zero means no limit
+    retryMethods     []string
+    classifyError    ProxyErrorClassifier
+
+    requestBuffers  *bytePool
+    responseBuffers *bytePool
+
+    draining atomic.Bool
+
+    diagnosticHeaders bool
+
+    scorer BackendScorer
+
+    // rampDown, when set, lowers the effective weight of servers with a
+    // high passive error rate.
+    rampDown *RampDownConfig
+
+    poolName           string
+    registerer         prometheus.Registerer
+    extraCollectors    []prometheus.Collector
+    metricsHandler     http.Handler
+    metricsHandlerOnce sync.Once
+}
+
+func NewWeightedLeastConnection(servers []*Server, opts ...Option) *WeightedLeastConnection {
+    wlc := &WeightedLeastConnection{
+        BackendPool: NewBackendPool(servers),
+        startTime:   time.Now(),
+
+        globalOptionsAllow: DefaultGlobalOptionsMethods,
+        classifyError:      DefaultProxyErrorClassifier,
+        scorer:             DefaultScorer{},
+
+        MaxQueueWait: DefaultMaxQueueWait,
+
+        healthCheckInterval:    DefaultHealthCheckInterval,
+        healthCheckConcurrency: runtime.NumCPU(),
+    }
+    for _, opt := range opts {
+        opt(wlc)
+    }
+    for _, server := range wlc.servers {
+        wlc.adopt(server)
+    }
+    return wlc
+}
+
+// adopt applies the balancer's per-server settings to a server joining the
+// pool.
+func (wlc *WeightedLeastConnection) adopt(server *Server) {
+    if wlc.responseBuffers != nil && server.ReverseProxy.BufferPool == nil {
+        server.ReverseProxy.BufferPool = wlc.responseBuffers
+    }
+    server.classifyError = wlc.classifyError
+    server.requestBuffers = wlc.requestBuffers
+}
+
+// Add puts server into the pool.
+func (wlc *WeightedLeastConnection) Add(server *Server) {
+    wlc.adopt(server)
+    wlc.BackendPool.Add(server)
+    wlc.publish(events.Event{Type: events.BackendAdded, Backend: server.URL.String()})
 }
 
-func NewWeightedLeastConnection(servers []*Server) *WeightedLeastConnection {
-    return &WeightedLeastConnection{
-        servers: servers,
+// Remove takes the server with the given URL out of the pool and returns it,
+// or nil if there is no such server. The server's idle connections are
+// closed; requests in flight to it complete.
+func (wlc *WeightedLeastConnection) Remove(url string) *Server {
+    server := wlc.BackendPool.Remove(url)
+    if server != nil {
+        server.closeIdleConnections()
+        wlc.publish(events.Event{Type: events.BackendRemoved, Backend: url})
     }
+    return server
 }
 
+func (wlc *WeightedLeastConnection) publish(e events.Event) {
+    if wlc.events != nil {
+        wlc.events.Publish(e)
+    }
+}
+
+// TotalRequests returns how many requests have been forwarded to a backend.
+func (wlc *WeightedLeastConnection) TotalRequests() uint64 {
+    wlc.mu2.Lock()
+    defer wlc.mu2.Unlock()
+
+    return wlc.totalRequests
+}
+
+// UpdateWeight changes the weight of the backend identified by host. An
+// unknown host is reported before an invalid weight.
+func (wlc *WeightedLeastConnection) UpdateWeight(host string, weight int) error {
+    wlc.mu.Lock()
+    defer wlc.mu.Unlock()
+
+    for _, server := range wlc.servers {
+        if server.URL.Host == host {
+            if err := validateWeight(host, weight); err != nil {
+                return err
+            }
+            server.Weight = weight
+            log.Printf("[ADMIN] Updated weight of %s to %d", host, weight)
+            return nil
+        }
+    }
+    return &ErrServerNotFound{URL: host}
+}
+
+// NextServer returns the available server with the lowest score among those
+// of the highest Priority that has one, or nil if none is available. A
+// non-nil result was healthy and not manually disabled, at capacity or
+// backing off when it was chosen.
 func (wlc *WeightedLeastConnection) NextServer() *Server {
+    return wlc.nextServer(nil)
+}
+
+// SelectServer returns the server the next request would be sent to, or
+// ErrNoHealthyBackend if none is available.
+func (wlc *WeightedLeastConnection) SelectServer() (*Server, error) {
+    server := wlc.NextServer()
+    if server == nil {
+        return nil, ErrNoHealthyBackend
+    }
+    return server, nil
+}
+
+// nextServer picks the server with the lowest score within the highest
+// priority level that has an eligible server, skipping any in exclude.
+func (wlc *WeightedLeastConnection) nextServer(exclude map[*Server]bool) *Server {
     wlc.mu.RLock()
     defer wlc.mu.RUnlock()
 
@@ -36,12 +247,24 @@ func (wlc *WeightedLeastConnection) NextServer() *Server {
     }
 
     var bestServer *Server
-    bestRatio := 1e18
+    bestScore := 1e18
+    now := time.Now()
 
     for _, server := range wlc.servers {
-        ratio := server.Ratio()
-        if ratio < bestRatio {
-            bestRatio = ratio
+        // Filter here rather than trusting the scorer to rank unavailable
+        // servers last; a custom BackendScorer need not know about health.
+        if !server.Available() || server.AtCapacity() || server.BackingOff() || exclude[server] {
+            continue
+        }
+        score := wlc.scorer.Score(server)
+        if wlc.rampDown != nil {
+            // Scores are per unit of weight, so shrinking the weight
+            // raises the score in proportion.
+            score /= server.rampDownFactor(*wlc.rampDown, now)
+        }
+        if bestServer == nil || server.Priority < bestServer.Priority ||
+            (server.Priority == bestServer.Priority && score < bestScore) {
+            bestScore = score
             bestServer = server
         }
     }
@@ -50,67 +273,309 @@ func (wlc *WeightedLeastConnection) NextServer() *Server {
 }
 
 func (wlc *WeightedLeastConnection) StartHealthChecks(ctx context.Context) {
-    ticker := time.NewTicker(10 * time.Second)
+    if wlc.failover != nil {
+        go wlc.failover.StartHealthChecks(ctx)
+    }
+
+    if wlc.WatchdogInterval < 0 {
+        wlc.runHealthChecks(ctx, wlc.healthLoopGen.Add(1))
+        return
+    }
+    wlc.superviseHealthChecks(ctx)
+}
+
+// runHealthChecks checks every backend each health check interval until ctx
+// is done or, once a check returns, until the loop of generation gen has
+// been replaced.
+func (wlc *WeightedLeastConnection) runHealthChecks(ctx context.Context, gen uint64) {
+    ticker := time.NewTicker(wlc.healthCheckInterval)
     defer ticker.Stop()
 
-    wlc.performHealthChecks()
+    wlc.performHealthChecks(ctx)
 
     for {
+        if wlc.healthLoopGen.Load() != gen {
+            return
+        }
         select {
         case <-ctx.Done():
             log.Println("Stopping health checks")
             return
         case <-ticker.C:
-            wlc.performHealthChecks()
+            wlc.performHealthChecks(ctx)
         }
     }
 }
 
-func (wlc *WeightedLeastConnection) performHealthChecks() {
-    wlc.mu.RLock()
-    servers := make([]*Server, len(wlc.servers))
-    copy(servers, wlc.servers)
-    wlc.mu.RUnlock()
-
-    for _, server := range servers {
-        err := server.HealthCheck()
-        wasHealthy := server.IsHealthy.Load()
-        isHealthy := err == nil
-
-        server.IsHealthy.Store(isHealthy)
-
-        if wasHealthy != isHealthy {
-            if isHealthy {
-                log.Printf("[HEALTH] ✅ Server %s is now HEALTHY (failures: %d)", 
-                    server.URL.Host, server.FailureCount.Load())
-            } else {
-                log.Printf("[HEALTH] ❌ Server %s is now UNHEALTHY: %v (failures: %d)", 
-                    server.URL.Host, err, server.FailureCount.Load())
+// performHealthChecks checks every backend, running up to
+// healthCheckConcurrency checks at once, and records how long the cycle took.
+func (wlc *WeightedLeastConnection) performHealthChecks(ctx context.Context) {
+    start := time.Now()
+
+    var wg sync.WaitGroup
+    sem := make(chan struct{}, wlc.healthCheckConcurrency)
+
+    for _, server := range wlc.All() {
+        if !wlc.healthCheckDue(server, start) {
+            continue
+        }
+
+        if wlc.HealthCheckRateLimiter != nil {
+            if err := wlc.HealthCheckRateLimiter.Wait(ctx); err != nil {
+                wg.Wait()
+                return
             }
         }
+
+        sem <- struct{}{}
+        wg.Add(1)
+        go func(server *Server) {
+            defer wg.Done()
+            defer func() { <-sem }()
+            wlc.checkServer(ctx, server, start)
+        }(server)
+    }
+    wg.Wait()
+    if ctx.Err() != nil {
+        // An abandoned cycle must not look like progress to the watchdog.
+        return
+    }
+
+    end := time.Now()
+    wlc.healthCycleDuration.Store(int64(end.Sub(start)))
+    wlc.lastHealthCycle.Store(end.UnixNano())
+}
+
+// checkServer health checks one server, schedules its next check and
+// publishes any change in its health. It returns the health check's error.
+// A check interrupted by ctx changes nothing.
+func (wlc *WeightedLeastConnection) checkServer(ctx context.Context, server *Server, cycleStart time.Time) error {
+    wasHealthy := server.IsHealthy.Load()
+    err := server.runHealthCheck(ctx)
+    if ctx.Err() != nil {
+        return err
+    }
+    // A recovering server is probed at the normal rate so it can collect
+    // its consecutive passes.
+    wlc.scheduleNextHealthCheck(server, cycleStart, err == nil || errors.Is(err, errHealthRecovering))
+    if isHealthy := err == nil; isHealthy != wasHealthy {
+        wlc.publish(events.Event{
+            Type:    events.BackendHealthChanged,
+            Backend: server.URL.String(),
+            Healthy: isHealthy,
+        })
+    }
+    return err
+}
+
+// HealthCheckResult is the outcome of checking one backend.
+type HealthCheckResult struct {
+    Server  *Server
+    Latency time.Duration
+    Err     error // nil if the backend is healthy
+}
+
+// BulkHealthCheck checks every backend now, regardless of when each is next
+// due, and waits for all the results. Like the periodic checks it runs up to
+// healthCheckConcurrency checks at once and records their outcome. Results
+// are in pool order.
+func (wlc *WeightedLeastConnection) BulkHealthCheck() []HealthCheckResult {
+    start := time.Now()
+    servers := wlc.All()
+    results := make([]HealthCheckResult, len(servers))
+
+    wlc.forEachConcurrently(servers, func(i int, server *Server) {
+        checkStart := time.Now()
+        err := wlc.checkServer(context.Background(), server, start)
+        results[i] = HealthCheckResult{
+            Server:  server,
+            Latency: time.Since(checkStart),
+            Err:     err,
+        }
+    })
+    return results
+}
+
+// HealthCheckDryRunResult is what a health check of one backend would
+// conclude.
+type HealthCheckDryRunResult struct {
+    URL            string
+    WouldBeHealthy bool
+    Latency        time.Duration
+    Error          error
+}
+
+// HealthCheckDryRun health checks every backend without recording the
+// results: IsHealthy, failure counts and tags are left as they are. A
+// passing check of an unhealthy server that still needs more consecutive
+// passes is reported as not yet healthy. Results are in pool order.
+func (wlc *WeightedLeastConnection) HealthCheckDryRun(ctx context.Context) []HealthCheckDryRunResult {
+    servers := wlc.All()
+    results := make([]HealthCheckDryRunResult, len(servers))
+
+    wlc.forEachConcurrently(servers, func(i int, server *Server) {
+        start := time.Now()
+        err := server.probeHealth(ctx, false)
+        latency := time.Since(start)
+
+        if n, required := server.consecutiveSuccesses.Load()+1, server.successesRequired(); err == nil && n < required && !server.IsHealthy.Load() {
+            err = fmt.Errorf("%w: %d of %d consecutive checks passed", errHealthRecovering, n, required)
+        }
+        results[i] = HealthCheckDryRunResult{
+            URL:            server.URL.String(),
+            WouldBeHealthy: err == nil,
+            Latency:        latency,
+            Error:          err,
+        }
+    })
+    return results
+}
+
+// forEachConcurrently calls fn for every server, running up to
+// healthCheckConcurrency calls at once, and waits for them all.
+func (wlc *WeightedLeastConnection) forEachConcurrently(servers []*Server, fn func(i int, server *Server)) {
+    var wg sync.WaitGroup
+    sem := make(chan struct{}, wlc.healthCheckConcurrency)
+
+    for i, server := range servers {
+        sem <- struct{}{}
+        wg.Add(1)
+        go func(i int, server *Server) {
+            defer wg.Done()
+            defer func() { <-sem }()
+            fn(i, server)
+        }(i, server)
     }
+    wg.Wait()
 }
 
 func (wlc *WeightedLeastConnection) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+    arrived := time.Now()
+
+    if wlc.maxURLBytes > 0 && len(r.RequestURI) > wlc.maxURLBytes {
+        http.Error(w, "URI Too Long", http.StatusRequestURITooLong)
+        return
+    }
+
+    // OPTIONS * asks about the server itself, not a resource, so answer it
+    // here rather than forwarding it.
+    if r.Method == http.MethodOptions && r.RequestURI == "*" {
+        w.Header().Set("Allow", wlc.globalOptionsAllow)
+        w.Header().Set("Content-Length", "0")
+        w.WriteHeader(http.StatusOK)
+        return
+    }
+
     if r.URL.Path == "/health" || r.URL.Path == "/healthz" {
         wlc.handleHealthEndpoint(w, r)
         return
     }
 
+    if wlc.draining.Load() {
+        w.Header().Set("Connection", "close")
+        http.Error(w, "Service Unavailable: Load balancer is shutting down.", http.StatusServiceUnavailable)
+        return
+    }
+
+    if r.Method == http.MethodConnect {
+        wlc.serveConnect(w, r)
+        return
+    }
+
     if r.URL.Path == "/metrics" {
         wlc.handleMetricsEndpoint(w, r)
         return
     }
 
-    server := wlc.NextServer()
+    if r.URL.Path == "/metrics/prometheus" {
+        wlc.handlePrometheusEndpoint(w, r)
+        return
+    }
+
+    if pool := wlc.shapedPool(r); pool != nil {
+        pool.ServeHTTP(w, r)
+        return
+    }
+
+    body, ok := wlc.prepareRequestBody(w, r)
+    if !ok {
+        return
+    }
+    if body != nil {
+        defer body.release()
+    }
+
+    // A non-nil server holds a connection slot, which forward releases.
+    server := wlc.acquireServer(nil)
+
+    if server == nil && wlc.allAtCapacity() {
+        if wlc.FailFast {
+            w.Header().Set("Retry-After", "1")
+            http.Error(w, "Service Unavailable: All backends are at capacity.", http.StatusServiceUnavailable)
+            return
+        }
+        if server = wlc.waitForCapacity(r.Context()); server == nil {
+            http.Error(w, "Service Unavailable: All backends are at capacity.", http.StatusServiceUnavailable)
+            return
+        }
+    }
+
+    if server == nil && wlc.WaitForHealthy > 0 {
+        server = wlc.waitForHealthyServer(r.Context())
+    }
+
+    if server == nil && wlc.failover != nil {
+        wlc.failoverRequests.Add(1)
+        log.Printf("[FAILOVER] No healthy primary backend, routing %s %s to failover pool", r.Method, r.URL.Path)
+        wlc.failover.ServeHTTP(w, r)
+        return
+    }
 
-    if server == nil || !server.IsHealthy.Load() {
+    if server == nil {
         log.Printf("[ERROR] No healthy backend available for request %s %s", r.Method, r.URL.Path)
+        if wlc.serveMaintenance(w, r) {
+            return
+        }
         http.Error(w, "Service Unavailable: No healthy backend servers available.", http.StatusServiceUnavailable)
         return
     }
 
-    server.ActiveConnections.Add(1)
+    if wlc.latencyBudget {
+        var (
+            cancel context.CancelFunc
+            ok     bool
+        )
+        r, cancel, ok = wlc.applyLatencyBudget(r, arrived)
+        defer cancel()
+        if !ok {
+            server.ActiveConnections.Add(-1)
+            log.Printf("[BUDGET] Latency budget for %s %s exhausted before forwarding", r.Method, r.URL.Path)
+            http.Error(w, "Gateway Timeout: Request latency budget exhausted.", http.StatusGatewayTimeout)
+            return
+        }
+    }
+
+    if wlc.propagateDeadline {
+        var cancel context.CancelFunc
+        r, cancel = withClientDeadline(r, arrived)
+        defer cancel()
+        propagateDeadline(r)
+    }
+
+    if wlc.stripTrailers {
+        w = &trailerStrippingWriter{ResponseWriter: w}
+    }
+
+    if wlc.retryable(r) {
+        wlc.forwardWithRetries(w, r, server)
+        return
+    }
+    wlc.forward(w, r, server)
+}
+
+// forward proxies a single attempt of r to server, releasing the connection
+// slot the caller acquired on it.
+func (wlc *WeightedLeastConnection) forward(w http.ResponseWriter, r *http.Request, server *Server) {
     server.RequestCount.Add(1)
     
     wlc.mu2.Lock()
@@ -127,22 +592,67 @@ func (wlc *WeightedLeastConnection) ServeHTTP(w http.ResponseWriter, r *http.Req
 
     defer server.ActiveConnections.Add(-1)
 
-    server.ReverseProxy.ServeHTTP(w, r)
+    wlc.publish(events.Event{Type: events.RequestRouted, Backend: server.URL.String()})
+
+    if recorder, ok := w.(BackendRecorder); ok {
+        recorder.RecordBackend(server.URL.Host)
+    }
+
+    if wlc.diagnosticHeaders {
+        r = withDiagnostics(r, "wlc")
+    }
+
+    server.proxy(w, r)
+}
+
+// waitForHealthyServer polls for a backend to recover for up to
+// WaitForHealthy and returns it with a connection slot acquired, or nil if
+// none recovers in time.
+func (wlc *WeightedLeastConnection) waitForHealthyServer(ctx context.Context) *Server {
+    ctx, cancel := context.WithTimeout(ctx, wlc.WaitForHealthy)
+    defer cancel()
+
+    ticker := time.NewTicker(healthyPollInterval)
+    defer ticker.Stop()
+
+    for {
+        select {
+        case <-ctx.Done():
+            return nil
+        case <-ticker.C:
+            if server := wlc.acquireServer(nil); server != nil {
+                return server
+            }
+        }
+    }
 }
 
 func (wlc *WeightedLeastConnection) handleHealthEndpoint(w http.ResponseWriter, r *http.Request) {
+    if wlc.draining.Load() {
+        w.WriteHeader(http.StatusServiceUnavailable)
+        w.Write([]byte("UNHEALTHY: Draining"))
+        return
+    }
+
     wlc.mu.RLock()
     defer wlc.mu.RUnlock()
 
     healthyCount := 0
+    disabledCount := 0
     for _, server := range wlc.servers {
-        if server.IsHealthy.Load() {
+        if server.ManuallyDisabled.Load() {
+            disabledCount++
+        } else if server.IsHealthy.Load() {
             healthyCount++
         }
     }
 
     if healthyCount == 0 {
         w.WriteHeader(http.StatusServiceUnavailable)
+        if disabledCount > 0 {
+            fmt.Fprintf(w, "UNHEALTHY: No healthy backends (%d disabled)", disabledCount)
+            return
+        }
         w.Write([]byte("UNHEALTHY: No healthy backends"))
         return
     }
@@ -165,15 +675,19 @@ func (wlc *WeightedLeastConnection) handleMetricsEndpoint(w http.ResponseWriter,
     w.Write([]byte("# Load Balancer Metrics\n\n"))
     w.Write([]byte("## Overall\n"))
     fmt.Fprintf(w, "Total Requests: %d\n", totalReqs)
-    fmt.Fprintf(w, "Backend Servers: %d\n\n", len(wlc.servers))
+    fmt.Fprintf(w, "Backend Servers: %d\n", len(wlc.servers))
+    fmt.Fprintf(w, "Failover Requests: %d\n\n", wlc.failoverRequests.Load())
 
     w.Write([]byte("## Backend Servers\n"))
     for i, server := range wlc.servers {
         fmt.Fprintf(w, "[%d] %s\n", i+1, server.URL.Host)
-        fmt.Fprintf(w, "  Status: %s\n", map[bool]string{true: "HEALTHY", false: "UNHEALTHY"}[server.IsHealthy.Load()])
+        fmt.Fprintf(w, "  Status: %s\n", strings.ToUpper(server.Status()))
         fmt.Fprintf(w, "  Weight: %d\n", server.Weight)
         fmt.Fprintf(w, "  Active Connections: %d\n", server.ActiveConnections.Load())
+        fmt.Fprintf(w, "  Peak Connections: %d\n", server.PeakConnections.Load())
         fmt.Fprintf(w, "  Total Requests: %d\n", server.RequestCount.Load())
+        fmt.Fprintf(w, "  Bytes Sent: %d\n", server.BytesSent.Load())
+        fmt.Fprintf(w, "  Bytes Received: %d\n", server.BytesReceived.Load())
         fmt.Fprintf(w, "  Failure Count: %d\n", server.FailureCount.Load())
         fmt.Fprintf(w, "  Last Check: %s\n", time.Unix(server.LastCheckTime.Load(), 0).Format(time.RFC3339))
         fmt.Fprintf(w, "  Ratio: %.2f\n\n", server.Ratio())
@@ -0,0 +1,265 @@
+package balancer
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	lbtesting "github.com/Adi-ty/go-loadbalancer/pkg/testing"
+)
+
+func TestHealthCheckRateLimit(t *testing.T) {
+    if testing.Short() {
+        t.Skip("takes over 4s")
+    }
+
+    var servers []*Server
+    for i := 0; i < 10; i++ {
+        servers = append(servers, newTestServer(t, lbtesting.NewFakeBackend(t).URL, 1))
+    }
+    wlc := NewWeightedLeastConnection(servers, WithHealthCheckRateLimit(2, 1))
+
+    start := time.Now()
+    wlc.performHealthChecks(context.Background())
+    if elapsed := time.Since(start); elapsed < 4*time.Second {
+        t.Errorf("10 checks at 2/s took %v, want at least 4s", elapsed)
+    }
+}
+
+func TestMaxURLBytes(t *testing.T) {
+    backend := lbtesting.NewFakeBackend(t)
+    wlc := NewWeightedLeastConnection([]*Server{newTestServer(t, backend.URL, 1)}, WithMaxURLBytes(64))
+
+    tests := []struct {
+        uri  string
+        want int
+    }{
+        {uri: "/" + strings.Repeat("a", 63), want: http.StatusOK},
+        {uri: "/" + strings.Repeat("a", 64), want: http.StatusRequestURITooLong},
+    }
+    for _, tt := range tests {
+        if rec := serveRequest(wlc, httptest.NewRequest(http.MethodGet, tt.uri, nil)); rec.Code != tt.want {
+            t.Errorf("%d byte URI: status = %d, want %d", len(tt.uri), rec.Code, tt.want)
+        }
+    }
+}
+
+func TestMaxHeaderBytes(t *testing.T) {
+    backend := lbtesting.NewFakeBackend(t)
+    wlc := NewWeightedLeastConnection([]*Server{newTestServer(t, backend.URL, 1)})
+
+    // Configured as cmd/main.go does with --max-header-bytes.
+    const maxHeaderBytes = 1024
+    front := httptest.NewUnstartedServer(wlc)
+    front.Config.MaxHeaderBytes = maxHeaderBytes
+    front.Start()
+    t.Cleanup(front.Close)
+
+    // net/http reads up to 4096 bytes past MaxHeaderBytes, counting the
+    // request line and every header.
+    const limit = maxHeaderBytes + 4096
+    tests := []struct {
+        size int
+        want int
+    }{
+        {size: limit, want: http.StatusOK},
+        {size: limit + 1, want: http.StatusRequestHeaderFieldsTooLarge},
+    }
+    for _, tt := range tests {
+        // Written by hand so the request head is exactly tt.size bytes.
+        head := fmt.Sprintf("GET / HTTP/1.1\r\nHost: %s\r\nX-Large: ", front.Listener.Addr())
+        const end = "\r\n\r\n"
+        head += strings.Repeat("a", tt.size-len(head)-len(end)) + end
+
+        conn, err := net.Dial("tcp", front.Listener.Addr().String())
+        if err != nil {
+            t.Fatal(err)
+        }
+        defer conn.Close()
+        if _, err := conn.Write([]byte(head)); err != nil {
+            t.Fatalf("%d byte request head: %v", tt.size, err)
+        }
+        resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+        if err != nil {
+            t.Fatalf("%d byte request head: %v", tt.size, err)
+        }
+        resp.Body.Close()
+        if resp.StatusCode != tt.want {
+            t.Errorf("%d byte request head: status = %d, want %d", tt.size, resp.StatusCode, tt.want)
+        }
+    }
+}
+
+func TestWaitForHealthy(t *testing.T) {
+    backend := lbtesting.NewFakeBackend(t)
+    server := newTestServer(t, backend.URL, 1)
+    server.IsHealthy.Store(false)
+    wlc := NewWeightedLeastConnection([]*Server{server})
+    wlc.WaitForHealthy = time.Second
+
+    time.AfterFunc(500*time.Millisecond, func() { server.IsHealthy.Store(true) })
+
+    start := time.Now()
+    rec := serveRequest(wlc, httptest.NewRequest(http.MethodGet, "/", nil))
+    if rec.Code != http.StatusOK {
+        t.Errorf("status = %d, want %d once the backend recovered", rec.Code, http.StatusOK)
+    }
+    if elapsed := time.Since(start); elapsed > time.Second {
+        t.Errorf("request took %v, want it routed within WaitForHealthy", elapsed)
+    }
+}
+
+func TestWaitForHealthyTimesOut(t *testing.T) {
+    server := newTestServer(t, lbtesting.NewFakeBackend(t).URL, 1)
+    server.IsHealthy.Store(false)
+    wlc := NewWeightedLeastConnection([]*Server{server})
+    wlc.WaitForHealthy = 300 * time.Millisecond
+
+    start := time.Now()
+    rec := serveRequest(wlc, httptest.NewRequest(http.MethodGet, "/", nil))
+    if rec.Code != http.StatusServiceUnavailable {
+        t.Errorf("status = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+    }
+    if elapsed := time.Since(start); elapsed < 300*time.Millisecond {
+        t.Errorf("gave up after %v, want it to wait the full 300ms", elapsed)
+    }
+}
+
+func TestFailoverPool(t *testing.T) {
+    primary := lbtesting.NewFakeBackend(t)
+    primary.SetBody("primary")
+    backup := lbtesting.NewFakeBackend(t)
+    backup.SetBody("backup")
+
+    primaryServer := newTestServer(t, primary.URL, 1)
+    wlc := NewWeightedLeastConnection([]*Server{primaryServer},
+        WithFailoverPool(NewWeightedLeastConnection([]*Server{newTestServer(t, backup.URL, 1)})))
+
+    if rec := serveRequest(wlc, httptest.NewRequest(http.MethodGet, "/", nil)); rec.Body.String() != "primary" {
+        t.Errorf("with a healthy primary got %q, want the primary's response", rec.Body.String())
+    }
+
+    primaryServer.IsHealthy.Store(false)
+    rec := serveRequest(wlc, httptest.NewRequest(http.MethodGet, "/", nil))
+    if rec.Code != http.StatusOK || rec.Body.String() != "backup" {
+        t.Errorf("with the primary down got %d %q, want 200 from the backup pool", rec.Code, rec.Body.String())
+    }
+    if got := backup.CallCount(); got != 1 {
+        t.Errorf("backup pool received %d requests, want 1", got)
+    }
+    if got := wlc.failoverRequests.Load(); got != 1 {
+        t.Errorf("failover requests = %d, want 1", got)
+    }
+}
+
+func TestGlobalOptions(t *testing.T) {
+    tests := []struct {
+        name  string
+        opts  []Option
+        allow string
+    }{
+        {"default", nil, DefaultGlobalOptionsMethods},
+        {"configured", []Option{WithGlobalOptionsMethods([]string{"GET", "HEAD"})}, "GET, HEAD"},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            backend := lbtesting.NewFakeBackend(t)
+            wlc := NewWeightedLeastConnection([]*Server{newTestServer(t, backend.URL, 1)}, tt.opts...)
+            lb := httptest.NewUnstartedServer(wlc)
+            // As in main; otherwise net/http answers OPTIONS * itself.
+            lb.Config.DisableGeneralOptionsHandler = true
+            lb.Start()
+            t.Cleanup(lb.Close)
+
+            // http.Client cannot send OPTIONS *, so write it by hand.
+            conn, err := net.Dial("tcp", lb.Listener.Addr().String())
+            if err != nil {
+                t.Fatal(err)
+            }
+            defer conn.Close()
+            fmt.Fprintf(conn, "OPTIONS * HTTP/1.1\r\nHost: %s\r\n\r\n", lb.Listener.Addr())
+
+            resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+            if err != nil {
+                t.Fatal(err)
+            }
+            resp.Body.Close()
+            if resp.StatusCode != http.StatusOK {
+                t.Errorf("status = %d, want 200", resp.StatusCode)
+            }
+            if got := resp.Header.Get("Allow"); got != tt.allow {
+                t.Errorf("Allow = %q, want %q", got, tt.allow)
+            }
+            if got := backend.CallCount(); got != 0 {
+                t.Errorf("OPTIONS * reached the backend %d times", got)
+            }
+        })
+    }
+}
+
+func TestHealthCheckConcurrency(t *testing.T) {
+    const latency = 100 * time.Millisecond
+    newSlowServers := func(n int) []*Server {
+        var servers []*Server
+        for i := 0; i < n; i++ {
+            backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+                time.Sleep(latency)
+            }))
+            t.Cleanup(backend.Close)
+            servers = append(servers, newTestServer(t, backend.URL, 1))
+        }
+        return servers
+    }
+
+    wlc := NewWeightedLeastConnection(newSlowServers(20), WithHealthCheckConcurrency(20))
+    wlc.performHealthChecks(context.Background())
+    if got := time.Duration(wlc.healthCycleDuration.Load()); got >= latency*3/2 {
+        t.Errorf("20 checks at concurrency 20 took %v, want under %v", got, latency*3/2)
+    }
+
+    wlc = NewWeightedLeastConnection(newSlowServers(4), WithHealthCheckConcurrency(2))
+    wlc.performHealthChecks(context.Background())
+    if got := time.Duration(wlc.healthCycleDuration.Load()); got < 2*latency {
+        t.Errorf("4 checks at concurrency 2 took %v, want at least %v", got, 2*latency)
+    }
+}
+
+func TestUnhealthyServerNeverChosen(t *testing.T) {
+    unhealthy := lbtesting.NewFakeBackend(t)
+    healthy := lbtesting.NewFakeBackend(t)
+    preferred := newTestServer(t, unhealthy.URL, 1)
+    preferred.IsHealthy.Store(false)
+
+    // The scorer ranks the unhealthy server best, as one unaware of health
+    // might; it must still never be picked.
+    wlc := NewWeightedLeastConnection([]*Server{preferred, newTestServer(t, healthy.URL, 1)},
+        WithBackendScorer(firstServerScorer{first: preferred}))
+
+    if server := wlc.NextServer(); server == preferred {
+        t.Fatal("NextServer() returned the unhealthy server")
+    }
+    for i := 0; i < 5; i++ {
+        serveRequest(wlc, httptest.NewRequest(http.MethodGet, "/", nil))
+    }
+    if unhealthy.CallCount() != 0 || healthy.CallCount() != 5 {
+        t.Errorf("requests: unhealthy %d, healthy %d; want all 5 on the healthy server", unhealthy.CallCount(), healthy.CallCount())
+    }
+
+    wlc = NewWeightedLeastConnection([]*Server{preferred}, WithBackendScorer(firstServerScorer{first: preferred}))
+    if server := wlc.NextServer(); server != nil {
+        t.Errorf("NextServer() = %s with only an unhealthy server, want nil", server.URL)
+    }
+    if rec := serveRequest(wlc, httptest.NewRequest(http.MethodGet, "/", nil)); rec.Code != http.StatusServiceUnavailable {
+        t.Errorf("status = %d with only an unhealthy server, want %d", rec.Code, http.StatusServiceUnavailable)
+    }
+    if unhealthy.CallCount() != 0 {
+        t.Errorf("unhealthy server got %d requests", unhealthy.CallCount())
+    }
+}
@@ -0,0 +1,62 @@
+package balancer
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+)
+
+// DefaultMaxBufferedBody is the largest body of unknown length buffered when
+// no maximum request body size is set.
+const DefaultMaxBufferedBody = 10 << 20
+
+// prepareRequestBody enforces maxRequestBody and, if enabled, reads a body
+// sent without Content-Length into memory so it is forwarded with one. It
+// returns false if it has already answered the request. A non-nil body was
+// buffered and must be released once the request has been forwarded.
+func (wlc *WeightedLeastConnection) prepareRequestBody(w http.ResponseWriter, r *http.Request) (body *pooledBody, ok bool) {
+    if r.Body == nil || r.Body == http.NoBody {
+        return nil, true
+    }
+
+    if wlc.maxRequestBody > 0 {
+        if r.ContentLength > wlc.maxRequestBody {
+            http.Error(w, "Request Entity Too Large", http.StatusRequestEntityTooLarge)
+            return nil, false
+        }
+        r.Body = http.MaxBytesReader(w, r.Body, wlc.maxRequestBody)
+    }
+
+    if !wlc.bufferUnknownLength || r.ContentLength >= 0 {
+        return nil, true
+    }
+
+    limit := wlc.maxRequestBody
+    if limit <= 0 {
+        limit = DefaultMaxBufferedBody
+    }
+
+    body, err := readPooledBody(wlc.requestBuffers, r.Body, limit)
+    var maxBytesErr *http.MaxBytesError
+    if int64(len(body.data)) > limit || errors.As(err, &maxBytesErr) {
+        body.release()
+        http.Error(w, "Request Entity Too Large", http.StatusRequestEntityTooLarge)
+        return nil, false
+    }
+    if err != nil {
+        body.release()
+        log.Printf("[ERROR] Reading body of %s %s: %v", r.Method, r.URL.Path, err)
+        http.Error(w, "Bad Request", http.StatusBadRequest)
+        return nil, false
+    }
+    r.Body.Close()
+
+    r.Body, _ = body.reader(nil)
+    r.GetBody = func() (io.ReadCloser, error) {
+        return body.reader(nil)
+    }
+    r.ContentLength = int64(len(body.data))
+    r.TransferEncoding = nil
+    return body, true
+}
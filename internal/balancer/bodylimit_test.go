@@ -0,0 +1,93 @@
+package balancer
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	lbtesting "github.com/Adi-ty/go-loadbalancer/pkg/testing"
+)
+
+// chunkedRequest returns a POST of body sent without a Content-Length.
+func chunkedRequest(body []byte) *http.Request {
+    req := httptest.NewRequest(http.MethodPost, "/", io.MultiReader(bytes.NewReader(body)))
+    req.ContentLength = -1
+    req.TransferEncoding = []string{"chunked"}
+    return req
+}
+
+func TestBufferUnknownLengthBodies(t *testing.T) {
+    backend := lbtesting.NewFakeBackend(t)
+    wlc := NewWeightedLeastConnection([]*Server{newTestServer(t, backend.URL, 1)}, WithBufferUnknownLengthBodies(true))
+
+    body := bytes.Repeat([]byte("chunk"), 1000)
+    if rec := serveRequest(wlc, chunkedRequest(body)); rec.Code != http.StatusOK {
+        t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+    }
+
+    last := backend.LastRequest()
+    if last.ContentLength != int64(len(body)) || len(last.TransferEncoding) != 0 {
+        t.Errorf("forwarded with Content-Length %d and Transfer-Encoding %v, want Content-Length %d",
+            last.ContentLength, last.TransferEncoding, len(body))
+    }
+    if got, _ := io.ReadAll(last.Body); !bytes.Equal(got, body) {
+        t.Errorf("backend got %d bytes, want the %d byte body", len(got), len(body))
+    }
+}
+
+func TestUnknownLengthBodiesStreamedByDefault(t *testing.T) {
+    backend := lbtesting.NewFakeBackend(t)
+    wlc := NewWeightedLeastConnection([]*Server{newTestServer(t, backend.URL, 1)})
+
+    serveRequest(wlc, chunkedRequest([]byte("streamed")))
+    if last := backend.LastRequest(); last.ContentLength != -1 {
+        t.Errorf("forwarded with Content-Length %d, want the body streamed without one", last.ContentLength)
+    }
+}
+
+func TestMaxRequestBodyExceeded(t *testing.T) {
+    tests := []struct {
+        name string
+        opts []Option
+    }{
+        {name: "buffered", opts: []Option{WithBufferUnknownLengthBodies(true)}},
+        {name: "streamed"},
+        {name: "retried", opts: []Option{WithRetries(1, []string{http.MethodPost})}},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            backend := lbtesting.NewFakeBackend(t)
+            server := newTestServer(t, backend.URL, 1)
+            wlc := NewWeightedLeastConnection([]*Server{server}, append(tt.opts, WithMaxRequestBody(100))...)
+
+            rec := serveRequest(wlc, chunkedRequest(bytes.Repeat([]byte("x"), 1000)))
+            if rec.Code != http.StatusRequestEntityTooLarge {
+                t.Errorf("status = %d, want %d", rec.Code, http.StatusRequestEntityTooLarge)
+            }
+            if got := server.ErrorRate(); got != 0 {
+                t.Errorf("backend error rate = %v, want the oversized body not blamed on it", got)
+            }
+            if !server.IsHealthy.Load() {
+                t.Errorf("backend taken out of rotation for an oversized body")
+            }
+        })
+    }
+}
+
+func TestMaxRequestBodyContentLength(t *testing.T) {
+    backend := lbtesting.NewFakeBackend(t)
+    wlc := NewWeightedLeastConnection([]*Server{newTestServer(t, backend.URL, 1)}, WithMaxRequestBody(100))
+
+    req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(make([]byte, 101)))
+    req.Header.Set("Content-Length", strconv.Itoa(101))
+    if rec := serveRequest(wlc, req); rec.Code != http.StatusRequestEntityTooLarge {
+        t.Errorf("status = %d, want %d", rec.Code, http.StatusRequestEntityTooLarge)
+    }
+    if got := backend.CallCount(); got != 0 {
+        t.Errorf("backend received %d requests with a declared oversized body", got)
+    }
+}
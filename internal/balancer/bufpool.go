@@ -0,0 +1,172 @@
+package balancer
+
+import (
+	"io"
+	"net/http"
+	"sync"
+)
+
+// DefaultRequestBufferSize is the size of pooled request body buffers.
+const DefaultRequestBufferSize = 32 * 1024
+
+// DefaultResponseBufferSize is the size of pooled buffers used to copy
+// response bodies, matching the buffer io.Copy would allocate.
+const DefaultResponseBufferSize = 32 * 1024
+
+// bytePool recycles byte slices of a fixed size. It satisfies
+// httputil.BufferPool.
+type bytePool struct {
+    size int
+    pool sync.Pool
+}
+
+func newBytePool(size int) *bytePool {
+    return &bytePool{size: size}
+}
+
+// Get returns a slice of length size, reusing a pooled one if available.
+func (p *bytePool) Get() []byte {
+    if buf, ok := p.pool.Get().(*[]byte); ok {
+        return *buf
+    }
+    return make([]byte, p.size)
+}
+
+// Put returns buf to the pool. Slices that were grown or shrunk past the
+// pool's size are dropped.
+func (p *bytePool) Put(buf []byte) {
+    if cap(buf) != p.size {
+        return
+    }
+    buf = buf[:p.size]
+    p.pool.Put(&buf)
+}
+
+// pooledBody is a request body read into memory, possibly into a buffer from
+// a bytePool. The transport may still be reading a body after the handler
+// that sent it has returned, so the buffer only goes back to the pool once
+// the caller has called release and every reader has been closed.
+type pooledBody struct {
+    data []byte
+    pool *bytePool // Nil if data was not taken from a pool
+
+    mu   sync.Mutex
+    refs int // The caller's reference plus one per open reader
+}
+
+// readPooledBody reads src, up to limit+1 bytes so callers can tell an
+// oversized body apart, into a buffer taken from pool. A nil pool
+// allocates.
+func readPooledBody(pool *bytePool, src io.Reader, limit int64) (*pooledBody, error) {
+    var buf []byte
+    if pool != nil {
+        buf = pool.Get()[:0]
+    } else {
+        // Start where io.ReadAll does.
+        buf = make([]byte, 0, 512)
+    }
+
+    src = io.LimitReader(src, limit+1)
+    var err error
+    for {
+        if len(buf) == cap(buf) {
+            buf = append(buf, 0)[:len(buf)]
+        }
+        var n int
+        n, err = src.Read(buf[len(buf):cap(buf)])
+        buf = buf[:len(buf)+n]
+        if err != nil {
+            break
+        }
+    }
+    if err == io.EOF {
+        err = nil
+    }
+    return &pooledBody{data: buf, pool: pool, refs: 1}, err
+}
+
+// release drops a reference, recycling the buffer after the last one.
+func (pb *pooledBody) release() {
+    pb.mu.Lock()
+    pb.refs--
+    recycle := pb.refs == 0
+    pb.mu.Unlock()
+
+    if recycle && pb.pool != nil {
+        pb.pool.Put(pb.data)
+    }
+}
+
+// reader returns a reader over the body followed by rest, which may be nil.
+// Closing the reader closes rest. It fails once the buffer has been
+// recycled.
+func (pb *pooledBody) reader(rest io.ReadCloser) (io.ReadCloser, error) {
+    pb.mu.Lock()
+    defer pb.mu.Unlock()
+
+    if pb.refs == 0 {
+        return nil, http.ErrBodyReadAfterClose
+    }
+    pb.refs++
+    return &pooledBodyReader{body: pb, rest: rest}, nil
+}
+
+// pooledBodyReader reads a pooledBody. Reads after Close fail rather than
+// see a buffer that may already hold another request's body.
+type pooledBodyReader struct {
+    body *pooledBody
+    rest io.ReadCloser
+
+    mu     sync.Mutex
+    off    int
+    closed bool
+    done   bool // The reference to body has been dropped
+}
+
+func (br *pooledBodyReader) Read(p []byte) (int, error) {
+    br.mu.Lock()
+    if br.closed {
+        br.mu.Unlock()
+        return 0, http.ErrBodyReadAfterClose
+    }
+    if !br.done {
+        n := copy(p, br.body.data[br.off:])
+        br.off += n
+        if br.off == len(br.body.data) {
+            br.done = true
+            br.body.release()
+        }
+        br.mu.Unlock()
+        if n > 0 {
+            return n, nil
+        }
+    } else {
+        br.mu.Unlock()
+    }
+
+    // Past the buffered bytes, read rest without holding the lock so Close
+    // is not held up by a slow client.
+    if br.rest == nil {
+        return 0, io.EOF
+    }
+    return br.rest.Read(p)
+}
+
+func (br *pooledBodyReader) Close() error {
+    br.mu.Lock()
+    if br.closed {
+        br.mu.Unlock()
+        return nil
+    }
+    br.closed = true
+    if !br.done {
+        br.done = true
+        br.body.release()
+    }
+    br.mu.Unlock()
+
+    if br.rest != nil {
+        return br.rest.Close()
+    }
+    return nil
+}
@@ -0,0 +1,201 @@
+package balancer
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	lbtesting "github.com/Adi-ty/go-loadbalancer/pkg/testing"
+)
+
+// replayBody buffers the body of r for retries, as forwardWithRetries does,
+// and reads it back once.
+func replayBody(wlc *WeightedLeastConnection, r *http.Request, src *bytes.Reader, payload []byte) {
+    src.Reset(payload)
+    r.Body = io.NopCloser(src)
+
+    body, _, _ := wlc.bufferRetryBody(r)
+    rc, _ := body.reader(nil)
+    io.Copy(io.Discard, rc)
+    rc.Close()
+    body.release()
+}
+
+func BenchmarkBufferRetryBody(b *testing.B) {
+    payload := bytes.Repeat([]byte("x"), 4096)
+
+    for _, bc := range []struct {
+        name string
+        opts []Option
+    }{
+        {name: "unpooled"},
+        {name: "pooled", opts: []Option{WithRequestBufferPool(DefaultRequestBufferSize)}},
+    } {
+        b.Run(bc.name, func(b *testing.B) {
+            wlc := NewWeightedLeastConnection(nil, bc.opts...)
+            r := httptest.NewRequest(http.MethodPost, "/", nil)
+            src := bytes.NewReader(nil)
+
+            b.ReportAllocs()
+            for i := 0; i < b.N; i++ {
+                replayBody(wlc, r, src, payload)
+            }
+        })
+    }
+}
+
+func TestRequestBufferPoolHalvesAllocations(t *testing.T) {
+    payload := bytes.Repeat([]byte("x"), 4096)
+
+    allocs := func(opts ...Option) float64 {
+        wlc := NewWeightedLeastConnection(nil, opts...)
+        r := httptest.NewRequest(http.MethodPost, "/", nil)
+        src := bytes.NewReader(nil)
+        return testing.AllocsPerRun(100, func() {
+            replayBody(wlc, r, src, payload)
+        })
+    }
+
+    unpooled := allocs()
+    pooled := allocs(WithRequestBufferPool(DefaultRequestBufferSize))
+    if pooled > unpooled/2 {
+        t.Errorf("pooled buffering makes %.1f allocs/op, want at most half of the %.1f unpooled", pooled, unpooled)
+    }
+}
+
+func TestPooledBodyOutlivesRelease(t *testing.T) {
+    pool := newBytePool(DefaultRequestBufferSize)
+    body, err := readPooledBody(pool, bytes.NewReader([]byte("payload")), maxRetryBodyBytes)
+    if err != nil {
+        t.Fatalf("readPooledBody: %v", err)
+    }
+    rc, err := body.reader(nil)
+    if err != nil {
+        t.Fatalf("reader: %v", err)
+    }
+
+    // The handler is done, but the transport has not finished reading.
+    body.release()
+    if got, err := io.ReadAll(rc); err != nil || string(got) != "payload" {
+        t.Errorf("read %q, %v after release, want %q", got, err, "payload")
+    }
+
+    rc.Close()
+    if _, err := rc.Read(make([]byte, 1)); !errors.Is(err, http.ErrBodyReadAfterClose) {
+        t.Errorf("Read after Close = %v, want %v", err, http.ErrBodyReadAfterClose)
+    }
+    if _, err := body.reader(nil); !errors.Is(err, http.ErrBodyReadAfterClose) {
+        t.Errorf("reader() after the last release = %v, want %v", err, http.ErrBodyReadAfterClose)
+    }
+}
+
+func TestRequestBufferPoolRetriesBody(t *testing.T) {
+    backend := lbtesting.NewFakeBackend(t)
+    wlc := NewWeightedLeastConnection(
+        []*Server{newTestServer(t, refusingURL(t), 1), newTestServer(t, backend.URL, 1)},
+        WithRetries(1, []string{http.MethodPost}),
+        WithRequestBufferPool(DefaultRequestBufferSize))
+
+    payload := bytes.Repeat([]byte("abc"), 2000)
+    for i := 0; i < 3; i++ {
+        rec := serveRequest(wlc, httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(payload)))
+        if rec.Code != http.StatusOK {
+            t.Fatalf("request %d: status = %d, want %d", i, rec.Code, http.StatusOK)
+        }
+        got, _ := io.ReadAll(backend.LastRequest().Body)
+        if !bytes.Equal(got, payload) {
+            t.Fatalf("request %d: backend got %d bytes, want the %d byte body", i, len(got), len(payload))
+        }
+    }
+}
+
+func TestRequestBufferPoolOversizedBody(t *testing.T) {
+    backend := lbtesting.NewFakeBackend(t)
+    wlc := NewWeightedLeastConnection([]*Server{newTestServer(t, backend.URL, 1)},
+        WithRetries(1, []string{http.MethodPost}),
+        WithRequestBufferPool(DefaultRequestBufferSize))
+
+    // Sent without a Content-Length, so it is only found to be too large to
+    // retry after the first megabyte has been read.
+    payload := bytes.Repeat([]byte("x"), maxRetryBodyBytes+4096)
+    req := httptest.NewRequest(http.MethodPost, "/", io.MultiReader(bytes.NewReader(payload)))
+    req.ContentLength = -1
+    if rec := serveRequest(wlc, req); rec.Code != http.StatusOK {
+        t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+    }
+    got, _ := io.ReadAll(backend.LastRequest().Body)
+    if !bytes.Equal(got, payload) {
+        t.Errorf("backend got %d bytes, want the %d byte body", len(got), len(payload))
+    }
+}
+
+// discardWriter is a ResponseWriter that throws the response away, so
+// benchmarks measure the proxy rather than a recorder's buffer.
+type discardWriter struct {
+    header http.Header
+}
+
+func (w *discardWriter) Header() http.Header         { return w.header }
+func (w *discardWriter) Write(p []byte) (int, error) { return len(p), nil }
+func (w *discardWriter) WriteHeader(statusCode int)  {}
+func (w *discardWriter) Flush()                      {}
+
+func BenchmarkServeHTTPLargeResponse(b *testing.B) {
+    payload := bytes.Repeat([]byte("x"), 1<<20)
+    backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        w.Write(payload)
+    }))
+    b.Cleanup(backend.Close)
+
+    for _, bc := range []struct {
+        name string
+        opts []Option
+    }{
+        {name: "unpooled"},
+        {name: "pooled", opts: []Option{WithResponseBufferPool(DefaultResponseBufferSize)}},
+    } {
+        b.Run(bc.name, func(b *testing.B) {
+            wlc := NewWeightedLeastConnection([]*Server{newTestServer(b, backend.URL, 1)}, bc.opts...)
+            r := httptest.NewRequest(http.MethodGet, "/", nil)
+
+            b.ReportAllocs()
+            b.SetBytes(int64(len(payload)))
+            for i := 0; i < b.N; i++ {
+                wlc.ServeHTTP(&discardWriter{header: make(http.Header)}, r)
+            }
+        })
+    }
+}
+
+func TestResponseBufferPoolStreamsResponses(t *testing.T) {
+    payload := bytes.Repeat([]byte("0123456789"), 100_000)
+    backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if r.URL.Path == "/events" {
+            w.Header().Set("Content-Type", "text/event-stream")
+            for i := 0; i < 3; i++ {
+                w.Write([]byte("data: tick\n\n"))
+                w.(http.Flusher).Flush()
+            }
+            return
+        }
+        w.Write(payload)
+    }))
+    t.Cleanup(backend.Close)
+
+    wlc := NewWeightedLeastConnection([]*Server{newTestServer(t, backend.URL, 1)},
+        WithResponseBufferPool(DefaultResponseBufferSize))
+    for i := 0; i < 3; i++ {
+        rec := serveRequest(wlc, httptest.NewRequest(http.MethodGet, "/", nil))
+        if !bytes.Equal(rec.Body.Bytes(), payload) {
+            t.Fatalf("request %d: got %d bytes, want the %d byte body", i, rec.Body.Len(), len(payload))
+        }
+    }
+    rec := serveRequest(wlc, httptest.NewRequest(http.MethodGet, "/events", nil))
+    if want := strings.Repeat("data: tick\n\n", 3); rec.Body.String() != want {
+        t.Errorf("event stream = %q, want %q", rec.Body.String(), want)
+    }
+}
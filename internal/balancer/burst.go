@@ -0,0 +1,99 @@
+package balancer
+
+import (
+	"context"
+	"log"
+	"math"
+	"net/http"
+	"time"
+)
+
+// connectionSampleInterval is how often BurstScoreBalancer folds each
+// server's active connections into its average.
+const connectionSampleInterval = time.Second
+
+// AvgConnections returns the moving average of the server's active
+// connections, sampled every second while a BurstScoreBalancer runs.
+func (s *Server) AvgConnections() float64 {
+    return math.Float64frombits(s.avgConnBits.Load())
+}
+
+// sampleConnections folds the current active connections into the average.
+// Unlike ewma, the first sample is not taken as the average: a server's
+// average starts at zero and climbs as it gets busy.
+func (s *Server) sampleConnections() {
+    sample := float64(s.ActiveConnections.Load())
+    for {
+        old := s.avgConnBits.Load()
+        next := ewmaAlpha*sample + (1-ewmaAlpha)*math.Float64frombits(old)
+        if s.avgConnBits.CompareAndSwap(old, math.Float64bits(next)) {
+            return
+        }
+    }
+}
+
+// BurstScoreBalancer sends each request to the healthy server with the most
+// headroom below its own average load, AvgConnections minus
+// ActiveConnections. A server that is momentarily quieter than usual gets
+// the next request, which evens out load across servers whose capacity
+// comes and goes. Ties go to the server with the lowest Ratio.
+type BurstScoreBalancer struct {
+    *BackendPool
+}
+
+func NewBurstScoreBalancer(servers []*Server) *BurstScoreBalancer {
+    return &BurstScoreBalancer{
+        BackendPool: NewBackendPool(servers),
+    }
+}
+
+// NextServer returns the healthy server with the highest burst score, or nil
+// if none is available.
+func (bb *BurstScoreBalancer) NextServer() *Server {
+    var bestServer *Server
+    var bestScore float64
+
+    for _, server := range bb.Healthy() {
+        if server.AtCapacity() {
+            continue
+        }
+        score := server.AvgConnections() - float64(server.ActiveConnections.Load())
+        if bestServer == nil || score > bestScore ||
+            (score == bestScore && server.Ratio() < bestServer.Ratio()) {
+            bestScore = score
+            bestServer = server
+        }
+    }
+    return bestServer
+}
+
+func (bb *BurstScoreBalancer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+    server := bb.NextServer()
+    if server == nil {
+        log.Printf("[ERROR] No healthy backend available for request %s %s", r.Method, r.URL.Path)
+        http.Error(w, "Service Unavailable: No healthy backend servers available.", http.StatusServiceUnavailable)
+        return
+    }
+
+    server.serve(w, r)
+}
+
+// StartHealthChecks health checks the pool and samples every server's
+// active connections once a second until ctx is cancelled.
+func (bb *BurstScoreBalancer) StartHealthChecks(ctx context.Context) {
+    go bb.BackendPool.StartHealthChecks(ctx)
+
+    ticker := time.NewTicker(connectionSampleInterval)
+    defer ticker.Stop()
+
+    for {
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+            for _, server := range bb.All() {
+                server.sampleConnections()
+            }
+        }
+    }
+}
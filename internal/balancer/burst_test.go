@@ -0,0 +1,102 @@
+package balancer
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestBurstScorePicksMostHeadroom(t *testing.T) {
+    busy := newTestServer(t, "http://10.0.0.1:8080", 1)
+    quiet := newTestServer(t, "http://10.0.0.2:8080", 1)
+
+    // busy usually has 10 connections and now has 8; quiet usually has 2
+    // and now has 3. Least connections would pick quiet.
+    busy.ActiveConnections.Store(10)
+    quiet.ActiveConnections.Store(2)
+    for i := 0; i < 100; i++ {
+        busy.sampleConnections()
+        quiet.sampleConnections()
+    }
+    busy.ActiveConnections.Store(8)
+    quiet.ActiveConnections.Store(3)
+
+    bb := NewBurstScoreBalancer([]*Server{busy, quiet})
+    if got := bb.NextServer(); got != busy {
+        t.Errorf("NextServer() = %s, want the server furthest below its average", got.URL)
+    }
+
+    busy.IsHealthy.Store(false)
+    if got := bb.NextServer(); got != quiet {
+        t.Errorf("NextServer() = %v with the busy server down, want the quiet one", got)
+    }
+}
+
+// simulateBursts sends 10000 requests, five per tick, to two servers through
+// next. Requests normally take 2-4 ticks; the second server spends about a
+// third of the time in bursts where they take 20-39. Connections are sampled
+// every 10 ticks. It returns how many requests each server got.
+func simulateBursts(servers []*Server, next func() *Server) [2]int {
+    rng := rand.New(rand.NewSource(1))
+    type inflight struct {
+        server *Server
+        done   int
+    }
+    var flights []inflight
+    var counts [2]int
+    bursting := false
+
+    for tick, sent := 0, 0; sent < 10000; tick++ {
+        if tick%50 == 0 {
+            bursting = rng.Intn(3) == 0
+        }
+        if tick%10 == 0 {
+            for _, server := range servers {
+                server.sampleConnections()
+            }
+        }
+
+        kept := flights[:0]
+        for _, f := range flights {
+            if f.done <= tick {
+                f.server.ActiveConnections.Add(-1)
+            } else {
+                kept = append(kept, f)
+            }
+        }
+        flights = kept
+
+        for i := 0; i < 5; i++ {
+            server := next()
+            server.ActiveConnections.Add(1)
+            sent++
+
+            duration := 2 + rng.Intn(3)
+            if server == servers[1] {
+                counts[1]++
+                if bursting {
+                    duration = 20 + rng.Intn(20)
+                }
+            } else {
+                counts[0]++
+            }
+            flights = append(flights, inflight{server, tick + duration})
+        }
+    }
+    return counts
+}
+
+func TestBurstScoreEvensOutBursts(t *testing.T) {
+    newServers := func() []*Server {
+        return []*Server{newTestServer(t, "http://10.0.0.1:8080", 1), newTestServer(t, "http://10.0.0.2:8080", 1)}
+    }
+    skew := func(counts [2]int) int { return max(counts[0]-counts[1], counts[1]-counts[0]) }
+
+    servers := newServers()
+    wlc := simulateBursts(servers, NewWeightedLeastConnection(servers).NextServer)
+    servers = newServers()
+    burst := simulateBursts(servers, NewBurstScoreBalancer(servers).NextServer)
+
+    if skew(burst) >= skew(wlc) {
+        t.Errorf("burst score split %v, least connection %v; want burst score more even", burst, wlc)
+    }
+}
@@ -0,0 +1,82 @@
+package balancer
+
+import (
+	"context"
+	"time"
+)
+
+// capacityPollInterval is how often a request queued for a backend at
+// MaxConnections checks for a free slot.
+const capacityPollInterval = 10 * time.Millisecond
+
+// AtCapacity reports whether the server has reached MaxConnections.
+func (s *Server) AtCapacity() bool {
+    return s.MaxConnections > 0 && s.ActiveConnections.Load() >= s.MaxConnections
+}
+
+// acquire takes one of the server's connection slots, failing if it is at
+// MaxConnections. The check and the increment are one atomic step, so
+// concurrent requests cannot together push the server past the limit.
+// Release the slot with ActiveConnections.Add(-1).
+func (s *Server) acquire() bool {
+    for {
+        n := s.ActiveConnections.Load()
+        if s.MaxConnections > 0 && n >= s.MaxConnections {
+            return false
+        }
+        if s.ActiveConnections.CompareAndSwap(n, n+1) {
+            s.trackPeak(n + 1)
+            return true
+        }
+    }
+}
+
+// acquireServer is nextServer, also taking a connection slot on the server
+// it returns. A server that another request fills after it is chosen is
+// passed over for the next best.
+func (wlc *WeightedLeastConnection) acquireServer(exclude map[*Server]bool) *Server {
+    for {
+        server := wlc.nextServer(exclude)
+        if server == nil || server.acquire() {
+            return server
+        }
+    }
+}
+
+// allAtCapacity reports whether at least one backend is available and every
+// available backend is at MaxConnections.
+func (wlc *WeightedLeastConnection) allAtCapacity() bool {
+    full := false
+    for _, server := range wlc.All() {
+        if !server.Available() {
+            continue
+        }
+        if !server.AtCapacity() {
+            return false
+        }
+        full = true
+    }
+    return full
+}
+
+// waitForCapacity queues for up to MaxQueueWait until a backend drops below
+// MaxConnections and returns it with a connection slot acquired, or nil if
+// none frees up in time.
+func (wlc *WeightedLeastConnection) waitForCapacity(ctx context.Context) *Server {
+    ctx, cancel := context.WithTimeout(ctx, wlc.MaxQueueWait)
+    defer cancel()
+
+    ticker := time.NewTicker(capacityPollInterval)
+    defer ticker.Stop()
+
+    for {
+        select {
+        case <-ctx.Done():
+            return nil
+        case <-ticker.C:
+            if server := wlc.acquireServer(nil); server != nil {
+                return server
+            }
+        }
+    }
+}
@@ -0,0 +1,119 @@
+package balancer
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFailFast(t *testing.T) {
+    ab := newAlgorithmBackends(t, []int{1}, true)
+    ab.servers[0].MaxConnections = 1
+    wlc := NewWeightedLeastConnection(ab.servers, WithFailFast(true))
+
+    const n = 5
+    codes := make(chan int, n)
+    start := make(chan struct{})
+    var wg sync.WaitGroup
+    for i := 0; i < n; i++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            <-start
+            rec := serveRequest(wlc, algorithmRequest(i))
+            if rec.Code == http.StatusServiceUnavailable && rec.Header().Get("Retry-After") != "1" {
+                t.Errorf("503 has Retry-After %q, want 1", rec.Header().Get("Retry-After"))
+            }
+            codes <- rec.Code
+        }()
+    }
+    close(start)
+
+    // The rejected requests must not wait for the held one to finish.
+    rejected := 0
+    timeout := time.After(time.Second)
+    for rejected < n-1 {
+        select {
+        case code := <-codes:
+            if code != http.StatusServiceUnavailable {
+                t.Fatalf("status %d while the backend was full, want 503", code)
+            }
+            rejected++
+        case <-timeout:
+            t.Fatalf("%d of %d requests rejected within 1s, want %d", rejected, n, n-1)
+        }
+    }
+    for ab.arrivals.Load() < 1 {
+        time.Sleep(time.Millisecond)
+    }
+    if got := ab.arrivals.Load(); got != 1 {
+        t.Errorf("backend received %d requests with MaxConnections 1, want 1", got)
+    }
+
+    ab.releaseAll()
+    wg.Wait()
+    if code := <-codes; code != http.StatusOK {
+        t.Errorf("admitted request finished with %d, want 200", code)
+    }
+}
+
+func TestAtCapacityQueues(t *testing.T) {
+    ab := newAlgorithmBackends(t, []int{1}, true)
+    ab.servers[0].MaxConnections = 1
+    wlc := NewWeightedLeastConnection(ab.servers)
+
+    codes := make(chan int, 2)
+    for i := 0; i < 2; i++ {
+        go func() {
+            codes <- serveRequest(wlc, algorithmRequest(i)).Code
+        }()
+    }
+
+    select {
+    case code := <-codes:
+        t.Fatalf("request finished with %d while the backend was full, want it queued", code)
+    case <-time.After(100 * time.Millisecond):
+    }
+    if got := ab.arrivals.Load(); got != 1 {
+        t.Fatalf("backend received %d requests with MaxConnections 1, want 1", got)
+    }
+
+    ab.releaseAll()
+    for i := 0; i < 2; i++ {
+        if code := <-codes; code != http.StatusOK {
+            t.Errorf("status %d, want the queued request served once a slot freed", code)
+        }
+    }
+    if got := ab.arrivals.Load(); got != 2 {
+        t.Errorf("backend received %d requests, want 2", got)
+    }
+}
+
+func TestAtCapacityQueueTimeout(t *testing.T) {
+    ab := newAlgorithmBackends(t, []int{1}, true)
+    ab.servers[0].MaxConnections = 1
+    wlc := NewWeightedLeastConnection(ab.servers, WithMaxQueueWait(50*time.Millisecond))
+
+    held := make(chan int, 1)
+    go func() {
+        held <- serveRequest(wlc, algorithmRequest(0)).Code
+    }()
+    for ab.arrivals.Load() < 1 {
+        time.Sleep(time.Millisecond)
+    }
+
+    start := time.Now()
+    rec := serveRequest(wlc, algorithmRequest(1))
+    if rec.Code != http.StatusServiceUnavailable {
+        t.Errorf("status = %d, want 503 once the queue wait expired", rec.Code)
+    }
+    if elapsed := time.Since(start); elapsed > time.Second {
+        t.Errorf("queued request took %v, want it failed near the 50ms queue wait", elapsed)
+    }
+
+    ab.releaseAll()
+    if code := <-held; code != http.StatusOK {
+        t.Errorf("admitted request finished with %d, want 200", code)
+    }
+}
@@ -0,0 +1,65 @@
+package balancer
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"syscall"
+)
+
+// ErrorClass tells the balancer how to react to a proxy error.
+type ErrorClass int
+
+const (
+    // ErrorTransient errors are retried on another backend.
+    ErrorTransient ErrorClass = iota
+    // ErrorPermanent errors are not retried; the client gets a 502.
+    ErrorPermanent
+    // ErrorCircuitBreak errors take the backend out of rotation until its
+    // next passing health check, then retry on another backend.
+    ErrorCircuitBreak
+)
+
+func (c ErrorClass) String() string {
+    switch c {
+    case ErrorTransient:
+        return "transient"
+    case ErrorPermanent:
+        return "permanent"
+    case ErrorCircuitBreak:
+        return "circuit-break"
+    default:
+        return "unknown"
+    }
+}
+
+// ProxyErrorClassifier maps an error returned while proxying to a backend to
+// an ErrorClass.
+type ProxyErrorClassifier func(error) ErrorClass
+
+// classifyProxyError classifies an error proxying to s with the owning
+// balancer's classifier, or DefaultProxyErrorClassifier outside a balancer.
+func (s *Server) classifyProxyError(err error) ErrorClass {
+    if s.classifyError == nil {
+        return DefaultProxyErrorClassifier(err)
+    }
+    return s.classifyError(err)
+}
+
+// DefaultProxyErrorClassifier treats timeouts and refused connections as
+// transient and certificate failures as permanent. Anything else is
+// transient.
+func DefaultProxyErrorClassifier(err error) ErrorClass {
+    var certInvalid x509.CertificateInvalidError
+    var unknownAuthority x509.UnknownAuthorityError
+    var hostname x509.HostnameError
+
+    switch {
+    case errors.As(err, &certInvalid), errors.As(err, &unknownAuthority), errors.As(err, &hostname):
+        return ErrorPermanent
+    case errors.Is(err, context.DeadlineExceeded), errors.Is(err, syscall.ECONNREFUSED):
+        return ErrorTransient
+    default:
+        return ErrorTransient
+    }
+}
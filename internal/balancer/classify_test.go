@@ -0,0 +1,81 @@
+package balancer
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"syscall"
+	"testing"
+
+	lbtesting "github.com/Adi-ty/go-loadbalancer/pkg/testing"
+)
+
+func TestDefaultProxyErrorClassifier(t *testing.T) {
+    tests := []struct {
+        name string
+        err  error
+        want ErrorClass
+    }{
+        {name: "deadline exceeded", err: fmt.Errorf("dial: %w", context.DeadlineExceeded), want: ErrorTransient},
+        {name: "certificate invalid", err: x509.CertificateInvalidError{Reason: x509.Expired}, want: ErrorPermanent},
+        {name: "unknown authority", err: x509.UnknownAuthorityError{}, want: ErrorPermanent},
+        {name: "connection refused", err: fmt.Errorf("dial tcp: %w", syscall.ECONNREFUSED), want: ErrorTransient},
+        {name: "other", err: errors.New("boom"), want: ErrorTransient},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if got := DefaultProxyErrorClassifier(tt.err); got != tt.want {
+                t.Errorf("DefaultProxyErrorClassifier(%v) = %v, want %v", tt.err, got, tt.want)
+            }
+        })
+    }
+}
+
+func TestProxyErrorClassifierActions(t *testing.T) {
+    tests := []struct {
+        name        string
+        class       ErrorClass
+        method      string
+        wantStatus  int
+        wantRetried bool
+        wantHealthy bool
+    }{
+        {name: "transient", class: ErrorTransient, method: http.MethodGet, wantStatus: http.StatusOK, wantRetried: true, wantHealthy: true},
+        {name: "permanent", class: ErrorPermanent, method: http.MethodGet, wantStatus: http.StatusBadGateway, wantHealthy: true},
+        {name: "circuit break", class: ErrorCircuitBreak, method: http.MethodGet, wantStatus: http.StatusOK, wantRetried: true},
+        {name: "circuit break without retry", class: ErrorCircuitBreak, method: http.MethodPost, wantStatus: http.StatusBadGateway},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            backend := lbtesting.NewFakeBackend(t)
+            // Ties go to the first server, so every request tries the
+            // broken backend first.
+            broken := newTestServer(t, refusingURL(t), 1)
+            wlc := NewWeightedLeastConnection([]*Server{broken, newTestServer(t, backend.URL, 1)},
+                WithRetries(1, nil),
+                WithProxyErrorClassifier(func(err error) ErrorClass {
+                    if !errors.Is(err, syscall.ECONNREFUSED) {
+                        t.Errorf("classified %v, want the connection refused error", err)
+                    }
+                    return tt.class
+                }))
+
+            rec := serveRequest(wlc, httptest.NewRequest(tt.method, "/", strings.NewReader("body")))
+            if rec.Code != tt.wantStatus {
+                t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+            }
+            if retried := backend.CallCount() > 0; retried != tt.wantRetried {
+                t.Errorf("retried on the working backend = %v, want %v", retried, tt.wantRetried)
+            }
+            if healthy := broken.IsHealthy.Load(); healthy != tt.wantHealthy {
+                t.Errorf("broken backend healthy = %v, want %v", healthy, tt.wantHealthy)
+            }
+        })
+    }
+}
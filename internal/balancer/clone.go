@@ -0,0 +1,114 @@
+package balancer
+
+import (
+	"slices"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Clone returns a new server for the same backend with the same
+// configuration. Counters, statistics and health state start afresh as from
+// NewServer, and the clone has its own transport and connection pool.
+func (s *Server) Clone() *Server {
+    clone, err := NewServer(s.URL.String(), s.Weight)
+    if err != nil {
+        // s was built by NewServer, so its URL and weight are valid.
+        panic(err)
+    }
+
+    clone.Transport = s.Transport.Clone()
+    clone.setDialer(s.dialer)
+
+    clone.MaxConnections = s.MaxConnections
+    clone.Priority = s.Priority
+    clone.HealthPaths = slices.Clone(s.HealthPaths)
+    clone.HealthCheckExpectedStatuses = slices.Clone(s.HealthCheckExpectedStatuses)
+    clone.HealthCheckBodyContains = s.HealthCheckBodyContains
+    clone.HealthCheckBodyNotContains = s.HealthCheckBodyNotContains
+    clone.HealthCheckPingFirst = s.HealthCheckPingFirst
+    clone.HealthCheckConsecutiveSuccessesRequired = s.HealthCheckConsecutiveSuccessesRequired
+    clone.HealthCheckParseResponse = s.HealthCheckParseResponse
+    clone.HealthFlappingThreshold = s.HealthFlappingThreshold
+    clone.Tags = s.TagsSnapshot()
+    clone.CustomDirector = s.CustomDirector
+    clone.PreserveHost = s.PreserveHost
+    clone.UseSNIFromHost = s.UseSNIFromHost
+    clone.TrustedForwardedProtoHops = s.TrustedForwardedProtoHops
+    clone.ProxyHideHeaders = slices.Clone(s.ProxyHideHeaders)
+    clone.ProxyPassHeaders = slices.Clone(s.ProxyPassHeaders)
+    clone.ConnectJitter = s.ConnectJitter
+    clone.DebugHeader = s.DebugHeader
+    clone.BackendLoadHeader = s.BackendLoadHeader
+    clone.SLOTarget = s.SLOTarget
+    clone.classifyError = s.classifyError
+    clone.requestBuffers = s.requestBuffers
+    return clone
+}
+
+// Clone returns a new balancer with the same options and a clone of every
+// server, so it shares no counters or connections with wlc. Request and
+// health statistics start at zero. Shared collaborators such as the event
+// bus, failover pool and Prometheus registerer are reused, not copied.
+func (wlc *WeightedLeastConnection) Clone() *WeightedLeastConnection {
+    servers := wlc.All()
+    for i, server := range servers {
+        servers[i] = server.Clone()
+    }
+
+    clone := &WeightedLeastConnection{
+        BackendPool: NewBackendPool(servers),
+        startTime:   time.Now(),
+
+        WaitForHealthy:   wlc.WaitForHealthy,
+        WatchdogInterval: wlc.WatchdogInterval,
+        FailFast:         wlc.FailFast,
+        MaxQueueWait:     wlc.MaxQueueWait,
+
+        healthCheckInterval:    wlc.healthCheckInterval,
+        maxHealthCheckInterval: wlc.maxHealthCheckInterval,
+        healthCheckConcurrency: wlc.healthCheckConcurrency,
+
+        maxURLBytes:         wlc.maxURLBytes,
+        propagateDeadline:   wlc.propagateDeadline,
+        stripTrailers:       wlc.stripTrailers,
+        maxRequestBody:      wlc.maxRequestBody,
+        bufferUnknownLength: wlc.bufferUnknownLength,
+        latencyBudget:       wlc.latencyBudget,
+        minRemainingBudget:  wlc.minRemainingBudget,
+        trafficShaping:      slices.Clone(wlc.trafficShaping),
+
+        events:      wlc.events,
+        maintenance: wlc.maintenance,
+        failover:    wlc.failover,
+
+        globalOptionsAllow:  wlc.globalOptionsAllow,
+        connectAllowedPorts: slices.Clone(wlc.connectAllowedPorts),
+
+        maxRetries:       wlc.maxRetries,
+        maxRetryDuration: wlc.maxRetryDuration,
+        retryMethods:     slices.Clone(wlc.retryMethods),
+        classifyError:    wlc.classifyError,
+
+        requestBuffers:  wlc.requestBuffers,
+        responseBuffers: wlc.responseBuffers,
+
+        diagnosticHeaders: wlc.diagnosticHeaders,
+        scorer:            wlc.scorer,
+        rampDown:          wlc.rampDown,
+
+        poolName:        wlc.poolName,
+        registerer:      wlc.registerer,
+        extraCollectors: slices.Clone(wlc.extraCollectors),
+    }
+
+    // A shared limiter would let one balancer's health checks use up the
+    // other's budget.
+    if limiter := wlc.HealthCheckRateLimiter; limiter != nil {
+        clone.HealthCheckRateLimiter = rate.NewLimiter(limiter.Limit(), limiter.Burst())
+    }
+    for _, server := range servers {
+        clone.adopt(server)
+    }
+    return clone
+}
@@ -0,0 +1,63 @@
+package balancer
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	lbtesting "github.com/Adi-ty/go-loadbalancer/pkg/testing"
+)
+
+func TestCloneStartsFresh(t *testing.T) {
+    var servers []*Server
+    for i := 0; i < 2; i++ {
+        server := newTestServer(t, lbtesting.NewFakeBackend(t).URL, i+1)
+        server.MaxConnections = 10
+        server.PreserveHost = true
+        servers = append(servers, server)
+    }
+    wlc := NewWeightedLeastConnection(servers)
+    for i := 0; i < 100; i++ {
+        serveRequest(wlc, httptest.NewRequest(http.MethodGet, "/", nil))
+    }
+
+    clone := wlc.Clone()
+    if got := clone.TotalRequests(); got != 0 {
+        t.Errorf("clone TotalRequests() = %d, want 0", got)
+    }
+    cloned := clone.All()
+    if len(cloned) != len(servers) {
+        t.Fatalf("clone has %d servers, want %d", len(cloned), len(servers))
+    }
+    for i, server := range cloned {
+        original := servers[i]
+        if server == original {
+            t.Fatalf("clone shares server %s with the original", original.URL.Host)
+        }
+        if server.URL.String() != original.URL.String() || server.Weight != original.Weight ||
+            server.MaxConnections != original.MaxConnections || server.PreserveHost != original.PreserveHost {
+            t.Errorf("clone of %s did not keep its configuration", original.URL.Host)
+        }
+        if got := server.RequestCount.Load(); got != 0 {
+            t.Errorf("clone of %s RequestCount = %d, want 0", original.URL.Host, got)
+        }
+    }
+
+    // Traffic through the clone leaves the original's counters alone.
+    for i := 0; i < 10; i++ {
+        serveRequest(clone, httptest.NewRequest(http.MethodGet, "/", nil))
+    }
+    if got := clone.TotalRequests(); got != 10 {
+        t.Errorf("clone TotalRequests() = %d after 10 requests, want 10", got)
+    }
+    if got := wlc.TotalRequests(); got != 100 {
+        t.Errorf("original TotalRequests() = %d after requests to the clone, want 100", got)
+    }
+    var total uint64
+    for _, server := range servers {
+        total += server.RequestCount.Load()
+    }
+    if total != 100 {
+        t.Errorf("original servers counted %d requests, want 100", total)
+    }
+}
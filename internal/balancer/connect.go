@@ -0,0 +1,113 @@
+package balancer
+
+import (
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// connectDialTimeout bounds how long a CONNECT request waits for the backend
+// connection to be established.
+const connectDialTimeout = 10 * time.Second
+
+// serveConnect tunnels a CONNECT request to a backend. The target's hostname
+// picks the backend: only servers whose host matches it are considered, so
+// the balancer cannot be used to reach arbitrary hosts. The backend is dialed
+// on the target's port, which must be one of connectAllowedPorts.
+func (wlc *WeightedLeastConnection) serveConnect(w http.ResponseWriter, r *http.Request) {
+    if len(wlc.connectAllowedPorts) == 0 {
+        http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+        return
+    }
+
+    host, portStr, err := net.SplitHostPort(r.Host)
+    if err != nil {
+        http.Error(w, "Bad Request: CONNECT target must be host:port", http.StatusBadRequest)
+        return
+    }
+    port, err := strconv.Atoi(portStr)
+    if err != nil || !slices.Contains(wlc.connectAllowedPorts, port) {
+        log.Printf("[CONNECT] Rejected tunnel to %s: port not allowed", r.Host)
+        http.Error(w, "Forbidden: CONNECT port not allowed", http.StatusForbidden)
+        return
+    }
+
+    exclude := make(map[*Server]bool)
+    for _, server := range wlc.All() {
+        if server.URL.Hostname() != host {
+            exclude[server] = true
+        }
+    }
+    server := wlc.acquireServer(exclude)
+    if server == nil {
+        http.Error(w, "Service Unavailable: No healthy backend for CONNECT target.", http.StatusServiceUnavailable)
+        return
+    }
+    defer server.ActiveConnections.Add(-1)
+
+    backendConn, err := net.DialTimeout("tcp", net.JoinHostPort(server.URL.Hostname(), portStr), connectDialTimeout)
+    if err != nil {
+        log.Printf("[CONNECT] Dial %s via %s failed: %v", r.Host, server.URL.Host, err)
+        http.Error(w, "Bad Gateway", http.StatusBadGateway)
+        return
+    }
+    defer backendConn.Close()
+
+    clientConn, buffered, err := http.NewResponseController(w).Hijack()
+    if err != nil {
+        log.Printf("[CONNECT] Cannot hijack connection for %s: %v", r.Host, err)
+        http.Error(w, "CONNECT not supported on this connection", http.StatusInternalServerError)
+        return
+    }
+    defer clientConn.Close()
+
+    server.RequestCount.Add(1)
+
+    wlc.mu2.Lock()
+    wlc.totalRequests++
+    wlc.mu2.Unlock()
+
+    if _, err := clientConn.Write([]byte("HTTP/1.1 200 Connection Established\r\n\r\n")); err != nil {
+        return
+    }
+    log.Printf("[CONNECT] Tunnel %s -> %s opened (Active: %d)", r.RemoteAddr, server.URL.Host, server.ActiveConnections.Load())
+
+    // Bytes the client sent after the CONNECT request may already sit in
+    // the server's read buffer.
+    if n := buffered.Reader.Buffered(); n > 0 {
+        peeked, _ := buffered.Reader.Peek(n)
+        if _, err := backendConn.Write(peeked); err != nil {
+            return
+        }
+    }
+
+    done := make(chan struct{}, 2)
+    go func() {
+        io.Copy(backendConn, clientConn)
+        closeWrite(backendConn)
+        done <- struct{}{}
+    }()
+    go func() {
+        io.Copy(clientConn, backendConn)
+        closeWrite(clientConn)
+        done <- struct{}{}
+    }()
+    <-done
+    <-done
+
+    log.Printf("[CONNECT] Tunnel %s -> %s closed", r.RemoteAddr, server.URL.Host)
+}
+
+// closeWrite half-closes conn so the peer sees EOF while data still flows the
+// other way.
+func closeWrite(conn net.Conn) {
+    if cw, ok := conn.(interface{ CloseWrite() error }); ok {
+        cw.CloseWrite()
+        return
+    }
+    conn.Close()
+}
@@ -0,0 +1,67 @@
+package balancer
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestConnectTunnel(t *testing.T) {
+    target := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        w.Write([]byte("secret"))
+    }))
+    defer target.Close()
+    _, portStr, _ := net.SplitHostPort(target.Listener.Addr().String())
+    port, _ := strconv.Atoi(portStr)
+
+    // The backend's host, not its port, is what CONNECT targets must match.
+    server := newTestServer(t, "http://127.0.0.1:1", 1)
+    wlc := NewWeightedLeastConnection([]*Server{server}, WithConnectTunneling([]int{port}))
+    proxy := httptest.NewServer(wlc)
+    defer proxy.Close()
+    proxyURL, _ := url.Parse(proxy.URL)
+
+    transport := target.Client().Transport.(*http.Transport).Clone()
+    transport.Proxy = http.ProxyURL(proxyURL)
+    client := &http.Client{Transport: transport, Timeout: 5 * time.Second}
+
+    resp, err := client.Get(target.URL)
+    if err != nil {
+        t.Fatalf("GET %s through the CONNECT tunnel: %v", target.URL, err)
+    }
+    body, _ := io.ReadAll(resp.Body)
+    resp.Body.Close()
+    if resp.StatusCode != http.StatusOK || string(body) != "secret" {
+        t.Errorf("response = %d %q, want 200 \"secret\"", resp.StatusCode, body)
+    }
+    if got := server.RequestCount.Load(); got != 1 {
+        t.Errorf("RequestCount = %d, want 1 for the tunnel", got)
+    }
+
+    transport.CloseIdleConnections()
+    deadline := time.Now().Add(5 * time.Second)
+    for server.ActiveConnections.Load() != 0 {
+        if time.Now().After(deadline) {
+            t.Fatalf("ActiveConnections = %d after the tunnel closed, want 0", server.ActiveConnections.Load())
+        }
+        time.Sleep(5 * time.Millisecond)
+    }
+
+    // Ports outside the allowed list are refused.
+    req := httptest.NewRequest(http.MethodConnect, "/", nil)
+    req.Host = "127.0.0.1:22"
+    if rec := serveRequest(wlc, req); rec.Code != http.StatusForbidden {
+        t.Errorf("CONNECT to a port not allowed = %d, want %d", rec.Code, http.StatusForbidden)
+    }
+    // So are hosts that are not backends.
+    req = httptest.NewRequest(http.MethodConnect, "/", nil)
+    req.Host = net.JoinHostPort("192.0.2.1", portStr)
+    if rec := serveRequest(wlc, req); rec.Code != http.StatusServiceUnavailable {
+        t.Errorf("CONNECT to a host that is not a backend = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+    }
+}
@@ -0,0 +1,50 @@
+package balancer
+
+import (
+	"context"
+	"math/rand/v2"
+	"net"
+	"time"
+)
+
+// dialFunc matches http.Transport.DialContext.
+type dialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// setDialer makes d the dialer for new backend connections, delayed by
+// ConnectJitter where it applies.
+func (s *Server) setDialer(d *net.Dialer) {
+    s.dialer = d
+    s.Transport.DialContext = s.jitteredDial(d.DialContext)
+}
+
+// jitteredDial wraps dial so that, when ConnectJitter is set and the
+// backend has just become healthy again, each dial first waits a random time
+// up to ConnectJitter. After a backend restart every balancer reconnects to
+// it at once; the delay spreads their connections out. Dials at startup and
+// once a connection has been made are not delayed.
+func (s *Server) jitteredDial(dial dialFunc) dialFunc {
+    return func(ctx context.Context, network, addr string) (net.Conn, error) {
+        if jitter := s.ConnectJitter; jitter > 0 && s.newlyHealthy() {
+            timer := time.NewTimer(rand.N(jitter))
+            select {
+            case <-ctx.Done():
+                timer.Stop()
+                return nil, ctx.Err()
+            case <-timer.C:
+            }
+        }
+
+        conn, err := dial(ctx, network, addr)
+        if err == nil {
+            s.lastConnectedTime.Store(time.Now().UnixNano())
+        }
+        return conn, err
+    }
+}
+
+// newlyHealthy reports whether the server has turned healthy after being
+// unhealthy and no connection has been made to it since.
+func (s *Server) newlyHealthy() bool {
+    recovered := s.recoveredTime.Load()
+    return recovered != 0 && s.lastConnectedTime.Load() < recovered
+}
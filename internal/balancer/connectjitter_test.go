@@ -0,0 +1,78 @@
+package balancer
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	lbtesting "github.com/Adi-ty/go-loadbalancer/pkg/testing"
+)
+
+// timedDials dials the server's backend from n goroutines at once and
+// returns how long after the start each connection was made.
+func timedDials(t *testing.T, server *Server, n int) []time.Duration {
+    t.Helper()
+
+    start := time.Now()
+    delays := make([]time.Duration, n)
+    var wg sync.WaitGroup
+    for i := 0; i < n; i++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            conn, err := server.Transport.DialContext(context.Background(), "tcp", server.URL.Host)
+            if err != nil {
+                t.Errorf("dial: %v", err)
+                return
+            }
+            delays[i] = time.Since(start)
+            conn.Close()
+        }()
+    }
+    wg.Wait()
+    return delays
+}
+
+func TestConnectJitterAfterRestart(t *testing.T) {
+    backend := lbtesting.NewFakeBackend(t)
+    server := newTestServer(t, backend.URL, 1)
+    const jitter = 200 * time.Millisecond
+    server.ConnectJitter = jitter
+
+    // The backend restarts: it fails a health check, then passes again.
+    backend.SetHealthy(false)
+    server.RunHealthCheck()
+    backend.SetHealthy(true)
+    if err := server.RunHealthCheck(); err != nil {
+        t.Fatalf("health check after restart: %v", err)
+    }
+
+    delays := timedDials(t, server, 20)
+    if spread := slices.Max(delays) - slices.Min(delays); spread < jitter/2 {
+        t.Errorf("connections spread over %v, want at least %v", spread, jitter/2)
+    }
+
+    // Once connected again, further dials are not delayed.
+    if delays := timedDials(t, server, 5); slices.Max(delays) > jitter/4 {
+        t.Errorf("dial after reconnecting took %v, want no jitter", slices.Max(delays))
+    }
+}
+
+func TestConnectJitterNotAtStartup(t *testing.T) {
+    backend := lbtesting.NewFakeBackend(t)
+    server := newTestServer(t, backend.URL, 1)
+    server.ConnectJitter = time.Second
+    server.Transport.IdleConnTimeout = time.Nanosecond
+
+    // A backend that was never unhealthy is dialled straight away, however
+    // long ago the last connection was made.
+    for i := 0; i < 2; i++ {
+        if delays := timedDials(t, server, 5); slices.Max(delays) > 100*time.Millisecond {
+            t.Errorf("round %d: dial took %v, want no jitter", i, slices.Max(delays))
+        }
+        time.Sleep(time.Millisecond)
+    }
+}
+
@@ -0,0 +1,55 @@
+package balancer
+
+import (
+	"io"
+	"net/http"
+	"sync/atomic"
+)
+
+// countingReader adds the number of bytes read to n.
+type countingReader struct {
+    io.ReadCloser
+    n       *atomic.Uint64
+    buffers *bytePool // Nil copies through io.Copy's own buffer
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+    read, err := cr.ReadCloser.Read(p)
+    cr.n.Add(uint64(read))
+    return read, err
+}
+
+// WriteTo copies the body to w through a pooled buffer, so the transport
+// streaming a chunked body does not allocate one per request.
+func (cr *countingReader) WriteTo(w io.Writer) (int64, error) {
+    if cr.buffers == nil {
+        return io.Copy(w, readerOnly{cr})
+    }
+    buf := cr.buffers.Get()
+    defer cr.buffers.Put(buf)
+    return io.CopyBuffer(w, readerOnly{cr}, buf)
+}
+
+// readerOnly hides every method but Read, so io.Copy does not call back
+// into WriteTo.
+type readerOnly struct {
+    io.Reader
+}
+
+// countingWriter adds the number of response body bytes written to n.
+type countingWriter struct {
+    http.ResponseWriter
+    n *atomic.Uint64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+    written, err := cw.ResponseWriter.Write(p)
+    cw.n.Add(uint64(written))
+    return written, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer for
+// flushing and hijacking.
+func (cw *countingWriter) Unwrap() http.ResponseWriter {
+    return cw.ResponseWriter
+}
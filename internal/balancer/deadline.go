@@ -0,0 +1,148 @@
+package balancer
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// withClientDeadline bounds r's context by the timeout the client sent in
+// X-Timeout-Ms or Grpc-Timeout, counted from when the request arrived.
+// Requests without either header are returned unchanged.
+func withClientDeadline(r *http.Request, arrived time.Time) (*http.Request, context.CancelFunc) {
+    timeout, ok := clientTimeout(r)
+    if !ok {
+        return r, func() {}
+    }
+    ctx, cancel := context.WithDeadline(r.Context(), arrived.Add(timeout))
+    return r.WithContext(ctx), cancel
+}
+
+// clientTimeout returns the timeout the client sent with r, from
+// X-Timeout-Ms or else Grpc-Timeout.
+func clientTimeout(r *http.Request) (time.Duration, bool) {
+    if v := r.Header.Get("X-Timeout-Ms"); v != "" {
+        ms, err := strconv.ParseInt(v, 10, 64)
+        if err != nil || ms < 0 {
+            return 0, false
+        }
+        return time.Duration(ms) * time.Millisecond, true
+    }
+    return parseGRPCTimeout(r.Header.Get("Grpc-Timeout"))
+}
+
+// propagateDeadline forwards the time left on the request context to the
+// backend so it can abandon work the client will no longer wait for.
+func propagateDeadline(r *http.Request) {
+    deadline, ok := r.Context().Deadline()
+    if !ok {
+        return
+    }
+
+    remaining := time.Until(deadline).Milliseconds()
+    if remaining < 0 {
+        remaining = 0
+    }
+
+    r.Header.Set("X-Timeout-Ms", strconv.FormatInt(remaining, 10))
+    if strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+        r.Header.Set("Grpc-Timeout", strconv.FormatInt(remaining, 10)+"m")
+    }
+}
+
+// Headers carrying a client's latency budget in and the part of it left
+// over out to the backend.
+const (
+    RequestDeadlineHeader   = "X-Request-Deadline-Ms"
+    RemainingDeadlineHeader = "X-Remaining-Deadline-Ms"
+)
+
+// DefaultMinRemainingBudget is the least latency budget a request may have
+// left when it is forwarded.
+const DefaultMinRemainingBudget = 10 * time.Millisecond
+
+// requestBudget returns the latency budget the client gave r, from
+// X-Request-Deadline-Ms or else Grpc-Timeout.
+func requestBudget(r *http.Request) (time.Duration, bool) {
+    if v := r.Header.Get(RequestDeadlineHeader); v != "" {
+        ms, err := strconv.ParseInt(v, 10, 64)
+        if err != nil || ms < 0 {
+            return 0, false
+        }
+        return time.Duration(ms) * time.Millisecond, true
+    }
+    return parseGRPCTimeout(r.Header.Get("Grpc-Timeout"))
+}
+
+// parseGRPCTimeout parses a Grpc-Timeout value, an integer of at most eight
+// digits followed by a unit of H, M, S, m, u or n.
+func parseGRPCTimeout(v string) (time.Duration, bool) {
+    if len(v) < 2 || len(v) > 9 {
+        return 0, false
+    }
+
+    n, err := strconv.ParseInt(v[:len(v)-1], 10, 64)
+    if err != nil || n < 0 {
+        return 0, false
+    }
+
+    var unit time.Duration
+    switch v[len(v)-1] {
+    case 'H':
+        unit = time.Hour
+    case 'M':
+        unit = time.Minute
+    case 'S':
+        unit = time.Second
+    case 'm':
+        unit = time.Millisecond
+    case 'u':
+        unit = time.Microsecond
+    case 'n':
+        unit = time.Nanosecond
+    default:
+        return 0, false
+    }
+    return time.Duration(n) * unit, true
+}
+
+// applyLatencyBudget charges the time r has spent in the balancer since
+// arrived against its latency budget. It returns false if too little of the
+// budget is left to be worth forwarding; otherwise it passes the remainder on
+// to the backend and bounds the request's context by it.
+func (wlc *WeightedLeastConnection) applyLatencyBudget(r *http.Request, arrived time.Time) (*http.Request, context.CancelFunc, bool) {
+    budget, ok := requestBudget(r)
+    if !ok {
+        return r, func() {}, true
+    }
+
+    remaining := budget - time.Since(arrived)
+    if remaining < wlc.minRemainingBudget {
+        return r, func() {}, false
+    }
+
+    r.Header.Set(RemainingDeadlineHeader, strconv.FormatInt(remaining.Milliseconds(), 10))
+    if r.Header.Get("Grpc-Timeout") != "" {
+        r.Header.Set("Grpc-Timeout", strconv.FormatInt(remaining.Milliseconds(), 10)+"m")
+    }
+
+    ctx, cancel := context.WithTimeout(r.Context(), remaining)
+    return r.WithContext(ctx), cancel, true
+}
+
+// proxyErrorStatus is the status sent to the client when proxying failed
+// with err: 413 if the request body passed the maximum size, 504 if the
+// request ran out of time, 502 otherwise.
+func proxyErrorStatus(err error) int {
+    var tooLarge *http.MaxBytesError
+    switch {
+    case errors.As(err, &tooLarge):
+        return http.StatusRequestEntityTooLarge
+    case errors.Is(err, context.DeadlineExceeded):
+        return http.StatusGatewayTimeout
+    }
+    return http.StatusBadGateway
+}
@@ -0,0 +1,140 @@
+package balancer
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	lbtesting "github.com/Adi-ty/go-loadbalancer/pkg/testing"
+)
+
+func TestDeadlinePropagationFromContext(t *testing.T) {
+    backend := lbtesting.NewFakeBackend(t)
+    wlc := NewWeightedLeastConnection([]*Server{newTestServer(t, backend.URL, 1)}, WithDeadlinePropagation(true))
+
+    ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+    defer cancel()
+    rec := serveRequest(wlc, httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx))
+    if rec.Code != http.StatusOK {
+        t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+    }
+
+    got, err := strconv.Atoi(backend.LastRequest().Header.Get("X-Timeout-Ms"))
+    if err != nil || got < 450 || got > 500 {
+        t.Errorf("X-Timeout-Ms = %q, want within 50ms of 500", backend.LastRequest().Header.Get("X-Timeout-Ms"))
+    }
+}
+
+func TestDeadlinePropagationFromHeaders(t *testing.T) {
+    tests := []struct {
+        name        string
+        header      string
+        value       string
+        contentType string
+        wantHeader  string
+        wantMin     int
+        wantMax     int
+        wantSuffix  string
+    }{
+        {name: "timeout ms", header: "X-Timeout-Ms", value: "500", wantHeader: "X-Timeout-Ms", wantMin: 450, wantMax: 500},
+        {name: "grpc timeout", header: "Grpc-Timeout", value: "300m", contentType: "application/grpc",
+            wantHeader: "Grpc-Timeout", wantMin: 250, wantMax: 300, wantSuffix: "m"},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            backend := lbtesting.NewFakeBackend(t)
+            wlc := NewWeightedLeastConnection([]*Server{newTestServer(t, backend.URL, 1)}, WithDeadlinePropagation(true))
+
+            req := httptest.NewRequest(http.MethodPost, "/", nil)
+            req.Header.Set(tt.header, tt.value)
+            if tt.contentType != "" {
+                req.Header.Set("Content-Type", tt.contentType)
+            }
+            if rec := serveRequest(wlc, req); rec.Code != http.StatusOK {
+                t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+            }
+
+            value := backend.LastRequest().Header.Get(tt.wantHeader)
+            if len(value) < len(tt.wantSuffix) || value[len(value)-len(tt.wantSuffix):] != tt.wantSuffix {
+                t.Fatalf("%s = %q, want suffix %q", tt.wantHeader, value, tt.wantSuffix)
+            }
+            got, err := strconv.Atoi(value[:len(value)-len(tt.wantSuffix)])
+            if err != nil || got < tt.wantMin || got > tt.wantMax {
+                t.Errorf("%s = %q, want between %d and %d", tt.wantHeader, value, tt.wantMin, tt.wantMax)
+            }
+        })
+    }
+}
+
+func TestDeadlinePropagationCancelsSlowBackend(t *testing.T) {
+    backend := lbtesting.NewFakeBackend(t)
+    backend.SetDelay(time.Second)
+    wlc := NewWeightedLeastConnection([]*Server{newTestServer(t, backend.URL, 1)}, WithDeadlinePropagation(true))
+
+    req := httptest.NewRequest(http.MethodGet, "/", nil)
+    req.Header.Set("X-Timeout-Ms", "50")
+
+    start := time.Now()
+    rec := serveRequest(wlc, req)
+    if rec.Code != http.StatusGatewayTimeout {
+        t.Errorf("status = %d, want %d", rec.Code, http.StatusGatewayTimeout)
+    }
+    if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+        t.Errorf("request took %v, want it cut off near the client's 50ms deadline", elapsed)
+    }
+}
+
+func TestDeadlinePropagationDisabled(t *testing.T) {
+    backend := lbtesting.NewFakeBackend(t)
+    wlc := NewWeightedLeastConnection([]*Server{newTestServer(t, backend.URL, 1)})
+
+    ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+    defer cancel()
+    serveRequest(wlc, httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx))
+
+    if got := backend.LastRequest().Header.Get("X-Timeout-Ms"); got != "" {
+        t.Errorf("X-Timeout-Ms = %q without deadline propagation", got)
+    }
+}
+
+func TestLatencyBudget(t *testing.T) {
+    // queue sends a request with the given budget while the only backend
+    // is at capacity, freeing it after 80ms.
+    queue := func(t *testing.T, budget string) (*httptest.ResponseRecorder, *lbtesting.FakeBackend) {
+        backend := lbtesting.NewFakeBackend(t)
+        server := newTestServer(t, backend.URL, 1)
+        server.MaxConnections = 1
+        server.ActiveConnections.Store(1)
+        wlc := NewWeightedLeastConnection([]*Server{server}, WithLatencyBudget(50*time.Millisecond))
+
+        time.AfterFunc(80*time.Millisecond, func() { server.ActiveConnections.Add(-1) })
+        req := httptest.NewRequest(http.MethodGet, "/", nil)
+        req.Header.Set(RequestDeadlineHeader, budget)
+        return serveRequest(wlc, req), backend
+    }
+
+    t.Run("exhausted in queue", func(t *testing.T) {
+        rec, backend := queue(t, "100")
+        if rec.Code != http.StatusGatewayTimeout {
+            t.Errorf("status = %d, want %d", rec.Code, http.StatusGatewayTimeout)
+        }
+        if backend.CallCount() != 0 {
+            t.Errorf("backend received %d requests, want the request rejected before forwarding", backend.CallCount())
+        }
+    })
+
+    t.Run("remainder forwarded", func(t *testing.T) {
+        rec, backend := queue(t, "500")
+        if rec.Code != http.StatusOK {
+            t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+        }
+        value := backend.LastRequest().Header.Get(RemainingDeadlineHeader)
+        if got, err := strconv.Atoi(value); err != nil || got < 370 || got > 420 {
+            t.Errorf("%s = %q, want the 500ms budget less about 80ms in the queue", RemainingDeadlineHeader, value)
+        }
+    })
+}
@@ -0,0 +1,29 @@
+package balancer
+
+import (
+	"context"
+	"net/http"
+)
+
+type diagnosticsKey struct{}
+
+// withDiagnostics asks the backend's ModifyResponse to add X-LB-* headers
+// naming the backend and algorithm that served r.
+func withDiagnostics(r *http.Request, algorithm string) *http.Request {
+    return r.WithContext(context.WithValue(r.Context(), diagnosticsKey{}, algorithm))
+}
+
+// setDiagnosticHeaders adds the X-LB-* headers to resp if they were requested
+// with withDiagnostics.
+func (s *Server) setDiagnosticHeaders(resp *http.Response) {
+    algorithm, ok := resp.Request.Context().Value(diagnosticsKey{}).(string)
+    if !ok {
+        return
+    }
+
+    resp.Header.Set("X-LB-Server", s.URL.Host)
+    resp.Header.Set("X-LB-Algorithm", algorithm)
+    if id := resp.Request.Header.Get("X-Request-ID"); id != "" {
+        resp.Header.Set("X-LB-Request-ID", id)
+    }
+}
@@ -0,0 +1,37 @@
+package balancer
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Adi-ty/go-loadbalancer/pkg/middleware"
+	lbtesting "github.com/Adi-ty/go-loadbalancer/pkg/testing"
+)
+
+func TestDiagnosticHeaders(t *testing.T) {
+    for _, enabled := range []bool{true, false} {
+        backend := lbtesting.NewFakeBackend(t)
+        server := newTestServer(t, backend.URL, 1)
+        wlc := NewWeightedLeastConnection([]*Server{server}, WithDiagnosticHeaders(enabled))
+        h := middleware.Chain(middleware.RequestID())(wlc)
+
+        rec := serveRequest(h, httptest.NewRequest(http.MethodGet, "/", nil))
+        if rec.Header().Get(middleware.RequestIDHeader) == "" {
+            t.Fatal("no request ID assigned")
+        }
+        want := map[string]string{
+            "X-LB-Server":     server.URL.Host,
+            "X-LB-Algorithm":  "wlc",
+            "X-LB-Request-ID": rec.Header().Get(middleware.RequestIDHeader),
+        }
+        for name, value := range want {
+            if !enabled {
+                value = ""
+            }
+            if got := rec.Header().Get(name); got != value {
+                t.Errorf("diagnostics %v: %s = %q, want %q", enabled, name, got, value)
+            }
+        }
+    }
+}
@@ -0,0 +1,51 @@
+package balancer
+
+import (
+	"context"
+	"time"
+)
+
+// drainPollInterval is how often DrainUntilIdle checks for in-flight
+// requests.
+const drainPollInterval = 50 * time.Millisecond
+
+// totalInFlight returns the number of requests currently being proxied
+// across all backends.
+func (p *BackendPool) totalInFlight() int64 {
+    var total int64
+    for _, server := range p.All() {
+        total += int64(server.ActiveConnections.Load())
+    }
+    return total
+}
+
+// DrainUntilIdle blocks until no request is in flight to any backend,
+// returning nil, or until ctx is done, returning ctx.Err().
+func (p *BackendPool) DrainUntilIdle(ctx context.Context) error {
+    ticker := time.NewTicker(drainPollInterval)
+    defer ticker.Stop()
+
+    for {
+        if p.totalInFlight() == 0 {
+            return nil
+        }
+
+        select {
+        case <-ctx.Done():
+            return ctx.Err()
+        case <-ticker.C:
+        }
+    }
+}
+
+// StartDraining makes the balancer answer new requests with 503 while those
+// already in flight complete. /health reports unhealthy so upstream
+// balancers stop sending traffic.
+func (wlc *WeightedLeastConnection) StartDraining() {
+    wlc.draining.Store(true)
+}
+
+// Draining reports whether StartDraining has been called.
+func (wlc *WeightedLeastConnection) Draining() bool {
+    return wlc.draining.Load()
+}
@@ -0,0 +1,100 @@
+package balancer
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDrainUntilIdle(t *testing.T) {
+    ab := newAlgorithmBackends(t, []int{1, 1}, true)
+    wlc := NewWeightedLeastConnection(ab.servers)
+
+    var requests sync.WaitGroup
+    for i := 0; i < 10; i++ {
+        requests.Add(1)
+        go func() {
+            defer requests.Done()
+            serveRequest(wlc, algorithmRequest(i))
+        }()
+    }
+    deadline := time.Now().Add(5 * time.Second)
+    for ab.arrivals.Load() < 10 {
+        if time.Now().After(deadline) {
+            t.Fatal("requests did not reach the backends")
+        }
+        time.Sleep(time.Millisecond)
+    }
+
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+    drained := make(chan error, 1)
+    go func() {
+        drained <- wlc.DrainUntilIdle(ctx)
+    }()
+
+    select {
+    case err := <-drained:
+        t.Fatalf("DrainUntilIdle returned %v with 10 requests in flight", err)
+    case <-time.After(200 * time.Millisecond):
+    }
+
+    ab.releaseAll()
+    requests.Wait()
+    completed := time.Now()
+    if err := <-drained; err != nil {
+        t.Fatalf("DrainUntilIdle: %v", err)
+    }
+    if elapsed := time.Since(completed); elapsed > drainPollInterval+20*time.Millisecond {
+        t.Errorf("DrainUntilIdle returned %v after the last request completed, want within %v", elapsed, drainPollInterval)
+    }
+}
+
+func TestDrainUntilIdleTimesOut(t *testing.T) {
+    ab := newAlgorithmBackends(t, []int{1}, true)
+    wlc := NewWeightedLeastConnection(ab.servers)
+
+    go serveRequest(wlc, algorithmRequest(0))
+    for ab.arrivals.Load() < 1 {
+        time.Sleep(time.Millisecond)
+    }
+
+    ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+    defer cancel()
+    if err := wlc.DrainUntilIdle(ctx); err != context.DeadlineExceeded {
+        t.Errorf("DrainUntilIdle = %v with a request stuck in flight, want context.DeadlineExceeded", err)
+    }
+}
+
+func TestStartDraining(t *testing.T) {
+    ab := newAlgorithmBackends(t, []int{1}, true)
+    wlc := NewWeightedLeastConnection(ab.servers)
+
+    inFlight := make(chan int, 1)
+    go func() {
+        inFlight <- serveRequest(wlc, algorithmRequest(0)).Code
+    }()
+    for ab.arrivals.Load() < 1 {
+        time.Sleep(time.Millisecond)
+    }
+
+    wlc.StartDraining()
+    rec := serveRequest(wlc, algorithmRequest(1))
+    if rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Connection") != "close" {
+        t.Errorf("new request while draining got %d, Connection %q; want 503 and close", rec.Code, rec.Header().Get("Connection"))
+    }
+    if got := ab.arrivals.Load(); got != 1 {
+        t.Errorf("backend received %d requests, want only the one in flight", got)
+    }
+    if rec := serveRequest(wlc, httptest.NewRequest(http.MethodGet, "/health", nil)); rec.Code != http.StatusServiceUnavailable {
+        t.Errorf("/health while draining = %d, want 503", rec.Code)
+    }
+
+    ab.releaseAll()
+    if code := <-inFlight; code != http.StatusOK {
+        t.Errorf("in-flight request finished with %d, want 200", code)
+    }
+}
@@ -0,0 +1,54 @@
+package balancer
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrNoHealthyBackend is returned when no backend is available to take a
+// request.
+var ErrNoHealthyBackend = errors.New("no healthy backend available")
+
+// ErrServerNotFound is returned when no server in the pool matches URL, which
+// may be a full URL or a host.
+type ErrServerNotFound struct {
+    URL string
+}
+
+func (e *ErrServerNotFound) Error() string {
+    return fmt.Sprintf("server %s not found", e.URL)
+}
+
+// ErrDuplicateServer is returned when a server with the same host as URL is
+// already configured.
+type ErrDuplicateServer struct {
+    URL string
+}
+
+func (e *ErrDuplicateServer) Error() string {
+    return fmt.Sprintf("duplicate server %s", e.URL)
+}
+
+// ErrInvalidWeight is returned for a weight outside 1..MaxWeight.
+type ErrInvalidWeight struct {
+    URL    string
+    Weight int
+}
+
+func (e *ErrInvalidWeight) Error() string {
+    return fmt.Sprintf("invalid weight %d for server %s. Must be an integer between 1 and %d", e.Weight, e.URL, MaxWeight)
+}
+
+// ErrInvalidURL is returned when a backend URL cannot be used.
+type ErrInvalidURL struct {
+    Raw   string
+    Cause error
+}
+
+func (e *ErrInvalidURL) Error() string {
+    return fmt.Sprintf("invalid server URL %q: %v", e.Raw, e.Cause)
+}
+
+func (e *ErrInvalidURL) Unwrap() error {
+    return e.Cause
+}
@@ -0,0 +1,73 @@
+package balancer
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestErrorTypes(t *testing.T) {
+    t.Run("invalid weight", func(t *testing.T) {
+        _, err := NewServer("http://localhost:8081", 0)
+
+        var invalidWeight *ErrInvalidWeight
+        if !errors.As(err, &invalidWeight) {
+            t.Fatalf("NewServer with weight 0 = %v, want *ErrInvalidWeight", err)
+        }
+        if invalidWeight.Weight != 0 || invalidWeight.URL != "http://localhost:8081" {
+            t.Errorf("ErrInvalidWeight = %+v", invalidWeight)
+        }
+    })
+
+    t.Run("invalid URL", func(t *testing.T) {
+        _, err := NewServer("localhost", 1)
+
+        var invalidURL *ErrInvalidURL
+        if !errors.As(err, &invalidURL) {
+            t.Fatalf("NewServer without a host = %v, want *ErrInvalidURL", err)
+        }
+        if invalidURL.Raw != "localhost" || invalidURL.Cause == nil {
+            t.Errorf("ErrInvalidURL = %+v", invalidURL)
+        }
+    })
+
+    t.Run("duplicate server", func(t *testing.T) {
+        _, err := ParseServerList("localhost:8081/1,localhost:8081/2")
+
+        var duplicate *ErrDuplicateServer
+        if !errors.As(err, &duplicate) {
+            t.Fatalf("ParseServerList with a repeated host = %v, want *ErrDuplicateServer", err)
+        }
+        if duplicate.URL != "localhost:8081" {
+            t.Errorf("ErrDuplicateServer.URL = %q, want %q", duplicate.URL, "localhost:8081")
+        }
+    })
+
+    t.Run("server not found", func(t *testing.T) {
+        wlc := NewWeightedLeastConnection([]*Server{newTestServer(t, "http://localhost:8081", 1)})
+
+        // An unknown host is reported even when the weight is invalid too.
+        err := wlc.UpdateWeight("localhost:9999", 0)
+        var notFound *ErrServerNotFound
+        if !errors.As(err, &notFound) {
+            t.Fatalf("UpdateWeight of an unknown host = %v, want *ErrServerNotFound", err)
+        }
+        if notFound.URL != "localhost:9999" {
+            t.Errorf("ErrServerNotFound.URL = %q, want %q", notFound.URL, "localhost:9999")
+        }
+
+        var invalidWeight *ErrInvalidWeight
+        if err := wlc.UpdateWeight("localhost:8081", 0); !errors.As(err, &invalidWeight) {
+            t.Errorf("UpdateWeight with weight 0 = %v, want *ErrInvalidWeight", err)
+        }
+    })
+
+    t.Run("no healthy backend", func(t *testing.T) {
+        server := newTestServer(t, "http://localhost:8081", 1)
+        server.IsHealthy.Store(false)
+        wlc := NewWeightedLeastConnection([]*Server{server})
+
+        if _, err := wlc.SelectServer(); !errors.Is(err, ErrNoHealthyBackend) {
+            t.Errorf("SelectServer() = %v, want ErrNoHealthyBackend", err)
+        }
+    })
+}
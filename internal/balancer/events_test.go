@@ -0,0 +1,42 @@
+package balancer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Adi-ty/go-loadbalancer/internal/events"
+	lbtesting "github.com/Adi-ty/go-loadbalancer/pkg/testing"
+)
+
+func TestBackendHealthChangedEvent(t *testing.T) {
+    backend := lbtesting.NewFakeBackend(t)
+    server := newTestServer(t, backend.URL, 1)
+
+    bus := events.NewEventBus()
+    received := make(chan events.Event, 2)
+    bus.Subscribe(events.BackendHealthChanged, func(e events.Event) {
+        received <- e
+    })
+    wlc := NewWeightedLeastConnection([]*Server{server}, WithEventBus(bus))
+
+    for _, healthy := range []bool{false, true} {
+        backend.SetHealthy(healthy)
+        wlc.BulkHealthCheck()
+
+        select {
+        case e := <-received:
+            if e.Backend != backend.URL || e.Healthy != healthy {
+                t.Errorf("event = %+v, want backend %s with Healthy %v", e, backend.URL, healthy)
+            }
+        case <-time.After(100 * time.Millisecond):
+            t.Fatalf("no BackendHealthChanged event within 100ms of the backend turning healthy = %v", healthy)
+        }
+    }
+
+    wlc.BulkHealthCheck()
+    select {
+    case e := <-received:
+        t.Errorf("unexpected event %+v when health did not change", e)
+    case <-time.After(50 * time.Millisecond):
+    }
+}
@@ -0,0 +1,65 @@
+package balancer
+
+import "log"
+
+// DefaultHealthFlappingThreshold is the number of consecutive checks a new
+// health state must hold before it is logged.
+const DefaultHealthFlappingThreshold = 3
+
+// healthLogState tracks whether a health state change is waiting to be
+// confirmed before it is logged.
+type healthLogState int
+
+const (
+    healthLogStable healthLogState = iota
+    healthLogPendingHealthy
+    healthLogPendingUnhealthy
+)
+
+// healthLog debounces health state change logs so a flapping backend does
+// not flood them.
+type healthLog struct {
+    state   healthLogState
+    pending int  // Consecutive checks in the pending state
+    healthy bool // Last logged state
+}
+
+// logHealthTransition records a check result and logs a state change once
+// it has held for HealthFlappingThreshold consecutive checks.
+func (s *Server) logHealthTransition(healthy bool, err error) {
+    s.healthLogMu.Lock()
+    defer s.healthLogMu.Unlock()
+
+    hl := &s.healthLog
+    if healthy == hl.healthy {
+        hl.state = healthLogStable
+        hl.pending = 0
+        return
+    }
+
+    want := healthLogPendingUnhealthy
+    if healthy {
+        want = healthLogPendingHealthy
+    }
+    if hl.state != want {
+        hl.state = want
+        hl.pending = 0
+    }
+    hl.pending++
+
+    if hl.pending < s.HealthFlappingThreshold {
+        return
+    }
+
+    hl.state = healthLogStable
+    hl.pending = 0
+    hl.healthy = healthy
+
+    if healthy {
+        log.Printf("[HEALTH] ✅ Server %s is now HEALTHY (failures: %d)",
+            s.URL.Host, s.FailureCount.Load())
+    } else {
+        log.Printf("[HEALTH] ❌ Server %s is now UNHEALTHY: %v (failures: %d)",
+            s.URL.Host, err, s.FailureCount.Load())
+    }
+}
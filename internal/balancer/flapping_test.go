@@ -0,0 +1,41 @@
+package balancer
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"strings"
+	"testing"
+
+	lbtesting "github.com/Adi-ty/go-loadbalancer/pkg/testing"
+)
+
+func TestHealthFlappingThreshold(t *testing.T) {
+    var logs bytes.Buffer
+    log.SetOutput(&logs)
+    t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+    backend := lbtesting.NewFakeBackend(t)
+    server := newTestServer(t, backend.URL, 1)
+
+    // Oscillation first, then each state held for the default threshold of
+    // three checks.
+    checks := []bool{false, true, false, true, false, true, false, false, false, true, false, true, true, true}
+    for _, healthy := range checks {
+        backend.SetHealthy(healthy)
+        server.RunHealthCheck()
+    }
+
+    var transitions []string
+    for _, line := range strings.Split(logs.String(), "\n") {
+        if strings.Contains(line, " is now ") {
+            transitions = append(transitions, line)
+        }
+    }
+    if len(transitions) != 2 {
+        t.Fatalf("logged %d state changes, want 2:\n%s", len(transitions), strings.Join(transitions, "\n"))
+    }
+    if !strings.Contains(transitions[0], "UNHEALTHY") || !strings.Contains(transitions[1], "is now HEALTHY") {
+        t.Errorf("state changes logged in the wrong order:\n%s", strings.Join(transitions, "\n"))
+    }
+}
@@ -0,0 +1,35 @@
+package balancer
+
+import (
+	"net/http"
+	"strings"
+)
+
+// setForwardedProto sets X-Forwarded-Proto on an outgoing request to the
+// scheme the client used. With no trusted proxies in front of the balancer
+// that is the scheme of the incoming connection, and any X-Forwarded-Proto
+// sent by the client is replaced. With trustedHops proxies in front, each
+// appending its own entry, the entry added by the outermost trusted proxy
+// is used instead.
+func setForwardedProto(req *http.Request, trustedHops int) {
+    proto := "http"
+    if req.TLS != nil {
+        proto = "https"
+    }
+
+    if trustedHops > 0 {
+        var entries []string
+        for _, value := range req.Header.Values("X-Forwarded-Proto") {
+            for _, entry := range strings.Split(value, ",") {
+                if entry = strings.TrimSpace(entry); entry != "" {
+                    entries = append(entries, entry)
+                }
+            }
+        }
+        if len(entries) > 0 {
+            proto = entries[max(len(entries)-trustedHops, 0)]
+        }
+    }
+
+    req.Header.Set("X-Forwarded-Proto", proto)
+}
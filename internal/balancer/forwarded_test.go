@@ -0,0 +1,100 @@
+package balancer
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	lbtesting "github.com/Adi-ty/go-loadbalancer/pkg/testing"
+)
+
+func TestForwardedProtoFromConnection(t *testing.T) {
+    backend := lbtesting.NewFakeBackend(t)
+    wlc := NewWeightedLeastConnection([]*Server{newTestServer(t, backend.URL, 1)})
+
+    for _, tt := range []struct {
+        name  string
+        start func(http.Handler) *httptest.Server
+        want  string
+    }{
+        {"TLS", httptest.NewTLSServer, "https"},
+        {"plain", httptest.NewServer, "http"},
+    } {
+        t.Run(tt.name, func(t *testing.T) {
+            front := tt.start(wlc)
+            defer front.Close()
+
+            req, _ := http.NewRequest(http.MethodGet, front.URL, nil)
+            // A client cannot claim a scheme it did not use.
+            req.Header.Set("X-Forwarded-Proto", "gopher")
+            resp, err := front.Client().Do(req)
+            if err != nil {
+                t.Fatal(err)
+            }
+            resp.Body.Close()
+
+            if got := backend.LastRequest().Header.Get("X-Forwarded-Proto"); got != tt.want {
+                t.Errorf("backend saw X-Forwarded-Proto %q, want %q", got, tt.want)
+            }
+        })
+    }
+}
+
+func TestForwardedProtoTrustedHops(t *testing.T) {
+    tests := []struct {
+        hops   int
+        values []string
+        want   string
+    }{
+        {0, []string{"https"}, "http"},
+        {1, []string{"https"}, "https"},
+        {1, []string{"https, http"}, "http"},
+        {2, []string{"https, http"}, "https"},
+        {2, []string{"https", "http"}, "https"},
+        {5, []string{"ftp, https, http"}, "ftp"},
+        {1, nil, "http"},
+    }
+
+    for _, tt := range tests {
+        req := httptest.NewRequest(http.MethodGet, "/", nil)
+        for _, value := range tt.values {
+            req.Header.Add("X-Forwarded-Proto", value)
+        }
+        setForwardedProto(req, tt.hops)
+        if got := req.Header.Get("X-Forwarded-Proto"); got != tt.want || len(req.Header.Values("X-Forwarded-Proto")) != 1 {
+            t.Errorf("%d trusted hops with %q: X-Forwarded-Proto = %q, want %q", tt.hops, tt.values, req.Header.Values("X-Forwarded-Proto"), tt.want)
+        }
+    }
+}
+
+func TestPreserveHost(t *testing.T) {
+    for _, tt := range []struct {
+        preserve      bool
+        wantHost      string
+        wantForwarded string
+    }{
+        {true, "api.example.com", "api.example.com"},
+        {false, "", ""},
+    } {
+        backend := lbtesting.NewFakeBackend(t)
+        server := newTestServer(t, backend.URL, 1)
+        server.PreserveHost = tt.preserve
+        wlc := NewWeightedLeastConnection([]*Server{server})
+
+        req := httptest.NewRequest(http.MethodGet, "/", nil)
+        req.Host = "api.example.com"
+        serveRequest(wlc, req)
+
+        wantHost := tt.wantHost
+        if wantHost == "" {
+            wantHost = server.URL.Host
+        }
+        got := backend.LastRequest()
+        if got.Host != wantHost {
+            t.Errorf("preserve_host %v: backend saw Host %q, want %q", tt.preserve, got.Host, wantHost)
+        }
+        if forwarded := got.Header.Get("X-Forwarded-Host"); forwarded != tt.wantForwarded {
+            t.Errorf("preserve_host %v: backend saw X-Forwarded-Host %q, want %q", tt.preserve, forwarded, tt.wantForwarded)
+        }
+    }
+}
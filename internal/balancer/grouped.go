@@ -0,0 +1,98 @@
+package balancer
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"slices"
+	"sync"
+)
+
+// GroupBalancer balances requests within one BackendGroup. Every algorithm
+// built on BackendPool satisfies it.
+type GroupBalancer interface {
+    LoadBalancer
+    Healthy() []*Server
+}
+
+// BackendGroup is a set of servers, such as one availability zone, that is
+// used as a unit: GroupedBalancer only moves on to the next group once none
+// of this group's servers is healthy.
+type BackendGroup struct {
+    Name     string
+    Servers  []*Server
+    Priority int // Lower is preferred
+
+    // Balancer picks servers within the group. Defaults to a
+    // WeightedLeastConnection over Servers built with the options given to
+    // NewGroupedBalancer.
+    Balancer GroupBalancer
+}
+
+// GroupedBalancer sends every request to the most preferred group that has
+// a healthy server, e.g. AZ-1 while it is up and AZ-2 only when it is not.
+type GroupedBalancer struct {
+    groups []BackendGroup // Sorted by Priority
+}
+
+func NewGroupedBalancer(groups []BackendGroup, opts ...Option) *GroupedBalancer {
+    groups = slices.Clone(groups)
+    for i := range groups {
+        if groups[i].Balancer == nil {
+            groups[i].Balancer = NewWeightedLeastConnection(groups[i].Servers, opts...)
+        }
+    }
+    slices.SortStableFunc(groups, func(a, b BackendGroup) int {
+        return a.Priority - b.Priority
+    })
+
+    return &GroupedBalancer{groups: groups}
+}
+
+// NextGroup returns the most preferred group with at least one healthy
+// server, or nil if there is none.
+func (gb *GroupedBalancer) NextGroup() *BackendGroup {
+    for i := range gb.groups {
+        if len(gb.groups[i].Balancer.Healthy()) > 0 {
+            return &gb.groups[i]
+        }
+    }
+    return nil
+}
+
+// NextServer returns the server the active group's balancer would choose.
+// It returns nil if no group is healthy or the group's balancer picks
+// servers per request, as RendezvousHash does.
+func (gb *GroupedBalancer) NextServer() *Server {
+    group := gb.NextGroup()
+    if group == nil {
+        return nil
+    }
+    if picker, ok := group.Balancer.(interface{ NextServer() *Server }); ok {
+        return picker.NextServer()
+    }
+    return nil
+}
+
+func (gb *GroupedBalancer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+    group := gb.NextGroup()
+    if group == nil {
+        log.Printf("[ERROR] No backend group has a healthy server for request %s %s", r.Method, r.URL.Path)
+        http.Error(w, "Service Unavailable: No healthy backend servers available.", http.StatusServiceUnavailable)
+        return
+    }
+    group.Balancer.ServeHTTP(w, r)
+}
+
+// StartHealthChecks runs every group's health checks until ctx is cancelled.
+func (gb *GroupedBalancer) StartHealthChecks(ctx context.Context) {
+    var wg sync.WaitGroup
+    for _, group := range gb.groups {
+        wg.Add(1)
+        go func(balancer GroupBalancer) {
+            defer wg.Done()
+            balancer.StartHealthChecks(ctx)
+        }(group.Balancer)
+    }
+    wg.Wait()
+}
@@ -0,0 +1,66 @@
+package balancer
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	lbtesting "github.com/Adi-ty/go-loadbalancer/pkg/testing"
+)
+
+func TestGroupedBalancerFailsOverBetweenGroups(t *testing.T) {
+    newGroup := func(name string, priority int) (BackendGroup, []*lbtesting.FakeBackend) {
+        var backends []*lbtesting.FakeBackend
+        var servers []*Server
+        for i := 0; i < 2; i++ {
+            backend := lbtesting.NewFakeBackend(t)
+            backends = append(backends, backend)
+            servers = append(servers, newTestServer(t, backend.URL, 1))
+        }
+        return BackendGroup{Name: name, Servers: servers, Priority: priority}, backends
+    }
+    calls := func(backends []*lbtesting.FakeBackend) int {
+        n := 0
+        for _, backend := range backends {
+            n += backend.CallCount()
+        }
+        return n
+    }
+
+    az1, az1Backends := newGroup("az-1", 1)
+    az2, az2Backends := newGroup("az-2", 2)
+    // Groups are ordered by priority, not by position.
+    gb := NewGroupedBalancer([]BackendGroup{az2, az1})
+
+    for i := 0; i < 4; i++ {
+        serveRequest(gb, httptest.NewRequest(http.MethodGet, "/", nil))
+    }
+    if got := calls(az1Backends); got != 4 {
+        t.Errorf("az-1 got %d of 4 requests while healthy, want all", got)
+    }
+
+    for _, server := range az1.Servers {
+        server.IsHealthy.Store(false)
+    }
+    if group := gb.NextGroup(); group == nil || group.Name != "az-2" {
+        t.Fatalf("NextGroup() = %v with az-1 down, want az-2", group)
+    }
+    for i := 0; i < 4; i++ {
+        if rec := serveRequest(gb, httptest.NewRequest(http.MethodGet, "/", nil)); rec.Code != http.StatusOK {
+            t.Fatalf("status = %d with az-1 down, want %d", rec.Code, http.StatusOK)
+        }
+    }
+    if got := calls(az2Backends); got != 4 {
+        t.Errorf("az-2 got %d of 4 requests with az-1 down, want all", got)
+    }
+    if got := calls(az1Backends); got != 4 {
+        t.Errorf("az-1 got %d more requests while down", got-4)
+    }
+
+    for _, server := range az2.Servers {
+        server.IsHealthy.Store(false)
+    }
+    if rec := serveRequest(gb, httptest.NewRequest(http.MethodGet, "/", nil)); rec.Code != http.StatusServiceUnavailable {
+        t.Errorf("status = %d with every group down, want %d", rec.Code, http.StatusServiceUnavailable)
+    }
+}
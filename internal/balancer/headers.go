@@ -0,0 +1,36 @@
+package balancer
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// DefaultProxyHideHeaders are backend response headers never passed to
+// clients unless listed in a server's ProxyPassHeaders. Connection and
+// Upgrade are left to the reverse proxy, which needs them for protocol
+// upgrades and strips them itself otherwise.
+var DefaultProxyHideHeaders = []string{
+    "Proxy-Authenticate",
+    "Proxy-Connection",
+    "Keep-Alive",
+    "X-Accel-Buffering",
+    "X-Accel-Charset",
+    "X-Accel-Expires",
+    "X-Accel-Limit-Rate",
+    "X-Accel-Redirect",
+    "X-Pad",
+}
+
+// hideResponseHeaders removes DefaultProxyHideHeaders and ProxyHideHeaders
+// from a backend response, except those named in ProxyPassHeaders.
+func (s *Server) hideResponseHeaders(h http.Header) {
+    for _, name := range slices.Concat(DefaultProxyHideHeaders, s.ProxyHideHeaders) {
+        passed := slices.ContainsFunc(s.ProxyPassHeaders, func(pass string) bool {
+            return strings.EqualFold(pass, name)
+        })
+        if !passed {
+            h.Del(name)
+        }
+    }
+}
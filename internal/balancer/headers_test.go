@@ -0,0 +1,34 @@
+package balancer
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestProxyHideAndPassHeaders(t *testing.T) {
+    backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        w.Header().Set("X-Internal-Token", "secret")
+        w.Header().Set("X-Accel-Redirect", "/protected/file")
+        w.Header().Set("Proxy-Authenticate", `Basic realm="backend"`)
+        w.Header().Set("X-Version", "1.2.3")
+    }))
+    defer backend.Close()
+
+    server := newTestServer(t, backend.URL, 1)
+    server.ProxyHideHeaders = []string{"x-internal-token"}
+    server.ProxyPassHeaders = []string{"x-accel-redirect"}
+    wlc := NewWeightedLeastConnection([]*Server{server})
+
+    rec := serveRequest(wlc, httptest.NewRequest(http.MethodGet, "/", nil))
+    for name, want := range map[string]string{
+        "X-Internal-Token":   "",
+        "Proxy-Authenticate": "",
+        "X-Accel-Redirect":   "/protected/file",
+        "X-Version":          "1.2.3",
+    } {
+        if got := rec.Header().Get(name); got != want {
+            t.Errorf("%s = %q, want %q", name, got, want)
+        }
+    }
+}
@@ -0,0 +1,72 @@
+package balancer
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	lbtesting "github.com/Adi-ty/go-loadbalancer/pkg/testing"
+)
+
+func TestAlternatingChecksDoNotRecover(t *testing.T) {
+    backend := lbtesting.NewFakeBackend(t)
+    server := newTestServer(t, backend.URL, 1)
+    server.HealthCheckConsecutiveSuccessesRequired = 3
+
+    backend.SetHealthy(false)
+    server.RunHealthCheck()
+
+    for i := 0; i < 6; i++ {
+        backend.SetHealthy(i%2 == 0)
+        server.RunHealthCheck()
+        if server.IsHealthy.Load() {
+            t.Fatalf("healthy after check %d of alternating fail and pass", i+1)
+        }
+    }
+
+    backend.SetHealthy(true)
+    for i := 0; i < 3; i++ {
+        err := server.RunHealthCheck()
+        if i < 2 && !errors.Is(err, errHealthRecovering) {
+            t.Errorf("pass %d returned %v, want it still recovering", i+1, err)
+        }
+    }
+    if !server.IsHealthy.Load() {
+        t.Errorf("not healthy after 3 passes in a row")
+    }
+}
+
+// errReader fails every read, so forwarding a request with it as the body
+// fails with a proxy error.
+type errReader struct{}
+
+func (errReader) Read([]byte) (int, error) {
+    return 0, errors.New("client went away")
+}
+
+func TestCircuitBreakRestartsRecovery(t *testing.T) {
+    backend := lbtesting.NewFakeBackend(t)
+    server := newTestServer(t, backend.URL, 1)
+    server.HealthCheckConsecutiveSuccessesRequired = 3
+    wlc := NewWeightedLeastConnection([]*Server{server},
+        WithProxyErrorClassifier(func(error) ErrorClass { return ErrorCircuitBreak }))
+
+    // Passes counted while healthy must not carry over.
+    for i := 0; i < 5; i++ {
+        server.RunHealthCheck()
+    }
+
+    serveRequest(wlc, httptest.NewRequest(http.MethodPost, "/", io.MultiReader(errReader{})))
+    if server.IsHealthy.Load() {
+        t.Fatal("backend still healthy after a circuit-breaking error")
+    }
+
+    if err := server.RunHealthCheck(); !errors.Is(err, errHealthRecovering) {
+        t.Errorf("first pass after the circuit broke returned %v, want it still recovering", err)
+    }
+    if server.IsHealthy.Load() {
+        t.Errorf("healthy after 1 pass, want 3 in a row")
+    }
+}
@@ -0,0 +1,128 @@
+package balancer
+
+import (
+	"log"
+	"net/http"
+)
+
+// HealthScoreWeights sets how much each signal contributes to
+// Server.HealthScore. Weights are normalised, so only their proportions
+// matter.
+type HealthScoreWeights struct {
+    // FailureRate weighs the share of recent failed health checks.
+    FailureRate float64
+    // ErrorRate weighs the EWMA of failed proxied requests.
+    ErrorRate float64
+    // Latency weighs the server's latency relative to the pool average.
+    Latency float64
+}
+
+var DefaultHealthScoreWeights = HealthScoreWeights{
+    FailureRate: 0.4,
+    ErrorRate:   0.4,
+    Latency:     0.2,
+}
+
+// HealthScore returns a value between 0 (unusable) and 1 (perfectly healthy)
+// combining recent health check failures and the passive error rate, using
+// DefaultHealthScoreWeights. Latency is only scored relative to a pool, see
+// HealthScoreBalancer.
+func (s *Server) HealthScore() float64 {
+    return s.healthScore(DefaultHealthScoreWeights, 0)
+}
+
+// healthScore computes the weighted score. poolLatencyMs is the pool's
+// average latency; zero scores the latency component as perfect.
+func (s *Server) healthScore(weights HealthScoreWeights, poolLatencyMs float64) float64 {
+    if !s.Available() {
+        return 0
+    }
+
+    total := weights.FailureRate + weights.ErrorRate + weights.Latency
+    if total <= 0 {
+        return 1
+    }
+
+    latencyScore := 1.0
+    if latency := s.LatencyMs(); poolLatencyMs > 0 && latency > poolLatencyMs {
+        latencyScore = poolLatencyMs / latency
+    }
+
+    score := weights.FailureRate*(1-s.HealthFailureRate()) +
+        weights.ErrorRate*(1-s.ErrorRate()) +
+        weights.Latency*latencyScore
+
+    return score / total
+}
+
+// HealthScoreBalancer sends each request to the server with the highest
+// health score among those whose Ratio is below RatioThreshold.
+type HealthScoreBalancer struct {
+    *BackendPool
+
+    Weights HealthScoreWeights
+
+    // RatioThreshold excludes servers that are already busy. If every
+    // available server is at or above it, the whole healthy set is scored.
+    RatioThreshold float64
+}
+
+func NewHealthScoreBalancer(servers []*Server, weights HealthScoreWeights, ratioThreshold float64) *HealthScoreBalancer {
+    return &HealthScoreBalancer{
+        BackendPool:    NewBackendPool(servers),
+        Weights:        weights,
+        RatioThreshold: ratioThreshold,
+    }
+}
+
+func (hb *HealthScoreBalancer) NextServer() *Server {
+    healthy := hb.Healthy()
+    if len(healthy) == 0 {
+        return nil
+    }
+
+    var latencySum float64
+    var latencyCount int
+    for _, server := range healthy {
+        if latency := server.LatencyMs(); latency > 0 {
+            latencySum += latency
+            latencyCount++
+        }
+    }
+    var poolLatency float64
+    if latencyCount > 0 {
+        poolLatency = latencySum / float64(latencyCount)
+    }
+
+    candidates := make([]*Server, 0, len(healthy))
+    for _, server := range healthy {
+        if server.Ratio() < hb.RatioThreshold {
+            candidates = append(candidates, server)
+        }
+    }
+    if len(candidates) == 0 {
+        candidates = healthy
+    }
+
+    var bestServer *Server
+    bestScore := -1.0
+    for _, server := range candidates {
+        score := server.healthScore(hb.Weights, poolLatency)
+        if score > bestScore {
+            bestScore = score
+            bestServer = server
+        }
+    }
+    return bestServer
+}
+
+func (hb *HealthScoreBalancer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+    server := hb.NextServer()
+    if server == nil {
+        log.Printf("[ERROR] No healthy backend available for request %s %s", r.Method, r.URL.Path)
+        http.Error(w, "Service Unavailable: No healthy backend servers available.", http.StatusServiceUnavailable)
+        return
+    }
+
+    server.serve(w, r)
+}
@@ -0,0 +1,57 @@
+package balancer
+
+import (
+	"testing"
+)
+
+func TestHealthScoreDecreasesWithFailureRate(t *testing.T) {
+    server := newTestServer(t, "http://127.0.0.1:1", 1)
+
+    for i := 0; i < healthHistorySize; i++ {
+        server.recordHealthCheck(true)
+        server.recordOutcome(false)
+    }
+    previous := server.HealthScore()
+    if previous != 1 {
+        t.Fatalf("server without failures scored %v, want 1", previous)
+    }
+    for i := 0; i < healthHistorySize; i++ {
+        server.recordHealthCheck(false)
+        server.recordOutcome(true)
+
+        score := server.HealthScore()
+        if score >= previous {
+            t.Fatalf("after %d failures score = %v, want below %v", i+1, score, previous)
+        }
+        previous = score
+    }
+}
+
+func TestErrorRateAfterSuccesses(t *testing.T) {
+    server := newTestServer(t, "http://127.0.0.1:1", 1)
+
+    for i := 0; i < 10; i++ {
+        server.recordOutcome(false)
+    }
+    server.recordOutcome(true)
+
+    // A single failure after a run of successes moves the average by
+    // ewmaAlpha; it must not be mistaken for the first sample.
+    if got := server.ErrorRate(); got < ewmaAlpha-1e-9 || got > ewmaAlpha+1e-9 {
+        t.Errorf("ErrorRate() = %v, want %v", got, ewmaAlpha)
+    }
+}
+
+func TestHealthScoreBalancerPrefersHealthierServer(t *testing.T) {
+    flaky := newTestServer(t, "http://127.0.0.1:1", 1)
+    steady := newTestServer(t, "http://127.0.0.1:2", 1)
+    for i := 0; i < 5; i++ {
+        flaky.recordHealthCheck(false)
+        steady.recordHealthCheck(true)
+    }
+
+    hb := NewHealthScoreBalancer([]*Server{flaky, steady}, DefaultHealthScoreWeights, 1)
+    if got := hb.NextServer(); got != steady {
+        t.Errorf("NextServer() = %v, want the server without failed checks", got.URL)
+    }
+}
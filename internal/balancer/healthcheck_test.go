@@ -0,0 +1,140 @@
+package balancer
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newHealthBackend starts a backend that answers health checks with handler.
+func newHealthBackend(t *testing.T, handler http.HandlerFunc) *httptest.Server {
+    t.Helper()
+
+    backend := httptest.NewServer(handler)
+    t.Cleanup(backend.Close)
+    return backend
+}
+
+func TestHealthPathsAnyPasses(t *testing.T) {
+    var probed []string
+    backend := newHealthBackend(t, func(w http.ResponseWriter, r *http.Request) {
+        probed = append(probed, r.URL.Path)
+        if r.URL.Path != "/readyz" {
+            w.WriteHeader(http.StatusServiceUnavailable)
+        }
+    })
+
+    server := newTestServer(t, backend.URL, 1)
+    server.HealthPaths = []string{"/health", "/readyz", "/livez"}
+    for i := 0; i < 3; i++ {
+        if err := server.RunHealthCheck(); err != nil {
+            t.Fatalf("RunHealthCheck with a passing second path: %v", err)
+        }
+    }
+    if !server.IsHealthy.Load() || server.FailureCount.Load() != 0 {
+        t.Errorf("healthy = %v, failures = %d; want healthy with no failures", server.IsHealthy.Load(), server.FailureCount.Load())
+    }
+    if len(probed) != 6 || probed[len(probed)-1] != "/readyz" {
+        t.Errorf("probed %v, want each check to stop at /readyz", probed)
+    }
+
+    server.HealthPaths = []string{"/health", "/livez"}
+    if err := server.RunHealthCheck(); err == nil {
+        t.Errorf("RunHealthCheck passed with every path failing")
+    }
+    if got := server.FailureCount.Load(); got != 1 {
+        t.Errorf("FailureCount = %d after one check with every path failing, want 1", got)
+    }
+}
+
+func TestHealthCheckExpectedStatuses(t *testing.T) {
+    tests := []struct {
+        name     string
+        status   int
+        expected []int
+        healthy  bool
+    }{
+        {"200 by default", http.StatusOK, nil, true},
+        {"204 not expected by default", http.StatusNoContent, nil, false},
+        {"204 expected", http.StatusNoContent, []int{200, 204}, true},
+        {"200 expected", http.StatusOK, []int{200, 204}, true},
+        {"404 by default", http.StatusNotFound, nil, false},
+        {"404 not in slice", http.StatusNotFound, []int{200, 204}, false},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            backend := newHealthBackend(t, func(w http.ResponseWriter, r *http.Request) {
+                w.WriteHeader(tt.status)
+            })
+
+            server := newTestServer(t, backend.URL, 1)
+            server.HealthCheckExpectedStatuses = tt.expected
+            err := server.RunHealthCheck()
+            if got := server.IsHealthy.Load(); got != tt.healthy {
+                t.Errorf("healthy = %v (err %v), want %v", got, err, tt.healthy)
+            }
+        })
+    }
+}
+
+func TestHealthCheckReusesConnection(t *testing.T) {
+    var connCount atomic.Int64
+    backend := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        time.Sleep(50 * time.Millisecond)
+    }))
+    backend.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+        if state == http.StateNew {
+            connCount.Add(1)
+        }
+    }
+    backend.Start()
+    t.Cleanup(backend.Close)
+
+    server := newTestServer(t, backend.URL, 1)
+    for i := 0; i < 5; i++ {
+        if err := server.RunHealthCheck(); err != nil {
+            t.Fatalf("health check %d: %v", i, err)
+        }
+    }
+    if got := connCount.Load(); got != 1 {
+        t.Errorf("5 health checks opened %d connections, want 1", got)
+    }
+}
+
+func TestHealthCheckBodyAssertions(t *testing.T) {
+    tests := []struct {
+        name        string
+        contains    string
+        notContains string
+    }{
+        {"contains", `"ok":true`, ""},
+        {"not contains", "", `"error"`},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            var checks atomic.Int64
+            backend := newHealthBackend(t, func(w http.ResponseWriter, r *http.Request) {
+                if checks.Add(1)%2 == 1 {
+                    w.Write([]byte(`{"ok":true}`))
+                } else {
+                    w.Write([]byte(`{"error":"db down"}`))
+                }
+            })
+
+            server := newTestServer(t, backend.URL, 1)
+            server.HealthCheckBodyContains = tt.contains
+            server.HealthCheckBodyNotContains = tt.notContains
+            for i, want := range []bool{true, false, true, false} {
+                err := server.RunHealthCheck()
+                if got := server.IsHealthy.Load(); got != want {
+                    t.Errorf("check %d: healthy = %v (err %v), want %v", i, got, err, want)
+                }
+            }
+        })
+    }
+}
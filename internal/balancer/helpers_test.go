@@ -0,0 +1,126 @@
+package balancer
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestServer returns a Server proxying to rawURL, failing the test if it
+// cannot be created.
+func newTestServer(t testing.TB, rawURL string, weight int) *Server {
+    t.Helper()
+
+    server, err := NewServer(rawURL, weight)
+    if err != nil {
+        t.Fatalf("NewServer(%q, %d): %v", rawURL, weight, err)
+    }
+    return server
+}
+
+// serveRequest sends r through h and returns the recorded response.
+func serveRequest(h http.Handler, r *http.Request) *httptest.ResponseRecorder {
+    rec := httptest.NewRecorder()
+    h.ServeHTTP(rec, r)
+    return rec
+}
+
+// refusingURL returns the URL of a port nothing listens on, so connections
+// to it are refused.
+func refusingURL(t testing.TB) string {
+    t.Helper()
+
+    backend := httptest.NewServer(http.NotFoundHandler())
+    backend.Close()
+    return backend.URL
+}
+
+// algorithmBackends are test backends that count the requests they receive
+// and, while held, keep them open until release is called, so balancers
+// see them as active connections.
+type algorithmBackends struct {
+    servers  []*Server
+    hits     []atomic.Int64
+    arrivals atomic.Int64
+
+    held    bool
+    release chan struct{}
+}
+
+func newAlgorithmBackends(t *testing.T, weights []int, held bool) *algorithmBackends {
+    t.Helper()
+
+    ab := &algorithmBackends{
+        hits:    make([]atomic.Int64, len(weights)),
+        held:    held,
+        release: make(chan struct{}),
+    }
+    for i, weight := range weights {
+        backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+            ab.hits[i].Add(1)
+            ab.arrivals.Add(1)
+            if ab.held {
+                <-ab.release
+            }
+        }))
+        t.Cleanup(backend.Close)
+        ab.servers = append(ab.servers, newTestServer(t, backend.URL, weight))
+    }
+    // Unblock held requests before the backends are closed.
+    t.Cleanup(ab.releaseAll)
+    return ab
+}
+
+func (ab *algorithmBackends) releaseAll() {
+    select {
+    case <-ab.release:
+    default:
+        close(ab.release)
+    }
+}
+
+// algorithmRequest returns the i-th request of a run. Client addresses and
+// paths vary so hash-based algorithms spread the requests.
+func algorithmRequest(i int) *http.Request {
+    r := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/item/%d", i), nil)
+    r.RemoteAddr = fmt.Sprintf("10.%d.%d.%d:40000", i/65536%256, i/256%256, i%256)
+    return r
+}
+
+// sendHeld sends n requests through lb one after another, waiting for each
+// to reach a backend before sending the next, and leaves them all open. It
+// returns how many each backend received.
+func (ab *algorithmBackends) sendHeld(t *testing.T, lb LoadBalancer, n int) []int64 {
+    t.Helper()
+
+    var wg sync.WaitGroup
+    for i := 0; i < n; i++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            serveRequest(lb, algorithmRequest(i))
+        }()
+
+        deadline := time.Now().Add(5 * time.Second)
+        for ab.arrivals.Load() < int64(i+1) {
+            if time.Now().After(deadline) {
+                ab.releaseAll()
+                wg.Wait()
+                t.Fatalf("request %d did not reach a backend", i)
+            }
+            time.Sleep(time.Millisecond)
+        }
+    }
+
+    counts := make([]int64, len(ab.hits))
+    for i := range ab.hits {
+        counts[i] = ab.hits[i].Load()
+    }
+    ab.releaseAll()
+    wg.Wait()
+    return counts
+}
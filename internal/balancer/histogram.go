@@ -0,0 +1,72 @@
+package balancer
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// latencyBucketsMs are the upper bounds of the latency histogram buckets. A
+// final overflow bucket catches everything slower.
+var latencyBucketsMs = [...]float64{1, 2, 5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000}
+
+// latencyHistogram counts request latencies in fixed buckets. It is safe for
+// concurrent use.
+type latencyHistogram struct {
+    counts [len(latencyBucketsMs) + 1]atomic.Uint64 // Last bucket is overflow
+}
+
+func (h *latencyHistogram) observe(d time.Duration) {
+    ms := float64(d.Microseconds()) / 1000
+    for i, bound := range latencyBucketsMs {
+        if ms <= bound {
+            h.counts[i].Add(1)
+            return
+        }
+    }
+    h.counts[len(latencyBucketsMs)].Add(1)
+}
+
+func (h *latencyHistogram) reset() {
+    for i := range h.counts {
+        h.counts[i].Store(0)
+    }
+}
+
+// snapshot returns the current bucket counts.
+func (h *latencyHistogram) snapshot() []uint64 {
+    counts := make([]uint64, len(h.counts))
+    for i := range h.counts {
+        counts[i] = h.counts[i].Load()
+    }
+    return counts
+}
+
+// percentileMs estimates the q-th quantile (0 < q <= 1) from bucket counts
+// as the upper bound of the bucket containing it. Samples in the overflow
+// bucket are reported as the largest bound.
+func percentileMs(counts []uint64, q float64) float64 {
+    var total uint64
+    for _, c := range counts {
+        total += c
+    }
+    if total == 0 {
+        return 0
+    }
+
+    rank := uint64(q*float64(total) + 0.5)
+    if rank < 1 {
+        rank = 1
+    }
+
+    var seen uint64
+    for i, c := range counts {
+        seen += c
+        if seen >= rank {
+            if i < len(latencyBucketsMs) {
+                return latencyBucketsMs[i]
+            }
+            break
+        }
+    }
+    return latencyBucketsMs[len(latencyBucketsMs)-1]
+}
@@ -0,0 +1,34 @@
+package balancer
+
+import (
+	"net"
+	"time"
+)
+
+// Defaults for TCP keepalive probes on backend connections. A connection
+// whose peer has vanished without a FIN, e.g. behind a firewall that drops
+// idle flows, is aborted after roughly interval * (count + 1).
+const (
+    DefaultBackendKeepAliveInterval = 30 * time.Second
+    DefaultBackendKeepAliveCount    = 3
+)
+
+// backendDialer returns a dialer that enables TCP keepalive with the given
+// probe interval and number of unanswered probes before giving up.
+func backendDialer(interval time.Duration, count int) *net.Dialer {
+    return &net.Dialer{
+        Timeout: 30 * time.Second,
+        KeepAliveConfig: net.KeepAliveConfig{
+            Enable:   true,
+            Idle:     interval,
+            Interval: interval,
+            Count:    count,
+        },
+    }
+}
+
+// SetKeepAlive changes the TCP keepalive probes used on new connections to
+// the backend. Existing connections keep their settings.
+func (s *Server) SetKeepAlive(interval time.Duration, count int) {
+    s.setDialer(backendDialer(interval, count))
+}
@@ -0,0 +1,94 @@
+//go:build linux
+
+// TCP_REPAIR, which lets a backend vanish without sending a FIN or RST, is
+// Linux only.
+
+package balancer
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"syscall"
+	"testing"
+	"time"
+)
+
+const tcpRepair = 19 // TCP_REPAIR from linux/tcp.h
+
+// vanish closes conn without telling the peer, as if a firewall had started
+// dropping the flow or the host had crashed. The next packet the peer sends
+// is answered with a reset, so only a keepalive probe can reveal the loss of
+// an idle connection.
+func vanish(t *testing.T, conn net.Conn) {
+    t.Helper()
+
+    raw, err := conn.(*net.TCPConn).SyscallConn()
+    if err != nil {
+        t.Fatal(err)
+    }
+    var sockErr error
+    if err := raw.Control(func(fd uintptr) {
+        sockErr = syscall.SetsockoptInt(int(fd), syscall.IPPROTO_TCP, tcpRepair, 1)
+    }); err != nil {
+        t.Fatal(err)
+    }
+    if sockErr != nil {
+        t.Skipf("TCP_REPAIR unavailable: %v", sockErr)
+    }
+    conn.Close()
+}
+
+func TestBackendKeepAliveDetectsDeadConnection(t *testing.T) {
+    ln, err := net.Listen("tcp", "127.0.0.1:0")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer ln.Close()
+
+    // The backend reads the request, then disappears without answering.
+    accepted := make(chan net.Conn, 1)
+    go func() {
+        conn, err := ln.Accept()
+        if err != nil {
+            return
+        }
+        http.ReadRequest(bufio.NewReader(conn))
+        accepted <- conn
+    }()
+
+    server := newTestServer(t, "http://"+ln.Addr().String(), 1)
+    server.SetKeepAlive(time.Second, 2)
+
+    const deadline = 5 * time.Second
+    ctx, cancel := context.WithTimeout(context.Background(), 2*deadline)
+    defer cancel()
+
+    errc := make(chan error, 1)
+    start := time.Now()
+    go func() {
+        req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL.String(), nil)
+        resp, err := server.Transport.RoundTrip(req)
+        if err == nil {
+            resp.Body.Close()
+        }
+        errc <- err
+    }()
+
+    select {
+    case conn := <-accepted:
+        vanish(t, conn)
+    case <-time.After(deadline):
+        t.Fatal("backend did not receive the request")
+    }
+
+    err = <-errc
+    if err == nil {
+        t.Fatal("request to a vanished backend succeeded")
+    }
+    if errors.Is(err, context.DeadlineExceeded) || time.Since(start) > deadline {
+        t.Errorf("dead connection detected after %v (%v), want within %v", time.Since(start), err, deadline)
+    }
+}
@@ -0,0 +1,28 @@
+package balancer
+
+import (
+	"net/http"
+)
+
+// maintenanceWriter turns the file server's 200 responses into 503 so
+// clients and caches know the content is a temporary stand-in.
+type maintenanceWriter struct {
+    http.ResponseWriter
+}
+
+func (mw *maintenanceWriter) WriteHeader(statusCode int) {
+    if statusCode == http.StatusOK {
+        statusCode = http.StatusServiceUnavailable
+    }
+    mw.ResponseWriter.WriteHeader(statusCode)
+}
+
+// serveMaintenance serves the maintenance page, if configured, when no
+// backend is available. It reports whether a response was written.
+func (wlc *WeightedLeastConnection) serveMaintenance(w http.ResponseWriter, r *http.Request) bool {
+    if wlc.maintenance == nil {
+        return false
+    }
+    wlc.maintenance.ServeHTTP(&maintenanceWriter{ResponseWriter: w}, r)
+    return true
+}
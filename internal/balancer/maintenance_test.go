@@ -0,0 +1,42 @@
+package balancer
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	lbtesting "github.com/Adi-ty/go-loadbalancer/pkg/testing"
+)
+
+func TestMaintenanceDir(t *testing.T) {
+    dir := t.TempDir()
+    page := "<h1>Down for maintenance</h1>"
+    if err := os.WriteFile(filepath.Join(dir, "maintenance.html"), []byte(page), 0o644); err != nil {
+        t.Fatal(err)
+    }
+    if err := os.WriteFile(filepath.Join(dir, "index.html"), []byte("index"), 0o644); err != nil {
+        t.Fatal(err)
+    }
+
+    backend := lbtesting.NewFakeBackend(t)
+    backend.SetBody("from backend")
+    server := newTestServer(t, backend.URL, 1)
+    wlc := NewWeightedLeastConnection([]*Server{server}, WithMaintenanceDir(dir))
+
+    rec := serveRequest(wlc, httptest.NewRequest(http.MethodGet, "/maintenance.html", nil))
+    if rec.Code != http.StatusOK || rec.Body.String() != "from backend" {
+        t.Errorf("with a healthy backend got %d %q, want the backend's response", rec.Code, rec.Body.String())
+    }
+
+    server.IsHealthy.Store(false)
+    rec = serveRequest(wlc, httptest.NewRequest(http.MethodGet, "/maintenance.html", nil))
+    if rec.Code != http.StatusServiceUnavailable || rec.Body.String() != page {
+        t.Errorf("with no healthy backend got %d %q, want 503 %q", rec.Code, rec.Body.String(), page)
+    }
+    rec = serveRequest(wlc, httptest.NewRequest(http.MethodGet, "/", nil))
+    if rec.Code != http.StatusServiceUnavailable || rec.Body.String() != "index" {
+        t.Errorf("directory request got %d %q, want 503 with index.html", rec.Code, rec.Body.String())
+    }
+}
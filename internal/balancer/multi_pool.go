@@ -0,0 +1,90 @@
+package balancer
+
+import (
+	"context"
+	"math/rand/v2"
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+// WeightedPool is a pool and its share of MultiPool traffic.
+type WeightedPool struct {
+    Pool   LoadBalancer
+    Weight int
+}
+
+// MultiPool splits traffic across several pools by weight, e.g. 70% to a v1
+// pool and 30% to a v2 pool.
+type MultiPool struct {
+    pools       []WeightedPool
+    totalWeight int
+    requests    []atomic.Uint64 // Per pool, same order as pools
+}
+
+func NewMultiPool(pools []WeightedPool) *MultiPool {
+    mp := &MultiPool{
+        pools:    pools,
+        requests: make([]atomic.Uint64, len(pools)),
+    }
+    for _, wp := range pools {
+        if wp.Weight > 0 {
+            mp.totalWeight += wp.Weight
+        }
+    }
+    return mp
+}
+
+// nextPool picks a pool index by weighted random, or -1 if no pool has a
+// positive weight.
+func (mp *MultiPool) nextPool() int {
+    if mp.totalWeight == 0 {
+        return -1
+    }
+
+    n := rand.IntN(mp.totalWeight)
+    for i, wp := range mp.pools {
+        if wp.Weight <= 0 {
+            continue
+        }
+        if n < wp.Weight {
+            return i
+        }
+        n -= wp.Weight
+    }
+    return -1
+}
+
+func (mp *MultiPool) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+    i := mp.nextPool()
+    if i < 0 {
+        http.Error(w, "Service Unavailable: No backend pools configured.", http.StatusServiceUnavailable)
+        return
+    }
+
+    mp.requests[i].Add(1)
+    mp.pools[i].Pool.ServeHTTP(w, r)
+}
+
+// RequestCounts returns how many requests each pool has received, in the
+// order the pools were given to NewMultiPool.
+func (mp *MultiPool) RequestCounts() []uint64 {
+    counts := make([]uint64, len(mp.requests))
+    for i := range mp.requests {
+        counts[i] = mp.requests[i].Load()
+    }
+    return counts
+}
+
+// StartHealthChecks runs every pool's health checks until ctx is cancelled.
+func (mp *MultiPool) StartHealthChecks(ctx context.Context) {
+    var wg sync.WaitGroup
+    for _, wp := range mp.pools {
+        wg.Add(1)
+        go func(pool LoadBalancer) {
+            defer wg.Done()
+            pool.StartHealthChecks(ctx)
+        }(wp.Pool)
+    }
+    wg.Wait()
+}
@@ -0,0 +1,49 @@
+package balancer
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+// countingPool is a LoadBalancer that answers every request itself and
+// counts them.
+type countingPool struct {
+    requests atomic.Int64
+}
+
+func (p *countingPool) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+    p.requests.Add(1)
+}
+
+func (p *countingPool) StartHealthChecks(ctx context.Context) {}
+
+func TestMultiPoolWeights(t *testing.T) {
+    v1, v2 := &countingPool{}, &countingPool{}
+    mp := NewMultiPool([]WeightedPool{{Pool: v1, Weight: 70}, {Pool: v2, Weight: 30}})
+
+    const n = 10000
+    for i := 0; i < n; i++ {
+        serveRequest(mp, httptest.NewRequest(http.MethodGet, "/", nil))
+    }
+
+    if got := v1.requests.Load(); got < 6800 || got > 7200 {
+        t.Errorf("70%% pool got %d of %d requests, want 6800-7200", got, n)
+    }
+    if got := v1.requests.Load() + v2.requests.Load(); got != n {
+        t.Errorf("pools got %d requests in total, want %d", got, n)
+    }
+    counts := mp.RequestCounts()
+    if int64(counts[0]) != v1.requests.Load() || int64(counts[1]) != v2.requests.Load() {
+        t.Errorf("RequestCounts() = %v, want [%d %d]", counts, v1.requests.Load(), v2.requests.Load())
+    }
+}
+
+func TestMultiPoolNoWeights(t *testing.T) {
+    mp := NewMultiPool([]WeightedPool{{Pool: &countingPool{}, Weight: 0}})
+    if rec := serveRequest(mp, httptest.NewRequest(http.MethodGet, "/", nil)); rec.Code != http.StatusServiceUnavailable {
+        t.Errorf("status = %d with no weighted pool, want 503", rec.Code)
+    }
+}
@@ -0,0 +1,300 @@
+package balancer
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Adi-ty/go-loadbalancer/internal/events"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/time/rate"
+)
+
+// Option configures optional behaviour on a WeightedLeastConnection.
+type Option func(*WeightedLeastConnection)
+
+// WithHealthCheckRateLimit throttles health check requests to rps checks per
+// second, allowing bursts of up to burst checks.
+func WithHealthCheckRateLimit(rps float64, burst int) Option {
+    return func(wlc *WeightedLeastConnection) {
+        wlc.HealthCheckRateLimiter = rate.NewLimiter(rate.Limit(rps), burst)
+    }
+}
+
+// WithMaxURLBytes rejects requests whose request URI is longer than n bytes
+// with 414 URI Too Long. Zero disables the check.
+func WithMaxURLBytes(n int) Option {
+    return func(wlc *WeightedLeastConnection) {
+        wlc.maxURLBytes = n
+    }
+}
+
+// WithMaxRequestBody rejects requests whose body is larger than n bytes with
+// 413 Request Entity Too Large. Zero means no limit.
+func WithMaxRequestBody(n int64) Option {
+    return func(wlc *WeightedLeastConnection) {
+        wlc.maxRequestBody = n
+    }
+}
+
+// WithBufferUnknownLengthBodies reads request bodies sent without a
+// Content-Length, such as chunked uploads, fully into memory before
+// forwarding them with a Content-Length. Bodies are limited to the
+// WithMaxRequestBody size, or DefaultMaxBufferedBody if none is set.
+func WithBufferUnknownLengthBodies(enabled bool) Option {
+    return func(wlc *WeightedLeastConnection) {
+        wlc.bufferUnknownLength = enabled
+    }
+}
+
+// WithDeadlinePropagation forwards the remaining client deadline to backends
+// as an X-Timeout-Ms header (and Grpc-Timeout for gRPC requests). The
+// deadline is taken from the request context or, failing that, from an
+// X-Timeout-Ms or Grpc-Timeout header sent by the client, and the proxied
+// request is cancelled when it passes.
+func WithDeadlinePropagation(enabled bool) Option {
+    return func(wlc *WeightedLeastConnection) {
+        wlc.propagateDeadline = enabled
+    }
+}
+
+// WithTrailerForwarding controls whether HTTP trailers sent by backends are
+// relayed to clients. Trailers are forwarded by default.
+func WithTrailerForwarding(enabled bool) Option {
+    return func(wlc *WeightedLeastConnection) {
+        wlc.stripTrailers = !enabled
+    }
+}
+
+// WithWaitForHealthy makes requests wait up to d for a backend to become
+// healthy instead of failing immediately when none is available.
+func WithWaitForHealthy(d time.Duration) Option {
+    return func(wlc *WeightedLeastConnection) {
+        wlc.WaitForHealthy = d
+    }
+}
+
+// WithTrafficShaping routes a share of matching requests to other pools.
+// Rules are evaluated in order and the first one that matches and wins its
+// percentage roll handles the request.
+func WithTrafficShaping(rules []TrafficShapingRule) Option {
+    return func(wlc *WeightedLeastConnection) {
+        wlc.trafficShaping = rules
+    }
+}
+
+// WithEventBus publishes pool lifecycle and routing events to bus.
+func WithEventBus(bus *events.EventBus) Option {
+    return func(wlc *WeightedLeastConnection) {
+        wlc.events = bus
+    }
+}
+
+// WithMaintenanceDir serves static files from dir, with status 503, while no
+// backend is healthy.
+func WithMaintenanceDir(dir string) Option {
+    return func(wlc *WeightedLeastConnection) {
+        wlc.maintenance = http.FileServer(http.Dir(dir))
+    }
+}
+
+// WithFailoverPool routes requests to backup while every primary backend is
+// unavailable.
+func WithFailoverPool(backup LoadBalancer) Option {
+    return func(wlc *WeightedLeastConnection) {
+        wlc.failover = backup
+    }
+}
+
+// WithGlobalOptionsMethods sets the Allow header returned for OPTIONS *.
+func WithGlobalOptionsMethods(methods []string) Option {
+    return func(wlc *WeightedLeastConnection) {
+        wlc.globalOptionsAllow = strings.Join(methods, ", ")
+    }
+}
+
+// WithConnectTunneling accepts CONNECT requests for targets on one of ports
+// and tunnels them to the backend whose host matches the target.
+func WithConnectTunneling(ports []int) Option {
+    return func(wlc *WeightedLeastConnection) {
+        wlc.connectAllowedPorts = ports
+    }
+}
+
+// WithRetries retries requests whose method is in methods on up to
+// maxRetries other backends when the chosen backend cannot be reached. A nil
+// methods uses DefaultRetryMethods.
+func WithRetries(maxRetries int, methods []string) Option {
+    return func(wlc *WeightedLeastConnection) {
+        if methods == nil {
+            methods = DefaultRetryMethods
+        }
+        wlc.maxRetries = maxRetries
+        wlc.retryMethods = methods
+    }
+}
+
+// WithMaxRetryDuration limits the time a retryable request may spend across
+// all its attempts waiting for response headers. A request that runs out is
+// answered 504; once headers arrive the response body is not limited.
+func WithMaxRetryDuration(d time.Duration) Option {
+    return func(wlc *WeightedLeastConnection) {
+        wlc.maxRetryDuration = d
+    }
+}
+
+// WithProxyErrorClassifier decides whether a failed request is retried,
+// failed outright or trips the backend out of rotation. Every proxy error is
+// classified, so ErrorCircuitBreak applies to requests that are not retried
+// too. The default is DefaultProxyErrorClassifier.
+func WithProxyErrorClassifier(classify ProxyErrorClassifier) Option {
+    return func(wlc *WeightedLeastConnection) {
+        wlc.classifyError = classify
+    }
+}
+
+// WithRequestBufferPool reuses bufSize-byte buffers when request bodies are
+// read into memory for retries or to give them a Content-Length, and when
+// they are streamed to backends, instead of allocating them per request.
+func WithRequestBufferPool(bufSize int) Option {
+    return func(wlc *WeightedLeastConnection) {
+        if bufSize <= 0 {
+            bufSize = DefaultRequestBufferSize
+        }
+        wlc.requestBuffers = newBytePool(bufSize)
+    }
+}
+
+// WithResponseBufferPool copies response bodies, including streamed and
+// flushed ones, through pooled bufSize-byte buffers rather than a fresh
+// buffer per response.
+func WithResponseBufferPool(bufSize int) Option {
+    return func(wlc *WeightedLeastConnection) {
+        if bufSize <= 0 {
+            bufSize = DefaultResponseBufferSize
+        }
+        wlc.responseBuffers = newBytePool(bufSize)
+    }
+}
+
+// WithFailFast answers 503 straight away when every backend is at
+// MaxConnections rather than queueing the request.
+func WithFailFast(enabled bool) Option {
+    return func(wlc *WeightedLeastConnection) {
+        wlc.FailFast = enabled
+    }
+}
+
+// WithMaxQueueWait limits how long a request waits for a free slot when
+// every backend is at MaxConnections. The default is DefaultMaxQueueWait.
+func WithMaxQueueWait(d time.Duration) Option {
+    return func(wlc *WeightedLeastConnection) {
+        wlc.MaxQueueWait = d
+    }
+}
+
+// WithDiagnosticHeaders adds X-LB-Server, X-LB-Algorithm and, when the
+// request carries an X-Request-ID, X-LB-Request-ID to every proxied
+// response. Meant for debugging rather than production.
+func WithDiagnosticHeaders(enabled bool) Option {
+    return func(wlc *WeightedLeastConnection) {
+        wlc.diagnosticHeaders = enabled
+    }
+}
+
+// WithPrometheusRegisterer makes RegisterMetrics register the lb_* metrics on
+// reg instead of prometheus.DefaultRegisterer, so several balancers can live
+// in one process without conflicting.
+func WithPrometheusRegisterer(reg prometheus.Registerer) Option {
+    return func(wlc *WeightedLeastConnection) {
+        wlc.registerer = reg
+    }
+}
+
+// WithPoolName labels every lb_* metric of the pool with pool_name=name, so
+// several pools can be registered on one registry, e.g. a primary and its
+// canary or failover pool.
+func WithPoolName(name string) Option {
+    return func(wlc *WeightedLeastConnection) {
+        wlc.poolName = name
+    }
+}
+
+// WithWatchdogInterval restarts the health check loop if it goes longer than
+// d without completing a cycle. A negative d disables the watchdog.
+func WithWatchdogInterval(d time.Duration) Option {
+    return func(wlc *WeightedLeastConnection) {
+        wlc.WatchdogInterval = d
+    }
+}
+
+// WithBackendScorer replaces DefaultScorer as the function NextServer uses to
+// rank servers.
+func WithBackendScorer(scorer BackendScorer) Option {
+    return func(wlc *WeightedLeastConnection) {
+        wlc.scorer = scorer
+    }
+}
+
+// WithHealthCheckConcurrency limits how many backends are health checked at
+// once. The default is runtime.NumCPU().
+func WithHealthCheckConcurrency(n int) Option {
+    return func(wlc *WeightedLeastConnection) {
+        if n < 1 {
+            n = 1
+        }
+        wlc.healthCheckConcurrency = n
+    }
+}
+
+// WithHealthCheckInterval sets how often backends are health checked. The
+// default is DefaultHealthCheckInterval.
+func WithHealthCheckInterval(d time.Duration) Option {
+    return func(wlc *WeightedLeastConnection) {
+        if d > 0 {
+            wlc.healthCheckInterval = d
+        }
+    }
+}
+
+// WithMaxHealthCheckInterval caps how far checks of a repeatedly failing
+// backend are spaced out. The default is five health check intervals.
+func WithMaxHealthCheckInterval(d time.Duration) Option {
+    return func(wlc *WeightedLeastConnection) {
+        wlc.maxHealthCheckInterval = d
+    }
+}
+
+// WithLatencyBudget honours latency budgets sent by clients in
+// X-Request-Deadline-Ms or Grpc-Timeout. Time spent in the balancer is
+// deducted and the remainder forwarded as X-Remaining-Deadline-Ms; requests
+// with less than minRemaining left are answered 504 instead of forwarded. A
+// zero minRemaining uses DefaultMinRemainingBudget.
+func WithLatencyBudget(minRemaining time.Duration) Option {
+    return func(wlc *WeightedLeastConnection) {
+        if minRemaining <= 0 {
+            minRemaining = DefaultMinRemainingBudget
+        }
+        wlc.latencyBudget = true
+        wlc.minRemainingBudget = minRemaining
+    }
+}
+
+// WithErrorRateRampDown gradually lowers the effective weight of servers
+// whose passive error rate exceeds cfg.Threshold instead of leaving them at
+// full weight until a health check fails. Zero fields take the
+// DefaultRampDown* values.
+func WithErrorRateRampDown(cfg RampDownConfig) Option {
+    return func(wlc *WeightedLeastConnection) {
+        if cfg.Threshold <= 0 {
+            cfg.Threshold = DefaultRampDownThreshold
+        }
+        if cfg.Slope <= 0 {
+            cfg.Slope = DefaultRampDownSlope
+        }
+        if cfg.RecoveryPeriod <= 0 {
+            cfg.RecoveryPeriod = DefaultRampDownRecoveryPeriod
+        }
+        wlc.rampDown = &cfg
+    }
+}
@@ -0,0 +1,56 @@
+package balancer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseServerList parses a comma-separated list of host:port/weight entries,
+// e.g. "localhost:8081/5, localhost:8082/1", into servers. Addresses without
+// a scheme default to http. The weight follows the last slash, so addresses
+// may carry a path ("localhost:8081/api/2") or be IPv6 ("[::1]:8081/1").
+func ParseServerList(input string) ([]*Server, error) {
+    var servers []*Server
+    seen := make(map[string]bool)
+
+    for _, part := range strings.Split(input, ",") {
+        part = strings.TrimSpace(part)
+        if part == "" {
+            continue
+        }
+
+        slash := strings.LastIndex(part, "/")
+        if slash < 0 || strings.HasSuffix(part[:slash], ":/") {
+            return nil, fmt.Errorf("invalid format for server %s. Expected: host:port/weight", part)
+        }
+
+        rawURL := part[:slash]
+        if !strings.HasPrefix(rawURL, "http://") && !strings.HasPrefix(rawURL, "https://") {
+            rawURL = "http://" + rawURL
+        }
+
+        weight, err := strconv.Atoi(part[slash+1:])
+        if err != nil {
+            return nil, fmt.Errorf("invalid weight for server %s. Must be an integer", rawURL)
+        }
+
+        server, err := NewServer(rawURL, weight)
+        if err != nil {
+            return nil, err
+        }
+        // The pool identifies servers by host, so two entries for one
+        // host, even with different paths, would be indistinguishable.
+        if seen[server.URL.Host] {
+            return nil, &ErrDuplicateServer{URL: server.URL.Host}
+        }
+        seen[server.URL.Host] = true
+
+        servers = append(servers, server)
+    }
+
+    if len(servers) == 0 {
+        return nil, fmt.Errorf("no valid backend servers configured")
+    }
+    return servers, nil
+}
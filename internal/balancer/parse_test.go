@@ -0,0 +1,81 @@
+package balancer
+
+import (
+	"testing"
+)
+
+func TestParseServerList(t *testing.T) {
+    tests := []struct {
+        name        string
+        input       string
+        wantURLs    []string
+        wantWeights []int
+        wantErr     bool
+    }{
+        {
+            name:        "valid input",
+            input:       "localhost:8081/5, localhost:8082/1",
+            wantURLs:    []string{"http://localhost:8081", "http://localhost:8082"},
+            wantWeights: []int{5, 1},
+        },
+        {
+            name:        "explicit scheme",
+            input:       "https://backend.internal:8443/2",
+            wantURLs:    []string{"https://backend.internal:8443"},
+            wantWeights: []int{2},
+        },
+        {name: "missing weight", input: "localhost:8081", wantErr: true},
+        {name: "non-numeric weight", input: "localhost:8081/heavy", wantErr: true},
+        {name: "weight zero", input: "localhost:8081/0", wantErr: true},
+        {name: "weight above maximum", input: "localhost:8081/1001", wantErr: true},
+        {name: "duplicate URLs", input: "localhost:8081/1,localhost:8081/2", wantErr: true},
+        {name: "duplicate hosts with different paths", input: "localhost:8081/a/1,localhost:8081/b/2", wantErr: true},
+        {name: "empty string", input: "", wantErr: true},
+        {name: "whitespace only", input: "  ,  , ", wantErr: true},
+        {
+            name:        "URL with path component",
+            input:       "localhost:8081/api/v1/3",
+            wantURLs:    []string{"http://localhost:8081/api/v1"},
+            wantWeights: []int{3},
+        },
+        {
+            name:        "IPv6 address",
+            input:       "[::1]:8081/4",
+            wantURLs:    []string{"http://[::1]:8081"},
+            wantWeights: []int{4},
+        },
+        {
+            name:        "surrounding whitespace and empty entries",
+            input:       " localhost:8081/1 ,, localhost:8082/2 ",
+            wantURLs:    []string{"http://localhost:8081", "http://localhost:8082"},
+            wantWeights: []int{1, 2},
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            servers, err := ParseServerList(tt.input)
+            if tt.wantErr {
+                if err == nil {
+                    t.Fatalf("ParseServerList(%q) returned %d servers, want an error", tt.input, len(servers))
+                }
+                return
+            }
+            if err != nil {
+                t.Fatalf("ParseServerList(%q): %v", tt.input, err)
+            }
+
+            if len(servers) != len(tt.wantURLs) {
+                t.Fatalf("got %d servers, want %d", len(servers), len(tt.wantURLs))
+            }
+            for i, server := range servers {
+                if got := server.URL.String(); got != tt.wantURLs[i] {
+                    t.Errorf("server %d URL = %q, want %q", i, got, tt.wantURLs[i])
+                }
+                if server.Weight != tt.wantWeights[i] {
+                    t.Errorf("server %d weight = %d, want %d", i, server.Weight, tt.wantWeights[i])
+                }
+            }
+        })
+    }
+}
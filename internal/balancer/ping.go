@@ -0,0 +1,97 @@
+package balancer
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// pingTimeout bounds each ICMP echo sent before a health check.
+const pingTimeout = time.Second
+
+// errPingUnavailable means ICMP sockets cannot be opened, usually for lack of
+// CAP_NET_RAW, so the ping step is skipped.
+var errPingUnavailable = errors.New("ICMP ping unavailable")
+
+var (
+    pingSeq         atomic.Uint32
+    pingWarningOnce sync.Once
+)
+
+// pingServer pings a server before its health check. Tests replace it to
+// simulate hosts that drop pings.
+var pingServer = (*Server).ping
+
+// ping sends one ICMP echo to the server's host and waits for the reply.
+func (s *Server) ping() error {
+    ips, err := net.LookupIP(s.URL.Hostname())
+    if err != nil {
+        return err
+    }
+    if len(ips) == 0 {
+        return fmt.Errorf("no addresses for %s", s.URL.Hostname())
+    }
+    ip := ips[0]
+
+    network, listenAddr, proto := "ip4:icmp", "0.0.0.0", 1
+    var echoType, replyType icmp.Type = ipv4.ICMPTypeEcho, ipv4.ICMPTypeEchoReply
+    if ip.To4() == nil {
+        network, listenAddr, proto = "ip6:ipv6-icmp", "::", 58
+        echoType, replyType = ipv6.ICMPTypeEchoRequest, ipv6.ICMPTypeEchoReply
+    }
+
+    conn, err := icmp.ListenPacket(network, listenAddr)
+    if err != nil {
+        if errors.Is(err, os.ErrPermission) {
+            pingWarningOnce.Do(func() {
+                log.Printf("[HEALTH] ⚠️  Cannot open ICMP socket (%v), skipping pings. Grant CAP_NET_RAW to enable them.", err)
+            })
+            return errPingUnavailable
+        }
+        return err
+    }
+    defer conn.Close()
+
+    id := os.Getpid() & 0xffff
+    seq := int(pingSeq.Add(1) & 0xffff)
+    msg, err := (&icmp.Message{
+        Type: echoType,
+        Body: &icmp.Echo{ID: id, Seq: seq, Data: []byte("go-loadbalancer")},
+    }).Marshal(nil)
+    if err != nil {
+        return err
+    }
+
+    conn.SetDeadline(time.Now().Add(pingTimeout))
+    if _, err := conn.WriteTo(msg, &net.IPAddr{IP: ip}); err != nil {
+        return err
+    }
+
+    // The raw socket sees every ICMP packet for this host, so skip replies
+    // meant for other pings.
+    buf := make([]byte, 1500)
+    for {
+        n, peer, err := conn.ReadFrom(buf)
+        if err != nil {
+            return err
+        }
+        if addr, ok := peer.(*net.IPAddr); ok && addr.IP.Equal(ip) {
+            reply, err := icmp.ParseMessage(proto, buf[:n])
+            if err != nil || reply.Type != replyType {
+                continue
+            }
+            if echo, ok := reply.Body.(*icmp.Echo); ok && echo.ID == id && echo.Seq == seq {
+                return nil
+            }
+        }
+    }
+}
@@ -0,0 +1,55 @@
+package balancer
+
+import (
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+)
+
+func TestHealthCheckPingFirst(t *testing.T) {
+    var probes atomic.Int64
+    backend := newHealthBackend(t, func(w http.ResponseWriter, r *http.Request) {
+        probes.Add(1)
+    })
+    server := newTestServer(t, backend.URL, 1)
+    server.HealthCheckPingFirst = true
+
+    t.Run("ping rejected", func(t *testing.T) {
+        defer func(ping func(*Server) error) { pingServer = ping }(pingServer)
+        pingServer = func(*Server) error { return errors.New("destination host unreachable") }
+
+        if err := server.RunHealthCheck(); err == nil {
+            t.Fatal("RunHealthCheck passed for a host that rejects pings")
+        }
+        if server.IsHealthy.Load() {
+            t.Error("server still healthy after a failed ping")
+        }
+        if got := probes.Load(); got != 0 {
+            t.Errorf("backend got %d HTTP health checks, want none after a failed ping", got)
+        }
+    })
+
+    t.Run("ping unavailable", func(t *testing.T) {
+        defer func(ping func(*Server) error) { pingServer = ping }(pingServer)
+        pingServer = func(*Server) error { return errPingUnavailable }
+
+        if err := server.RunHealthCheck(); err != nil {
+            t.Fatalf("RunHealthCheck without ICMP sockets = %v, want the HTTP check alone to decide", err)
+        }
+        if got := probes.Load(); got != 1 {
+            t.Errorf("backend got %d HTTP health checks, want 1", got)
+        }
+    })
+}
+
+func TestPingLoopback(t *testing.T) {
+    server := newTestServer(t, "http://127.0.0.1:1", 1)
+    err := server.ping()
+    if errors.Is(err, errPingUnavailable) {
+        t.Skip("no CAP_NET_RAW")
+    }
+    if err != nil {
+        t.Errorf("ping 127.0.0.1 = %v", err)
+    }
+}
@@ -0,0 +1,120 @@
+package balancer
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// BackendPool is the set of servers an algorithm balances across. It is safe
+// for concurrent use and is embedded by every algorithm so pool management
+// lives in one place.
+type BackendPool struct {
+    mu      sync.RWMutex
+    servers []*Server
+
+    // HealthCheckInterval is how often StartHealthChecks checks every
+    // server. Zero means DefaultHealthCheckInterval.
+    HealthCheckInterval time.Duration
+}
+
+func NewBackendPool(servers []*Server) *BackendPool {
+    return &BackendPool{
+        servers: servers,
+    }
+}
+
+// Add appends server to the pool.
+func (p *BackendPool) Add(server *Server) {
+    p.mu.Lock()
+    defer p.mu.Unlock()
+
+    p.servers = append(p.servers, server)
+}
+
+// Remove deletes the server whose URL equals url and returns it, or nil if
+// no server matched.
+func (p *BackendPool) Remove(url string) *Server {
+    p.mu.Lock()
+    defer p.mu.Unlock()
+
+    for i, server := range p.servers {
+        if server.URL.String() == url {
+            // Copy rather than reslice in place so snapshots returned by
+            // All are never mutated.
+            servers := make([]*Server, 0, len(p.servers)-1)
+            servers = append(servers, p.servers[:i]...)
+            p.servers = append(servers, p.servers[i+1:]...)
+            return server
+        }
+    }
+    return nil
+}
+
+// Find returns the server whose URL host matches host, or nil.
+func (p *BackendPool) Find(host string) *Server {
+    p.mu.RLock()
+    defer p.mu.RUnlock()
+
+    for _, server := range p.servers {
+        if server.URL.Host == host {
+            return server
+        }
+    }
+    return nil
+}
+
+// Healthy returns a snapshot of the servers currently available for traffic.
+func (p *BackendPool) Healthy() []*Server {
+    p.mu.RLock()
+    defer p.mu.RUnlock()
+
+    var healthy []*Server
+    for _, server := range p.servers {
+        if server.Available() {
+            healthy = append(healthy, server)
+        }
+    }
+    return healthy
+}
+
+// All returns a snapshot of every server in the pool.
+func (p *BackendPool) All() []*Server {
+    p.mu.RLock()
+    defer p.mu.RUnlock()
+
+    servers := make([]*Server, len(p.servers))
+    copy(servers, p.servers)
+    return servers
+}
+
+// Len returns the number of servers in the pool.
+func (p *BackendPool) Len() int {
+    p.mu.RLock()
+    defer p.mu.RUnlock()
+
+    return len(p.servers)
+}
+
+// StartHealthChecks checks every server in the pool every
+// HealthCheckInterval until ctx is cancelled.
+func (p *BackendPool) StartHealthChecks(ctx context.Context) {
+    interval := p.HealthCheckInterval
+    if interval <= 0 {
+        interval = DefaultHealthCheckInterval
+    }
+    ticker := time.NewTicker(interval)
+    defer ticker.Stop()
+
+    for {
+        for _, server := range p.All() {
+            server.RunHealthCheck()
+        }
+
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+        }
+    }
+}
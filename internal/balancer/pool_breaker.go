@@ -0,0 +1,303 @@
+package balancer
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Defaults for PoolCircuitBreakerConfig.
+const (
+    DefaultPoolErrorThreshold = 0.5
+    DefaultPoolErrorWindow    = 10 * time.Second
+    DefaultPoolMinRequests    = 20
+    DefaultPoolOpenDuration   = 30 * time.Second
+)
+
+// poolWindowBuckets is how many slices the rolling error window is kept in.
+const poolWindowBuckets = 10
+
+// PoolCircuitBreakerConfig configures PoolCircuitBreaker.
+type PoolCircuitBreakerConfig struct {
+    // PoolErrorThreshold is the share of requests, 0-1, answered with a 5xx
+    // within Window above which the circuit opens.
+    PoolErrorThreshold float64
+
+    // Window is the rolling period the error rate is measured over.
+    Window time.Duration
+
+    // MinRequests is how many requests the window must hold before the
+    // error rate is acted on, so a handful of failures after a quiet spell
+    // do not open the circuit.
+    MinRequests int
+
+    // PoolOpenDuration is how long the circuit stays open before a probe
+    // request is let through.
+    PoolOpenDuration time.Duration
+}
+
+type poolCircuitState int32
+
+const (
+    poolCircuitClosed poolCircuitState = iota
+    poolCircuitOpen
+    poolCircuitHalfOpen
+)
+
+func (s poolCircuitState) String() string {
+    switch s {
+    case poolCircuitOpen:
+        return "open"
+    case poolCircuitHalfOpen:
+        return "half-open"
+    }
+    return "closed"
+}
+
+// PoolCircuitBreaker guards a whole pool rather than single backends. When
+// the pool's error rate over the rolling window passes PoolErrorThreshold,
+// as happens when every backend shares a failing dependency, the circuit
+// opens and every request is answered 503 for PoolOpenDuration without
+// reaching a backend. It then lets a single probe request through: if that
+// succeeds the circuit closes, otherwise it opens again.
+//
+// The balancer's own /health and /metrics endpoints are always served.
+type PoolCircuitBreaker struct {
+    *WeightedLeastConnection
+    cfg PoolCircuitBreakerConfig
+
+    mu        sync.Mutex
+    state     poolCircuitState
+    openUntil time.Time
+    probing   bool
+    buckets   [poolWindowBuckets]poolWindowBucket
+
+    opened   atomic.Uint64
+    rejected atomic.Uint64
+
+    openedDesc   *prometheus.Desc
+    rejectedDesc *prometheus.Desc
+    openDesc     *prometheus.Desc
+}
+
+// poolWindowBucket counts the requests completed in one slice of the window.
+type poolWindowBucket struct {
+    slot     int64
+    requests uint64
+    errors   uint64
+}
+
+// NewPoolCircuitBreaker wraps wlc in a pool-level circuit breaker. Zero
+// fields of cfg take the Default* values.
+func NewPoolCircuitBreaker(wlc *WeightedLeastConnection, cfg PoolCircuitBreakerConfig) *PoolCircuitBreaker {
+    if cfg.PoolErrorThreshold <= 0 {
+        cfg.PoolErrorThreshold = DefaultPoolErrorThreshold
+    }
+    if cfg.Window <= 0 {
+        cfg.Window = DefaultPoolErrorWindow
+    }
+    if cfg.MinRequests <= 0 {
+        cfg.MinRequests = DefaultPoolMinRequests
+    }
+    if cfg.PoolOpenDuration <= 0 {
+        cfg.PoolOpenDuration = DefaultPoolOpenDuration
+    }
+
+    var labels prometheus.Labels
+    if wlc.poolName != "" {
+        labels = prometheus.Labels{"pool_name": wlc.poolName}
+    }
+
+    return &PoolCircuitBreaker{
+        WeightedLeastConnection: wlc,
+        cfg:                     cfg,
+
+        openedDesc: prometheus.NewDesc("lb_pool_circuit_opened_total",
+            "Times the pool-level circuit breaker opened.", nil, labels),
+        rejectedDesc: prometheus.NewDesc("lb_pool_circuit_rejected_total",
+            "Requests answered 503 because the pool-level circuit was open.", nil, labels),
+        openDesc: prometheus.NewDesc("lb_pool_circuit_open",
+            "Whether the pool-level circuit is open or half-open.", nil, labels),
+    }
+}
+
+func (pcb *PoolCircuitBreaker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+    switch r.URL.Path {
+    case "/health", "/healthz", "/metrics", "/metrics/prometheus":
+        pcb.WeightedLeastConnection.ServeHTTP(w, r)
+        return
+    }
+
+    probe, ok := pcb.allow(time.Now())
+    if !ok {
+        pcb.rejected.Add(1)
+        w.Header().Set("Retry-After", strconv.Itoa(int(pcb.cfg.PoolOpenDuration.Seconds())+1))
+        http.Error(w, "Service Unavailable: Backend pool circuit is open.", http.StatusServiceUnavailable)
+        return
+    }
+
+    sw := &statusWriter{ResponseWriter: w}
+    completed := false
+    defer func() {
+        // A panic, such as http.ErrAbortHandler when the backend fails
+        // mid-response, counts as a failure; the probe must be accounted
+        // for either way or the circuit would stay half-open for good.
+        pcb.record(time.Now(), !completed || sw.status >= http.StatusInternalServerError, probe)
+    }()
+    pcb.WeightedLeastConnection.ServeHTTP(sw, r)
+    completed = true
+}
+
+// allow reports whether a request may be forwarded and whether it is the
+// half-open probe.
+func (pcb *PoolCircuitBreaker) allow(now time.Time) (probe, ok bool) {
+    pcb.mu.Lock()
+    defer pcb.mu.Unlock()
+
+    switch pcb.state {
+    case poolCircuitOpen:
+        if now.Before(pcb.openUntil) {
+            return false, false
+        }
+        pcb.state = poolCircuitHalfOpen
+        log.Printf("[CIRCUIT] Pool circuit half-open, sending a probe request")
+        fallthrough
+    case poolCircuitHalfOpen:
+        if pcb.probing {
+            return false, false
+        }
+        pcb.probing = true
+        return true, true
+    }
+    return false, true
+}
+
+// record counts a completed request and opens or closes the circuit.
+func (pcb *PoolCircuitBreaker) record(now time.Time, failed, probe bool) {
+    pcb.mu.Lock()
+    defer pcb.mu.Unlock()
+
+    if probe {
+        pcb.probing = false
+        if failed {
+            pcb.open(now, "probe request failed")
+            return
+        }
+        pcb.state = poolCircuitClosed
+        pcb.buckets = [poolWindowBuckets]poolWindowBucket{}
+        log.Printf("[CIRCUIT] Pool circuit closed after a successful probe")
+        return
+    }
+
+    // Requests already in flight when the circuit opened finish here; they
+    // must not affect the next window.
+    if pcb.state != poolCircuitClosed {
+        return
+    }
+
+    bucketSize := int64(pcb.cfg.Window / poolWindowBuckets)
+    slot := now.UnixNano() / max(bucketSize, 1)
+    b := &pcb.buckets[slot%poolWindowBuckets]
+    if b.slot != slot {
+        *b = poolWindowBucket{slot: slot}
+    }
+    b.requests++
+    if failed {
+        b.errors++
+    }
+
+    var requests, errors uint64
+    for _, b := range pcb.buckets {
+        if b.slot > slot-poolWindowBuckets {
+            requests += b.requests
+            errors += b.errors
+        }
+    }
+    if requests >= uint64(pcb.cfg.MinRequests) &&
+        float64(errors)/float64(requests) > pcb.cfg.PoolErrorThreshold {
+        pcb.open(now, fmt.Sprintf("%d of %d requests failed", errors, requests))
+    }
+}
+
+// open opens the circuit for PoolOpenDuration. pcb.mu must be held.
+func (pcb *PoolCircuitBreaker) open(now time.Time, reason string) {
+    pcb.state = poolCircuitOpen
+    pcb.openUntil = now.Add(pcb.cfg.PoolOpenDuration)
+    pcb.buckets = [poolWindowBuckets]poolWindowBucket{}
+    pcb.opened.Add(1)
+    log.Printf("[CIRCUIT] Pool circuit opened for %v: %s", pcb.cfg.PoolOpenDuration, reason)
+}
+
+// State returns "closed", "open" or "half-open".
+func (pcb *PoolCircuitBreaker) State() string {
+    pcb.mu.Lock()
+    defer pcb.mu.Unlock()
+
+    return pcb.state.String()
+}
+
+// Opened returns how many times the circuit has opened.
+func (pcb *PoolCircuitBreaker) Opened() uint64 {
+    return pcb.opened.Load()
+}
+
+// Rejected returns how many requests were refused while the circuit was
+// open.
+func (pcb *PoolCircuitBreaker) Rejected() uint64 {
+    return pcb.rejected.Load()
+}
+
+func (pcb *PoolCircuitBreaker) Describe(ch chan<- *prometheus.Desc) {
+    ch <- pcb.openedDesc
+    ch <- pcb.rejectedDesc
+    ch <- pcb.openDesc
+}
+
+func (pcb *PoolCircuitBreaker) Collect(ch chan<- prometheus.Metric) {
+    var open float64
+    if pcb.State() != "closed" {
+        open = 1
+    }
+    ch <- prometheus.MustNewConstMetric(pcb.openedDesc, prometheus.CounterValue, float64(pcb.opened.Load()))
+    ch <- prometheus.MustNewConstMetric(pcb.rejectedDesc, prometheus.CounterValue, float64(pcb.rejected.Load()))
+    ch <- prometheus.MustNewConstMetric(pcb.openDesc, prometheus.GaugeValue, open)
+}
+
+// statusWriter records the status code of a response.
+type statusWriter struct {
+    http.ResponseWriter
+    status int
+}
+
+func (sw *statusWriter) WriteHeader(status int) {
+    if sw.status == 0 && status >= http.StatusOK {
+        sw.status = status
+    }
+    sw.ResponseWriter.WriteHeader(status)
+}
+
+func (sw *statusWriter) Write(p []byte) (int, error) {
+    if sw.status == 0 {
+        sw.status = http.StatusOK
+    }
+    return sw.ResponseWriter.Write(p)
+}
+
+// RecordBackend passes the backend on to an access logger further out.
+func (sw *statusWriter) RecordBackend(host string) {
+    if recorder, ok := sw.ResponseWriter.(BackendRecorder); ok {
+        recorder.RecordBackend(host)
+    }
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer for
+// flushing and hijacking.
+func (sw *statusWriter) Unwrap() http.ResponseWriter {
+    return sw.ResponseWriter
+}
@@ -0,0 +1,101 @@
+package balancer
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPoolCircuitBreakerOpens(t *testing.T) {
+    // Every backend fails three requests in five.
+    var calls atomic.Int64
+    var servers []*Server
+    for i := 0; i < 3; i++ {
+        backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+            if calls.Add(1)%5 < 3 {
+                w.WriteHeader(http.StatusInternalServerError)
+            }
+        }))
+        t.Cleanup(backend.Close)
+        servers = append(servers, newTestServer(t, backend.URL, 1))
+    }
+
+    pcb := NewPoolCircuitBreaker(NewWeightedLeastConnection(servers), PoolCircuitBreakerConfig{
+        PoolErrorThreshold: 0.5,
+        MinRequests:        10,
+        PoolOpenDuration:   time.Minute,
+    })
+
+    for i := 0; i < 10; i++ {
+        serveRequest(pcb, httptest.NewRequest(http.MethodGet, "/", nil))
+    }
+    if got := pcb.State(); got != "open" {
+        t.Fatalf("state after a 60%% error rate = %q, want open", got)
+    }
+    if got := pcb.Opened(); got != 1 {
+        t.Errorf("Opened() = %d, want 1", got)
+    }
+
+    before := calls.Load()
+    rec := serveRequest(pcb, httptest.NewRequest(http.MethodGet, "/", nil))
+    if rec.Code != http.StatusServiceUnavailable {
+        t.Errorf("status while open = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+    }
+    if calls.Load() != before {
+        t.Errorf("a request reached a backend while the circuit was open")
+    }
+    if got := pcb.Rejected(); got != 1 {
+        t.Errorf("Rejected() = %d, want 1", got)
+    }
+}
+
+func TestPoolCircuitBreakerProbeAborted(t *testing.T) {
+    var abort atomic.Bool
+    backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if abort.Load() {
+            // Promise more than is sent, so the proxy fails mid-body.
+            w.Header().Set("Content-Length", "100")
+            w.Write([]byte("partial"))
+            w.(http.Flusher).Flush()
+            panic(http.ErrAbortHandler)
+        }
+    }))
+    t.Cleanup(backend.Close)
+
+    pcb := NewPoolCircuitBreaker(NewWeightedLeastConnection([]*Server{newTestServer(t, backend.URL, 1)}),
+        PoolCircuitBreakerConfig{PoolOpenDuration: 10 * time.Millisecond})
+    pcb.mu.Lock()
+    pcb.open(time.Now(), "test")
+    pcb.mu.Unlock()
+    time.Sleep(20 * time.Millisecond)
+
+    // Served as by net/http, so the proxy aborts the response with a panic.
+    abort.Store(true)
+    req := httptest.NewRequest(http.MethodGet, "/", nil)
+    req = req.WithContext(context.WithValue(req.Context(), http.ServerContextKey, &http.Server{}))
+    func() {
+        defer func() {
+            if v := recover(); v != http.ErrAbortHandler {
+                t.Fatalf("recovered %v, want http.ErrAbortHandler", v)
+            }
+        }()
+        serveRequest(pcb, req)
+    }()
+
+    if got := pcb.State(); got != "open" {
+        t.Fatalf("state after an aborted probe = %q, want open", got)
+    }
+
+    // The aborted probe must not block the next one.
+    abort.Store(false)
+    time.Sleep(20 * time.Millisecond)
+    if rec := serveRequest(pcb, httptest.NewRequest(http.MethodGet, "/", nil)); rec.Code != http.StatusOK {
+        t.Errorf("next probe status = %d, want %d", rec.Code, http.StatusOK)
+    }
+    if got := pcb.State(); got != "closed" {
+        t.Errorf("state after a successful probe = %q, want closed", got)
+    }
+}
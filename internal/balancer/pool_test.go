@@ -0,0 +1,88 @@
+package balancer
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	lbtesting "github.com/Adi-ty/go-loadbalancer/pkg/testing"
+)
+
+func TestBackendPoolConcurrentAddRemove(t *testing.T) {
+    pool := NewBackendPool(nil)
+    servers := make([]*Server, 100)
+    for i := range servers {
+        servers[i] = newTestServer(t, fmt.Sprintf("http://10.0.%d.%d:8080", i/256, i%256), 1)
+    }
+
+    var wg sync.WaitGroup
+    for _, server := range servers {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            pool.Add(server)
+            if n := pool.Len(); n < 1 || n > len(servers) {
+                t.Errorf("Len() = %d with a server just added, want 1-%d", n, len(servers))
+            }
+            if n := len(pool.All()); n < 1 || n > len(servers) {
+                t.Errorf("All() returned %d servers, want 1-%d", n, len(servers))
+            }
+        }()
+    }
+    wg.Wait()
+    if n := pool.Len(); n != len(servers) {
+        t.Fatalf("Len() = %d after %d concurrent adds, want %d", n, len(servers), len(servers))
+    }
+
+    for i, server := range servers {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            if pool.Remove(server.URL.String()) != server {
+                t.Errorf("Remove(%s) did not return server %d", server.URL, i)
+            }
+        }()
+    }
+    wg.Wait()
+    if n := pool.Len(); n != 0 {
+        t.Errorf("Len() = %d after removing every server, want 0", n)
+    }
+}
+
+func TestBackendPoolHealthy(t *testing.T) {
+    servers := []*Server{
+        newTestServer(t, "http://10.0.0.1:8080", 1),
+        newTestServer(t, "http://10.0.0.2:8080", 1),
+        newTestServer(t, "http://10.0.0.3:8080", 1),
+    }
+    servers[1].IsHealthy.Store(false)
+    pool := NewBackendPool(servers)
+
+    healthy := pool.Healthy()
+    if len(healthy) != 2 || healthy[0] != servers[0] || healthy[1] != servers[2] {
+        t.Errorf("Healthy() = %v, want the first and third servers", healthy)
+    }
+    if pool.Remove("http://10.0.0.9:8080") != nil {
+        t.Errorf("Remove of an unknown URL returned a server")
+    }
+}
+
+func TestBackendPoolHealthCheckInterval(t *testing.T) {
+    backend := lbtesting.NewFakeBackend(t)
+    server := newTestServer(t, backend.URL, 1)
+    // FakeBackend does not count /health, so check a path it does count.
+    server.HealthPaths = []string{"/"}
+    pool := NewBackendPool([]*Server{server})
+    pool.HealthCheckInterval = 20 * time.Millisecond
+
+    ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+    defer cancel()
+    pool.StartHealthChecks(ctx)
+
+    // At the 10s default only the immediate first check would have run.
+    if got := backend.CallCount(); got < 3 {
+        t.Errorf("backend health checked %d times in 200ms at a 20ms interval, want at least 3", got)
+    }
+}
@@ -0,0 +1,106 @@
+package balancer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// warmupRequestTimeout bounds each warmup request, including reading the
+// response, so a hung backend cannot hold up startup.
+var warmupRequestTimeout = 5 * time.Second
+
+// PreWarm sends requestsPerBackend requests, one after another, to every
+// available backend through the transport used for proxying, so connections
+// are set up and the backend has served a few requests before live traffic
+// arrives. Backends are warmed in parallel. Each request is a GET of the
+// backend's first health path, or a copy of warmupRequest, if given, with
+// its URL pointed at the backend. Each request is given up after
+// warmupRequestTimeout. Warmup requests count towards RequestCount. The
+// error reports every failed request.
+func (wlc *WeightedLeastConnection) PreWarm(ctx context.Context, requestsPerBackend int, warmupRequest *http.Request) error {
+    var (
+        wg   sync.WaitGroup
+        mu   sync.Mutex
+        errs []error
+    )
+
+    for _, server := range wlc.All() {
+        if !server.Available() {
+            continue
+        }
+
+        wg.Add(1)
+        go func(server *Server) {
+            defer wg.Done()
+            for i := 0; i < requestsPerBackend; i++ {
+                if err := server.warmup(ctx, warmupRequest); err != nil {
+                    mu.Lock()
+                    errs = append(errs, fmt.Errorf("warmup request to %s failed: %w", server.URL.Host, err))
+                    mu.Unlock()
+                }
+                if ctx.Err() != nil {
+                    return
+                }
+            }
+        }(server)
+    }
+    wg.Wait()
+
+    if len(errs) == 0 {
+        log.Printf("[PREWARM] Sent %d warmup requests to each healthy backend", requestsPerBackend)
+    }
+    return errors.Join(errs...)
+}
+
+// warmup sends one warmup request to s and discards the response.
+func (s *Server) warmup(ctx context.Context, template *http.Request) error {
+    ctx, cancel := context.WithTimeout(ctx, warmupRequestTimeout)
+    defer cancel()
+
+    var req *http.Request
+    if template != nil {
+        req = template.Clone(ctx)
+        if template.GetBody != nil {
+            body, err := template.GetBody()
+            if err != nil {
+                return err
+            }
+            req.Body = body
+        }
+        req.URL.Scheme = s.URL.Scheme
+        req.URL.Host = s.URL.Host
+        req.Host = ""
+        req.RequestURI = ""
+    } else {
+        path := "/health"
+        if len(s.HealthPaths) > 0 {
+            path = s.HealthPaths[0]
+        }
+        var err error
+        req, err = http.NewRequestWithContext(ctx, http.MethodGet, s.URL.String()+path, nil)
+        if err != nil {
+            return err
+        }
+    }
+
+    s.RequestCount.Add(1)
+    resp, err := s.Transport.RoundTrip(req)
+    if err != nil {
+        return err
+    }
+    defer resp.Body.Close()
+    if _, err := io.Copy(io.Discard, resp.Body); err != nil {
+        return err
+    }
+
+    if resp.StatusCode >= http.StatusInternalServerError {
+        return fmt.Errorf("status %d", resp.StatusCode)
+    }
+    return nil
+}
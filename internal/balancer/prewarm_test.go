@@ -0,0 +1,59 @@
+package balancer
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	lbtesting "github.com/Adi-ty/go-loadbalancer/pkg/testing"
+)
+
+func TestPreWarm(t *testing.T) {
+    backend := lbtesting.NewFakeBackend(t)
+    server := newTestServer(t, backend.URL, 1)
+    wlc := NewWeightedLeastConnection([]*Server{server})
+
+    if err := wlc.PreWarm(context.Background(), 5, nil); err != nil {
+        t.Fatalf("PreWarm: %v", err)
+    }
+    if got := server.RequestCount.Load(); got != 5 {
+        t.Errorf("RequestCount = %d after 5 warmup requests", got)
+    }
+}
+
+func TestPreWarmTemplate(t *testing.T) {
+    backend := lbtesting.NewFakeBackend(t)
+    wlc := NewWeightedLeastConnection([]*Server{newTestServer(t, backend.URL, 1)})
+
+    template := httptest.NewRequest(http.MethodGet, "http://placeholder/warm", nil)
+    template.Header.Set("X-Warmup", "1")
+    if err := wlc.PreWarm(context.Background(), 3, template); err != nil {
+        t.Fatalf("PreWarm: %v", err)
+    }
+    if got := backend.CallCount(); got != 3 {
+        t.Errorf("backend received %d warmup requests, want 3", got)
+    }
+    if last := backend.LastRequest(); last.URL.Path != "/warm" || last.Header.Get("X-Warmup") != "1" {
+        t.Errorf("warmup request was %s with X-Warmup %q, want a copy of the template", last.URL.Path, last.Header.Get("X-Warmup"))
+    }
+}
+
+func TestPreWarmRequestTimeout(t *testing.T) {
+    defer func(d time.Duration) { warmupRequestTimeout = d }(warmupRequestTimeout)
+    warmupRequestTimeout = 50 * time.Millisecond
+
+    backend := lbtesting.NewFakeBackend(t)
+    backend.SetDelay(time.Minute)
+    wlc := NewWeightedLeastConnection([]*Server{newTestServer(t, backend.URL, 1)})
+
+    start := time.Now()
+    template := httptest.NewRequest(http.MethodGet, "http://placeholder/", nil)
+    if err := wlc.PreWarm(context.Background(), 2, template); err == nil {
+        t.Errorf("PreWarm against a hung backend succeeded")
+    }
+    if elapsed := time.Since(start); elapsed > time.Second {
+        t.Errorf("PreWarm took %v, want each request cut off after %v", elapsed, warmupRequestTimeout)
+    }
+}
@@ -0,0 +1,40 @@
+package balancer
+
+import "testing"
+
+func TestPriorityOverflow(t *testing.T) {
+    ab := newAlgorithmBackends(t, []int{1, 5}, true)
+    primary, secondary := ab.servers[0], ab.servers[1]
+    primary.Priority = 1
+    primary.MaxConnections = 2
+    secondary.Priority = 2
+    wlc := NewWeightedLeastConnection(ab.servers)
+
+    // The secondary's higher weight would win it traffic without
+    // priorities; it only gets what the primary has no room for.
+    counts := ab.sendHeld(t, wlc, 5)
+    if counts[0] != 2 || counts[1] != 3 {
+        t.Errorf("requests per server = %v, want [2 3]: the primary filled to capacity, then overflow", counts)
+    }
+
+    if got := wlc.NextServer(); got != primary {
+        t.Errorf("NextServer() = %s with the primary free, want the primary", got.URL)
+    }
+    primary.IsHealthy.Store(false)
+    if got := wlc.NextServer(); got != secondary {
+        t.Errorf("NextServer() = %v with the primary down, want the secondary", got)
+    }
+}
+
+func TestPriorityWithinLevel(t *testing.T) {
+    ab := newAlgorithmBackends(t, []int{1, 1, 1}, true)
+    ab.servers[0].Priority = 1
+    ab.servers[1].Priority = 1
+    ab.servers[2].Priority = 2
+    wlc := NewWeightedLeastConnection(ab.servers)
+
+    counts := ab.sendHeld(t, wlc, 6)
+    if counts[0] != 3 || counts[1] != 3 || counts[2] != 0 {
+        t.Errorf("requests per server = %v, want [3 3 0]: least connection within priority 1", counts)
+    }
+}
@@ -0,0 +1,160 @@
+package balancer
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// metricDescs describes the lb_* metrics of one pool. The pool name, if
+// set, is attached to every metric as a pool_name label so pools sharing a
+// registry do not collide.
+type metricDescs struct {
+    requestsTotal        *prometheus.Desc
+    failoverRequests     *prometheus.Desc
+    healthCycleDuration  *prometheus.Desc
+    backendUp            *prometheus.Desc
+    backendActive        *prometheus.Desc
+    backendPeak          *prometheus.Desc
+    backendRequests      *prometheus.Desc
+    backendBytesSent     *prometheus.Desc
+    backendBytesReceived *prometheus.Desc
+    backendBackedOff     *prometheus.Desc
+    backendErrorBudget   *prometheus.Desc
+}
+
+func newMetricDescs(poolName string) *metricDescs {
+    var labels prometheus.Labels
+    if poolName != "" {
+        labels = prometheus.Labels{"pool_name": poolName}
+    }
+    backend := []string{"backend"}
+
+    return &metricDescs{
+        requestsTotal: prometheus.NewDesc("lb_requests_total",
+            "Total requests forwarded to backends.", nil, labels),
+        failoverRequests: prometheus.NewDesc("lb_failover_requests_total",
+            "Requests routed to the failover pool.", nil, labels),
+        healthCycleDuration: prometheus.NewDesc("lb_health_check_cycle_duration_seconds",
+            "Time taken by the last complete round of health checks.", nil, labels),
+        backendUp: prometheus.NewDesc("lb_backend_up",
+            "Whether the backend is available for traffic.", backend, labels),
+        backendActive: prometheus.NewDesc("lb_backend_active_connections",
+            "Requests currently in flight to the backend.", backend, labels),
+        backendPeak: prometheus.NewDesc("lb_backend_peak_connections",
+            "Highest number of concurrent requests seen by the backend.", backend, labels),
+        backendRequests: prometheus.NewDesc("lb_backend_requests_total",
+            "Requests forwarded to the backend.", backend, labels),
+        backendBytesSent: prometheus.NewDesc("lb_backend_bytes_sent_total",
+            "Request body bytes forwarded to the backend.", backend, labels),
+        backendBytesReceived: prometheus.NewDesc("lb_backend_bytes_received_total",
+            "Response body bytes relayed from the backend.", backend, labels),
+        backendBackedOff: prometheus.NewDesc("lb_backend_backed_off_total",
+            "429 and 503 responses with Retry-After that took the backend out of rotation.", backend, labels),
+        backendErrorBudget: prometheus.NewDesc("lb_backend_error_budget_remaining",
+            "Failed requests the backend can still have before missing its SLO target; negative once exhausted.", backend, labels),
+    }
+}
+
+// metricsCollector exports the pool's live counters as lb_* metrics. Values
+// are read at scrape time, so nothing is updated on the request path.
+type metricsCollector struct {
+    wlc   *WeightedLeastConnection
+    descs *metricDescs
+}
+
+func newMetricsCollector(wlc *WeightedLeastConnection) metricsCollector {
+    return metricsCollector{wlc: wlc, descs: newMetricDescs(wlc.poolName)}
+}
+
+func (c metricsCollector) Describe(ch chan<- *prometheus.Desc) {
+    d := c.descs
+    ch <- d.requestsTotal
+    ch <- d.failoverRequests
+    ch <- d.healthCycleDuration
+    ch <- d.backendUp
+    ch <- d.backendActive
+    ch <- d.backendPeak
+    ch <- d.backendRequests
+    ch <- d.backendBytesSent
+    ch <- d.backendBytesReceived
+    ch <- d.backendBackedOff
+    ch <- d.backendErrorBudget
+}
+
+func (c metricsCollector) Collect(ch chan<- prometheus.Metric) {
+    wlc, d := c.wlc, c.descs
+
+    wlc.mu2.Lock()
+    totalReqs := wlc.totalRequests
+    wlc.mu2.Unlock()
+
+    ch <- prometheus.MustNewConstMetric(d.requestsTotal, prometheus.CounterValue, float64(totalReqs))
+    ch <- prometheus.MustNewConstMetric(d.failoverRequests, prometheus.CounterValue, float64(wlc.failoverRequests.Load()))
+    ch <- prometheus.MustNewConstMetric(d.healthCycleDuration, prometheus.GaugeValue,
+        time.Duration(wlc.healthCycleDuration.Load()).Seconds())
+
+    for _, server := range wlc.All() {
+        host := server.URL.Host
+        up := 0.0
+        if server.Available() {
+            up = 1
+        }
+
+        ch <- prometheus.MustNewConstMetric(d.backendUp, prometheus.GaugeValue, up, host)
+        ch <- prometheus.MustNewConstMetric(d.backendActive, prometheus.GaugeValue, float64(server.ActiveConnections.Load()), host)
+        ch <- prometheus.MustNewConstMetric(d.backendPeak, prometheus.GaugeValue, float64(server.PeakConnections.Load()), host)
+        ch <- prometheus.MustNewConstMetric(d.backendRequests, prometheus.CounterValue, float64(server.RequestCount.Load()), host)
+        ch <- prometheus.MustNewConstMetric(d.backendBytesSent, prometheus.CounterValue, float64(server.BytesSent.Load()), host)
+        ch <- prometheus.MustNewConstMetric(d.backendBytesReceived, prometheus.CounterValue, float64(server.BytesReceived.Load()), host)
+        ch <- prometheus.MustNewConstMetric(d.backendBackedOff, prometheus.CounterValue, float64(server.BackedOffCount.Load()), host)
+        if server.SLOTarget > 0 {
+            ch <- prometheus.MustNewConstMetric(d.backendErrorBudget, prometheus.GaugeValue, server.ErrorBudgetRemaining(), host)
+        }
+    }
+}
+
+// RegisterMetrics registers the lb_* metrics on the registerer given with
+// WithPrometheusRegisterer, or on prometheus.DefaultRegisterer if none was.
+// It returns an error, rather than panicking, if they are already
+// registered there.
+func (wlc *WeightedLeastConnection) RegisterMetrics() error {
+    reg := wlc.registerer
+    if reg == nil {
+        reg = prometheus.DefaultRegisterer
+    }
+
+    for _, c := range append([]prometheus.Collector{newMetricsCollector(wlc)}, wlc.extraCollectors...) {
+        if err := reg.Register(c); err != nil {
+            var already prometheus.AlreadyRegisteredError
+            if errors.As(err, &already) {
+                return fmt.Errorf("lb metrics already registered: %w", err)
+            }
+            return fmt.Errorf("failed to register lb metrics: %w", err)
+        }
+    }
+    return nil
+}
+
+// AddMetricsCollector exports c's metrics alongside the pool's own, for
+// handlers wrapped around the balancer that keep lb_* metrics of their own.
+// It must be called before metrics are registered or first served.
+func (wlc *WeightedLeastConnection) AddMetricsCollector(c prometheus.Collector) {
+    wlc.extraCollectors = append(wlc.extraCollectors, c)
+}
+
+// handlePrometheusEndpoint serves this pool's lb_* metrics from a private
+// registry, so it works whether or not RegisterMetrics was called.
+func (wlc *WeightedLeastConnection) handlePrometheusEndpoint(w http.ResponseWriter, r *http.Request) {
+    wlc.metricsHandlerOnce.Do(func() {
+        registry := prometheus.NewRegistry()
+        registry.MustRegister(newMetricsCollector(wlc))
+        registry.MustRegister(wlc.extraCollectors...)
+        wlc.metricsHandler = promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+    })
+    wlc.metricsHandler.ServeHTTP(w, r)
+}
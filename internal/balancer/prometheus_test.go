@@ -0,0 +1,122 @@
+package balancer
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/Adi-ty/go-loadbalancer/pkg/middleware"
+	lbtesting "github.com/Adi-ty/go-loadbalancer/pkg/testing"
+)
+
+func TestPoolNameLabelsSeparatePools(t *testing.T) {
+    reg := prometheus.NewRegistry()
+    requests := map[string]int{"primary": 3, "canary": 1}
+
+    for name, n := range requests {
+        backend := lbtesting.NewFakeBackend(t)
+        wlc := NewWeightedLeastConnection([]*Server{newTestServer(t, backend.URL, 1)},
+            WithPoolName(name), WithPrometheusRegisterer(reg))
+        wlc.AddMetricsCollector(middleware.NewRequestCollapser(wlc, middleware.RequestCollapserConfig{PoolName: name}))
+        if err := wlc.RegisterMetrics(); err != nil {
+            t.Fatalf("registering pool %q: %v", name, err)
+        }
+        for i := 0; i < n; i++ {
+            serveRequest(wlc, httptest.NewRequest(http.MethodGet, "/", nil))
+        }
+    }
+
+    families, err := reg.Gather()
+    if err != nil {
+        t.Fatal(err)
+    }
+    seen := make(map[string]bool)
+    for _, family := range families {
+        if !strings.HasPrefix(family.GetName(), "lb_") {
+            continue
+        }
+        seen[family.GetName()] = true
+        for _, metric := range family.GetMetric() {
+            pool := ""
+            for _, label := range metric.GetLabel() {
+                if label.GetName() == "pool_name" {
+                    pool = label.GetValue()
+                }
+            }
+            if _, ok := requests[pool]; !ok {
+                t.Errorf("%s has pool_name %q, want primary or canary", family.GetName(), pool)
+                continue
+            }
+            if family.GetName() == "lb_requests_total" {
+                if got := metric.GetCounter().GetValue(); got != float64(requests[pool]) {
+                    t.Errorf("lb_requests_total{pool_name=%q} = %v, want %d", pool, got, requests[pool])
+                }
+            }
+        }
+    }
+    for _, name := range []string{"lb_requests_total", "lb_backend_up", "lb_collapsed_requests_total"} {
+        if !seen[name] {
+            t.Errorf("%s not gathered", name)
+        }
+    }
+}
+
+// gatheredRequests returns lb_requests_total from reg.
+func gatheredRequests(t *testing.T, reg *prometheus.Registry) float64 {
+    t.Helper()
+
+    families, err := reg.Gather()
+    if err != nil {
+        t.Fatal(err)
+    }
+    for _, family := range families {
+        if family.GetName() == "lb_requests_total" {
+            return family.GetMetric()[0].GetCounter().GetValue()
+        }
+    }
+    t.Fatal("lb_requests_total not gathered")
+    return 0
+}
+
+func TestPrometheusRegisterersAreSeparate(t *testing.T) {
+    var regs []*prometheus.Registry
+    for i, n := range []int{2, 5} {
+        reg := prometheus.NewRegistry()
+        regs = append(regs, reg)
+        backend := lbtesting.NewFakeBackend(t)
+        wlc := NewWeightedLeastConnection([]*Server{newTestServer(t, backend.URL, 1)}, WithPrometheusRegisterer(reg))
+        if err := wlc.RegisterMetrics(); err != nil {
+            t.Fatalf("registering instance %d: %v", i, err)
+        }
+        for j := 0; j < n; j++ {
+            serveRequest(wlc, httptest.NewRequest(http.MethodGet, "/", nil))
+        }
+    }
+
+    if a, b := gatheredRequests(t, regs[0]), gatheredRequests(t, regs[1]); a != 2 || b != 5 {
+        t.Errorf("lb_requests_total = %v and %v, want 2 and 5", a, b)
+    }
+}
+
+func TestPrometheusDuplicateRegistration(t *testing.T) {
+    reg := prometheus.NewRegistry()
+    defaultRegisterer := prometheus.DefaultRegisterer
+    prometheus.DefaultRegisterer = reg
+    t.Cleanup(func() { prometheus.DefaultRegisterer = defaultRegisterer })
+
+    for i := 0; i < 2; i++ {
+        wlc := NewWeightedLeastConnection([]*Server{newTestServer(t, "http://localhost:8081", 1)})
+        err := wlc.RegisterMetrics()
+        if i == 0 && err != nil {
+            t.Fatalf("first registration: %v", err)
+        }
+        var already prometheus.AlreadyRegisteredError
+        if i == 1 && !errors.As(err, &already) {
+            t.Errorf("second registration on the default registerer = %v, want a wrapped AlreadyRegisteredError", err)
+        }
+    }
+}
@@ -0,0 +1,69 @@
+package balancer
+
+import (
+	"sync"
+	"time"
+)
+
+// Defaults for RampDownConfig.
+const (
+    DefaultRampDownThreshold      = 0.1
+    DefaultRampDownSlope          = 5.0
+    DefaultRampDownRecoveryPeriod = 30 * time.Second
+)
+
+// minRampDownFactor is the smallest share of its weight a failing server
+// keeps; taking it out entirely is left to health checks.
+const minRampDownFactor = 0.1
+
+// RampDownConfig scales a server's effective weight down as its passive
+// error rate rises above Threshold, by max(0.1, 1 - (rate-Threshold)*Slope).
+// Once the rate falls back under Threshold the full weight is restored
+// linearly over RecoveryPeriod.
+type RampDownConfig struct {
+    Threshold      float64
+    Slope          float64
+    RecoveryPeriod time.Duration
+}
+
+// rampDownState tracks a server's reduced weight factor.
+type rampDownState struct {
+    mu            sync.Mutex
+    factor        float64 // Factor while failing, or the one recovery started from
+    recoveryStart time.Time
+}
+
+// rampDownFactor returns the share of its weight the server currently gets.
+func (s *Server) rampDownFactor(cfg RampDownConfig, now time.Time) float64 {
+    st := &s.rampDown
+    st.mu.Lock()
+    defer st.mu.Unlock()
+
+    if rate := s.ErrorRate(); rate > cfg.Threshold {
+        st.factor = max(minRampDownFactor, 1-(rate-cfg.Threshold)*cfg.Slope)
+        st.recoveryStart = time.Time{}
+        return st.factor
+    }
+
+    if st.factor == 0 || st.factor >= 1 {
+        return 1
+    }
+    if st.recoveryStart.IsZero() {
+        st.recoveryStart = now
+    }
+
+    elapsed := now.Sub(st.recoveryStart)
+    if elapsed >= cfg.RecoveryPeriod {
+        st.factor = 0
+        return 1
+    }
+    return st.factor + (1-st.factor)*float64(elapsed)/float64(cfg.RecoveryPeriod)
+}
+
+// EffectiveWeight returns server's weight after any error rate ramp-down.
+func (wlc *WeightedLeastConnection) EffectiveWeight(server *Server) float64 {
+    if wlc.rampDown == nil {
+        return float64(server.Weight)
+    }
+    return float64(server.Weight) * server.rampDownFactor(*wlc.rampDown, time.Now())
+}
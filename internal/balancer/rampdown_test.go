@@ -0,0 +1,56 @@
+package balancer
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestErrorRateRampDown(t *testing.T) {
+    ab := newAlgorithmBackends(t, []int{4, 4, 4}, true)
+    wlc := NewWeightedLeastConnection(ab.servers, WithErrorRateRampDown(RampDownConfig{}))
+    failing := ab.servers[0]
+    failing.errorRate.update(0.2)
+
+    // 20% errors is 10 points over the default threshold: 1 - 0.1*5 = 0.5.
+    if got := wlc.EffectiveWeight(failing); got != 2 {
+        t.Errorf("EffectiveWeight of the failing server = %v, want 2", got)
+    }
+    for _, server := range ab.servers[1:] {
+        if got := wlc.EffectiveWeight(server); got != 4 {
+            t.Errorf("EffectiveWeight of a healthy server = %v, want 4", got)
+        }
+    }
+
+    counts := ab.sendHeld(t, wlc, 50)
+    if counts[0] != 10 || counts[1] != 20 || counts[2] != 20 {
+        t.Errorf("requests per server = %v, want [10 20 20]", counts)
+    }
+}
+
+func TestErrorRateRampDownRecovery(t *testing.T) {
+    server := newTestServer(t, "http://10.0.0.1:8080", 1)
+    cfg := RampDownConfig{Threshold: 0.1, Slope: 5, RecoveryPeriod: time.Minute}
+    now := time.Now()
+
+    server.errorRate.update(0.5)
+    if got := server.rampDownFactor(cfg, now); got != minRampDownFactor {
+        t.Errorf("factor at 50%% errors = %v, want the floor %v", got, minRampDownFactor)
+    }
+
+    server.errorRate.reset()
+    server.errorRate.update(0)
+    for _, tt := range []struct {
+        elapsed time.Duration
+        want    float64
+    }{
+        {0, 0.1},
+        {30 * time.Second, 0.55},
+        {time.Minute, 1},
+        {2 * time.Minute, 1},
+    } {
+        if got := server.rampDownFactor(cfg, now.Add(tt.elapsed)); math.Abs(got-tt.want) > 1e-9 {
+            t.Errorf("factor %v into recovery = %v, want %v", tt.elapsed, got, tt.want)
+        }
+    }
+}
@@ -0,0 +1,50 @@
+package balancer
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/cespare/xxhash/v2"
+)
+
+// RendezvousHash implements highest random weight hashing: each key goes to
+// the healthy server with the highest hash(key + server). When a server
+// leaves the pool only the keys it owned move, and they spread evenly over
+// the remaining servers.
+type RendezvousHash struct {
+    *BackendPool
+}
+
+func NewRendezvousHash(servers []*Server) *RendezvousHash {
+    return &RendezvousHash{
+        BackendPool: NewBackendPool(servers),
+    }
+}
+
+// NextServer returns the healthy server that owns key, or nil if none is
+// available.
+func (rh *RendezvousHash) NextServer(key string) *Server {
+    var bestServer *Server
+    var bestHash uint64
+
+    for _, server := range rh.Healthy() {
+        h := xxhash.Sum64String(key + server.URL.Host)
+        if bestServer == nil || h > bestHash {
+            bestHash = h
+            bestServer = server
+        }
+    }
+    return bestServer
+}
+
+// ServeHTTP routes by client IP.
+func (rh *RendezvousHash) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+    server := rh.NextServer(remoteIP(r))
+    if server == nil {
+        log.Printf("[ERROR] No healthy backend available for request %s %s", r.Method, r.URL.Path)
+        http.Error(w, "Service Unavailable: No healthy backend servers available.", http.StatusServiceUnavailable)
+        return
+    }
+
+    server.serve(w, r)
+}
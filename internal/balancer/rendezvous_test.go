@@ -0,0 +1,40 @@
+package balancer
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestRendezvousHashMinimalDisruption(t *testing.T) {
+    var servers []*Server
+    for i := 0; i < 5; i++ {
+        servers = append(servers, newTestServer(t, fmt.Sprintf("http://10.0.0.%d:8080", i+1), 1))
+    }
+    rh := NewRendezvousHash(servers)
+
+    const keys = 10000
+    before := make([]*Server, keys)
+    for i := range before {
+        before[i] = rh.NextServer(fmt.Sprintf("key-%d", i))
+    }
+
+    removed := rh.Remove(servers[2].URL.String())
+    if removed == nil {
+        t.Fatal("Remove did not find the server")
+    }
+
+    moved := 0
+    for i, owner := range before {
+        after := rh.NextServer(fmt.Sprintf("key-%d", i))
+        if after == owner {
+            continue
+        }
+        moved++
+        if owner != removed {
+            t.Fatalf("key-%d moved from %s to %s though its server stayed", i, owner.URL.Host, after.URL.Host)
+        }
+    }
+    if share := float64(moved) / keys; share < 0.18 || share > 0.22 {
+        t.Errorf("removing one of five servers moved %.1f%% of keys, want 20%% ± 2%%", share*100)
+    }
+}
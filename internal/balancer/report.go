@@ -0,0 +1,79 @@
+package balancer
+
+import (
+	"time"
+)
+
+// Report is a machine-readable performance summary of a pool.
+type Report struct {
+    UptimeSeconds     float64         `json:"uptime_seconds"`
+    TotalRequests     uint64          `json:"total_requests"`
+    RequestsPerSecond float64         `json:"requests_per_second"`
+    P50LatencyMs      float64         `json:"p50_latency_ms"`
+    P99LatencyMs      float64         `json:"p99_latency_ms"`
+    HealthyBackends   int             `json:"healthy_backends"`
+    TotalBackends     int             `json:"total_backends"`
+    Backends          []BackendReport `json:"backends"`
+}
+
+// BackendReport summarises a single backend.
+type BackendReport struct {
+    URL               string  `json:"url"`
+    Status            string  `json:"status"`
+    Weight            int     `json:"weight"`
+    ActiveConnections int32   `json:"active_connections"`
+    TotalRequests     uint64  `json:"total_requests"`
+    ErrorRate         float64 `json:"error_rate"`
+    P50LatencyMs      float64 `json:"p50_latency_ms"`
+    P99LatencyMs      float64 `json:"p99_latency_ms"`
+}
+
+// PerformanceReport summarises the pool from its live counters. Latency
+// percentiles are bucket estimates.
+func (wlc *WeightedLeastConnection) PerformanceReport() Report {
+    wlc.mu.RLock()
+    defer wlc.mu.RUnlock()
+
+    wlc.mu2.Lock()
+    totalReqs := wlc.totalRequests
+    wlc.mu2.Unlock()
+
+    uptime := time.Since(wlc.startTime).Seconds()
+    report := Report{
+        UptimeSeconds: uptime,
+        TotalRequests: totalReqs,
+        TotalBackends: len(wlc.servers),
+        Backends:      make([]BackendReport, 0, len(wlc.servers)),
+    }
+    if uptime > 0 {
+        report.RequestsPerSecond = float64(totalReqs) / uptime
+    }
+
+    poolCounts := make([]uint64, len(latencyBucketsMs)+1)
+    for _, server := range wlc.servers {
+        if server.Available() {
+            report.HealthyBackends++
+        }
+
+        counts := server.latency.snapshot()
+        for i, c := range counts {
+            poolCounts[i] += c
+        }
+
+        report.Backends = append(report.Backends, BackendReport{
+            URL:               server.URL.String(),
+            Status:            server.Status(),
+            Weight:            server.Weight,
+            ActiveConnections: server.ActiveConnections.Load(),
+            TotalRequests:     server.RequestCount.Load(),
+            ErrorRate:         server.ErrorRate(),
+            P50LatencyMs:      percentileMs(counts, 0.50),
+            P99LatencyMs:      percentileMs(counts, 0.99),
+        })
+    }
+
+    report.P50LatencyMs = percentileMs(poolCounts, 0.50)
+    report.P99LatencyMs = percentileMs(poolCounts, 0.99)
+
+    return report
+}
@@ -0,0 +1,45 @@
+package balancer
+
+import (
+	"testing"
+
+	lbtesting "github.com/Adi-ty/go-loadbalancer/pkg/testing"
+)
+
+func TestPerformanceReport(t *testing.T) {
+    var servers []*Server
+    for i := 0; i < 3; i++ {
+        servers = append(servers, newTestServer(t, lbtesting.NewFakeBackend(t).URL, 1))
+    }
+    wlc := NewWeightedLeastConnection(servers)
+
+    for i := 0; i < 100; i++ {
+        serveRequest(wlc, algorithmRequest(i))
+    }
+    servers[2].IsHealthy.Store(false)
+
+    report := wlc.PerformanceReport()
+    if report.TotalRequests != 100 {
+        t.Errorf("TotalRequests = %d, want 100", report.TotalRequests)
+    }
+    if report.HealthyBackends != 2 || report.TotalBackends != 3 {
+        t.Errorf("HealthyBackends/TotalBackends = %d/%d, want 2/3", report.HealthyBackends, report.TotalBackends)
+    }
+    if report.UptimeSeconds <= 0 || report.RequestsPerSecond <= 0 {
+        t.Errorf("UptimeSeconds = %v, RequestsPerSecond = %v, want both positive", report.UptimeSeconds, report.RequestsPerSecond)
+    }
+    if report.P50LatencyMs <= 0 || report.P99LatencyMs < report.P50LatencyMs {
+        t.Errorf("P50LatencyMs = %v, P99LatencyMs = %v, want 0 < p50 <= p99", report.P50LatencyMs, report.P99LatencyMs)
+    }
+
+    if len(report.Backends) != 3 {
+        t.Fatalf("got %d backend reports, want 3", len(report.Backends))
+    }
+    var perBackend uint64
+    for _, backend := range report.Backends {
+        perBackend += backend.TotalRequests
+    }
+    if perBackend != 100 {
+        t.Errorf("backend TotalRequests sum to %d, want 100", perBackend)
+    }
+}
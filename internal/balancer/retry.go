@@ -0,0 +1,180 @@
+package balancer
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"slices"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultRetryMethods are the methods retried on another backend when no
+// explicit list is configured.
+var DefaultRetryMethods = []string{"GET", "HEAD", "OPTIONS", "PUT", "DELETE"}
+
+// maxRetryBodyBytes is the largest request body buffered for replay. Larger
+// bodies are streamed and not retried.
+const maxRetryBodyBytes = 1 << 20
+
+// retryState is attached to the context of requests that may be retried.
+// The proxy error handler records the failure there instead of answering the
+// client, leaving the balancer free to try another backend.
+type retryState struct {
+    err   error
+    class ErrorClass
+}
+
+type retryStateKey struct{}
+
+func withRetryState(r *http.Request) (*http.Request, *retryState) {
+    state := &retryState{}
+    return r.WithContext(context.WithValue(r.Context(), retryStateKey{}, state)), state
+}
+
+func retryStateFrom(ctx context.Context) *retryState {
+    state, _ := ctx.Value(retryStateKey{}).(*retryState)
+    return state
+}
+
+func (wlc *WeightedLeastConnection) retryable(r *http.Request) bool {
+    return wlc.maxRetries > 0 && slices.Contains(wlc.retryMethods, r.Method)
+}
+
+// bufferRetryBody reads the request body into memory so it can be replayed.
+// It returns false, with the body left intact, if the body is too large to
+// buffer or could not be read, and a nil body if r has none. err is the
+// error reading the body, if any. The caller must release the body once
+// the request has been forwarded.
+func (wlc *WeightedLeastConnection) bufferRetryBody(r *http.Request) (body *pooledBody, ok bool, err error) {
+    if r.Body == nil || r.Body == http.NoBody {
+        return nil, true, nil
+    }
+    if r.ContentLength > maxRetryBodyBytes {
+        return nil, false, nil
+    }
+
+    body, err = readPooledBody(wlc.requestBuffers, r.Body, maxRetryBodyBytes)
+    if err != nil || len(body.data) > maxRetryBodyBytes {
+        // Forward what was read followed by the rest. The reader keeps
+        // the buffer until the transport closes it.
+        r.Body, _ = body.reader(r.Body)
+        body.release()
+        return nil, false, err
+    }
+    r.Body.Close()
+    return body, true, nil
+}
+
+// forwardWithRetries proxies r to server, on which the caller has acquired a
+// connection slot, and, if the backend cannot be reached, retries on other
+// backends up to maxRetries times. Bodies too large to buffer get a single
+// attempt. If maxRetryDuration is set, the attempts must have response
+// headers within it; streaming the response body afterwards is not limited.
+func (wlc *WeightedLeastConnection) forwardWithRetries(w http.ResponseWriter, r *http.Request, server *Server) {
+    body, ok, err := wlc.bufferRetryBody(r)
+    var tooLarge *http.MaxBytesError
+    if errors.As(err, &tooLarge) {
+        server.ActiveConnections.Add(-1)
+        http.Error(w, "Request Entity Too Large", http.StatusRequestEntityTooLarge)
+        return
+    }
+    if body != nil {
+        defer body.release()
+    }
+    maxRetries := wlc.maxRetries
+    if !ok {
+        maxRetries = 0
+    }
+
+    var expired atomic.Bool
+    if wlc.maxRetryDuration > 0 {
+        ctx, cancel := context.WithCancel(r.Context())
+        defer cancel()
+        timer := time.AfterFunc(wlc.maxRetryDuration, func() {
+            expired.Store(true)
+            cancel()
+        })
+        defer timer.Stop()
+        w = &headerDeadlineWriter{ResponseWriter: w, timer: timer}
+        r = r.WithContext(ctx)
+    }
+
+    r, state := withRetryState(r)
+    if body != nil {
+        r.GetBody = func() (io.ReadCloser, error) {
+            return body.reader(nil)
+        }
+    }
+    tried := make(map[*Server]bool)
+
+    for attempt := 0; ; attempt++ {
+        state.err = nil
+        if body != nil {
+            // Cannot fail: the body is not released until we return.
+            r.Body, _ = body.reader(nil)
+        }
+
+        wlc.forward(w, r, server)
+        if state.err == nil {
+            return
+        }
+        tried[server] = true
+
+        if state.class == ErrorPermanent || attempt >= maxRetries || r.Context().Err() != nil {
+            break
+        }
+        next := wlc.acquireServer(tried)
+        if next == nil {
+            break
+        }
+
+        log.Printf("[RETRY] %s %s failed on %s: %v, retrying on %s",
+            r.Method, r.URL.Path, server.URL.Host, state.err, next.URL.Host)
+        server = next
+    }
+
+    if expired.Load() || errors.Is(r.Context().Err(), context.DeadlineExceeded) {
+        log.Printf("[ERROR] %s %s ran out of time after trying %d backends: %v", r.Method, r.URL.Path, len(tried), state.err)
+        w.WriteHeader(http.StatusGatewayTimeout)
+        return
+    }
+
+    log.Printf("[ERROR] %s %s failed on all attempted backends: %v", r.Method, r.URL.Path, state.err)
+    w.WriteHeader(proxyErrorStatus(state.err))
+}
+
+// headerDeadlineWriter stops the retry budget's timer once a final response
+// status is written or the connection is hijacked for a protocol switch, so
+// the budget does not cut off the body or the upgraded connection.
+type headerDeadlineWriter struct {
+    http.ResponseWriter
+    timer *time.Timer
+}
+
+func (hw *headerDeadlineWriter) WriteHeader(code int) {
+    if code >= http.StatusOK || code == http.StatusSwitchingProtocols {
+        hw.timer.Stop()
+    }
+    hw.ResponseWriter.WriteHeader(code)
+}
+
+func (hw *headerDeadlineWriter) Write(p []byte) (int, error) {
+    hw.timer.Stop()
+    return hw.ResponseWriter.Write(p)
+}
+
+func (hw *headerDeadlineWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+    hw.timer.Stop()
+    return http.NewResponseController(hw.ResponseWriter).Hijack()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer for
+// flushing.
+func (hw *headerDeadlineWriter) Unwrap() http.ResponseWriter {
+    return hw.ResponseWriter
+}
@@ -0,0 +1,114 @@
+package balancer
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	lbtesting "github.com/Adi-ty/go-loadbalancer/pkg/testing"
+)
+
+func TestRetryMethods(t *testing.T) {
+    tests := []struct {
+        name    string
+        methods []string
+        want    int
+    }{
+        {"PATCH retryable", []string{"GET", "PATCH"}, http.StatusOK},
+        {"default methods", nil, http.StatusBadGateway},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            good := lbtesting.NewFakeBackend(t)
+            // The unreachable backend is first, so it is tried first.
+            wlc := NewWeightedLeastConnection([]*Server{
+                newTestServer(t, refusingURL(t), 1),
+                newTestServer(t, good.URL, 1),
+            }, WithRetries(1, tt.methods))
+
+            rec := serveRequest(wlc, httptest.NewRequest(http.MethodPatch, "/", bytes.NewReader([]byte(`{"name":"x"}`))))
+            if rec.Code != tt.want {
+                t.Errorf("status = %d, want %d", rec.Code, tt.want)
+            }
+            if tt.want != http.StatusOK {
+                return
+            }
+            if got := good.CallCount(); got != 1 {
+                t.Fatalf("good backend received %d requests, want 1", got)
+            }
+            if req := good.LastRequest(); req.Method != http.MethodPatch {
+                t.Errorf("retried request has method %s, want PATCH", req.Method)
+            }
+        })
+    }
+}
+
+func TestMaxRetryDurationLimitsHeaders(t *testing.T) {
+    tests := []struct {
+        name    string
+        retries int
+        delay   time.Duration
+        req     func() *http.Request
+        want    int
+    }{
+        {name: "buffered body", retries: 1, delay: time.Second, want: http.StatusGatewayTimeout, req: func() *http.Request {
+            return httptest.NewRequest(http.MethodPut, "/", bytes.NewReader([]byte("small")))
+        }},
+        {name: "streamed body", retries: 1, delay: time.Second, want: http.StatusGatewayTimeout, req: func() *http.Request {
+            req := chunkedRequest(make([]byte, maxRetryBodyBytes+1))
+            req.Method = http.MethodPut
+            return req
+        }},
+        {name: "headers within budget", retries: 3, delay: 30 * time.Millisecond, want: http.StatusOK, req: func() *http.Request {
+            return httptest.NewRequest(http.MethodGet, "/", nil)
+        }},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            backend := lbtesting.NewFakeBackend(t)
+            backend.SetDelay(tt.delay)
+            wlc := NewWeightedLeastConnection([]*Server{newTestServer(t, backend.URL, 1)},
+                WithRetries(tt.retries, nil), WithMaxRetryDuration(50*time.Millisecond))
+
+            start := time.Now()
+            rec := serveRequest(wlc, tt.req())
+            if rec.Code != tt.want {
+                t.Errorf("status = %d, want %d", rec.Code, tt.want)
+            }
+            if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+                t.Errorf("request took %v, want it cut off near the 50ms budget", elapsed)
+            }
+            if got := backend.CallCount(); got != 1 {
+                t.Errorf("backend received %d requests, want 1", got)
+            }
+        })
+    }
+}
+
+func TestMaxRetryDurationDoesNotLimitBody(t *testing.T) {
+    backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if r.URL.Path == "/health" {
+            return
+        }
+        w.Write([]byte("head,"))
+        w.(http.Flusher).Flush()
+        time.Sleep(150 * time.Millisecond)
+        w.Write([]byte("tail"))
+    }))
+    t.Cleanup(backend.Close)
+
+    wlc := NewWeightedLeastConnection([]*Server{newTestServer(t, backend.URL, 1)},
+        WithRetries(1, nil), WithMaxRetryDuration(50*time.Millisecond))
+
+    rec := serveRequest(wlc, httptest.NewRequest(http.MethodGet, "/", nil))
+    if rec.Code != http.StatusOK {
+        t.Errorf("status = %d, want %d", rec.Code, http.StatusOK)
+    }
+    if got := rec.Body.String(); got != "head,tail" {
+        t.Errorf("body = %q, want the whole body streamed past the budget", got)
+    }
+}
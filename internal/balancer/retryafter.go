@@ -0,0 +1,59 @@
+package balancer
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxRetryAfter caps how long a Retry-After header can keep a backend out of
+// rotation, so a bogus date cannot sideline it indefinitely.
+const maxRetryAfter = 5 * time.Minute
+
+// BackingOff reports whether the server asked, through Retry-After, not to
+// be sent requests for now.
+func (s *Server) BackingOff() bool {
+    return s.BackoffUntil.Load() > time.Now().UnixNano()
+}
+
+// recordRetryAfter takes the server out of rotation for as long as a 429 or
+// 503 response's Retry-After header asks.
+func (s *Server) recordRetryAfter(resp *http.Response) {
+    if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable {
+        return
+    }
+
+    delay, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+    if !ok || delay <= 0 {
+        return
+    }
+    delay = min(delay, maxRetryAfter)
+
+    s.BackoffUntil.Store(time.Now().Add(delay).UnixNano())
+    s.BackedOffCount.Add(1)
+    log.Printf("[BACKOFF] Server %s answered %d, skipping it for %s", s.URL.Host, resp.StatusCode, delay)
+}
+
+// parseRetryAfter parses a Retry-After value given as delay-seconds or as an
+// HTTP-date, returning the delay relative to now.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+    value = strings.TrimSpace(value)
+    if value == "" {
+        return 0, false
+    }
+
+    if secs, err := strconv.ParseInt(value, 10, 64); err == nil {
+        if secs < 0 {
+            return 0, false
+        }
+        return time.Duration(min(secs, int64(maxRetryAfter/time.Second))) * time.Second, true
+    }
+
+    t, err := http.ParseTime(value)
+    if err != nil {
+        return 0, false
+    }
+    return t.Sub(now), true
+}
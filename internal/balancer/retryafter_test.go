@@ -0,0 +1,73 @@
+package balancer
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	lbtesting "github.com/Adi-ty/go-loadbalancer/pkg/testing"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+    now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+    tests := []struct {
+        value string
+        want  time.Duration
+        ok    bool
+    }{
+        {"5", 5 * time.Second, true},
+        {" 0 ", 0, true},
+        {"86400", maxRetryAfter, true},
+        {now.Add(30 * time.Second).Format(http.TimeFormat), 30 * time.Second, true},
+        {"", 0, false},
+        {"-1", 0, false},
+        {"soon", 0, false},
+    }
+
+    for _, tt := range tests {
+        got, ok := parseRetryAfter(tt.value, now)
+        if got != tt.want || ok != tt.ok {
+            t.Errorf("parseRetryAfter(%q) = %v, %v; want %v, %v", tt.value, got, ok, tt.want, tt.ok)
+        }
+    }
+}
+
+func TestRetryAfterSkipsBackend(t *testing.T) {
+    var hits atomic.Int64
+    throttled := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if hits.Add(1) == 1 {
+            w.Header().Set("Retry-After", "1")
+            w.WriteHeader(http.StatusServiceUnavailable)
+        }
+    }))
+    defer throttled.Close()
+    other := lbtesting.NewFakeBackend(t)
+
+    server := newTestServer(t, throttled.URL, 1)
+    wlc := NewWeightedLeastConnection([]*Server{server, newTestServer(t, other.URL, 1)})
+
+    // Ties go to the first server, so it gets the first request.
+    if rec := serveRequest(wlc, httptest.NewRequest(http.MethodGet, "/", nil)); rec.Code != http.StatusServiceUnavailable {
+        t.Fatalf("first request status = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+    }
+    if got := server.BackedOffCount.Load(); got != 1 {
+        t.Errorf("BackedOffCount = %d, want 1", got)
+    }
+
+    deadline := time.Now().Add(900 * time.Millisecond)
+    for time.Now().Before(deadline) {
+        serveRequest(wlc, httptest.NewRequest(http.MethodGet, "/", nil))
+        time.Sleep(20 * time.Millisecond)
+    }
+    if got := hits.Load(); got != 1 {
+        t.Errorf("backend got %d requests within a second of Retry-After: 1, want only the first", got)
+    }
+
+    time.Sleep(200 * time.Millisecond)
+    serveRequest(wlc, httptest.NewRequest(http.MethodGet, "/", nil))
+    if got := hits.Load(); got != 2 {
+        t.Errorf("backend got %d requests after Retry-After expired, want 1", got-1)
+    }
+}
@@ -0,0 +1,29 @@
+package balancer
+
+// BackendScorer ranks servers for NextServer; the lowest score wins.
+type BackendScorer interface {
+    Score(s *Server) float64
+}
+
+// DefaultScorer scores servers by Ratio: active connections per unit of
+// weight, raised by any load the backend reports.
+type DefaultScorer struct{}
+
+func (DefaultScorer) Score(s *Server) float64 {
+    return s.Ratio()
+}
+
+// LatencyWeightedScorer scales the connection ratio by the server's average
+// latency, so a slow backend receives fewer requests than a fast one of the
+// same weight. The pending request is counted so idle servers are still
+// ordered by latency.
+type LatencyWeightedScorer struct{}
+
+func (LatencyWeightedScorer) Score(s *Server) float64 {
+    if !s.IsHealthy.Load() || s.Weight == 0 {
+        return 1e18
+    }
+
+    conns := float64(s.ActiveConnections.Load()) + 1
+    return conns / float64(s.Weight) * max(s.LatencyMs(), 1)
+}
@@ -0,0 +1,55 @@
+package balancer
+
+import (
+	"testing"
+	"time"
+)
+
+// firstServerScorer prefers first above every other server.
+type firstServerScorer struct {
+    first *Server
+}
+
+func (s firstServerScorer) Score(server *Server) float64 {
+    if server == s.first {
+        return 0
+    }
+    return 1e9
+}
+
+func TestCustomBackendScorer(t *testing.T) {
+    ab := newAlgorithmBackends(t, []int{1, 1, 1}, true)
+    wlc := NewWeightedLeastConnection(ab.servers, WithBackendScorer(firstServerScorer{first: ab.servers[0]}))
+
+    // Held requests pile up as active connections, which would spread them
+    // under the default scorer.
+    counts := ab.sendHeld(t, wlc, 30)
+    if counts[0] != 30 {
+        t.Errorf("requests per server = %v, want all 30 on the first", counts)
+    }
+}
+
+func TestLatencyWeightedScorer(t *testing.T) {
+    fast := newTestServer(t, "http://10.0.0.1:8080", 1)
+    slow := newTestServer(t, "http://10.0.0.2:8080", 1)
+    for i := 0; i < 20; i++ {
+        fast.recordLatency(5 * time.Millisecond)
+        slow.recordLatency(50 * time.Millisecond)
+    }
+    wlc := NewWeightedLeastConnection([]*Server{slow, fast}, WithBackendScorer(LatencyWeightedScorer{}))
+
+    if got := wlc.NextServer(); got != fast {
+        t.Errorf("NextServer() = %s with both idle, want the faster %s", got.URL.Host, fast.URL.Host)
+    }
+
+    // Five requests on the fast server still score below one on a server
+    // ten times slower.
+    fast.ActiveConnections.Store(5)
+    if got := wlc.NextServer(); got != fast {
+        t.Errorf("NextServer() = %s, want %s", got.URL.Host, fast.URL.Host)
+    }
+    fast.ActiveConnections.Store(20)
+    if got := wlc.NextServer(); got != slow {
+        t.Errorf("NextServer() = %s once the fast server is busy, want %s", got.URL.Host, slow.URL.Host)
+    }
+}
@@ -1,25 +1,228 @@
 package balancer
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"log"
+	"math"
+	"net"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 )
 
+// healthCheckTimeout bounds each health check request.
+const healthCheckTimeout = 3 * time.Second
+
+// healthCheckBodyLimit is how much of a health check response body is read.
+const healthCheckBodyLimit = 4096
+
+// MaxWeight is the largest weight a server may be given.
+const MaxWeight = 1000
+
+// maxReportedLoad is the scaled value of a fully loaded backend in
+// ReportedLoad.
+const maxReportedLoad = 10000
+
+// errHealthRecovering means a health check passed but the server has not yet
+// passed enough in a row to be marked healthy again.
+var errHealthRecovering = errors.New("recovering")
+
 type Server struct {
     URL          *url.URL
     ReverseProxy *httputil.ReverseProxy
 
+    // Transport carries proxied requests to this backend. Each server has
+    // its own so connection pool settings can be tuned per backend.
+    Transport *http.Transport
+    dialer    *net.Dialer // Set with setDialer, kept so Clone can rebuild it
+
+    // healthClient is reused across health checks so connections to the
+    // backend are kept alive.
+    healthClient *http.Client
+
     ActiveConnections atomic.Int32
-    Weight            int
+    PeakConnections   atomic.Int32
+
+    // Weight is the server's relative share of traffic, an integer from 1 to
+    // MaxWeight. A server with twice the weight of another is given twice as
+    // many concurrent requests.
+    Weight int
+
+    // MaxConnections caps concurrent requests to the server; further
+    // requests go to another backend or wait for a slot. It is a hard cap:
+    // a slot is taken atomically when the server is chosen, so concurrent
+    // requests cannot push it past the limit. Zero means unlimited.
+    MaxConnections int32
+
+    // Priority orders servers ahead of the balancing algorithm: requests go
+    // only to the lowest Priority value that has a server with room for
+    // them, and overflow to the next level. Zero is the highest priority.
+    Priority int
 
     RequestCount  atomic.Uint64
+    ErrorCount    atomic.Uint64 // Proxied requests that failed or returned a 5xx status
+    BytesSent     atomic.Uint64 // Request body bytes forwarded to the backend
+    BytesReceived atomic.Uint64 // Response body bytes relayed from the backend
     IsHealthy     atomic.Bool
     FailureCount  atomic.Uint32
     LastCheckTime atomic.Int64
+
+    // nextCheckTime, in Unix nanoseconds, delays health checks of a backend
+    // that keeps failing. Zero means check every cycle.
+    nextCheckTime atomic.Int64
+
+    // BackoffUntil, in Unix nanoseconds, keeps the server out of rotation
+    // after it answered 429 or 503 with Retry-After. BackedOffCount counts
+    // those responses.
+    BackoffUntil   atomic.Int64
+    BackedOffCount atomic.Uint64
+
+    // ManuallyDisabled takes the server out of rotation regardless of its
+    // health status.
+    ManuallyDisabled atomic.Bool
+
+    // HealthPaths are probed in order by HealthCheck; the server is healthy
+    // as soon as one returns 200. Defaults to /health.
+    HealthPaths []string
+
+    // HealthCheckExpectedStatuses are the status codes counted as healthy.
+    // Defaults to 200.
+    HealthCheckExpectedStatuses []int
+
+    // HealthCheckBodyContains, when non-empty, must appear in the first 4KB
+    // of the health check response body. HealthCheckBodyNotContains must
+    // not.
+    HealthCheckBodyContains    string
+    HealthCheckBodyNotContains string
+
+    // HealthCheckPingFirst sends an ICMP echo before the HTTP health check
+    // and marks the server unhealthy without making the request if there is
+    // no reply. Pings are skipped if the process lacks CAP_NET_RAW.
+    HealthCheckPingFirst bool
+
+    // HealthCheckConsecutiveSuccessesRequired is how many health checks in
+    // a row must pass before an unhealthy server is marked healthy and its
+    // FailureCount reset. Zero is treated as 1.
+    HealthCheckConsecutiveSuccessesRequired uint32
+    consecutiveSuccesses                    atomic.Uint32
+
+    // HealthCheckParseResponse decodes JSON health responses and copies
+    // their top-level fields into Tags.
+    HealthCheckParseResponse bool
+
+    // HealthFlappingThreshold is how many consecutive checks a new health
+    // state must hold before the change is logged. IsHealthy itself always
+    // follows the latest check.
+    HealthFlappingThreshold int
+    healthLog               healthLog
+    healthLogMu             sync.Mutex
+
+    // Tags are free-form labels such as region or version. Use Tag,
+    // TagsSnapshot and SetTags for concurrent access.
+    Tags   map[string]string
+    tagsMu sync.RWMutex
+
+    // CustomDirector, when set, runs after the default director and may
+    // rewrite the outgoing request, e.g. to strip a path prefix or inject
+    // credentials.
+    CustomDirector func(req *http.Request)
+
+    // PreserveHost forwards the client's Host header, and copies it to
+    // X-Forwarded-Host, instead of replacing it with the backend's host.
+    PreserveHost bool
+
+    // UseSNIFromHost sends the client's Host header, without its port, as
+    // the TLS server name to an https backend instead of the backend's own
+    // hostname, so a backend hosting several virtual hosts on one address
+    // can present the right certificate.
+    UseSNIFromHost bool
+
+    // TrustedForwardedProtoHops is how many proxies in front of the balancer
+    // are trusted to set X-Forwarded-Proto. Zero ignores the client's header
+    // and forwards the scheme of the incoming connection.
+    TrustedForwardedProtoHops int
+
+    // ProxyHideHeaders are backend response headers stripped before the
+    // response reaches the client, in addition to DefaultProxyHideHeaders.
+    // ProxyPassHeaders are passed on even if they are in either list.
+    ProxyHideHeaders []string
+    ProxyPassHeaders []string
+
+    // ConnectJitter, when set, delays the first connections to a backend
+    // that has just become healthy again by a random time up to
+    // ConnectJitter, so reconnects after a backend restart do not all
+    // arrive at once.
+    ConnectJitter     time.Duration
+    lastConnectedTime atomic.Int64 // Unix nanoseconds of the last successful dial
+    recoveredTime     atomic.Int64 // Unix nanoseconds it last turned healthy, 0 if never
+
+    // DebugHeader, when non-empty, names a response header set to this
+    // server's host so clients can see which backend answered.
+    DebugHeader string
+
+    // BackendLoadHeader, when non-empty, names a response header in which
+    // the backend reports its load as 0.0-1.0. The latest value is kept in
+    // ReportedLoad, scaled to 0-maxReportedLoad, and raises Ratio. The
+    // header is not passed on to clients.
+    BackendLoadHeader string
+    ReportedLoad      atomic.Uint32
+
+    // SLOTarget is the share of requests, e.g. 0.999, the backend is
+    // expected to serve successfully. It sets the error budget reported by
+    // ErrorBudgetRemaining. Zero disables error budget tracking.
+    SLOTarget            float64
+    errorBudgetExhausted atomic.Bool
+
+    // Passive statistics, see stats.go.
+    errorRate     ewma          // EWMA of failed proxied requests
+    latencyMs     ewma          // EWMA of proxied request latency in ms
+    healthHistory atomic.Uint32 // Bit i set if the i-th most recent check failed
+    healthChecks  atomic.Uint32 // Number of checks recorded, capped at healthHistorySize
+    latency       latencyHistogram
+    avgConnBits   atomic.Uint64 // EWMA of ActiveConnections, float64 bits, see burst.go
+    rampDown      rampDownState
+
+    // classifyError is the owning balancer's ProxyErrorClassifier, see
+    // classifyProxyError.
+    classifyError ProxyErrorClassifier
+
+    // requestBuffers, if set, is the owning balancer's request buffer pool.
+    requestBuffers *bytePool
+}
+
+// Disable pulls the server out of rotation without touching its health.
+func (s *Server) Disable() {
+    s.ManuallyDisabled.Store(true)
+}
+
+// Enable puts a manually disabled server back into rotation.
+func (s *Server) Enable() {
+    s.ManuallyDisabled.Store(false)
+}
+
+// Available reports whether the server may receive traffic.
+func (s *Server) Available() bool {
+    return s.IsHealthy.Load() && !s.ManuallyDisabled.Load()
+}
+
+// Status returns "disabled", "healthy" or "unhealthy".
+func (s *Server) Status() string {
+    if s.ManuallyDisabled.Load() {
+        return "disabled"
+    }
+    if s.IsHealthy.Load() {
+        return "healthy"
+    }
+    return "unhealthy"
 }
 
 func (s *Server) Ratio() float64 {
@@ -33,58 +236,324 @@ func (s *Server) Ratio() float64 {
     if w == 0 {
         return 1e18
     }
-    return conn / w
+    load := float64(s.ReportedLoad.Load()) / maxReportedLoad
+    return conn / w * (1 + load)
 }
 
-func (s *Server) HealthCheck() error {
-    client := &http.Client{
-        Timeout: 3 * time.Second,
+// recordReportedLoad stores the load the backend reported in resp and strips
+// the header from the response.
+func (s *Server) recordReportedLoad(resp *http.Response) {
+    value := resp.Header.Get(s.BackendLoadHeader)
+    if value == "" {
+        return
+    }
+    resp.Header.Del(s.BackendLoadHeader)
+
+    load, err := strconv.ParseFloat(value, 64)
+    if err != nil || math.IsNaN(load) {
+        log.Printf("[LOAD] Server %s reported invalid load %q", s.URL.Host, value)
+        return
     }
+    load = min(max(load, 0), 1)
+    s.ReportedLoad.Store(uint32(load * maxReportedLoad))
+}
+
+// Reset zeros the server's runtime counters, e.g. after a standby backend is
+// promoted. ActiveConnections is left alone since in-flight requests still
+// decrement it.
+func (s *Server) Reset() {
+    s.RequestCount.Store(0)
+    s.ErrorCount.Store(0)
+    s.BytesSent.Store(0)
+    s.BytesReceived.Store(0)
+    s.FailureCount.Store(0)
+    s.LastCheckTime.Store(0)
+    s.BackedOffCount.Store(0)
+    s.ResetPeak()
+    s.errorRate.reset()
+    s.latencyMs.reset()
+    s.avgConnBits.Store(0)
+    s.healthHistory.Store(0)
+    s.healthChecks.Store(0)
+    s.latency.reset()
+    s.errorBudgetExhausted.Store(false)
 
+    s.rampDown.mu.Lock()
+    s.rampDown.factor = 0
+    s.rampDown.mu.Unlock()
+}
+
+// trackPeak raises PeakConnections to active if it is a new high.
+func (s *Server) trackPeak(active int32) {
+    for {
+        peak := s.PeakConnections.Load()
+        if active <= peak || s.PeakConnections.CompareAndSwap(peak, active) {
+            return
+        }
+    }
+}
+
+// ResetPeak restarts peak tracking from the current number of active
+// connections.
+func (s *Server) ResetPeak() {
+    s.PeakConnections.Store(s.ActiveConnections.Load())
+}
+
+func (s *Server) HealthCheck() error {
+    return s.healthCheck(context.Background())
+}
+
+// healthCheck is HealthCheck, abandoned without counting a failure if ctx
+// is cancelled.
+func (s *Server) healthCheck(ctx context.Context) error {
     s.LastCheckTime.Store(time.Now().Unix())
 
-    resp, err := client.Get(s.URL.String() + "/health")
-    if err != nil {
+    if err := s.probeHealth(ctx, true); err != nil {
+        if ctx.Err() != nil {
+            return err
+        }
+        s.consecutiveSuccesses.Store(0)
         s.FailureCount.Add(1)
+        return err
+    }
+
+    if s.consecutiveSuccesses.Add(1) >= s.successesRequired() {
+        s.FailureCount.Store(0)
+    }
+    return nil
+}
+
+// probeHealth pings the server, if configured, and requests its health
+// paths. It changes no state except, when updateTags is set, Tags from a
+// parsed health response.
+func (s *Server) probeHealth(ctx context.Context, updateTags bool) error {
+    client := s.healthClient
+    if client == nil {
+        client = &http.Client{Timeout: healthCheckTimeout}
+    }
+
+    if s.HealthCheckPingFirst {
+        if err := pingServer(s); err != nil && !errors.Is(err, errPingUnavailable) {
+            return fmt.Errorf("ping failed: %w", err)
+        }
+    }
+
+    paths := s.HealthPaths
+    if len(paths) == 0 {
+        paths = []string{"/health"}
+    }
+
+    // Any passing path is enough; only report the last error when all fail.
+    var lastErr error
+    for _, path := range paths {
+        lastErr = s.checkHealthPath(ctx, client, path, updateTags)
+        if lastErr == nil {
+            return nil
+        }
+    }
+    return lastErr
+}
+
+// successesRequired returns HealthCheckConsecutiveSuccessesRequired, at
+// least 1.
+func (s *Server) successesRequired() uint32 {
+    return max(s.HealthCheckConsecutiveSuccessesRequired, 1)
+}
+
+func (s *Server) checkHealthPath(ctx context.Context, client *http.Client, path string, updateTags bool) error {
+    req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.URL.String()+path, nil)
+    if err != nil {
+        return fmt.Errorf("health check failed: %w", err)
+    }
+    resp, err := client.Do(req)
+    if err != nil {
         return fmt.Errorf("health check failed: %w", err)
     }
-    defer resp.Body.Close()
+    defer func() {
+        // Drain the body so the keep-alive connection can be reused.
+        io.Copy(io.Discard, io.LimitReader(resp.Body, healthCheckBodyLimit))
+        resp.Body.Close()
+    }()
     
-    if resp.StatusCode != http.StatusOK {
-        s.FailureCount.Add(1)
-        return fmt.Errorf("health check returned status %d", resp.StatusCode)
+    if !s.expectedHealthStatus(resp.StatusCode) {
+        return fmt.Errorf("health check %s returned status %d", path, resp.StatusCode)
+    }
+
+    parseResponse := s.HealthCheckParseResponse && updateTags
+    if s.HealthCheckBodyContains == "" && s.HealthCheckBodyNotContains == "" && !parseResponse {
+        return nil
+    }
+
+    body, err := io.ReadAll(io.LimitReader(resp.Body, healthCheckBodyLimit))
+    if err != nil {
+        return fmt.Errorf("health check %s body read failed: %w", path, err)
+    }
+    if s.HealthCheckBodyContains != "" && !strings.Contains(string(body), s.HealthCheckBodyContains) {
+        return fmt.Errorf("health check %s body does not contain %q", path, s.HealthCheckBodyContains)
+    }
+    if s.HealthCheckBodyNotContains != "" && strings.Contains(string(body), s.HealthCheckBodyNotContains) {
+        return fmt.Errorf("health check %s body contains %q", path, s.HealthCheckBodyNotContains)
+    }
+    if parseResponse {
+        if err := s.updateTagsFromHealthBody(body); err != nil {
+            log.Printf("[HEALTH] Server %s: %v", s.URL.Host, err)
+        }
+    }
+    return nil
+}
+
+func (s *Server) expectedHealthStatus(code int) bool {
+    if len(s.HealthCheckExpectedStatuses) == 0 {
+        return code == http.StatusOK
+    }
+    return slices.Contains(s.HealthCheckExpectedStatuses, code)
+}
+
+// RunHealthCheck performs a health check, records the result in IsHealthy and
+// logs state changes that outlast HealthFlappingThreshold checks. It returns
+// the health check error, if any. An unhealthy server whose check passes
+// stays unhealthy, with an error wrapping errHealthRecovering, until
+// HealthCheckConsecutiveSuccessesRequired checks have passed in a row.
+func (s *Server) RunHealthCheck() error {
+    return s.runHealthCheck(context.Background())
+}
+
+// runHealthCheck is RunHealthCheck, leaving the server's health as it was if
+// ctx is cancelled during the check.
+func (s *Server) runHealthCheck(ctx context.Context) error {
+    err := s.healthCheck(ctx)
+    if ctx.Err() != nil {
+        return ctx.Err()
+    }
+    s.recordHealthCheck(err == nil)
+
+    if n, required := s.consecutiveSuccesses.Load(), s.successesRequired(); err == nil && n < required && !s.IsHealthy.Load() {
+        err = fmt.Errorf("%w: %d of %d consecutive checks passed", errHealthRecovering, n, required)
+    }
+    isHealthy := err == nil
+
+    if wasHealthy := s.setHealthy(isHealthy); isHealthy && !wasHealthy {
+        s.recoveredTime.Store(time.Now().UnixNano())
+    }
+    s.logHealthTransition(isHealthy, err)
+    return err
+}
+
+// setHealthy records whether the server is healthy and returns whether it
+// was. A server that goes unhealthy must pass
+// HealthCheckConsecutiveSuccessesRequired checks afresh, so passes counted
+// while it was healthy do not bring it straight back.
+func (s *Server) setHealthy(healthy bool) (wasHealthy bool) {
+    wasHealthy = s.IsHealthy.Swap(healthy)
+    if wasHealthy && !healthy {
+        s.consecutiveSuccesses.Store(0)
+    }
+    return wasHealthy
+}
+
+// validateWeight checks that weight is within 1..MaxWeight.
+func validateWeight(rawURL string, weight int) error {
+    if weight < 1 || weight > MaxWeight {
+        return &ErrInvalidWeight{URL: rawURL, Weight: weight}
     }
-   
-    s.FailureCount.Store(0)
     return nil
 }
 
+// NewServer creates a server proxying to rawURL, which must be absolute.
+// weight must be between 1 and MaxWeight.
 func NewServer(rawURL string, weight int) (*Server, error) {
+    if err := validateWeight(rawURL, weight); err != nil {
+        return nil, err
+    }
+
     u, err := url.Parse(rawURL)
     if err != nil {
-        return nil, err
+        return nil, &ErrInvalidURL{Raw: rawURL, Cause: err}
+    }
+    if u.Host == "" {
+        return nil, &ErrInvalidURL{Raw: rawURL, Cause: errors.New("missing host, expected e.g. http://host:port")}
     }
 
     proxy := httputil.NewSingleHostReverseProxy(u)
+    transport := http.DefaultTransport.(*http.Transport).Clone()
+
+    server := &Server{
+        URL:          u,
+        ReverseProxy: proxy,
+        Transport:    transport,
+        Weight:       weight,
+
+        HealthFlappingThreshold: DefaultHealthFlappingThreshold,
+
+        healthLog:    healthLog{healthy: true},
+        healthClient: &http.Client{
+            Transport: http.DefaultTransport.(*http.Transport).Clone(),
+            Timeout:   healthCheckTimeout,
+        },
+    }
 
     // Enhanced error handling for proxy
     proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
-        w.WriteHeader(http.StatusBadGateway)
+        // A body over the size limit is the client's doing: the backend
+        // is not blamed and the request is not retried.
+        var tooLarge *http.MaxBytesError
+        if errors.As(err, &tooLarge) {
+            if state := retryStateFrom(r.Context()); state != nil {
+                state.err = err
+                state.class = ErrorPermanent
+                return
+            }
+            w.WriteHeader(http.StatusRequestEntityTooLarge)
+            return
+        }
+
+        server.recordOutcome(true)
+        class := server.classifyProxyError(err)
+        if class == ErrorCircuitBreak {
+            server.setHealthy(false)
+            log.Printf("[CIRCUIT] Server %s taken out of rotation after %v", server.URL.Host, err)
+        }
+        if state := retryStateFrom(r.Context()); state != nil {
+            state.err = err
+            state.class = class
+            return
+        }
+        w.WriteHeader(proxyErrorStatus(err))
+    }
+
+    proxy.ModifyResponse = func(resp *http.Response) error {
+        server.recordOutcome(resp.StatusCode >= http.StatusInternalServerError)
+        server.recordRetryAfter(resp)
+        server.hideResponseHeaders(resp.Header)
+        if server.BackendLoadHeader != "" {
+            server.recordReportedLoad(resp)
+        }
+        if server.DebugHeader != "" {
+            resp.Header.Set(server.DebugHeader, server.URL.Host)
+        }
+        server.setDiagnosticHeaders(resp)
+        return nil
     }
 
     originalDirector := proxy.Director
     proxy.Director = func(req *http.Request) {
         originalDirector(req)
-        req.Host = u.Host
+        if server.PreserveHost {
+            req.Header.Set("X-Forwarded-Host", req.Host)
+        } else {
+            req.Host = u.Host
+        }
         // load balancer identification
         req.Header.Set("X-Forwarded-By", "go-loadbalancer")
-    }
+        setForwardedProto(req, server.TrustedForwardedProtoHops)
 
-    server := &Server{
-        URL:          u,
-        ReverseProxy: proxy,
-        Weight:       weight,
+        if server.CustomDirector != nil {
+            server.CustomDirector(req)
+        }
     }
+
+    server.setDialer(backendDialer(DefaultBackendKeepAliveInterval, DefaultBackendKeepAliveCount))
+    proxy.Transport = &sniRoundTripper{server: server}
     server.IsHealthy.Store(true)
     server.LastCheckTime.Store(time.Now().Unix())
 
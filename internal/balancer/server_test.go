@@ -0,0 +1,261 @@
+package balancer
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	lbtesting "github.com/Adi-ty/go-loadbalancer/pkg/testing"
+)
+
+func TestServerReset(t *testing.T) {
+    backend := lbtesting.NewFakeBackend(t)
+    backend.SetBody("response")
+    server := newTestServer(t, backend.URL, 1)
+    wlc := NewWeightedLeastConnection([]*Server{server})
+
+    for i := 0; i < 3; i++ {
+        serveRequest(wlc, httptest.NewRequest(http.MethodPost, "/", nil))
+    }
+    backend.SetHealthy(false)
+    server.RunHealthCheck()
+    server.ActiveConnections.Store(2)
+    if server.RequestCount.Load() == 0 || server.BytesReceived.Load() == 0 || server.FailureCount.Load() == 0 {
+        t.Fatal("counters not raised before Reset")
+    }
+
+    server.Reset()
+
+    counters := map[string]uint64{
+        "RequestCount":  uint64(server.RequestCount.Load()),
+        "ErrorCount":    uint64(server.ErrorCount.Load()),
+        "BytesSent":     server.BytesSent.Load(),
+        "BytesReceived": server.BytesReceived.Load(),
+        "FailureCount":  uint64(server.FailureCount.Load()),
+        "LastCheckTime": uint64(server.LastCheckTime.Load()),
+    }
+    for name, got := range counters {
+        if got != 0 {
+            t.Errorf("%s = %d after Reset, want 0", name, got)
+        }
+    }
+    if got := server.ErrorRate(); got != 0 {
+        t.Errorf("ErrorRate() = %v after Reset, want 0", got)
+    }
+    if got := server.LatencyMs(); got != 0 {
+        t.Errorf("LatencyMs() = %v after Reset, want 0", got)
+    }
+    if got := server.latency.snapshot(); slices.ContainsFunc(got, func(n uint64) bool { return n != 0 }) {
+        t.Errorf("latency histogram = %v after Reset, want it empty", got)
+    }
+    if got := server.ActiveConnections.Load(); got != 2 {
+        t.Errorf("ActiveConnections = %d after Reset, want it left at 2", got)
+    }
+    if got := server.PeakConnections.Load(); got != 2 {
+        t.Errorf("PeakConnections = %d after Reset, want the current 2 active", got)
+    }
+}
+
+func TestServerDisableEnable(t *testing.T) {
+    disabledBackend := lbtesting.NewFakeBackend(t)
+    otherBackend := lbtesting.NewFakeBackend(t)
+    server := newTestServer(t, disabledBackend.URL, 1)
+    wlc := NewWeightedLeastConnection([]*Server{server, newTestServer(t, otherBackend.URL, 1)})
+
+    server.Disable()
+    if got := server.Status(); got != "disabled" {
+        t.Errorf("Status() = %q, want %q", got, "disabled")
+    }
+    for i := 0; i < 10; i++ {
+        serveRequest(wlc, httptest.NewRequest(http.MethodGet, "/", nil))
+    }
+    if got := disabledBackend.CallCount(); got != 0 {
+        t.Errorf("disabled server received %d requests", got)
+    }
+    if !server.IsHealthy.Load() {
+        t.Errorf("disabling the server changed its health")
+    }
+
+    server.Enable()
+    if got := server.Status(); got != "healthy" {
+        t.Errorf("Status() = %q after Enable, want %q", got, "healthy")
+    }
+    for i := 0; i < 10; i++ {
+        serveRequest(wlc, httptest.NewRequest(http.MethodGet, "/", nil))
+    }
+    if got := disabledBackend.CallCount(); got == 0 {
+        t.Errorf("re-enabled server received no requests")
+    }
+}
+
+func TestPeakConnections(t *testing.T) {
+    release := make(chan struct{})
+    var arrived sync.WaitGroup
+    arrived.Add(50)
+    backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        arrived.Done()
+        <-release
+    }))
+    t.Cleanup(backend.Close)
+
+    server := newTestServer(t, backend.URL, 1)
+    wlc := NewWeightedLeastConnection([]*Server{server})
+
+    var done sync.WaitGroup
+    for i := 0; i < 50; i++ {
+        done.Add(1)
+        go func() {
+            defer done.Done()
+            serveRequest(wlc, httptest.NewRequest(http.MethodGet, "/", nil))
+        }()
+    }
+    arrived.Wait()
+    if got := server.PeakConnections.Load(); got != 50 {
+        t.Errorf("PeakConnections = %d with 50 requests in flight, want 50", got)
+    }
+
+    close(release)
+    done.Wait()
+    if got := server.ActiveConnections.Load(); got != 0 {
+        t.Errorf("ActiveConnections = %d after the requests completed, want 0", got)
+    }
+    if got := server.PeakConnections.Load(); got != 50 {
+        t.Errorf("PeakConnections = %d after the requests completed, want it kept at 50", got)
+    }
+
+    server.ResetPeak()
+    if got := server.PeakConnections.Load(); got != 0 {
+        t.Errorf("PeakConnections = %d after ResetPeak, want 0", got)
+    }
+}
+
+func TestBackendIdleConnTimeout(t *testing.T) {
+    var connCount atomic.Int64
+    backend := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+    backend.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+        if state == http.StateNew {
+            connCount.Add(1)
+        }
+    }
+    // The backend drops idle connections soon after the balancer would
+    // evict them, so a connection kept past the timeout would be stale.
+    backend.Config.IdleTimeout = 150 * time.Millisecond
+    backend.Start()
+    t.Cleanup(backend.Close)
+
+    server := newTestServer(t, backend.URL, 1)
+    server.Transport.IdleConnTimeout = 100 * time.Millisecond
+    wlc := NewWeightedLeastConnection([]*Server{server})
+
+    if rec := serveRequest(wlc, httptest.NewRequest(http.MethodGet, "/", nil)); rec.Code != http.StatusOK {
+        t.Fatalf("first request: status %d, want 200", rec.Code)
+    }
+    time.Sleep(200 * time.Millisecond)
+    if rec := serveRequest(wlc, httptest.NewRequest(http.MethodGet, "/", nil)); rec.Code != http.StatusOK {
+        t.Fatalf("request after the idle timeout: status %d, want 200", rec.Code)
+    }
+    if got := connCount.Load(); got != 2 {
+        t.Errorf("backend saw %d connections, want 2 as the idle one was evicted", got)
+    }
+}
+
+func TestCustomDirector(t *testing.T) {
+    var gotPath string
+    backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        gotPath = r.URL.Path
+        if !strings.HasPrefix(r.URL.Path, "/api/v2/") {
+            w.WriteHeader(http.StatusNotFound)
+        }
+    }))
+    t.Cleanup(backend.Close)
+
+    server := newTestServer(t, backend.URL, 1)
+    server.CustomDirector = func(req *http.Request) {
+        req.URL.Path = "/api/v2" + req.URL.Path
+    }
+    wlc := NewWeightedLeastConnection([]*Server{server})
+
+    rec := serveRequest(wlc, httptest.NewRequest(http.MethodGet, "/users", nil))
+    if rec.Code != http.StatusOK {
+        t.Errorf("status = %d, want 200", rec.Code)
+    }
+    if gotPath != "/api/v2/users" {
+        t.Errorf("backend saw path %q, want /api/v2/users", gotPath)
+    }
+}
+
+func TestDebugBackendHeader(t *testing.T) {
+    var hosts []string
+    var servers []*Server
+    for i := 0; i < 3; i++ {
+        backend := lbtesting.NewFakeBackend(t)
+        server := newTestServer(t, backend.URL, 1)
+        server.DebugHeader = "X-Debug-Backend"
+        hosts = append(hosts, server.URL.Host)
+        servers = append(servers, server)
+    }
+    wlc := NewWeightedLeastConnection(servers)
+
+    for i := 0; i < 6; i++ {
+        rec := serveRequest(wlc, algorithmRequest(i))
+        if got := rec.Header().Get("X-Debug-Backend"); !slices.Contains(hosts, got) {
+            t.Errorf("X-Debug-Backend = %q, want one of %v", got, hosts)
+        }
+    }
+
+    for _, server := range servers {
+        server.DebugHeader = ""
+    }
+    rec := serveRequest(wlc, httptest.NewRequest(http.MethodGet, "/", nil))
+    if got := rec.Header().Get("X-Debug-Backend"); got != "" {
+        t.Errorf("X-Debug-Backend = %q with the header disabled, want it unset", got)
+    }
+}
+
+func TestBackendLoadHeader(t *testing.T) {
+    backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        w.Header().Set("X-Backend-Load", "0.9")
+    }))
+    t.Cleanup(backend.Close)
+    server := newTestServer(t, backend.URL, 2)
+    server.BackendLoadHeader = "X-Backend-Load"
+    wlc := NewWeightedLeastConnection([]*Server{server})
+
+    rec := serveRequest(wlc, httptest.NewRequest(http.MethodGet, "/", nil))
+    if got := rec.Header().Get("X-Backend-Load"); got != "" {
+        t.Errorf("client received X-Backend-Load %q, want it removed", got)
+    }
+    if got := server.ReportedLoad.Load(); got != 9000 {
+        t.Errorf("ReportedLoad = %d, want 9000", got)
+    }
+
+    // 3 connections over weight 2, raised by the reported 0.9 load.
+    server.ActiveConnections.Store(3)
+    if got, want := server.Ratio(), 1.5*1.9; math.Abs(got-want) > 1e-9 {
+        t.Errorf("Ratio() = %v, want %v", got, want)
+    }
+}
+
+func TestWeightBounds(t *testing.T) {
+    const rawURL = "http://localhost:8081"
+    wlc := NewWeightedLeastConnection([]*Server{newTestServer(t, rawURL, 1)})
+
+    for weight, valid := range map[int]bool{-1: false, 0: false, 1: true, MaxWeight: true, MaxWeight + 1: false} {
+        if _, err := NewServer(rawURL, weight); (err == nil) != valid {
+            t.Errorf("NewServer with weight %d = %v, want valid %v", weight, err, valid)
+        }
+        if err := wlc.UpdateWeight("localhost:8081", weight); (err == nil) != valid {
+            t.Errorf("UpdateWeight to %d = %v, want valid %v", weight, err, valid)
+        }
+    }
+    if MaxWeight != 1000 {
+        t.Errorf("MaxWeight = %d, want 1000", MaxWeight)
+    }
+}
@@ -0,0 +1,36 @@
+package balancer
+
+import (
+	"math/rand/v2"
+	"net/http"
+)
+
+// TrafficShapingRule sends Percentage percent of requests carrying
+// HeaderName: HeaderValue to Pool instead of the default backends.
+type TrafficShapingRule struct {
+    HeaderName  string
+    HeaderValue string
+    Pool        LoadBalancer
+    Percentage  int
+}
+
+func (rule TrafficShapingRule) matches(r *http.Request) bool {
+    return r.Header.Get(rule.HeaderName) == rule.HeaderValue
+}
+
+// shapedPool rolls the dice of the first rule matching r and returns its
+// pool if the roll hits, or nil if the request should use the default
+// backends. Later rules are not consulted once one has matched, so each
+// rule's Percentage is the share of its matching requests it receives.
+func (wlc *WeightedLeastConnection) shapedPool(r *http.Request) LoadBalancer {
+    for _, rule := range wlc.trafficShaping {
+        if !rule.matches(r) {
+            continue
+        }
+        if rand.IntN(100) < rule.Percentage {
+            return rule.Pool
+        }
+        return nil
+    }
+    return nil
+}
@@ -0,0 +1,72 @@
+package balancer
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	lbtesting "github.com/Adi-ty/go-loadbalancer/pkg/testing"
+)
+
+func TestTrafficShapingPercentage(t *testing.T) {
+    stable := lbtesting.NewFakeBackend(t)
+    canary := lbtesting.NewFakeBackend(t)
+    canaryPool := NewWeightedLeastConnection([]*Server{newTestServer(t, canary.URL, 1)})
+    wlc := NewWeightedLeastConnection([]*Server{newTestServer(t, stable.URL, 1)}, WithTrafficShaping([]TrafficShapingRule{
+        {HeaderName: "X-Experiment", HeaderValue: "beta", Pool: canaryPool, Percentage: 50},
+    }))
+
+    for i := 0; i < 1000; i++ {
+        req := httptest.NewRequest(http.MethodGet, "/", nil)
+        req.Header.Set("X-Experiment", "beta")
+        if rec := serveRequest(wlc, req); rec.Code != http.StatusOK {
+            t.Fatalf("request %d: status = %d", i, rec.Code)
+        }
+    }
+
+    if got := canary.CallCount(); got < 450 || got > 550 {
+        t.Errorf("canary got %d of 1000 requests, want 450-550", got)
+    }
+    if got := canary.CallCount() + stable.CallCount(); got != 1000 {
+        t.Errorf("backends got %d of 1000 requests", got)
+    }
+}
+
+func TestTrafficShapingNonMatchingRequests(t *testing.T) {
+    stable := lbtesting.NewFakeBackend(t)
+    canary := lbtesting.NewFakeBackend(t)
+    wlc := NewWeightedLeastConnection([]*Server{newTestServer(t, stable.URL, 1)}, WithTrafficShaping([]TrafficShapingRule{
+        {HeaderName: "X-Experiment", HeaderValue: "beta", Pool: NewWeightedLeastConnection([]*Server{newTestServer(t, canary.URL, 1)}), Percentage: 100},
+    }))
+
+    req := httptest.NewRequest(http.MethodGet, "/", nil)
+    req.Header.Set("X-Experiment", "alpha")
+    serveRequest(wlc, req)
+
+    if canary.CallCount() != 0 || stable.CallCount() != 1 {
+        t.Errorf("non-matching request reached canary %d times, stable %d times", canary.CallCount(), stable.CallCount())
+    }
+}
+
+func TestTrafficShapingFirstMatchWins(t *testing.T) {
+    stable := lbtesting.NewFakeBackend(t)
+    first := lbtesting.NewFakeBackend(t)
+    second := lbtesting.NewFakeBackend(t)
+    wlc := NewWeightedLeastConnection([]*Server{newTestServer(t, stable.URL, 1)}, WithTrafficShaping([]TrafficShapingRule{
+        {HeaderName: "X-Experiment", HeaderValue: "beta", Pool: NewWeightedLeastConnection([]*Server{newTestServer(t, first.URL, 1)}), Percentage: 0},
+        {HeaderName: "X-Experiment", HeaderValue: "beta", Pool: NewWeightedLeastConnection([]*Server{newTestServer(t, second.URL, 1)}), Percentage: 100},
+    }))
+
+    for i := 0; i < 50; i++ {
+        req := httptest.NewRequest(http.MethodGet, "/", nil)
+        req.Header.Set("X-Experiment", "beta")
+        serveRequest(wlc, req)
+    }
+
+    if first.CallCount() != 0 || second.CallCount() != 0 {
+        t.Errorf("rules after the first match got requests: first %d, second %d", first.CallCount(), second.CallCount())
+    }
+    if got := stable.CallCount(); got != 50 {
+        t.Errorf("default pool got %d of 50 requests", got)
+    }
+}
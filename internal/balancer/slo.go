@@ -0,0 +1,31 @@
+package balancer
+
+import (
+	"log"
+)
+
+// ErrorBudgetRemaining returns how many more failed requests the server can
+// have before missing SLOTarget: (1 - SLOTarget) * RequestCount -
+// ErrorCount. A negative value means the budget is spent. It is meaningful
+// only when SLOTarget is set.
+func (s *Server) ErrorBudgetRemaining() float64 {
+    // Written as n - target*n rather than (1-target)*n, which rounds away
+    // from whole numbers for targets like 0.999.
+    requests := float64(s.RequestCount.Load())
+    return requests - s.SLOTarget*requests - float64(s.ErrorCount.Load())
+}
+
+// checkErrorBudget logs a warning when the server's error budget becomes
+// exhausted, once each time it does.
+func (s *Server) checkErrorBudget() {
+    if s.SLOTarget <= 0 {
+        return
+    }
+
+    remaining := s.ErrorBudgetRemaining()
+    exhausted := remaining < 0
+    if s.errorBudgetExhausted.Swap(exhausted) != exhausted && exhausted {
+        log.Printf("[WARN] Backend %s has exhausted its error budget for a %.4g%% SLO: %d of %d requests failed",
+            s.URL.Host, s.SLOTarget*100, s.ErrorCount.Load(), s.RequestCount.Load())
+    }
+}
@@ -0,0 +1,60 @@
+package balancer
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	lbtesting "github.com/Adi-ty/go-loadbalancer/pkg/testing"
+)
+
+func TestErrorBudgetRemaining(t *testing.T) {
+    backend := lbtesting.NewFakeBackend(t)
+    server := newTestServer(t, backend.URL, 1)
+    server.SLOTarget = 0.999
+    reg := prometheus.NewRegistry()
+    wlc := NewWeightedLeastConnection([]*Server{server}, WithPrometheusRegisterer(reg))
+    if err := wlc.RegisterMetrics(); err != nil {
+        t.Fatal(err)
+    }
+
+    // 1000 requests allow one failure at 99.9%; two spend the budget.
+    backend.SetStatusCode(http.StatusInternalServerError)
+    for i := 0; i < 2; i++ {
+        serveRequest(wlc, httptest.NewRequest(http.MethodGet, "/", nil))
+    }
+    backend.SetStatusCode(http.StatusOK)
+    for i := 0; i < 998; i++ {
+        serveRequest(wlc, httptest.NewRequest(http.MethodGet, "/", nil))
+    }
+
+    if got := server.ErrorCount.Load(); got != 2 {
+        t.Fatalf("ErrorCount = %d, want 2", got)
+    }
+    if got := server.ErrorBudgetRemaining(); math.Abs(got+1) > 1e-9 {
+        t.Errorf("ErrorBudgetRemaining() = %v, want -1", got)
+    }
+
+    families, err := reg.Gather()
+    if err != nil {
+        t.Fatal(err)
+    }
+    found := false
+    for _, family := range families {
+        if family.GetName() != "lb_backend_error_budget_remaining" {
+            continue
+        }
+        for _, metric := range family.GetMetric() {
+            found = true
+            if got := metric.GetGauge().GetValue(); math.Abs(got+1) > 1e-9 {
+                t.Errorf("lb_backend_error_budget_remaining = %v, want -1", got)
+            }
+        }
+    }
+    if !found {
+        t.Error("lb_backend_error_budget_remaining not exported with an SLO target set")
+    }
+}
@@ -0,0 +1,111 @@
+package balancer
+
+import (
+	"container/list"
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"sync"
+)
+
+// maxSNITransports caps how many client hostnames keep their own connection
+// pool when UseSNIFromHost is set. Past it, the least recently used pool is
+// closed to make room, so clients sending many hostnames cannot pin an
+// unbounded number of connections.
+const maxSNITransports = 100
+
+type sniHostKey struct{}
+
+// withSNIHost records the client's Host header on r so the transport can
+// still see it after the director has rewritten Host.
+func withSNIHost(r *http.Request) *http.Request {
+    return r.WithContext(context.WithValue(r.Context(), sniHostKey{}, r.Host))
+}
+
+// sniRoundTripper sends requests through the server's Transport or, for
+// requests whose client Host should be the TLS server name, through a copy
+// of it with that ServerName. Each server name needs its own transport
+// because connections are pooled by address alone, and one negotiated for
+// one name must not be reused for another.
+type sniRoundTripper struct {
+    server *Server
+
+    mu         sync.Mutex
+    transports map[string]*list.Element // Values are *sniTransport
+    lru        list.List                // Most recently used first
+}
+
+type sniTransport struct {
+    host      string
+    transport *http.Transport
+}
+
+func (rt *sniRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+    return rt.transportFor(req).RoundTrip(req)
+}
+
+func (rt *sniRoundTripper) transportFor(req *http.Request) *http.Transport {
+    s := rt.server
+    if !s.UseSNIFromHost || req.URL.Scheme != "https" {
+        return s.Transport
+    }
+
+    host, _ := req.Context().Value(sniHostKey{}).(string)
+    if h, _, err := net.SplitHostPort(host); err == nil {
+        host = h
+    }
+    // SNI carries DNS names only.
+    if host == "" || net.ParseIP(host) != nil {
+        return s.Transport
+    }
+
+    rt.mu.Lock()
+    defer rt.mu.Unlock()
+
+    if e, ok := rt.transports[host]; ok {
+        rt.lru.MoveToFront(e)
+        return e.Value.(*sniTransport).transport
+    }
+    if rt.lru.Len() >= maxSNITransports {
+        // Requests in flight on the evicted transport complete; only its
+        // idle connections are closed.
+        oldest := rt.lru.Remove(rt.lru.Back()).(*sniTransport)
+        delete(rt.transports, oldest.host)
+        oldest.transport.CloseIdleConnections()
+    }
+
+    t := s.Transport.Clone()
+    if t.TLSClientConfig == nil {
+        t.TLSClientConfig = &tls.Config{}
+    }
+    t.TLSClientConfig.ServerName = host
+    if rt.transports == nil {
+        rt.transports = make(map[string]*list.Element)
+    }
+    rt.transports[host] = rt.lru.PushFront(&sniTransport{host: host, transport: t})
+    return t
+}
+
+// CloseIdleConnections closes the idle connections of the server's
+// Transport and of every per-hostname copy of it.
+func (rt *sniRoundTripper) CloseIdleConnections() {
+    rt.server.Transport.CloseIdleConnections()
+
+    rt.mu.Lock()
+    defer rt.mu.Unlock()
+    for e := rt.lru.Front(); e != nil; e = e.Next() {
+        e.Value.(*sniTransport).transport.CloseIdleConnections()
+    }
+}
+
+// closeIdleConnections closes the server's idle backend connections, on
+// every transport it has used.
+func (s *Server) closeIdleConnections() {
+    if s.ReverseProxy == nil {
+        return
+    }
+    if c, ok := s.ReverseProxy.Transport.(interface{ CloseIdleConnections() }); ok {
+        c.CloseIdleConnections()
+    }
+}
@@ -0,0 +1,108 @@
+package balancer
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newSNIBackend starts a TLS backend that answers with the server name its
+// client sent, and counts the connections it has seen closed.
+func newSNIBackend(t *testing.T) (*httptest.Server, *atomic.Int32) {
+    var closed atomic.Int32
+    backend := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        w.Write([]byte(r.TLS.ServerName))
+    }))
+    backend.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+        if state == http.StateClosed {
+            closed.Add(1)
+        }
+    }
+    backend.StartTLS()
+    t.Cleanup(backend.Close)
+    return backend, &closed
+}
+
+func newSNIServer(t *testing.T, backend *httptest.Server) *Server {
+    server := newTestServer(t, backend.URL, 1)
+    server.UseSNIFromHost = true
+    server.Transport.TLSClientConfig = backend.Client().Transport.(*http.Transport).TLSClientConfig.Clone()
+    return server
+}
+
+func TestUseSNIFromHost(t *testing.T) {
+    for _, enabled := range []bool{true, false} {
+        t.Run(fmt.Sprint(enabled), func(t *testing.T) {
+            backend, _ := newSNIBackend(t)
+            server := newSNIServer(t, backend)
+            server.UseSNIFromHost = enabled
+            wlc := NewWeightedLeastConnection([]*Server{server})
+
+            req := httptest.NewRequest(http.MethodGet, "/", nil)
+            req.Host = "tenant.example.com:8443"
+            rec := serveRequest(wlc, req)
+
+            // The backend's own host is an IP address, which is never sent
+            // as a server name.
+            want := ""
+            if enabled {
+                want = "tenant.example.com"
+            }
+            if got := rec.Body.String(); got != want {
+                t.Errorf("backend saw server name %q, want %q", got, want)
+            }
+        })
+    }
+}
+
+func sniRequest(host string) *http.Request {
+    req := httptest.NewRequest(http.MethodGet, "https://backend/", nil)
+    return req.WithContext(context.WithValue(req.Context(), sniHostKey{}, host))
+}
+
+func TestSNITransportsEvictLeastRecentlyUsed(t *testing.T) {
+    backend, _ := newSNIBackend(t)
+    rt := &sniRoundTripper{server: newSNIServer(t, backend)}
+
+    first := rt.transportFor(sniRequest("host0.example.com"))
+    for i := 1; i < maxSNITransports; i++ {
+        rt.transportFor(sniRequest(fmt.Sprintf("host%d.example.com", i)))
+    }
+    // Using host0 again makes host1 the least recently used.
+    rt.transportFor(sniRequest("host0.example.com"))
+    rt.transportFor(sniRequest("new.example.com"))
+
+    if got := rt.lru.Len(); got != maxSNITransports {
+        t.Errorf("kept %d transports, want %d", got, maxSNITransports)
+    }
+    if _, ok := rt.transports["host1.example.com"]; ok {
+        t.Errorf("least recently used hostname kept")
+    }
+    if rt.transportFor(sniRequest("host0.example.com")) != first {
+        t.Errorf("recently used hostname was evicted")
+    }
+}
+
+func TestRemoveClosesIdleSNIConnections(t *testing.T) {
+    backend, closed := newSNIBackend(t)
+    server := newSNIServer(t, backend)
+    wlc := NewWeightedLeastConnection([]*Server{server})
+
+    req := httptest.NewRequest(http.MethodGet, "/", nil)
+    req.Host = "tenant.example.com"
+    serveRequest(wlc, req)
+
+    wlc.Remove(server.URL.String())
+    deadline := time.Now().Add(time.Second)
+    for closed.Load() == 0 && time.Now().Before(deadline) {
+        time.Sleep(10 * time.Millisecond)
+    }
+    if closed.Load() == 0 {
+        t.Errorf("idle connection to the removed backend was left open")
+    }
+}
@@ -0,0 +1,72 @@
+package balancer
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// serverState is the transferable portion of a Server. Active connections
+// and request counters belong to the process that served them and are not
+// exported.
+type serverState struct {
+    URL      string `json:"url"`
+    Weight   int    `json:"weight"`
+    Healthy  bool   `json:"healthy"`
+    Disabled bool   `json:"disabled"`
+
+    Tags map[string]string `json:"tags,omitempty"`
+}
+
+// ExportState serialises the pool's server metadata to JSON so a standby
+// balancer can pick it up with ImportState.
+func (wlc *WeightedLeastConnection) ExportState() ([]byte, error) {
+    wlc.mu.RLock()
+    defer wlc.mu.RUnlock()
+
+    states := make([]serverState, 0, len(wlc.servers))
+    for _, server := range wlc.servers {
+        states = append(states, serverState{
+            URL:      server.URL.String(),
+            Weight:   server.Weight,
+            Healthy:  server.IsHealthy.Load(),
+            Disabled: server.ManuallyDisabled.Load(),
+            Tags:     server.TagsSnapshot(),
+        })
+    }
+
+    return json.Marshal(states)
+}
+
+// ImportState merges state produced by ExportState into the pool. Servers are
+// matched by URL; entries for unknown URLs are ignored.
+func (wlc *WeightedLeastConnection) ImportState(data []byte) error {
+    var states []serverState
+    if err := json.Unmarshal(data, &states); err != nil {
+        return fmt.Errorf("invalid state: %w", err)
+    }
+
+    wlc.mu.Lock()
+    defer wlc.mu.Unlock()
+
+    byURL := make(map[string]*Server, len(wlc.servers))
+    for _, server := range wlc.servers {
+        byURL[server.URL.String()] = server
+    }
+
+    for _, state := range states {
+        server, ok := byURL[state.URL]
+        if !ok {
+            continue
+        }
+        if validateWeight(state.URL, state.Weight) == nil {
+            server.Weight = state.Weight
+        }
+        server.setHealthy(state.Healthy)
+        server.ManuallyDisabled.Store(state.Disabled)
+        if len(state.Tags) > 0 {
+            server.SetTags(state.Tags)
+        }
+    }
+
+    return nil
+}
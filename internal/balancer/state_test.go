@@ -0,0 +1,64 @@
+package balancer
+
+import (
+	"maps"
+	"testing"
+)
+
+func TestExportImportState(t *testing.T) {
+    urls := []string{"http://10.0.0.1:8080", "http://10.0.0.2:8080", "http://10.0.0.3:8080"}
+    newPool := func() *WeightedLeastConnection {
+        var servers []*Server
+        for _, url := range urls {
+            servers = append(servers, newTestServer(t, url, 1))
+        }
+        return NewWeightedLeastConnection(servers)
+    }
+
+    primary := newPool()
+    primary.servers[0].Weight = 5
+    primary.servers[1].IsHealthy.Store(false)
+    primary.servers[2].Disable()
+    primary.servers[2].SetTags(map[string]string{"zone": "eu-west-1a"})
+
+    data, err := primary.ExportState()
+    if err != nil {
+        t.Fatalf("ExportState: %v", err)
+    }
+
+    standby := newPool()
+    if err := standby.ImportState(data); err != nil {
+        t.Fatalf("ImportState: %v", err)
+    }
+    for i, want := range primary.servers {
+        got := standby.servers[i]
+        if got.Weight != want.Weight || got.IsHealthy.Load() != want.IsHealthy.Load() ||
+            got.ManuallyDisabled.Load() != want.ManuallyDisabled.Load() || !maps.Equal(got.TagsSnapshot(), want.TagsSnapshot()) {
+            t.Errorf("%s imported as weight %d, healthy %v, disabled %v, tags %v; want %d, %v, %v, %v", urls[i],
+                got.Weight, got.IsHealthy.Load(), got.ManuallyDisabled.Load(), got.TagsSnapshot(),
+                want.Weight, want.IsHealthy.Load(), want.ManuallyDisabled.Load(), want.TagsSnapshot())
+        }
+    }
+}
+
+func TestImportStateIgnoresUnknownURLs(t *testing.T) {
+    wlc := NewWeightedLeastConnection([]*Server{newTestServer(t, "http://10.0.0.1:8080", 1)})
+
+    err := wlc.ImportState([]byte(`[{"url":"http://10.0.0.9:8080","weight":7,"healthy":false}]`))
+    if err != nil {
+        t.Fatalf("ImportState: %v", err)
+    }
+    if server := wlc.servers[0]; server.Weight != 1 || !server.IsHealthy.Load() {
+        t.Errorf("known server changed by state for an unknown URL")
+    }
+    if len(wlc.servers) != 1 {
+        t.Errorf("pool has %d servers, want unknown URLs not added", len(wlc.servers))
+    }
+}
+
+func TestImportStateInvalid(t *testing.T) {
+    wlc := NewWeightedLeastConnection(nil)
+    if err := wlc.ImportState([]byte("not json")); err == nil {
+        t.Errorf("ImportState accepted invalid JSON")
+    }
+}
@@ -0,0 +1,146 @@
+package balancer
+
+import (
+	"math/bits"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ewmaAlpha is the smoothing factor for the passive error rate and latency
+// averages: each new sample contributes 10%.
+const ewmaAlpha = 0.1
+
+// healthHistorySize is the number of recent health checks considered by
+// HealthFailureRate.
+const healthHistorySize = 10
+
+// ewma is an exponentially weighted moving average. The first sample seeds
+// it directly, so the average does not have to climb from zero.
+type ewma struct {
+    mu          sync.Mutex
+    value       float64
+    initialised bool
+}
+
+// update folds sample into the average.
+func (e *ewma) update(sample float64) {
+    e.mu.Lock()
+    defer e.mu.Unlock()
+
+    if !e.initialised {
+        e.value = sample
+        e.initialised = true
+        return
+    }
+    e.value = ewmaAlpha*sample + (1-ewmaAlpha)*e.value
+}
+
+// load returns the average, or 0 before the first sample.
+func (e *ewma) load() float64 {
+    e.mu.Lock()
+    defer e.mu.Unlock()
+    return e.value
+}
+
+// reset forgets every sample.
+func (e *ewma) reset() {
+    e.mu.Lock()
+    e.value = 0
+    e.initialised = false
+    e.mu.Unlock()
+}
+
+// recordOutcome feeds the result of a proxied request into the error rate
+// and error budget.
+func (s *Server) recordOutcome(failed bool) {
+    sample := 0.0
+    if failed {
+        sample = 1
+        s.ErrorCount.Add(1)
+    }
+    s.errorRate.update(sample)
+    s.checkErrorBudget()
+}
+
+// recordLatency feeds a proxied request's duration into the latency average
+// and histogram.
+func (s *Server) recordLatency(d time.Duration) {
+    s.latencyMs.update(float64(d.Microseconds()) / 1000)
+    s.latency.observe(d)
+}
+
+// recordHealthCheck shifts a health check result into the history window.
+func (s *Server) recordHealthCheck(healthy bool) {
+    for {
+        old := s.healthHistory.Load()
+        next := (old << 1) & (1<<healthHistorySize - 1)
+        if !healthy {
+            next |= 1
+        }
+        if s.healthHistory.CompareAndSwap(old, next) {
+            break
+        }
+    }
+
+    for {
+        n := s.healthChecks.Load()
+        if n >= healthHistorySize || s.healthChecks.CompareAndSwap(n, n+1) {
+            return
+        }
+    }
+}
+
+// ErrorRate returns the exponentially weighted share of proxied requests
+// that failed or returned a 5xx status.
+func (s *Server) ErrorRate() float64 {
+    return s.errorRate.load()
+}
+
+// LatencyMs returns the exponentially weighted proxied request latency in
+// milliseconds, or 0 before any request has completed.
+func (s *Server) LatencyMs() float64 {
+    return s.latencyMs.load()
+}
+
+// HealthFailureRate returns the share of failed checks among the most recent
+// health checks.
+func (s *Server) HealthFailureRate() float64 {
+    n := s.healthChecks.Load()
+    if n == 0 {
+        return 0
+    }
+    return float64(bits.OnesCount32(s.healthHistory.Load())) / float64(n)
+}
+
+// proxy forwards r to the server and records its latency and the bytes
+// transferred in each direction. Callers are
+// responsible for connection accounting.
+func (s *Server) proxy(w http.ResponseWriter, r *http.Request) {
+    if r.Body != nil && r.Body != http.NoBody {
+        r.Body = &countingReader{ReadCloser: r.Body, n: &s.BytesSent, buffers: s.requestBuffers}
+    }
+    w = &countingWriter{ResponseWriter: w, n: &s.BytesReceived}
+
+    if s.UseSNIFromHost {
+        r = withSNIHost(r)
+    }
+
+    start := time.Now()
+    s.ReverseProxy.ServeHTTP(w, r)
+    s.recordLatency(time.Since(start))
+}
+
+// serve is the common forwarding path for algorithms: it tracks the
+// connection, reports the backend to access loggers and proxies r.
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+    s.trackPeak(s.ActiveConnections.Add(1))
+    s.RequestCount.Add(1)
+    defer s.ActiveConnections.Add(-1)
+
+    if recorder, ok := w.(BackendRecorder); ok {
+        recorder.RecordBackend(s.URL.Host)
+    }
+
+    s.proxy(w, r)
+}
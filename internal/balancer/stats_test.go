@@ -0,0 +1,64 @@
+package balancer
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestBytesTransferred(t *testing.T) {
+    backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        body, _ := io.ReadAll(r.Body)
+        w.Write(body)
+    }))
+    t.Cleanup(backend.Close)
+
+    server := newTestServer(t, backend.URL, 1)
+    reg := prometheus.NewRegistry()
+    wlc := NewWeightedLeastConnection([]*Server{server}, WithPrometheusRegisterer(reg))
+    if err := wlc.RegisterMetrics(); err != nil {
+        t.Fatal(err)
+    }
+
+    body := bytes.Repeat([]byte("x"), 1000)
+    rec := serveRequest(wlc, httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body)))
+    if rec.Body.Len() != len(body) {
+        t.Fatalf("echoed %d bytes, want %d", rec.Body.Len(), len(body))
+    }
+
+    // Only bodies are counted, so HTTP framing adds nothing; allow a little
+    // slack in case that ever changes.
+    within := func(n uint64) bool { return n >= 1000 && n <= 1100 }
+    if got := server.BytesSent.Load(); !within(got) {
+        t.Errorf("BytesSent = %d, want about 1000", got)
+    }
+    if got := server.BytesReceived.Load(); !within(got) {
+        t.Errorf("BytesReceived = %d, want about 1000", got)
+    }
+
+    families, err := reg.Gather()
+    if err != nil {
+        t.Fatal(err)
+    }
+    want := map[string]uint64{
+        "lb_backend_bytes_sent_total":     server.BytesSent.Load(),
+        "lb_backend_bytes_received_total": server.BytesReceived.Load(),
+    }
+    for _, family := range families {
+        n, ok := want[family.GetName()]
+        if !ok {
+            continue
+        }
+        delete(want, family.GetName())
+        if got := family.GetMetric()[0].GetCounter().GetValue(); got != float64(n) {
+            t.Errorf("%s = %v, want %d", family.GetName(), got, n)
+        }
+    }
+    for name := range want {
+        t.Errorf("%s not exported", name)
+    }
+}
@@ -0,0 +1,66 @@
+package balancer
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Tag returns the value of the server tag key.
+func (s *Server) Tag(key string) (string, bool) {
+    s.tagsMu.RLock()
+    defer s.tagsMu.RUnlock()
+
+    value, ok := s.Tags[key]
+    return value, ok
+}
+
+// TagsSnapshot returns a copy of the server's tags.
+func (s *Server) TagsSnapshot() map[string]string {
+    s.tagsMu.RLock()
+    defer s.tagsMu.RUnlock()
+
+    tags := make(map[string]string, len(s.Tags))
+    for k, v := range s.Tags {
+        tags[k] = v
+    }
+    return tags
+}
+
+// SetTags merges tags into the server's tags. The map is replaced rather than
+// modified so readers holding the old one are unaffected.
+func (s *Server) SetTags(tags map[string]string) {
+    s.tagsMu.Lock()
+    defer s.tagsMu.Unlock()
+
+    merged := make(map[string]string, len(s.Tags)+len(tags))
+    for k, v := range s.Tags {
+        merged[k] = v
+    }
+    for k, v := range tags {
+        merged[k] = v
+    }
+    s.Tags = merged
+}
+
+// updateTagsFromHealthBody sets tags from the top-level fields of a JSON
+// health response such as {"status":"ok","region":"us-east"}. Nested objects
+// and arrays are ignored.
+func (s *Server) updateTagsFromHealthBody(body []byte) error {
+    var fields map[string]any
+    if err := json.Unmarshal(body, &fields); err != nil {
+        return fmt.Errorf("invalid JSON health response: %w", err)
+    }
+
+    tags := make(map[string]string, len(fields))
+    for k, v := range fields {
+        switch v := v.(type) {
+        case string:
+            tags[k] = v
+        case float64, bool:
+            tags[k] = fmt.Sprint(v)
+        }
+    }
+
+    s.SetTags(tags)
+    return nil
+}
@@ -0,0 +1,36 @@
+package balancer
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestHealthCheckParseResponse(t *testing.T) {
+    backend := newHealthBackend(t, func(w http.ResponseWriter, r *http.Request) {
+        w.Write([]byte(`{"status":"ok","load":"high","replicas":3,"meta":{"ignored":true}}`))
+    })
+
+    server := newTestServer(t, backend.URL, 1)
+    server.SetTags(map[string]string{"region": "us-east"})
+    if err := server.RunHealthCheck(); err != nil {
+        t.Fatal(err)
+    }
+    if _, ok := server.Tag("load"); ok {
+        t.Errorf("tags updated with HealthCheckParseResponse off")
+    }
+
+    server.HealthCheckParseResponse = true
+    if err := server.RunHealthCheck(); err != nil {
+        t.Fatal(err)
+    }
+    want := map[string]string{"region": "us-east", "status": "ok", "load": "high", "replicas": "3"}
+    got := server.TagsSnapshot()
+    for k, v := range want {
+        if got[k] != v {
+            t.Errorf("Tags[%q] = %q, want %q", k, got[k], v)
+        }
+    }
+    if _, ok := got["meta"]; ok {
+        t.Errorf("nested object became tag %q", got["meta"])
+    }
+}
@@ -0,0 +1,208 @@
+//go:build testing
+
+// The algorithm suite is slow and mostly re-covers the algorithms' own tests,
+// so it only runs with: go test -race -tags testing ./internal/balancer
+
+package balancer
+
+import (
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// algorithmTraits relax the parts of RunAlgorithmTests an algorithm does not
+// promise.
+type algorithmTraits struct {
+    ignoresWeights bool
+    unevenLoad     bool
+}
+
+type algorithmTrait func(*algorithmTraits)
+
+// ignoresWeights skips SkewedWeights for algorithms that do not weigh
+// servers, such as the hash-based ones.
+func ignoresWeights(at *algorithmTraits) {
+    at.ignoresWeights = true
+}
+
+// unevenLoad only requires EqualWeights to reach every server, for algorithms
+// that favour some servers over others by design.
+func unevenLoad(at *algorithmTraits) {
+    at.unevenLoad = true
+}
+
+// RunAlgorithmTests runs the suite of behaviour every balancing algorithm
+// must share against the balancers built by newBalancer: routing around
+// unhealthy servers, answering 503 when none is left, spreading load by
+// weight and staying race-free under concurrent use. Run it with -race.
+func RunAlgorithmTests(t *testing.T, newBalancer func([]*Server) LoadBalancer, traits ...algorithmTrait) {
+    var at algorithmTraits
+    for _, trait := range traits {
+        trait(&at)
+    }
+
+    t.Run("SingleHealthy", func(t *testing.T) {
+        ab := newAlgorithmBackends(t, []int{1, 1, 1}, false)
+        ab.servers[0].IsHealthy.Store(false)
+        ab.servers[2].IsHealthy.Store(false)
+        lb := newBalancer(ab.servers)
+
+        for i := 0; i < 20; i++ {
+            if rec := serveRequest(lb, algorithmRequest(i)); rec.Code != http.StatusOK {
+                t.Fatalf("request %d: status = %d, want %d", i, rec.Code, http.StatusOK)
+            }
+        }
+        if got := ab.hits[1].Load(); got != 20 {
+            t.Errorf("healthy server got %d of 20 requests", got)
+        }
+    })
+
+    t.Run("AllUnhealthy", func(t *testing.T) {
+        ab := newAlgorithmBackends(t, []int{1, 1}, false)
+        for _, server := range ab.servers {
+            server.IsHealthy.Store(false)
+        }
+        lb := newBalancer(ab.servers)
+
+        if rec := serveRequest(lb, algorithmRequest(0)); rec.Code != http.StatusServiceUnavailable {
+            t.Errorf("status = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+        }
+        if got := ab.arrivals.Load(); got != 0 {
+            t.Errorf("unhealthy backends received %d requests", got)
+        }
+    })
+
+    t.Run("EqualWeights", func(t *testing.T) {
+        ab := newAlgorithmBackends(t, []int{1, 1, 1, 1}, true)
+        lb := newBalancer(ab.servers)
+
+        const n = 100
+        for i, got := range ab.sendHeld(t, lb, n) {
+            if at.unevenLoad {
+                if got == 0 {
+                    t.Errorf("server %d got none of %d requests", i, n)
+                }
+                continue
+            }
+            if want := int64(n / 4); got < want/2 || got > want*2 {
+                t.Errorf("server %d got %d of %d requests, want about %d", i, got, n, want)
+            }
+        }
+    })
+
+    t.Run("SkewedWeights", func(t *testing.T) {
+        if at.ignoresWeights {
+            t.Skip("algorithm does not weigh servers")
+        }
+        ab := newAlgorithmBackends(t, []int{1, 3}, true)
+        lb := newBalancer(ab.servers)
+
+        counts := ab.sendHeld(t, lb, 100)
+        if light, heavy := counts[0], counts[1]; light == 0 || heavy < 2*light {
+            t.Errorf("weights 1:3 got %d:%d requests, want the weight 3 server to get at least twice as many", light, heavy)
+        }
+    })
+
+    t.Run("ConcurrentAccess", func(t *testing.T) {
+        ab := newAlgorithmBackends(t, []int{1, 2, 3}, false)
+        lb := newBalancer(ab.servers)
+
+        stop := make(chan struct{})
+        var flips sync.WaitGroup
+        flips.Add(1)
+        go func() {
+            defer flips.Done()
+            for healthy := false; ; healthy = !healthy {
+                select {
+                case <-stop:
+                    ab.servers[2].IsHealthy.Store(true)
+                    return
+                default:
+                }
+                ab.servers[2].IsHealthy.Store(healthy)
+                time.Sleep(100 * time.Microsecond)
+            }
+        }()
+
+        var wg sync.WaitGroup
+        var failed atomic.Int64
+        for g := 0; g < 20; g++ {
+            wg.Add(1)
+            go func() {
+                defer wg.Done()
+                for i := 0; i < 25; i++ {
+                    if rec := serveRequest(lb, algorithmRequest(g*25+i)); rec.Code != http.StatusOK {
+                        failed.Add(1)
+                    }
+                }
+            }()
+        }
+        wg.Wait()
+        close(stop)
+        flips.Wait()
+
+        if n := failed.Load(); n != 0 {
+            t.Errorf("%d of 500 concurrent requests failed", n)
+        }
+        if got := ab.arrivals.Load(); got != 500 {
+            t.Errorf("backends received %d of 500 requests", got)
+        }
+    })
+}
+
+func TestRunAlgorithmTestsWeightedLeastConnection(t *testing.T) {
+    RunAlgorithmTests(t, func(servers []*Server) LoadBalancer {
+        return NewWeightedLeastConnection(servers)
+    })
+}
+
+func TestRunAlgorithmTestsAttributeHash(t *testing.T) {
+    RunAlgorithmTests(t, func(servers []*Server) LoadBalancer {
+        ah, err := NewAttributeHash(servers)
+        if err != nil {
+            t.Fatal(err)
+        }
+        return ah
+    }, ignoresWeights)
+}
+
+func TestRunAlgorithmTestsHealthScoreBalancer(t *testing.T) {
+    RunAlgorithmTests(t, func(servers []*Server) LoadBalancer {
+        // Servers below a Ratio of 1 are preferred, so each gets a request
+        // before the best scored one takes the rest.
+        return NewHealthScoreBalancer(servers, DefaultHealthScoreWeights, 1)
+    }, ignoresWeights, unevenLoad)
+}
+
+func TestRunAlgorithmTestsRendezvousHash(t *testing.T) {
+    RunAlgorithmTests(t, func(servers []*Server) LoadBalancer {
+        return NewRendezvousHash(servers)
+    }, ignoresWeights)
+}
+
+func TestRunAlgorithmTestsMultiPool(t *testing.T) {
+    RunAlgorithmTests(t, func(servers []*Server) LoadBalancer {
+        return NewMultiPool([]WeightedPool{{Pool: NewWeightedLeastConnection(servers), Weight: 1}})
+    })
+}
+
+func TestRunAlgorithmTestsGroupedBalancer(t *testing.T) {
+    RunAlgorithmTests(t, func(servers []*Server) LoadBalancer {
+        return NewGroupedBalancer([]BackendGroup{{Name: "default", Servers: servers}})
+    })
+}
+
+func TestRunAlgorithmTestsBurstScoreBalancer(t *testing.T) {
+    RunAlgorithmTests(t, func(servers []*Server) LoadBalancer {
+        return NewBurstScoreBalancer(servers)
+    }, ignoresWeights)
+}
+
+func TestRunAlgorithmTestsPoolCircuitBreaker(t *testing.T) {
+    RunAlgorithmTests(t, func(servers []*Server) LoadBalancer {
+        return NewPoolCircuitBreaker(NewWeightedLeastConnection(servers), PoolCircuitBreakerConfig{})
+    })
+}
@@ -0,0 +1,50 @@
+package balancer
+
+import (
+	"net/http"
+)
+
+// trailerStrippingWriter drops any trailers the reverse proxy relays from
+// the backend. httputil.ReverseProxy forwards both trailers announced in the
+// backend's Trailer header and late ones declared with http.TrailerPrefix;
+// this writer hides the announcement before the header is sent and hands out
+// a scratch header map afterwards so late trailers never reach the client.
+type trailerStrippingWriter struct {
+    http.ResponseWriter
+    wroteHeader bool
+    scratch     http.Header
+}
+
+func (tw *trailerStrippingWriter) Header() http.Header {
+    if tw.wroteHeader {
+        if tw.scratch == nil {
+            tw.scratch = make(http.Header)
+        }
+        return tw.scratch
+    }
+    return tw.ResponseWriter.Header()
+}
+
+// WriteHeader latches only on the final status. Informational 1xx
+// responses, such as 103 Early Hints relayed by the proxy, are followed by
+// the real header, which must still reach the client.
+func (tw *trailerStrippingWriter) WriteHeader(statusCode int) {
+    if !tw.wroteHeader && statusCode >= http.StatusOK {
+        tw.ResponseWriter.Header().Del("Trailer")
+        tw.wroteHeader = true
+    }
+    tw.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (tw *trailerStrippingWriter) Write(b []byte) (int, error) {
+    if !tw.wroteHeader {
+        tw.WriteHeader(http.StatusOK)
+    }
+    return tw.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer for
+// flushing.
+func (tw *trailerStrippingWriter) Unwrap() http.ResponseWriter {
+    return tw.ResponseWriter
+}
@@ -0,0 +1,93 @@
+package balancer
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const trailerTestBody = "hello, trailers"
+
+// newTrailerBackend answers with trailerTestBody and a Checksum trailer
+// holding its MD5, announced in the Trailer header.
+func newTrailerBackend(t *testing.T) *httptest.Server {
+    t.Helper()
+
+    backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        w.Header().Set("Trailer", "Checksum")
+        io.WriteString(w, trailerTestBody)
+        sum := md5.Sum([]byte(trailerTestBody))
+        w.Header().Set("Checksum", hex.EncodeToString(sum[:]))
+    }))
+    t.Cleanup(backend.Close)
+    return backend
+}
+
+// getWithTrailers fetches url and returns the response, its body and its
+// trailers, which are only complete once the body has been read.
+func getWithTrailers(t *testing.T, url string) (*http.Response, string) {
+    t.Helper()
+
+    resp, err := http.Get(url)
+    if err != nil {
+        t.Fatalf("GET %s: %v", url, err)
+    }
+    defer resp.Body.Close()
+    body, err := io.ReadAll(resp.Body)
+    if err != nil {
+        t.Fatalf("reading body: %v", err)
+    }
+    return resp, string(body)
+}
+
+func TestTrailersForwarded(t *testing.T) {
+    backend := newTrailerBackend(t)
+    lb := httptest.NewServer(NewWeightedLeastConnection([]*Server{newTestServer(t, backend.URL, 1)}))
+    defer lb.Close()
+
+    resp, body := getWithTrailers(t, lb.URL)
+    if body != trailerTestBody {
+        t.Fatalf("body = %q, want %q", body, trailerTestBody)
+    }
+    sum := md5.Sum([]byte(trailerTestBody))
+    if got, want := resp.Trailer.Get("Checksum"), hex.EncodeToString(sum[:]); got != want {
+        t.Errorf("Checksum trailer = %q, want %q", got, want)
+    }
+}
+
+func TestTrailersStripped(t *testing.T) {
+    backend := newTrailerBackend(t)
+    lb := httptest.NewServer(NewWeightedLeastConnection([]*Server{newTestServer(t, backend.URL, 1)}, WithTrailerForwarding(false)))
+    defer lb.Close()
+
+    resp, body := getWithTrailers(t, lb.URL)
+    if body != trailerTestBody {
+        t.Fatalf("body = %q, want %q", body, trailerTestBody)
+    }
+    if got := resp.Trailer.Get("Checksum"); got != "" {
+        t.Errorf("Checksum trailer = %q with forwarding disabled", got)
+    }
+}
+
+func TestTrailersStrippedKeepsHeadersAfterEarlyHints(t *testing.T) {
+    backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        w.Header().Set("Link", "</style.css>; rel=preload")
+        w.WriteHeader(http.StatusEarlyHints)
+        w.Header().Set("X-Final", "yes")
+        io.WriteString(w, "ok")
+    }))
+    defer backend.Close()
+    lb := httptest.NewServer(NewWeightedLeastConnection([]*Server{newTestServer(t, backend.URL, 1)}, WithTrailerForwarding(false)))
+    defer lb.Close()
+
+    resp, body := getWithTrailers(t, lb.URL)
+    if resp.StatusCode != http.StatusOK || body != "ok" {
+        t.Fatalf("got %d %q, want 200 \"ok\"", resp.StatusCode, body)
+    }
+    if got := resp.Header.Get("X-Final"); got != "yes" {
+        t.Errorf("X-Final = %q after 103 Early Hints, want %q", got, "yes")
+    }
+}
@@ -0,0 +1,49 @@
+package balancer
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// superviseHealthChecks runs the health check loop and restarts it whenever
+// it fails to complete a cycle within the watchdog interval, e.g. because a
+// backend hangs a check despite its timeout.
+func (wlc *WeightedLeastConnection) superviseHealthChecks(ctx context.Context) {
+    interval := wlc.WatchdogInterval
+    if interval == 0 {
+        interval = 3 * wlc.healthCheckInterval
+    }
+
+    start := func() context.CancelFunc {
+        loopCtx, cancel := context.WithCancel(ctx)
+        gen := wlc.healthLoopGen.Add(1)
+        wlc.lastHealthCycle.Store(time.Now().UnixNano())
+        go wlc.runHealthChecks(loopCtx, gen)
+        return cancel
+    }
+    cancelLoop := start()
+
+    ticker := time.NewTicker(interval)
+    defer ticker.Stop()
+
+    for {
+        select {
+        case <-ctx.Done():
+            cancelLoop()
+            return
+        case <-ticker.C:
+            last := time.Unix(0, wlc.lastHealthCycle.Load())
+            if stalled := time.Since(last); stalled > interval {
+                log.Printf("[WATCHDOG] 🚨 CRITICAL: health checks have not completed a cycle in %s, restarting them",
+                    stalled.Round(time.Second))
+                // Cancelling interrupts checks that honour it. One that
+                // does not must not hold up the replacement, so start it
+                // now: the stale loop exits once its check returns, as its
+                // generation is no longer current.
+                cancelLoop()
+                cancelLoop = start()
+            }
+        }
+    }
+}
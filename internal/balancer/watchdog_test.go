@@ -0,0 +1,73 @@
+package balancer
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// stallingTransport answers health checks with 200, except the first, which
+// blocks until release is closed regardless of its context and then fails
+// with 503.
+type stallingTransport struct {
+    release chan struct{}
+    calls   atomic.Int32
+}
+
+func (st *stallingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+    status := http.StatusOK
+    if st.calls.Add(1) == 1 {
+        <-st.release
+        status = http.StatusServiceUnavailable
+    }
+    return &http.Response{
+        StatusCode: status,
+        Body:       io.NopCloser(strings.NewReader("")),
+        Request:    r,
+    }, nil
+}
+
+func TestWatchdogRestartsStalledHealthChecks(t *testing.T) {
+    transport := &stallingTransport{release: make(chan struct{})}
+    server := newTestServer(t, "http://10.0.0.1:8080", 1)
+    server.healthClient = &http.Client{Transport: transport}
+    const interval = 50 * time.Millisecond
+    wlc := NewWeightedLeastConnection([]*Server{server}, WithWatchdogInterval(interval))
+
+    ctx, cancel := context.WithCancel(context.Background())
+    done := make(chan struct{})
+    go func() {
+        defer close(done)
+        wlc.StartHealthChecks(ctx)
+    }()
+    defer func() {
+        cancel()
+        <-done
+    }()
+
+    // The first check ignores cancellation, so the watchdog must start a
+    // new loop, which checks again, without waiting for it.
+    deadline := time.Now().Add(3*interval + interval/2)
+    for transport.calls.Load() < 2 {
+        if time.Now().After(deadline) {
+            close(transport.release)
+            t.Fatalf("watchdog did not restart the stalled health check within 3 intervals")
+        }
+        time.Sleep(5 * time.Millisecond)
+    }
+
+    // The stale loop's check now fails. Its loop has been replaced, so the
+    // result must be dropped.
+    close(transport.release)
+    time.Sleep(interval)
+    if !server.IsHealthy.Load() {
+        t.Errorf("the stale loop's health check marked the server unhealthy")
+    }
+    if n := server.FailureCount.Load(); n != 0 {
+        t.Errorf("FailureCount = %d after the stale check failed, want 0", n)
+    }
+}
@@ -0,0 +1,725 @@
+package config
+
+import (
+	"fmt"
+	"net/http"
+	"net/netip"
+	"os"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Adi-ty/go-loadbalancer/internal/balancer"
+	"github.com/Adi-ty/go-loadbalancer/pkg/middleware"
+	"gopkg.in/yaml.v3"
+)
+
+const redactedValue = "[REDACTED]"
+
+// BackendConfig describes a single backend server.
+type BackendConfig struct {
+    URL    string `yaml:"url" json:"url"`
+    Weight int    `yaml:"weight" json:"weight"`
+
+    // Priority sends traffic to this backend only while no backend with a
+    // lower Priority has room for it. Zero is the highest priority.
+    Priority int `yaml:"priority" json:"priority"`
+
+    // PreserveHost sends the client's Host header to this backend, and in
+    // X-Forwarded-Host, rather than the backend's own host.
+    PreserveHost bool `yaml:"preserve_host" json:"preserve_host"`
+
+    // UseSNIFromHost sends the client's Host as the TLS server name to this
+    // https backend, for backends serving several virtual hosts.
+    UseSNIFromHost bool `yaml:"use_sni_from_host" json:"use_sni_from_host"`
+
+    // SLOTarget overrides the global slo_target for this backend.
+    SLOTarget float64 `yaml:"slo_target" json:"slo_target"`
+}
+
+// Config is the load balancer's runtime configuration. It can be loaded from
+// a YAML file and overridden by command line flags.
+//
+// String fields tagged `secret:"true"` are replaced by Redacted before the
+// config is exposed anywhere.
+type Config struct {
+    ListenPort         string `yaml:"listen_port" json:"listen_port"`
+    BindAddr           string `yaml:"bind_addr" json:"bind_addr"`
+    AdminAddr          string `yaml:"admin_addr" json:"admin_addr"`
+    ListenBacklog      int    `yaml:"listen_backlog" json:"listen_backlog"`
+    ReusePortListeners int    `yaml:"reuseport_listeners" json:"reuseport_listeners"`
+    MaxHeaderBytes     int    `yaml:"max_header_bytes" json:"max_header_bytes"`
+    MaxURLBytes        int    `yaml:"max_url_bytes" json:"max_url_bytes"`
+    ForwardTrailers    bool   `yaml:"forward_trailers" json:"forward_trailers"`
+    AccessLogFile      string `yaml:"access_log_file" json:"access_log_file"`
+    AccessLogFormat    string `yaml:"access_log_format" json:"access_log_format"`
+    LogRequestBody     int    `yaml:"log_request_body" json:"log_request_body"`
+    DebugBackendHeader string `yaml:"debug_backend_header" json:"debug_backend_header"`
+    BackendLoadHeader  string `yaml:"backend_load_header" json:"backend_load_header"`
+    DiagnosticHeaders  bool   `yaml:"diagnostic_headers" json:"diagnostic_headers"`
+    MaintenanceDir     string `yaml:"maintenance_dir" json:"maintenance_dir"`
+
+    // LogSamplingRate is the fraction, 0-1, of 2xx and 3xx requests written
+    // to the access log; 0 writes none of them. 4xx and 5xx responses are
+    // always logged.
+    LogSamplingRate float64 `yaml:"log_sampling_rate" json:"log_sampling_rate"`
+
+    // MaxTrackedPaths caps how many normalised request paths have their
+    // latencies recorded for /admin/stats/paths (0 disables path stats).
+    // Path segments matching PathIDPattern, by default UUIDs and integers,
+    // are replaced by {id}.
+    MaxTrackedPaths int    `yaml:"max_tracked_paths" json:"max_tracked_paths"`
+    PathIDPattern   string `yaml:"path_id_pattern" json:"path_id_pattern"`
+
+    // AdminToken, when set, must be sent to the admin API as a bearer token.
+    // AdminAllowedCIDRs, when set, limits the networks it accepts
+    // connections from.
+    AdminToken        string   `yaml:"admin_token" json:"admin_token" secret:"true"`
+    AdminAllowedCIDRs []string `yaml:"admin_allowed_cidrs" json:"admin_allowed_cidrs"`
+
+    // LatencyBudget honours client latency budgets sent as
+    // X-Request-Deadline-Ms or Grpc-Timeout, answering 504 when less than
+    // MinRemainingMs is left by the time a backend has been chosen.
+    LatencyBudget  bool `yaml:"latency_budget" json:"latency_budget"`
+    MinRemainingMs int  `yaml:"min_remaining_ms" json:"min_remaining_ms"`
+
+    // PropagateDeadline forwards the time left before the client's
+    // X-Timeout-Ms or Grpc-Timeout deadline to backends as X-Timeout-Ms,
+    // and cancels the proxied request once it passes.
+    PropagateDeadline bool `yaml:"propagate_deadline" json:"propagate_deadline"`
+
+    // GlobalOptionsMethods is a comma-separated list of methods advertised
+    // in response to OPTIONS *.
+    GlobalOptionsMethods string `yaml:"global_options_methods" json:"global_options_methods"`
+
+    // ConnectAllowedPorts are the target ports CONNECT requests may be
+    // tunneled to. Empty disables CONNECT tunneling.
+    ConnectAllowedPorts []int `yaml:"connect_allowed_ports" json:"connect_allowed_ports"`
+
+    // MaxRetries is how many other backends a request whose method is in
+    // RetryMethods is retried on when its backend cannot be reached.
+    MaxRetries   int      `yaml:"max_retries" json:"max_retries"`
+    RetryMethods []string `yaml:"retry_methods" json:"retry_methods"`
+
+    // MaxRetryDuration caps the total time a retryable request waits for
+    // response headers across all attempts (0 means no limit).
+    MaxRetryDuration time.Duration `yaml:"max_retry_duration" json:"max_retry_duration"`
+
+    // HTTPRedirectPort, when set with TLS enabled, serves plain HTTP on this
+    // port and redirects every request to HTTPS.
+    HTTPRedirectPort string `yaml:"http_redirect_port" json:"http_redirect_port"`
+
+    TLSCertFile           string `yaml:"tls_cert_file" json:"tls_cert_file"`
+    TLSKeyFile            string `yaml:"tls_key_file" json:"tls_key_file" secret:"true"`
+
+    // ACMEDomains, when set, obtains and renews certificates for these
+    // domains from an ACME CA (Let's Encrypt unless ACMEDirectoryURL says
+    // otherwise) instead of loading TLSCertFile. HTTP-01 challenges are
+    // answered on ACMEHTTPPort.
+    ACMEDomains      []string `yaml:"acme_domains" json:"acme_domains"`
+    ACMECacheDir     string   `yaml:"acme_cache_dir" json:"acme_cache_dir"`
+    ACMEHTTPPort     string   `yaml:"acme_http_port" json:"acme_http_port"`
+    ACMEEmail        string   `yaml:"acme_email" json:"acme_email"`
+    ACMEDirectoryURL string   `yaml:"acme_directory_url" json:"acme_directory_url"`
+
+    // TLSMinVersion is tls12 or tls13. TLSCipherSuites restricts the TLS
+    // 1.2 cipher suites offered; empty uses Go's defaults.
+    TLSMinVersion   string   `yaml:"tls_min_version" json:"tls_min_version"`
+    TLSCipherSuites []string `yaml:"tls_cipher_suites" json:"tls_cipher_suites"`
+
+    HSTSMaxAge            int    `yaml:"hsts_max_age" json:"hsts_max_age"`
+    HSTSIncludeSubdomains bool   `yaml:"hsts_include_subdomains" json:"hsts_include_subdomains"`
+    HSTSPreload           bool   `yaml:"hsts_preload" json:"hsts_preload"`
+
+    // SecurityHeaders adds the headers below to responses that do not
+    // already carry them. Set a header to "" to leave it out.
+    SecurityHeaders       bool   `yaml:"security_headers" json:"security_headers"`
+    ContentSecurityPolicy string `yaml:"content_security_policy" json:"content_security_policy"`
+    XContentTypeOptions   string `yaml:"x_content_type_options" json:"x_content_type_options"`
+    XFrameOptions         string `yaml:"x_frame_options" json:"x_frame_options"`
+    PermissionsPolicy     string `yaml:"permissions_policy" json:"permissions_policy"`
+    ReferrerPolicy        string `yaml:"referrer_policy" json:"referrer_policy"`
+
+    // RequestID gives every request an X-Request-ID, keeping one sent by
+    // the client, and echoes it on the response.
+    RequestID bool `yaml:"request_id" json:"request_id"`
+
+    // RateLimitRPS limits each client IP to this many requests per second,
+    // with bursts of up to RateLimitBurst. Zero disables rate limiting.
+    RateLimitRPS   float64 `yaml:"rate_limit_rps" json:"rate_limit_rps"`
+    RateLimitBurst int     `yaml:"rate_limit_burst" json:"rate_limit_burst"`
+
+    // CORSAllowedOrigins enables CORS headers for these origins ("*" for
+    // any). Empty disables CORS handling.
+    CORSAllowedOrigins   []string `yaml:"cors_allowed_origins" json:"cors_allowed_origins"`
+    CORSAllowedMethods   []string `yaml:"cors_allowed_methods" json:"cors_allowed_methods"`
+    CORSAllowedHeaders   []string `yaml:"cors_allowed_headers" json:"cors_allowed_headers"`
+    CORSAllowCredentials bool     `yaml:"cors_allow_credentials" json:"cors_allow_credentials"`
+    CORSMaxAge           int      `yaml:"cors_max_age" json:"cors_max_age"`
+
+    // Compression gzips responses for clients that accept it.
+    Compression bool `yaml:"compression" json:"compression"`
+
+    HealthExpectedStatuses []int  `yaml:"health_expected_statuses" json:"health_expected_statuses"`
+    HealthBodyContains     string `yaml:"hc_body_contains" json:"hc_body_contains"`
+    HealthBodyNotContains  string `yaml:"hc_body_not_contains" json:"hc_body_not_contains"`
+
+    // HealthCheckInterval is how often backends are checked. Checks of a
+    // backend that keeps failing back off up to MaxHealthCheckInterval
+    // (zero means five intervals).
+    HealthCheckInterval    time.Duration `yaml:"health_check_interval" json:"health_check_interval"`
+    MaxHealthCheckInterval time.Duration `yaml:"max_health_check_interval" json:"max_health_check_interval"`
+
+    // HealthCheckPing sends an ICMP echo before each HTTP health check and
+    // marks backends that don't reply unhealthy. Needs CAP_NET_RAW.
+    HealthCheckPing bool `yaml:"health_check_ping" json:"health_check_ping"`
+
+    // HealthFlappingThreshold is how many consecutive checks a backend's new
+    // health state must hold before the change is logged.
+    HealthFlappingThreshold int `yaml:"health_flapping_threshold" json:"health_flapping_threshold"`
+
+    // HealthConsecutiveSuccesses is how many health checks in a row an
+    // unhealthy backend must pass before it is marked healthy again.
+    HealthConsecutiveSuccesses int `yaml:"health_consecutive_successes" json:"health_consecutive_successes"`
+
+    BackendIdleConnTimeout     time.Duration `yaml:"backend_idle_conn_timeout" json:"backend_idle_conn_timeout"`
+    BackendMaxIdleConns        int           `yaml:"backend_max_idle_conns" json:"backend_max_idle_conns"`
+    BackendMaxIdleConnsPerHost int           `yaml:"backend_max_idle_conns_per_host" json:"backend_max_idle_conns_per_host"`
+
+    // BackendKeepAliveInterval is how often TCP keepalive probes are sent on
+    // idle backend connections, and BackendKeepAliveCount how many may go
+    // unanswered before the connection is treated as dead.
+    BackendKeepAliveInterval time.Duration `yaml:"backend_keepalive_interval" json:"backend_keepalive_interval"`
+    BackendKeepAliveCount    int           `yaml:"backend_keepalive_count" json:"backend_keepalive_count"`
+
+    // BackendConnectJitter delays the first connections to a backend that
+    // has just become healthy again by a random time up to this long, so a
+    // fleet of balancers does not reconnect in one burst after a restart.
+    BackendConnectJitter time.Duration `yaml:"backend_connect_jitter" json:"backend_connect_jitter"`
+
+    // ErrorRateRampDown lowers a backend's effective weight while its error
+    // rate is above RampDownThreshold, restoring it over
+    // RampDownRecoveryPeriod once the rate drops.
+    ErrorRateRampDown      bool          `yaml:"error_rate_ramp_down" json:"error_rate_ramp_down"`
+    RampDownThreshold      float64       `yaml:"ramp_down_threshold" json:"ramp_down_threshold"`
+    RampDownRecoveryPeriod time.Duration `yaml:"ramp_down_recovery_period" json:"ramp_down_recovery_period"`
+
+    // PoolCircuitBreaker answers every request with 503 for PoolOpenDuration
+    // once more than PoolErrorThreshold of the pool's requests within
+    // PoolErrorWindow failed, then probes with a single request.
+    PoolCircuitBreaker bool          `yaml:"pool_circuit_breaker" json:"pool_circuit_breaker"`
+    PoolErrorThreshold float64       `yaml:"pool_error_threshold" json:"pool_error_threshold"`
+    PoolErrorWindow    time.Duration `yaml:"pool_error_window" json:"pool_error_window"`
+    PoolOpenDuration   time.Duration `yaml:"pool_open_duration" json:"pool_open_duration"`
+
+    // SLOTarget, e.g. 0.999, is the share of requests each backend is
+    // expected to serve successfully, exported as an error budget in
+    // lb_backend_error_budget_remaining. Zero disables it.
+    SLOTarget float64 `yaml:"slo_target" json:"slo_target"`
+
+    // BackendMaxConnections caps concurrent requests per backend (0 means
+    // unlimited). Requests that find every backend full wait up to
+    // MaxQueueWait for a free slot, or with FailFast get 503 straight away.
+    BackendMaxConnections int           `yaml:"backend_max_connections" json:"backend_max_connections"`
+    FailFast              bool          `yaml:"fail_fast" json:"fail_fast"`
+    MaxQueueWait          time.Duration `yaml:"max_queue_wait" json:"max_queue_wait"`
+
+    // RequestBufferPool reuses RequestBufferSize-byte buffers for request
+    // bodies held in memory or streamed to backends.
+    RequestBufferPool bool `yaml:"request_buffer_pool" json:"request_buffer_pool"`
+    RequestBufferSize int  `yaml:"request_buffer_size" json:"request_buffer_size"`
+
+    // ResponseBufferPool copies response bodies through pooled
+    // ResponseBufferSize-byte buffers.
+    ResponseBufferPool bool `yaml:"response_buffer_pool" json:"response_buffer_pool"`
+    ResponseBufferSize int  `yaml:"response_buffer_size" json:"response_buffer_size"`
+
+    ReadTimeout  time.Duration `yaml:"read_timeout" json:"read_timeout"`
+    WriteTimeout time.Duration `yaml:"write_timeout" json:"write_timeout"`
+    IdleTimeout  time.Duration `yaml:"idle_timeout" json:"idle_timeout"`
+
+    // ShutdownDelay is how long the balancer keeps answering 503 after
+    // SIGTERM before draining, so upstream health checks notice. In-flight
+    // requests then get up to ShutdownGracePeriod to finish.
+    ShutdownDelay       time.Duration `yaml:"shutdown_delay" json:"shutdown_delay"`
+    ShutdownGracePeriod time.Duration `yaml:"shutdown_grace_period" json:"shutdown_grace_period"`
+
+    // EtcdEndpoints, when set, adds backends registered under EtcdPrefix
+    // in etcd to the pool and keeps them in sync.
+    EtcdEndpoints []string `yaml:"etcd_endpoints" json:"etcd_endpoints"`
+    EtcdPrefix    string   `yaml:"etcd_prefix" json:"etcd_prefix"`
+    EtcdUsername  string   `yaml:"etcd_username" json:"etcd_username"`
+    EtcdPassword  string   `yaml:"etcd_password" json:"etcd_password" secret:"true"`
+
+    // DiscoverySRV, when set, adds the targets of this DNS SRV record to the
+    // pool, weighted by their SRV weights, and re-resolves it every
+    // DiscoverySRVInterval.
+    DiscoverySRV         string        `yaml:"discovery_srv" json:"discovery_srv"`
+    DiscoverySRVInterval time.Duration `yaml:"discovery_srv_interval" json:"discovery_srv_interval"`
+
+    // RedisAddr, when set, shares backend weights with other instances
+    // through the RedisWeightsKey hash in Redis, read every
+    // RedisSyncInterval. Weight changes made through the admin API are
+    // written to it.
+    RedisAddr         string        `yaml:"redis_addr" json:"redis_addr"`
+    RedisPassword     string        `yaml:"redis_password" json:"redis_password" secret:"true"`
+    RedisWeightsKey   string        `yaml:"redis_weights_key" json:"redis_weights_key"`
+    RedisSyncInterval time.Duration `yaml:"redis_sync_interval" json:"redis_sync_interval"`
+
+    // ProxyHideHeaders are backend response headers stripped before
+    // responses reach clients, in addition to the default list of
+    // Proxy-Authenticate, Keep-Alive, X-Accel-* and similar. ProxyPassHeaders
+    // are passed on even if they would otherwise be hidden. Like nginx's
+    // proxy_hide_header and proxy_pass_header.
+    ProxyHideHeaders []string `yaml:"proxy_hide_headers" json:"proxy_hide_headers"`
+    ProxyPassHeaders []string `yaml:"proxy_pass_headers" json:"proxy_pass_headers"`
+
+    // TrustedForwardedProto is how many upstream proxies are trusted to set
+    // X-Forwarded-Proto. Zero always forwards the incoming connection's
+    // scheme.
+    TrustedForwardedProto int `yaml:"trusted_forwarded_proto" json:"trusted_forwarded_proto"`
+
+    // PoolName, when set, labels every lb_* metric with pool_name.
+    PoolName string `yaml:"pool_name" json:"pool_name"`
+
+    // MaxRequestBody limits request bodies to this many bytes (0 means
+    // unlimited). BufferUnknownLengthBodies reads bodies sent without
+    // Content-Length into memory, up to MaxRequestBody, so they are
+    // forwarded with one.
+    MaxRequestBody            int64 `yaml:"max_request_body" json:"max_request_body"`
+    BufferUnknownLengthBodies bool  `yaml:"buffer_unknown_length_bodies" json:"buffer_unknown_length_bodies"`
+
+    // PrewarmRequests is how many warmup requests are sent to each healthy
+    // backend at startup before traffic is accepted.
+    PrewarmRequests int `yaml:"prewarm_requests" json:"prewarm_requests"`
+
+    // CollapseRequests answers concurrent identical GET and HEAD requests
+    // with a single backend request.
+    CollapseRequests bool `yaml:"collapse_requests" json:"collapse_requests"`
+
+    // DiscoverCIDR, when set, scans every address in this range for
+    // DiscoverPort every DiscoverScanInterval and keeps the pool in line
+    // with those that accept connections. A backend is removed once it
+    // misses DiscoverRemoveAfter scans in a row.
+    DiscoverCIDR         string        `yaml:"discover_cidr" json:"discover_cidr"`
+    DiscoverPort         int           `yaml:"discover_port" json:"discover_port"`
+    DiscoverScanInterval time.Duration `yaml:"discover_scan_interval" json:"discover_scan_interval"`
+    DiscoverDialTimeout  time.Duration `yaml:"discover_dial_timeout" json:"discover_dial_timeout"`
+    DiscoverRemoveAfter  int           `yaml:"discover_remove_after" json:"discover_remove_after"`
+
+    Backends []BackendConfig `yaml:"backends" json:"backends"`
+}
+
+// Default returns the configuration used when no file or flags are given.
+func Default() Config {
+    securityHeaders := middleware.DefaultSecurityHeadersConfig()
+
+    return Config{
+        ListenPort:         "8080",
+        AdminAddr:          "127.0.0.1:9090",
+        ReusePortListeners: 1,
+        MaxHeaderBytes:     http.DefaultMaxHeaderBytes,
+        ForwardTrailers:    true,
+        MinRemainingMs:     10,
+        MaxTrackedPaths:    1000,
+        AccessLogFormat:    "json",
+        LogSamplingRate:    1,
+
+        GlobalOptionsMethods: balancer.DefaultGlobalOptionsMethods,
+
+        MaxRetries:   2,
+        RetryMethods: slices.Clone(balancer.DefaultRetryMethods),
+
+        MaxQueueWait: balancer.DefaultMaxQueueWait,
+
+        TLSMinVersion: "tls12",
+        ACMECacheDir:  "acme-cache",
+        ACMEHTTPPort:  "80",
+
+        HSTSMaxAge:            31536000,
+        HSTSIncludeSubdomains: true,
+
+        ContentSecurityPolicy: securityHeaders.ContentSecurityPolicy,
+        XContentTypeOptions:   securityHeaders.XContentTypeOptions,
+        XFrameOptions:         securityHeaders.XFrameOptions,
+        PermissionsPolicy:     securityHeaders.PermissionsPolicy,
+        ReferrerPolicy:        securityHeaders.ReferrerPolicy,
+
+        RateLimitBurst: 20,
+
+        HealthExpectedStatuses:     []int{http.StatusOK},
+        HealthFlappingThreshold:    3,
+        HealthConsecutiveSuccesses: 1,
+        HealthCheckInterval:        10 * time.Second,
+
+        BackendIdleConnTimeout:     90 * time.Second,
+        BackendMaxIdleConns:        100,
+        BackendMaxIdleConnsPerHost: http.DefaultMaxIdleConnsPerHost,
+        BackendKeepAliveInterval:   30 * time.Second,
+        BackendKeepAliveCount:      3,
+
+        RampDownThreshold:      0.1,
+        RampDownRecoveryPeriod: 30 * time.Second,
+
+        PoolErrorThreshold: 0.5,
+        PoolErrorWindow:    10 * time.Second,
+        PoolOpenDuration:   30 * time.Second,
+
+        RequestBufferSize:  32 * 1024,
+        ResponseBufferSize: 32 * 1024,
+
+        ReadTimeout:  15 * time.Second,
+        WriteTimeout: 15 * time.Second,
+        IdleTimeout:  60 * time.Second,
+
+        EtcdPrefix: "/backends/",
+
+        DiscoverySRVInterval: 30 * time.Second,
+        DiscoverScanInterval: 30 * time.Second,
+        DiscoverDialTimeout:  time.Second,
+        DiscoverRemoveAfter:  3,
+
+        RedisWeightsKey:   "lb:weights",
+        RedisSyncInterval: 10 * time.Second,
+
+        ShutdownDelay:       5 * time.Second,
+        ShutdownGracePeriod: 30 * time.Second,
+    }
+}
+
+// LoadFile reads the YAML file at path into cfg. Fields missing from the file
+// keep their current values.
+func LoadFile(path string, cfg *Config) error {
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return fmt.Errorf("failed to read config %s: %w", path, err)
+    }
+
+    if err := yaml.Unmarshal(data, cfg); err != nil {
+        return fmt.Errorf("failed to parse config %s: %w", path, err)
+    }
+    return nil
+}
+
+// GlobalOptions returns GlobalOptionsMethods as a list.
+func (c *Config) GlobalOptions() []string {
+    var methods []string
+    for _, method := range strings.Split(c.GlobalOptionsMethods, ",") {
+        if method = strings.TrimSpace(method); method != "" {
+            methods = append(methods, method)
+        }
+    }
+    return methods
+}
+
+// isMethodToken reports whether s is an upper-case HTTP method name.
+func isMethodToken(s string) bool {
+    if s == "" {
+        return false
+    }
+    for _, r := range s {
+        if r < 'A' || r > 'Z' {
+            return false
+        }
+    }
+    return true
+}
+
+// TLSEnabled reports whether the balancer terminates TLS.
+func (c *Config) TLSEnabled() bool {
+    return c.TLSCertFile != "" && c.TLSKeyFile != "" || c.ACMEEnabled()
+}
+
+// ACMEEnabled reports whether certificates are obtained through ACME.
+func (c *Config) ACMEEnabled() bool {
+    return len(c.ACMEDomains) > 0
+}
+
+// Validate checks field constraints.
+func (c *Config) Validate() error {
+    if c.ListenPort == "" {
+        return fmt.Errorf("listen_port must be set")
+    }
+    if c.ListenBacklog < 0 {
+        return fmt.Errorf("listen_backlog must be >= 0")
+    }
+    if c.ReusePortListeners < 1 {
+        return fmt.Errorf("reuseport_listeners must be >= 1")
+    }
+    if c.MaxHeaderBytes < 1 {
+        return fmt.Errorf("max_header_bytes must be >= 1")
+    }
+    if c.MaxTrackedPaths < 0 {
+        return fmt.Errorf("max_tracked_paths must be >= 0")
+    }
+    if _, err := regexp.Compile(c.PathIDPattern); err != nil {
+        return fmt.Errorf("invalid path_id_pattern: %w", err)
+    }
+    if c.MinRemainingMs < 0 {
+        return fmt.Errorf("min_remaining_ms must be >= 0")
+    }
+    if c.BindAddr != "" {
+        if _, err := netip.ParseAddr(c.BindAddr); err != nil {
+            return fmt.Errorf("bind_addr must be an IP address: %v", err)
+        }
+    }
+    if c.MaxURLBytes < 0 {
+        return fmt.Errorf("max_url_bytes must be >= 0")
+    }
+    if c.RateLimitRPS < 0 {
+        return fmt.Errorf("rate_limit_rps must be >= 0")
+    }
+    if c.RateLimitRPS > 0 && c.RateLimitBurst < 1 {
+        return fmt.Errorf("rate_limit_burst must be >= 1 when rate limiting is enabled")
+    }
+    if c.CORSMaxAge < 0 {
+        return fmt.Errorf("cors_max_age must be >= 0")
+    }
+    if c.TrustedForwardedProto < 0 {
+        return fmt.Errorf("trusted_forwarded_proto must be >= 0")
+    }
+    if c.MaxRequestBody < 0 {
+        return fmt.Errorf("max_request_body must be >= 0")
+    }
+    if c.LogRequestBody < 0 {
+        return fmt.Errorf("log_request_body must be >= 0")
+    }
+    if c.LogSamplingRate < 0 || c.LogSamplingRate > 1 {
+        return fmt.Errorf("log_sampling_rate must be between 0 and 1")
+    }
+    if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+        return fmt.Errorf("tls_cert_file and tls_key_file must be set together")
+    }
+    for _, method := range c.GlobalOptions() {
+        if !isMethodToken(method) {
+            return fmt.Errorf("global_options_methods contains invalid method %q", method)
+        }
+    }
+    for _, port := range c.ConnectAllowedPorts {
+        if port < 1 || port > 65535 {
+            return fmt.Errorf("connect_allowed_ports contains invalid port %d", port)
+        }
+    }
+    if c.MaxRetries < 0 {
+        return fmt.Errorf("max_retries must be >= 0")
+    }
+    if c.MaxRetryDuration < 0 {
+        return fmt.Errorf("max_retry_duration must be >= 0")
+    }
+    for _, method := range c.RetryMethods {
+        if !isMethodToken(method) {
+            return fmt.Errorf("retry_methods contains invalid method %q", method)
+        }
+    }
+    if _, err := c.TLSConfig(); err != nil {
+        return err
+    }
+    if c.ACMEEnabled() {
+        if c.TLSCertFile != "" {
+            return fmt.Errorf("acme_domains and tls_cert_file cannot both be set")
+        }
+        if c.ACMECacheDir == "" {
+            return fmt.Errorf("acme_cache_dir must be set when acme_domains is")
+        }
+        if c.ACMEHTTPPort == "" {
+            return fmt.Errorf("acme_http_port must be set when acme_domains is")
+        }
+        if c.HTTPRedirectPort == c.ACMEHTTPPort {
+            return fmt.Errorf("http_redirect_port must differ from acme_http_port, which already redirects to HTTPS")
+        }
+    }
+    if c.HTTPRedirectPort != "" && !c.TLSEnabled() {
+        return fmt.Errorf("http_redirect_port requires tls_cert_file and tls_key_file or acme_domains")
+    }
+    if c.HSTSMaxAge < 0 {
+        return fmt.Errorf("hsts_max_age must be >= 0")
+    }
+    if c.BackendIdleConnTimeout < 0 {
+        return fmt.Errorf("backend_idle_conn_timeout must be >= 0")
+    }
+    if c.BackendMaxIdleConns < 0 {
+        return fmt.Errorf("backend_max_idle_conns must be >= 0")
+    }
+    if c.BackendMaxIdleConnsPerHost < 0 {
+        return fmt.Errorf("backend_max_idle_conns_per_host must be >= 0")
+    }
+    if c.BackendKeepAliveInterval <= 0 {
+        return fmt.Errorf("backend_keepalive_interval must be > 0")
+    }
+    if c.BackendKeepAliveCount < 1 {
+        return fmt.Errorf("backend_keepalive_count must be >= 1")
+    }
+    if c.BackendConnectJitter < 0 {
+        return fmt.Errorf("backend_connect_jitter must be >= 0")
+    }
+    if c.RequestBufferSize < 1 {
+        return fmt.Errorf("request_buffer_size must be >= 1")
+    }
+    if c.ResponseBufferSize < 1 {
+        return fmt.Errorf("response_buffer_size must be >= 1")
+    }
+    if c.ReadTimeout <= 0 {
+        return fmt.Errorf("read_timeout must be > 0")
+    }
+    if c.WriteTimeout <= 0 {
+        return fmt.Errorf("write_timeout must be > 0")
+    }
+    if c.IdleTimeout < c.ReadTimeout {
+        return fmt.Errorf("idle_timeout must be >= read_timeout")
+    }
+    if c.RampDownThreshold <= 0 || c.RampDownThreshold >= 1 {
+        return fmt.Errorf("ramp_down_threshold must be between 0 and 1")
+    }
+    if c.RampDownRecoveryPeriod <= 0 {
+        return fmt.Errorf("ramp_down_recovery_period must be > 0")
+    }
+    if c.PoolErrorThreshold <= 0 || c.PoolErrorThreshold >= 1 {
+        return fmt.Errorf("pool_error_threshold must be between 0 and 1")
+    }
+    if c.PoolErrorWindow <= 0 {
+        return fmt.Errorf("pool_error_window must be > 0")
+    }
+    if c.PoolOpenDuration <= 0 {
+        return fmt.Errorf("pool_open_duration must be > 0")
+    }
+    if c.SLOTarget < 0 || c.SLOTarget >= 1 {
+        return fmt.Errorf("slo_target must be >= 0 and < 1")
+    }
+    if c.BackendMaxConnections < 0 {
+        return fmt.Errorf("backend_max_connections must be >= 0")
+    }
+    if c.MaxQueueWait <= 0 {
+        return fmt.Errorf("max_queue_wait must be > 0")
+    }
+    if _, err := c.AdminAllowedPrefixes(); err != nil {
+        return err
+    }
+    if len(c.EtcdEndpoints) > 0 && c.EtcdPrefix == "" {
+        return fmt.Errorf("etcd_prefix must be set when etcd_endpoints is")
+    }
+    if c.DiscoverySRVInterval <= 0 {
+        return fmt.Errorf("discovery_srv_interval must be > 0")
+    }
+    if c.RedisAddr != "" && c.RedisWeightsKey == "" {
+        return fmt.Errorf("redis_weights_key must be set when redis_addr is")
+    }
+    if c.RedisSyncInterval <= 0 {
+        return fmt.Errorf("redis_sync_interval must be > 0")
+    }
+    if c.PrewarmRequests < 0 {
+        return fmt.Errorf("prewarm_requests must be >= 0")
+    }
+    if c.DiscoverCIDR != "" {
+        if _, err := netip.ParsePrefix(c.DiscoverCIDR); err != nil {
+            return fmt.Errorf("invalid discover_cidr: %v", err)
+        }
+        if c.DiscoverPort < 1 || c.DiscoverPort > 65535 {
+            return fmt.Errorf("discover_port must be set to a port between 1 and 65535 when discover_cidr is")
+        }
+    }
+    if c.DiscoverScanInterval <= 0 {
+        return fmt.Errorf("discover_scan_interval must be > 0")
+    }
+    if c.DiscoverDialTimeout <= 0 {
+        return fmt.Errorf("discover_dial_timeout must be > 0")
+    }
+    if c.DiscoverRemoveAfter < 1 {
+        return fmt.Errorf("discover_remove_after must be >= 1")
+    }
+    if c.ShutdownDelay < 0 {
+        return fmt.Errorf("shutdown_delay must be >= 0")
+    }
+    if c.ShutdownGracePeriod < 0 {
+        return fmt.Errorf("shutdown_grace_period must be >= 0")
+    }
+    if c.HealthCheckInterval <= 0 {
+        return fmt.Errorf("health_check_interval must be > 0")
+    }
+    if c.MaxHealthCheckInterval != 0 && c.MaxHealthCheckInterval < c.HealthCheckInterval {
+        return fmt.Errorf("max_health_check_interval must be >= health_check_interval")
+    }
+    if c.HealthFlappingThreshold < 1 {
+        return fmt.Errorf("health_flapping_threshold must be >= 1")
+    }
+    if c.HealthConsecutiveSuccesses < 1 {
+        return fmt.Errorf("health_consecutive_successes must be >= 1")
+    }
+    for _, status := range c.HealthExpectedStatuses {
+        if status < 100 || status > 599 {
+            return fmt.Errorf("health_expected_statuses contains invalid status %d", status)
+        }
+    }
+    switch c.AccessLogFormat {
+    case "json", "clf", "combined":
+    default:
+        return fmt.Errorf("access_log_format must be one of json, clf, combined")
+    }
+    for _, backend := range c.Backends {
+        if backend.URL == "" {
+            return fmt.Errorf("backend url must be set")
+        }
+        if backend.Weight < 1 {
+            return fmt.Errorf("invalid weight for backend %s. Must be an integer >= 1", backend.URL)
+        }
+        if backend.Priority < 0 {
+            return fmt.Errorf("invalid priority for backend %s. Must be an integer >= 0", backend.URL)
+        }
+        if backend.SLOTarget < 0 || backend.SLOTarget >= 1 {
+            return fmt.Errorf("invalid slo_target for backend %s. Must be >= 0 and < 1", backend.URL)
+        }
+    }
+    return nil
+}
+
+// AdminAllowedPrefixes parses AdminAllowedCIDRs. A bare address is treated
+// as a single-host prefix.
+func (c *Config) AdminAllowedPrefixes() ([]netip.Prefix, error) {
+    prefixes := make([]netip.Prefix, 0, len(c.AdminAllowedCIDRs))
+    for _, cidr := range c.AdminAllowedCIDRs {
+        if !strings.Contains(cidr, "/") {
+            addr, err := netip.ParseAddr(cidr)
+            if err != nil {
+                return nil, fmt.Errorf("invalid admin_allowed_cidrs entry %q: %w", cidr, err)
+            }
+            prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+            continue
+        }
+
+        prefix, err := netip.ParsePrefix(cidr)
+        if err != nil {
+            return nil, fmt.Errorf("invalid admin_allowed_cidrs entry %q: %w", cidr, err)
+        }
+        prefixes = append(prefixes, prefix.Masked())
+    }
+    return prefixes, nil
+}
+
+// Redacted returns a copy of c with every non-empty secret field replaced by
+// "[REDACTED]".
+func (c Config) Redacted() Config {
+    v := reflect.ValueOf(&c).Elem()
+    t := v.Type()
+    for i := 0; i < t.NumField(); i++ {
+        field := t.Field(i)
+        if field.Tag.Get("secret") != "true" || field.Type.Kind() != reflect.String {
+            continue
+        }
+        if v.Field(i).String() != "" {
+            v.Field(i).SetString(redactedValue)
+        }
+    }
+
+    c.Backends = append([]BackendConfig(nil), c.Backends...)
+    c.HealthExpectedStatuses = append([]int(nil), c.HealthExpectedStatuses...)
+    c.RetryMethods = append([]string(nil), c.RetryMethods...)
+    c.ConnectAllowedPorts = append([]int(nil), c.ConnectAllowedPorts...)
+    c.ProxyHideHeaders = append([]string(nil), c.ProxyHideHeaders...)
+    c.ProxyPassHeaders = append([]string(nil), c.ProxyPassHeaders...)
+    c.EtcdEndpoints = append([]string(nil), c.EtcdEndpoints...)
+    c.AdminAllowedCIDRs = append([]string(nil), c.AdminAllowedCIDRs...)
+    c.TLSCipherSuites = append([]string(nil), c.TLSCipherSuites...)
+    c.ACMEDomains = append([]string(nil), c.ACMEDomains...)
+    return c
+}
@@ -0,0 +1,87 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Adi-ty/go-loadbalancer/pkg/middleware"
+)
+
+func TestDefaultSecurityHeaders(t *testing.T) {
+    cfg := Default()
+    want := middleware.DefaultSecurityHeadersConfig()
+
+    got := middleware.SecurityHeadersConfig{
+        ContentSecurityPolicy: cfg.ContentSecurityPolicy,
+        XContentTypeOptions:   cfg.XContentTypeOptions,
+        XFrameOptions:         cfg.XFrameOptions,
+        PermissionsPolicy:     cfg.PermissionsPolicy,
+        ReferrerPolicy:        cfg.ReferrerPolicy,
+    }
+    if got != want {
+        t.Errorf("Default() security headers = %+v, want %+v", got, want)
+    }
+}
+
+func TestValidateLogSamplingRate(t *testing.T) {
+    for rate, valid := range map[float64]bool{0: true, 0.5: true, 1: true, -0.1: false, 1.1: false} {
+        cfg := Default()
+        cfg.LogSamplingRate = rate
+        if err := cfg.Validate(); (err == nil) != valid {
+            t.Errorf("Validate() with log_sampling_rate %v = %v, want valid %v", rate, err, valid)
+        }
+    }
+}
+
+func TestValidateRetryMethods(t *testing.T) {
+    tests := []struct {
+        methods []string
+        valid   bool
+    }{
+        {[]string{"GET", "PATCH"}, true},
+        {[]string{}, true},
+        {[]string{"patch"}, false},
+        {[]string{"GET", ""}, false},
+        {[]string{"GET POST"}, false},
+    }
+
+    for _, tt := range tests {
+        cfg := Default()
+        cfg.RetryMethods = tt.methods
+        if err := cfg.Validate(); (err == nil) != tt.valid {
+            t.Errorf("Validate() with retry_methods %q = %v, want valid %v", tt.methods, err, tt.valid)
+        }
+    }
+}
+
+func TestValidateServerTimeouts(t *testing.T) {
+    tests := []struct {
+        name              string
+        read, write, idle time.Duration
+        valid             bool
+    }{
+        {"defaults", 15 * time.Second, 15 * time.Second, 60 * time.Second, true},
+        {"idle equals read", time.Second, time.Second, time.Second, true},
+        {"zero read", 0, time.Second, time.Second, false},
+        {"zero write", time.Second, 0, time.Second, false},
+        {"idle below read", 2 * time.Second, time.Second, time.Second, false},
+    }
+
+    for _, tt := range tests {
+        cfg := Default()
+        cfg.ReadTimeout, cfg.WriteTimeout, cfg.IdleTimeout = tt.read, tt.write, tt.idle
+        if err := cfg.Validate(); (err == nil) != tt.valid {
+            t.Errorf("%s: Validate() = %v, want valid %v", tt.name, err, tt.valid)
+        }
+    }
+}
+
+func TestValidateBindAddr(t *testing.T) {
+    for addr, valid := range map[string]bool{"": true, "127.0.0.1": true, "::1": true, "localhost": false, "127.0.0.1:8080": false} {
+        cfg := Default()
+        cfg.BindAddr = addr
+        if err := cfg.Validate(); (err == nil) != valid {
+            t.Errorf("Validate() with bind_addr %q = %v, want valid %v", addr, err, valid)
+        }
+    }
+}
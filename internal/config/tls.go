@@ -0,0 +1,58 @@
+package config
+
+import (
+	"crypto/tls"
+	"fmt"
+	"slices"
+)
+
+var tlsVersions = map[string]uint16{
+    "tls12": tls.VersionTLS12,
+    "tls13": tls.VersionTLS13,
+}
+
+// TLSConfig builds the server TLS settings from TLSMinVersion and
+// TLSCipherSuites.
+func (c *Config) TLSConfig() (*tls.Config, error) {
+    version, ok := tlsVersions[c.TLSMinVersion]
+    if !ok {
+        return nil, fmt.Errorf("tls_min_version must be one of tls12, tls13")
+    }
+
+    suites, err := cipherSuiteIDs(c.TLSCipherSuites)
+    if err != nil {
+        return nil, err
+    }
+
+    return &tls.Config{
+        MinVersion:   version,
+        CipherSuites: suites,
+    }, nil
+}
+
+// cipherSuiteIDs maps cipher suite names, as reported by crypto/tls (e.g.
+// TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256), to their IDs. Insecure suites and
+// TLS 1.3 suites, which Go does not allow to be configured, are rejected.
+func cipherSuiteIDs(names []string) ([]uint16, error) {
+    if len(names) == 0 {
+        return nil, nil
+    }
+
+    byName := make(map[string]*tls.CipherSuite)
+    for _, suite := range tls.CipherSuites() {
+        byName[suite.Name] = suite
+    }
+
+    ids := make([]uint16, 0, len(names))
+    for _, name := range names {
+        suite, ok := byName[name]
+        if !ok {
+            return nil, fmt.Errorf("tls_cipher_suites contains unsupported cipher suite %q", name)
+        }
+        if !slices.Contains(suite.SupportedVersions, tls.VersionTLS12) {
+            return nil, fmt.Errorf("tls_cipher_suites contains TLS 1.3 cipher suite %q, which cannot be configured", name)
+        }
+        ids = append(ids, suite.ID)
+    }
+    return ids, nil
+}
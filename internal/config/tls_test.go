@@ -0,0 +1,61 @@
+package config
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTLSConfigMinVersion(t *testing.T) {
+    cfg := Default()
+    cfg.TLSMinVersion = "tls13"
+    tlsConfig, err := cfg.TLSConfig()
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+    srv.TLS = tlsConfig
+    srv.StartTLS()
+    defer srv.Close()
+
+    conn, err := tls.Dial("tcp", srv.Listener.Addr().String(), &tls.Config{
+        InsecureSkipVerify: true,
+        MaxVersion:         tls.VersionTLS12,
+    })
+    if err == nil {
+        conn.Close()
+        t.Fatal("TLS 1.2 handshake succeeded with tls_min_version tls13")
+    }
+
+    conn, err = tls.Dial("tcp", srv.Listener.Addr().String(), &tls.Config{InsecureSkipVerify: true})
+    if err != nil {
+        t.Fatalf("TLS 1.3 handshake failed: %v", err)
+    }
+    defer conn.Close()
+    if v := conn.ConnectionState().Version; v != tls.VersionTLS13 {
+        t.Errorf("negotiated version = %x, want TLS 1.3", v)
+    }
+}
+
+func TestTLSConfigCipherSuites(t *testing.T) {
+    tests := []struct {
+        suites []string
+        valid  bool
+    }{
+        {[]string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256", "TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256"}, true},
+        {nil, true},
+        {[]string{"ECDHE-RSA-AES128-GCM-SHA256"}, false},
+        {[]string{"TLS_AES_128_GCM_SHA256"}, false},
+        {[]string{"TLS_RSA_WITH_RC4_128_SHA"}, false},
+    }
+
+    for _, tt := range tests {
+        cfg := Default()
+        cfg.TLSCipherSuites = tt.suites
+        if _, err := cfg.TLSConfig(); (err == nil) != tt.valid {
+            t.Errorf("TLSConfig() with tls_cipher_suites %q = %v, want valid %v", tt.suites, err, tt.valid)
+        }
+    }
+}
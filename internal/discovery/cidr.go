@@ -0,0 +1,207 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/netip"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Adi-ty/go-loadbalancer/internal/balancer"
+)
+
+// Defaults for CIDRConfig.
+const (
+    DefaultCIDRScanInterval = 30 * time.Second
+    DefaultCIDRDialTimeout  = time.Second
+    DefaultCIDRRemoveAfter  = 3
+)
+
+// MaxCIDRScanHosts is the largest number of addresses a CIDR scan may cover.
+const MaxCIDRScanHosts = 4096
+
+// cidrScanConcurrency is how many addresses are probed at once.
+const cidrScanConcurrency = 64
+
+// CIDRConfig configures CIDRDiscovery.
+type CIDRConfig struct {
+    // Prefix is the range of addresses to scan.
+    Prefix netip.Prefix
+
+    // Port is probed with a TCP connect on every address.
+    Port int
+
+    // Scheme of the backend URLs. Defaults to http.
+    Scheme string
+
+    // ScanInterval defaults to DefaultCIDRScanInterval.
+    ScanInterval time.Duration
+
+    // DialTimeout bounds each probe. Defaults to DefaultCIDRDialTimeout.
+    DialTimeout time.Duration
+
+    // RemoveAfter is how many scans in a row a backend must miss before it
+    // is removed, so a single dropped probe does not evict it. Defaults to
+    // DefaultCIDRRemoveAfter.
+    RemoveAfter int
+
+    // Configure, when set, is applied to every backend before it is added
+    // to the pool.
+    Configure func(*balancer.Server)
+
+    // DialContext opens the probe connections. Defaults to a net.Dialer.
+    DialContext func(ctx context.Context, network, address string) (net.Conn, error)
+}
+
+// CIDRDiscovery adds every address in a subnet that accepts TCP connections
+// on a port to the pool with weight 1, and removes addresses that stop
+// accepting them for RemoveAfter scans in a row. Removing a backend only stops new requests being sent to
+// it; requests already in flight complete.
+type CIDRDiscovery struct {
+    cfg  CIDRConfig
+    pool Pool
+
+    backends map[string]int // Scans missed in a row, keyed by backend URL
+}
+
+func NewCIDRDiscovery(cfg CIDRConfig, pool Pool) (*CIDRDiscovery, error) {
+    if !cfg.Prefix.IsValid() {
+        return nil, fmt.Errorf("invalid CIDR prefix")
+    }
+    if n := cidrHosts(cfg.Prefix); n > MaxCIDRScanHosts {
+        return nil, fmt.Errorf("CIDR %s covers more than %d addresses", cfg.Prefix, MaxCIDRScanHosts)
+    }
+    if cfg.Port < 1 || cfg.Port > 65535 {
+        return nil, fmt.Errorf("invalid port %d", cfg.Port)
+    }
+    if cfg.Scheme == "" {
+        cfg.Scheme = "http"
+    }
+    if cfg.ScanInterval <= 0 {
+        cfg.ScanInterval = DefaultCIDRScanInterval
+    }
+    if cfg.DialTimeout <= 0 {
+        cfg.DialTimeout = DefaultCIDRDialTimeout
+    }
+    if cfg.RemoveAfter <= 0 {
+        cfg.RemoveAfter = DefaultCIDRRemoveAfter
+    }
+    if cfg.DialContext == nil {
+        cfg.DialContext = (&net.Dialer{}).DialContext
+    }
+
+    return &CIDRDiscovery{
+        cfg:      cfg,
+        pool:     pool,
+        backends: make(map[string]int),
+    }, nil
+}
+
+// cidrHosts returns the number of addresses in prefix, capped just above
+// MaxCIDRScanHosts so large IPv6 prefixes do not overflow.
+func cidrHosts(prefix netip.Prefix) int {
+    bits := prefix.Addr().BitLen() - prefix.Bits()
+    if bits > 20 {
+        return MaxCIDRScanHosts + 1
+    }
+    return 1 << bits
+}
+
+// Run scans the range every ScanInterval until ctx is done.
+func (d *CIDRDiscovery) Run(ctx context.Context) {
+    ticker := time.NewTicker(d.cfg.ScanInterval)
+    defer ticker.Stop()
+
+    for {
+        d.Scan(ctx)
+
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+        }
+    }
+}
+
+// Scan probes every address in the range once and brings the pool in line
+// with the ones that answered.
+func (d *CIDRDiscovery) Scan(ctx context.Context) {
+    var (
+        mu        sync.Mutex
+        wg        sync.WaitGroup
+        responded = make(map[string]bool)
+        sem       = make(chan struct{}, cidrScanConcurrency)
+    )
+
+    port := strconv.Itoa(d.cfg.Port)
+    for addr := d.cfg.Prefix.Masked().Addr(); d.cfg.Prefix.Contains(addr); addr = addr.Next() {
+        sem <- struct{}{}
+        wg.Add(1)
+        go func(address string) {
+            defer wg.Done()
+            defer func() { <-sem }()
+            if d.probe(ctx, address) {
+                mu.Lock()
+                responded[d.cfg.Scheme+"://"+address] = true
+                mu.Unlock()
+            }
+        }(net.JoinHostPort(addr.String(), port))
+    }
+    wg.Wait()
+
+    // A cancelled scan says nothing about which backends are down.
+    if ctx.Err() != nil {
+        return
+    }
+
+    for rawURL := range responded {
+        if _, ok := d.backends[rawURL]; !ok {
+            d.add(rawURL)
+        }
+    }
+    for rawURL, missed := range d.backends {
+        if responded[rawURL] {
+            d.backends[rawURL] = 0
+            continue
+        }
+        missed++
+        if missed < d.cfg.RemoveAfter {
+            d.backends[rawURL] = missed
+            continue
+        }
+        delete(d.backends, rawURL)
+        if d.pool.Remove(rawURL) != nil {
+            log.Printf("[DISCOVERY] Removed backend %s no longer answering on port %d after %d scans", rawURL, d.cfg.Port, missed)
+        }
+    }
+}
+
+// probe reports whether address accepts a TCP connection.
+func (d *CIDRDiscovery) probe(ctx context.Context, address string) bool {
+    ctx, cancel := context.WithTimeout(ctx, d.cfg.DialTimeout)
+    defer cancel()
+
+    conn, err := d.cfg.DialContext(ctx, "tcp", address)
+    if err != nil {
+        return false
+    }
+    conn.Close()
+    return true
+}
+
+func (d *CIDRDiscovery) add(rawURL string) {
+    server, err := balancer.NewServer(rawURL, 1)
+    if err != nil {
+        log.Printf("[DISCOVERY] Ignoring %s: %v", rawURL, err)
+        return
+    }
+    if d.cfg.Configure != nil {
+        d.cfg.Configure(server)
+    }
+    d.pool.Add(server)
+    d.backends[rawURL] = 0
+    log.Printf("[DISCOVERY] Added backend %s found in %s", rawURL, d.cfg.Prefix)
+}
@@ -0,0 +1,139 @@
+package discovery
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"slices"
+	"sync"
+	"testing"
+)
+
+// urls returns the URLs of the backends in p, sorted.
+func (p *fakePool) urls() []string {
+    p.mu.Lock()
+    defer p.mu.Unlock()
+    var urls []string
+    for url := range p.servers {
+        urls = append(urls, url)
+    }
+    slices.Sort(urls)
+    return urls
+}
+
+// listeners stands in for a subnet: each scanned address that is mapped to
+// an open net.Listen listener answers, every other one refuses.
+type listeners struct {
+    mu    sync.Mutex
+    addrs map[string]net.Listener
+}
+
+func (l *listeners) listen(t *testing.T, address string) {
+    t.Helper()
+    ln, err := net.Listen("tcp", "127.0.0.1:0")
+    if err != nil {
+        t.Fatal(err)
+    }
+    go func() {
+        for {
+            conn, err := ln.Accept()
+            if err != nil {
+                return
+            }
+            conn.Close()
+        }
+    }()
+    t.Cleanup(func() { ln.Close() })
+
+    l.mu.Lock()
+    defer l.mu.Unlock()
+    if l.addrs == nil {
+        l.addrs = make(map[string]net.Listener)
+    }
+    l.addrs[address] = ln
+}
+
+func (l *listeners) close(address string) {
+    l.mu.Lock()
+    defer l.mu.Unlock()
+    l.addrs[address].Close()
+}
+
+func (l *listeners) dial(ctx context.Context, network, address string) (net.Conn, error) {
+    l.mu.Lock()
+    ln, ok := l.addrs[address]
+    l.mu.Unlock()
+    if !ok {
+        return nil, &net.OpError{Op: "dial", Net: network, Err: net.UnknownNetworkError("no listener")}
+    }
+    return (&net.Dialer{}).DialContext(ctx, network, ln.Addr().String())
+}
+
+func TestCIDRDiscoveryConverges(t *testing.T) {
+    var subnet listeners
+    subnet.listen(t, "10.0.0.1:8080")
+    subnet.listen(t, "10.0.0.3:8080")
+
+    pool := newFakePool()
+    d, err := NewCIDRDiscovery(CIDRConfig{
+        Prefix:      netip.MustParsePrefix("10.0.0.0/29"),
+        Port:        8080,
+        RemoveAfter: 2,
+        DialContext: subnet.dial,
+    }, pool)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    d.Scan(context.Background())
+    want := []string{"http://10.0.0.1:8080", "http://10.0.0.3:8080"}
+    if got := pool.urls(); !slices.Equal(got, want) {
+        t.Fatalf("pool after first scan = %v, want %v", got, want)
+    }
+
+    subnet.close("10.0.0.3:8080")
+    d.Scan(context.Background())
+    if got := pool.urls(); !slices.Equal(got, want) {
+        t.Errorf("pool after one missed scan = %v, want %v kept", got, want)
+    }
+
+    d.Scan(context.Background())
+    want = []string{"http://10.0.0.1:8080"}
+    if got := pool.urls(); !slices.Equal(got, want) {
+        t.Errorf("pool after two missed scans = %v, want %v", got, want)
+    }
+}
+
+func TestCIDRDiscoveryMissesResetOnAnswer(t *testing.T) {
+    var subnet listeners
+    subnet.listen(t, "10.0.0.1:8080")
+
+    pool := newFakePool()
+    d, err := NewCIDRDiscovery(CIDRConfig{
+        Prefix:      netip.MustParsePrefix("10.0.0.1/32"),
+        Port:        8080,
+        RemoveAfter: 2,
+        DialContext: subnet.dial,
+    }, pool)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    d.Scan(context.Background())
+    subnet.close("10.0.0.1:8080")
+    d.Scan(context.Background())
+    subnet.listen(t, "10.0.0.1:8080")
+    d.Scan(context.Background())
+    subnet.close("10.0.0.1:8080")
+    d.Scan(context.Background())
+
+    if got := pool.urls(); len(got) != 1 {
+        t.Errorf("pool = %v, want the backend kept after misses that were not in a row", got)
+    }
+}
+
+func TestNewCIDRDiscoveryRejectsLargeRanges(t *testing.T) {
+    if _, err := NewCIDRDiscovery(CIDRConfig{Prefix: netip.MustParsePrefix("10.0.0.0/8"), Port: 80}, newFakePool()); err == nil {
+        t.Errorf("NewCIDRDiscovery accepted a range of more than %d addresses", MaxCIDRScanHosts)
+    }
+}
@@ -0,0 +1,26 @@
+// Package discovery keeps a balancer's backend pool in sync with an external
+// registry.
+package discovery
+
+import (
+	"time"
+
+	"github.com/Adi-ty/go-loadbalancer/internal/balancer"
+)
+
+const (
+    initialBackoff = 500 * time.Millisecond
+    maxBackoff     = 30 * time.Second
+)
+
+// Pool is the part of a balancer that discovery updates.
+type Pool interface {
+    Add(server *balancer.Server)
+    Remove(url string) *balancer.Server
+    UpdateWeight(host string, weight int) error
+}
+
+// nextBackoff doubles d up to maxBackoff.
+func nextBackoff(d time.Duration) time.Duration {
+    return min(d*2, maxBackoff)
+}
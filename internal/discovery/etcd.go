@@ -0,0 +1,189 @@
+package discovery
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Adi-ty/go-loadbalancer/internal/balancer"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// EtcdConfig configures EtcdDiscovery.
+type EtcdConfig struct {
+    Endpoints []string
+    Prefix    string // e.g. /backends/
+    Username  string
+    Password  string
+
+    // Configure, when set, is applied to every backend before it is added
+    // to the pool.
+    Configure func(*balancer.Server)
+
+    // Client talks to etcd. Defaults to a client for Endpoints.
+    Client EtcdClient
+}
+
+// EtcdClient is the part of the etcd client that EtcdDiscovery uses.
+type EtcdClient interface {
+    Get(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.GetResponse, error)
+    Watch(ctx context.Context, key string, opts ...clientv3.OpOption) clientv3.WatchChan
+    Close() error
+}
+
+// etcdBackend is the JSON value stored under each key.
+type etcdBackend struct {
+    URL    string `json:"url"`
+    Weight int    `json:"weight"`
+}
+
+// EtcdDiscovery watches a key prefix in etcd and mirrors it into the pool:
+// each key holds one backend as {"url": "...", "weight": 3}. Deleting a key
+// takes the backend out of rotation; requests already in flight to it
+// complete.
+type EtcdDiscovery struct {
+    cfg    EtcdConfig
+    pool   Pool
+    client EtcdClient
+
+    backends map[string]etcdBackend // Keyed by etcd key, URL normalised by NewServer
+}
+
+func NewEtcdDiscovery(cfg EtcdConfig, pool Pool) (*EtcdDiscovery, error) {
+    if cfg.Client == nil {
+        client, err := clientv3.New(clientv3.Config{
+            Endpoints:   cfg.Endpoints,
+            Username:    cfg.Username,
+            Password:    cfg.Password,
+            DialTimeout: 5 * time.Second,
+        })
+        if err != nil {
+            return nil, fmt.Errorf("failed to create etcd client: %w", err)
+        }
+        cfg.Client = client
+    }
+
+    return &EtcdDiscovery{
+        cfg:      cfg,
+        pool:     pool,
+        client:   cfg.Client,
+        backends: make(map[string]etcdBackend),
+    }, nil
+}
+
+// Run keeps the pool in sync until ctx is done, reconnecting with
+// exponential backoff whenever etcd fails.
+func (d *EtcdDiscovery) Run(ctx context.Context) {
+    defer d.client.Close()
+
+    backoff := initialBackoff
+    for {
+        err := d.sync(ctx, func() { backoff = initialBackoff })
+        if ctx.Err() != nil {
+            return
+        }
+
+        log.Printf("[DISCOVERY] etcd: %v, retrying in %s", err, backoff)
+        select {
+        case <-ctx.Done():
+            return
+        case <-time.After(backoff):
+        }
+        backoff = nextBackoff(backoff)
+    }
+}
+
+// sync loads the current backends under the prefix, then applies watch
+// events until the watch fails. connected is called once the initial load
+// succeeds.
+func (d *EtcdDiscovery) sync(ctx context.Context, connected func()) error {
+    resp, err := d.client.Get(ctx, d.cfg.Prefix, clientv3.WithPrefix())
+    if err != nil {
+        return err
+    }
+    connected()
+
+    seen := make(map[string]bool, len(resp.Kvs))
+    for _, kv := range resp.Kvs {
+        seen[string(kv.Key)] = true
+        d.put(string(kv.Key), kv.Value)
+    }
+    for key := range d.backends {
+        if !seen[key] {
+            d.delete(key)
+        }
+    }
+
+    watchCtx := clientv3.WithRequireLeader(ctx)
+    watch := d.client.Watch(watchCtx, d.cfg.Prefix, clientv3.WithPrefix(), clientv3.WithRev(resp.Header.Revision+1))
+    for wresp := range watch {
+        if err := wresp.Err(); err != nil {
+            return err
+        }
+        for _, ev := range wresp.Events {
+            switch ev.Type {
+            case clientv3.EventTypePut:
+                d.put(string(ev.Kv.Key), ev.Kv.Value)
+            case clientv3.EventTypeDelete:
+                d.delete(string(ev.Kv.Key))
+            }
+        }
+    }
+    return errors.New("watch closed")
+}
+
+// put adds the backend stored under key, or updates it if the key is
+// already known.
+func (d *EtcdDiscovery) put(key string, value []byte) {
+    var backend etcdBackend
+    if err := json.Unmarshal(value, &backend); err != nil {
+        log.Printf("[DISCOVERY] Ignoring %s: invalid JSON: %v", key, err)
+        return
+    }
+    if backend.Weight == 0 {
+        backend.Weight = 1
+    }
+
+    server, err := balancer.NewServer(backend.URL, backend.Weight)
+    if err != nil {
+        log.Printf("[DISCOVERY] Ignoring %s: %v", key, err)
+        return
+    }
+
+    if old, ok := d.backends[key]; ok {
+        if old.URL == server.URL.String() {
+            if old.Weight != backend.Weight {
+                if err := d.pool.UpdateWeight(server.URL.Host, backend.Weight); err != nil {
+                    log.Printf("[DISCOVERY] Failed to update %s: %v", key, err)
+                    return
+                }
+                d.backends[key] = etcdBackend{URL: old.URL, Weight: backend.Weight}
+            }
+            return
+        }
+        d.delete(key)
+    }
+
+    if d.cfg.Configure != nil {
+        d.cfg.Configure(server)
+    }
+    d.pool.Add(server)
+    d.backends[key] = etcdBackend{URL: server.URL.String(), Weight: backend.Weight}
+    log.Printf("[DISCOVERY] Added backend %s (Weight: %d) from %s", server.URL, backend.Weight, key)
+}
+
+// delete takes the backend stored under key out of the pool.
+func (d *EtcdDiscovery) delete(key string) {
+    backend, ok := d.backends[key]
+    if !ok {
+        return
+    }
+    delete(d.backends, key)
+
+    if d.pool.Remove(backend.URL) != nil {
+        log.Printf("[DISCOVERY] Removed backend %s after %s was deleted", backend.URL, key)
+    }
+}
@@ -0,0 +1,196 @@
+package discovery
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+
+	"github.com/Adi-ty/go-loadbalancer/internal/balancer"
+)
+
+// fakeEtcd stands in for an etcd cluster: Get returns the stored keys and
+// each Watch hands its channel to the test through watches.
+type fakeEtcd struct {
+    mu      sync.Mutex
+    kvs     map[string]string
+    watches chan chan clientv3.WatchResponse
+}
+
+func newFakeEtcd(kvs map[string]string) *fakeEtcd {
+    return &fakeEtcd{kvs: kvs, watches: make(chan chan clientv3.WatchResponse, 1)}
+}
+
+func (e *fakeEtcd) Get(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.GetResponse, error) {
+    e.mu.Lock()
+    defer e.mu.Unlock()
+
+    resp := &clientv3.GetResponse{Header: &pb.ResponseHeader{Revision: 1}}
+    for _, k := range slices.Sorted(maps.Keys(e.kvs)) {
+        resp.Kvs = append(resp.Kvs, &mvccpb.KeyValue{Key: []byte(k), Value: []byte(e.kvs[k])})
+    }
+    return resp, nil
+}
+
+// Watch relays what the test sends on the handed-out channel, closing the
+// returned one when the test closes its channel or ctx is done, as the real
+// client does.
+func (e *fakeEtcd) Watch(ctx context.Context, key string, opts ...clientv3.OpOption) clientv3.WatchChan {
+    events := make(chan clientv3.WatchResponse)
+    e.watches <- events
+
+    watch := make(chan clientv3.WatchResponse)
+    go func() {
+        defer close(watch)
+        for {
+            select {
+            case <-ctx.Done():
+                return
+            case wresp, ok := <-events:
+                if !ok {
+                    return
+                }
+                select {
+                case watch <- wresp:
+                case <-ctx.Done():
+                    return
+                }
+            }
+        }
+    }()
+    return watch
+}
+
+func (e *fakeEtcd) Close() error { return nil }
+
+func (e *fakeEtcd) set(kvs map[string]string) {
+    e.mu.Lock()
+    defer e.mu.Unlock()
+    e.kvs = kvs
+}
+
+// nextWatch returns the channel of the next watch discovery starts.
+func (e *fakeEtcd) nextWatch(t *testing.T) chan clientv3.WatchResponse {
+    t.Helper()
+    select {
+    case watch := <-e.watches:
+        return watch
+    case <-time.After(5 * time.Second):
+        t.Fatal("discovery did not start watching etcd")
+        return nil
+    }
+}
+
+func putEvent(key, value string) *clientv3.Event {
+    return &clientv3.Event{Type: clientv3.EventTypePut, Kv: &mvccpb.KeyValue{Key: []byte(key), Value: []byte(value)}}
+}
+
+func deleteEvent(key string) *clientv3.Event {
+    return &clientv3.Event{Type: clientv3.EventTypeDelete, Kv: &mvccpb.KeyValue{Key: []byte(key)}}
+}
+
+// fakePool records the backends discovery keeps in it.
+type fakePool struct {
+    mu      sync.Mutex
+    servers map[string]*balancer.Server
+}
+
+func newFakePool() *fakePool {
+    return &fakePool{servers: make(map[string]*balancer.Server)}
+}
+
+func (p *fakePool) Add(server *balancer.Server) {
+    p.mu.Lock()
+    defer p.mu.Unlock()
+    p.servers[server.URL.String()] = server
+}
+
+func (p *fakePool) Remove(url string) *balancer.Server {
+    p.mu.Lock()
+    defer p.mu.Unlock()
+    server := p.servers[url]
+    delete(p.servers, url)
+    return server
+}
+
+func (p *fakePool) UpdateWeight(host string, weight int) error {
+    p.mu.Lock()
+    defer p.mu.Unlock()
+    for _, server := range p.servers {
+        if server.URL.Host == host {
+            server.Weight = weight
+            return nil
+        }
+    }
+    return &balancer.ErrServerNotFound{URL: host}
+}
+
+// weights returns the weight of every backend in the pool by URL.
+func (p *fakePool) weights() map[string]int {
+    p.mu.Lock()
+    defer p.mu.Unlock()
+    weights := make(map[string]int, len(p.servers))
+    for url, server := range p.servers {
+        weights[url] = server.Weight
+    }
+    return weights
+}
+
+// waitForWeights waits for the pool to hold exactly want.
+func waitForWeights(t *testing.T, pool *fakePool, want map[string]int) {
+    t.Helper()
+    deadline := time.Now().Add(5 * time.Second)
+    for !maps.Equal(pool.weights(), want) {
+        if time.Now().After(deadline) {
+            t.Fatalf("pool = %v, want %v", pool.weights(), want)
+        }
+        time.Sleep(5 * time.Millisecond)
+    }
+}
+
+func TestEtcdDiscovery(t *testing.T) {
+    etcd := newFakeEtcd(map[string]string{
+        "/backends/a": `{"url":"http://10.0.0.1:8080","weight":2}`,
+        "/backends/b": `{"url":"http://10.0.0.2:8080"}`,
+        "/backends/x": `not json`,
+    })
+    pool := newFakePool()
+    d, err := NewEtcdDiscovery(EtcdConfig{Prefix: "/backends/", Client: etcd}, pool)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    ctx, cancel := context.WithCancel(context.Background())
+    done := make(chan struct{})
+    go func() {
+        d.Run(ctx)
+        close(done)
+    }()
+    t.Cleanup(func() {
+        cancel()
+        <-done
+    })
+
+    watch := etcd.nextWatch(t)
+    waitForWeights(t, pool, map[string]int{"http://10.0.0.1:8080": 2, "http://10.0.0.2:8080": 1})
+
+    watch <- clientv3.WatchResponse{Events: []*clientv3.Event{
+        putEvent("/backends/c", `{"url":"http://10.0.0.3:8080","weight":4}`),
+        putEvent("/backends/a", `{"url":"http://10.0.0.1:8080","weight":5}`),
+        deleteEvent("/backends/b"),
+    }}
+    waitForWeights(t, pool, map[string]int{"http://10.0.0.1:8080": 5, "http://10.0.0.3:8080": 4})
+
+    // c is deleted while the watch is down; the reload after reconnecting
+    // must notice.
+    etcd.set(map[string]string{"/backends/a": `{"url":"http://10.0.0.1:8080","weight":5}`})
+    close(watch)
+    etcd.nextWatch(t)
+    waitForWeights(t, pool, map[string]int{"http://10.0.0.1:8080": 5})
+}
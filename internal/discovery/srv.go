@@ -0,0 +1,189 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Adi-ty/go-loadbalancer/internal/balancer"
+)
+
+// DefaultSRVRefreshInterval is how often SRV records are re-resolved. Go's
+// resolver does not expose record TTLs, so a fixed interval stands in for
+// them.
+const DefaultSRVRefreshInterval = 30 * time.Second
+
+// maxSRVWeight is the balancer weight given to the heaviest SRV target.
+const maxSRVWeight = 100
+
+// SRVConfig configures SRVDiscovery.
+type SRVConfig struct {
+    // Name is the SRV record to resolve, e.g. _http._tcp.service.consul.
+    Name string
+
+    // Scheme of the backend URLs. Defaults to http.
+    Scheme string
+
+    // RefreshInterval defaults to DefaultSRVRefreshInterval.
+    RefreshInterval time.Duration
+
+    // Configure, when set, is applied to every backend before it is added
+    // to the pool.
+    Configure func(*balancer.Server)
+
+    // LookupSRV resolves the record. Defaults to net.DefaultResolver.
+    LookupSRV func(ctx context.Context, service, proto, name string) (string, []*net.SRV, error)
+}
+
+// SRVDiscovery mirrors the targets of a DNS SRV record into the pool. Only
+// targets at the lowest priority are used, and their SRV weights are scaled
+// so the heaviest gets weight 100 and the others keep their proportions.
+type SRVDiscovery struct {
+    cfg  SRVConfig
+    pool Pool
+
+    backends map[string]int // Weight keyed by backend URL
+}
+
+func NewSRVDiscovery(cfg SRVConfig, pool Pool) *SRVDiscovery {
+    if cfg.Scheme == "" {
+        cfg.Scheme = "http"
+    }
+    if cfg.RefreshInterval <= 0 {
+        cfg.RefreshInterval = DefaultSRVRefreshInterval
+    }
+    if cfg.LookupSRV == nil {
+        cfg.LookupSRV = net.DefaultResolver.LookupSRV
+    }
+
+    return &SRVDiscovery{
+        cfg:      cfg,
+        pool:     pool,
+        backends: make(map[string]int),
+    }
+}
+
+// Run resolves the record every RefreshInterval until ctx is done. Failed
+// lookups leave the pool as it is and are retried with exponential backoff.
+func (d *SRVDiscovery) Run(ctx context.Context) {
+    backoff := initialBackoff
+    for {
+        wait := d.cfg.RefreshInterval
+        if err := d.Refresh(ctx); err != nil {
+            if ctx.Err() != nil {
+                return
+            }
+            log.Printf("[DISCOVERY] SRV %s: %v, retrying in %s", d.cfg.Name, err, backoff)
+            wait = min(backoff, d.cfg.RefreshInterval)
+            backoff = nextBackoff(backoff)
+        } else {
+            backoff = initialBackoff
+        }
+
+        select {
+        case <-ctx.Done():
+            return
+        case <-time.After(wait):
+        }
+    }
+}
+
+// Refresh resolves the record once and brings the pool in line with it.
+func (d *SRVDiscovery) Refresh(ctx context.Context) error {
+    _, records, err := d.cfg.LookupSRV(ctx, "", "", d.cfg.Name)
+    if err != nil {
+        return err
+    }
+    if len(records) == 0 {
+        return fmt.Errorf("no SRV records")
+    }
+
+    wanted := srvWeights(records, d.cfg.Scheme)
+    for rawURL, weight := range wanted {
+        d.put(rawURL, weight)
+    }
+    for rawURL := range d.backends {
+        if _, ok := wanted[rawURL]; !ok {
+            d.delete(rawURL)
+        }
+    }
+    return nil
+}
+
+// srvWeights maps the lowest-priority targets in records to backend URLs
+// and their scaled weights.
+func srvWeights(records []*net.SRV, scheme string) map[string]int {
+    priority := records[0].Priority
+    var heaviest uint16
+    for _, rec := range records {
+        priority = min(priority, rec.Priority)
+    }
+    for _, rec := range records {
+        if rec.Priority == priority {
+            heaviest = max(heaviest, rec.Weight)
+        }
+    }
+
+    weights := make(map[string]int)
+    for _, rec := range records {
+        if rec.Priority != priority {
+            continue
+        }
+
+        weight := 1
+        if heaviest > 0 {
+            weight = max(1, int(math.Round(float64(rec.Weight)*maxSRVWeight/float64(heaviest))))
+        }
+
+        host := strings.TrimSuffix(rec.Target, ".")
+        rawURL := scheme + "://" + net.JoinHostPort(host, strconv.Itoa(int(rec.Port)))
+        weights[rawURL] = weight
+    }
+    return weights
+}
+
+// put adds the backend at rawURL, or updates its weight if it is already in
+// the pool.
+func (d *SRVDiscovery) put(rawURL string, weight int) {
+    if old, ok := d.backends[rawURL]; ok {
+        if old == weight {
+            return
+        }
+        u, err := url.Parse(rawURL)
+        if err != nil {
+            return
+        }
+        if err := d.pool.UpdateWeight(u.Host, weight); err != nil {
+            log.Printf("[DISCOVERY] Failed to update %s: %v", rawURL, err)
+            return
+        }
+        d.backends[rawURL] = weight
+        return
+    }
+
+    server, err := balancer.NewServer(rawURL, weight)
+    if err != nil {
+        log.Printf("[DISCOVERY] Ignoring SRV target %s: %v", rawURL, err)
+        return
+    }
+    if d.cfg.Configure != nil {
+        d.cfg.Configure(server)
+    }
+    d.pool.Add(server)
+    d.backends[rawURL] = weight
+    log.Printf("[DISCOVERY] Added backend %s (Weight: %d) from SRV %s", rawURL, weight, d.cfg.Name)
+}
+
+// delete takes a backend that is no longer in the record out of the pool.
+func (d *SRVDiscovery) delete(rawURL string) {
+    delete(d.backends, rawURL)
+    if d.pool.Remove(rawURL) != nil {
+        log.Printf("[DISCOVERY] Removed backend %s no longer in SRV %s", rawURL, d.cfg.Name)
+    }
+}
@@ -0,0 +1,73 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"net"
+	"sync"
+	"testing"
+)
+
+// fakeSRV answers lookups with records, or err when set.
+type fakeSRV struct {
+    mu      sync.Mutex
+    records []*net.SRV
+    err     error
+}
+
+func (f *fakeSRV) lookup(ctx context.Context, service, proto, name string) (string, []*net.SRV, error) {
+    f.mu.Lock()
+    defer f.mu.Unlock()
+    return name, f.records, f.err
+}
+
+func (f *fakeSRV) set(records []*net.SRV, err error) {
+    f.mu.Lock()
+    defer f.mu.Unlock()
+    f.records, f.err = records, err
+}
+
+func TestSRVDiscoveryWeights(t *testing.T) {
+    srv := &fakeSRV{records: []*net.SRV{
+        {Target: "a.service.consul.", Port: 8080, Priority: 1, Weight: 10},
+        {Target: "b.service.consul.", Port: 8081, Priority: 1, Weight: 30},
+        {Target: "backup.service.consul.", Port: 8080, Priority: 2, Weight: 50},
+    }}
+    pool := newFakePool()
+    d := NewSRVDiscovery(SRVConfig{Name: "_http._tcp.service.consul", LookupSRV: srv.lookup}, pool)
+
+    if err := d.Refresh(context.Background()); err != nil {
+        t.Fatal(err)
+    }
+    weights := pool.weights()
+    want := map[string]int{"http://a.service.consul:8080": 33, "http://b.service.consul:8081": 100}
+    if !maps.Equal(weights, want) {
+        t.Fatalf("pool = %v, want %v", weights, want)
+    }
+    if ratio := float64(weights["http://b.service.consul:8081"]) / float64(weights["http://a.service.consul:8080"]); ratio < 2.9 || ratio > 3.1 {
+        t.Errorf("weight ratio = 1:%.2f, want 1:3 as in the SRV records", ratio)
+    }
+
+    // A failed lookup leaves the pool alone.
+    srv.set(nil, errors.New("no such host"))
+    if err := d.Refresh(context.Background()); err == nil {
+        t.Error("Refresh succeeded with a failing lookup")
+    }
+    if got := pool.weights(); !maps.Equal(got, want) {
+        t.Errorf("pool after a failed lookup = %v, want %v", got, want)
+    }
+
+    // Targets that change weight are updated, vanished ones removed.
+    srv.set([]*net.SRV{
+        {Target: "a.service.consul.", Port: 8080, Priority: 1, Weight: 20},
+        {Target: "c.service.consul.", Port: 8082, Priority: 1, Weight: 0},
+    }, nil)
+    if err := d.Refresh(context.Background()); err != nil {
+        t.Fatal(err)
+    }
+    want = map[string]int{"http://a.service.consul:8080": 100, "http://c.service.consul:8082": 1}
+    if got := pool.weights(); !maps.Equal(got, want) {
+        t.Errorf("pool after the records changed = %v, want %v", got, want)
+    }
+}
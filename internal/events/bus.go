@@ -0,0 +1,63 @@
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// Event types published by the balancer.
+const (
+    BackendAdded         = "BackendAdded"
+    BackendRemoved       = "BackendRemoved"
+    BackendHealthChanged = "BackendHealthChanged"
+    RequestRouted        = "RequestRouted"
+)
+
+// Event describes something that happened to the balancer.
+type Event struct {
+    Type string
+    Time time.Time
+
+    // Backend is the URL of the backend the event concerns.
+    Backend string
+
+    // Healthy is the new health status for BackendHealthChanged events.
+    Healthy bool
+}
+
+// EventBus fans events out to subscribers. Handlers run in their own
+// goroutines, so Publish never blocks on a slow subscriber.
+type EventBus struct {
+    mu       sync.RWMutex
+    handlers map[string][]func(Event)
+}
+
+func NewEventBus() *EventBus {
+    return &EventBus{
+        handlers: make(map[string][]func(Event)),
+    }
+}
+
+// Subscribe registers handler for events of eventType.
+func (b *EventBus) Subscribe(eventType string, handler func(Event)) {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+
+    b.handlers[eventType] = append(b.handlers[eventType], handler)
+}
+
+// Publish delivers e to every subscriber of e.Type. A zero e.Time is set to
+// the current time.
+func (b *EventBus) Publish(e Event) {
+    if e.Time.IsZero() {
+        e.Time = time.Now()
+    }
+
+    b.mu.RLock()
+    handlers := b.handlers[e.Type]
+    b.mu.RUnlock()
+
+    for _, handler := range handlers {
+        go handler(e)
+    }
+}
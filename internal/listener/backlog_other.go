@@ -0,0 +1,15 @@
+//go:build !unix
+
+package listener
+
+import (
+	"log"
+	"net"
+)
+
+// setBacklog is a no-op on platforms where the accept queue cannot be resized
+// after the socket is listening.
+func setBacklog(ln net.Listener, backlog int) error {
+    log.Printf("[WARN] Listen backlog %d is not supported on this platform, using the system default", backlog)
+    return nil
+}
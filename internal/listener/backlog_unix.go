@@ -0,0 +1,38 @@
+//go:build unix
+
+package listener
+
+import (
+	"fmt"
+	"net"
+	"syscall"
+)
+
+// setBacklog re-issues listen(2) on the already listening socket with the
+// requested backlog. Go always listens with the system maximum, and both
+// Linux and the BSDs (including macOS) accept a second listen call to resize
+// the accept queue. The kernel still silently caps the value: on Linux at
+// net.core.somaxconn, on macOS at kern.ipc.somaxconn.
+func setBacklog(ln net.Listener, backlog int) error {
+    tcpLn, ok := ln.(*net.TCPListener)
+    if !ok {
+        return fmt.Errorf("listen backlog requires a TCP listener, got %T", ln)
+    }
+
+    rawConn, err := tcpLn.SyscallConn()
+    if err != nil {
+        return err
+    }
+
+    var listenErr error
+    err = rawConn.Control(func(fd uintptr) {
+        listenErr = syscall.Listen(int(fd), backlog)
+    })
+    if err != nil {
+        return err
+    }
+    if listenErr != nil {
+        return fmt.Errorf("failed to set listen backlog to %d: %w", backlog, listenErr)
+    }
+    return nil
+}
@@ -0,0 +1,63 @@
+package listener
+
+import (
+	"context"
+	"net"
+)
+
+// Config controls how the balancer's TCP listener is created.
+type Config struct {
+    // Backlog is the requested length of the kernel accept queue. Zero keeps
+    // the platform default (net.core.somaxconn on Linux,
+    // kern.ipc.somaxconn on macOS).
+    Backlog int
+
+    // ReusePort sets SO_REUSEPORT on the socket so several listeners can
+    // bind the same address and the kernel spreads incoming connections
+    // between them.
+    ReusePort bool
+}
+
+// Listen opens a TCP listener on addr configured according to cfg.
+func Listen(ctx context.Context, addr string, cfg Config) (net.Listener, error) {
+    var lc net.ListenConfig
+    if cfg.ReusePort {
+        lc.Control = reusePortControl
+    }
+
+    ln, err := lc.Listen(ctx, "tcp", addr)
+    if err != nil {
+        return nil, err
+    }
+
+    if cfg.Backlog > 0 {
+        if err := setBacklog(ln, cfg.Backlog); err != nil {
+            ln.Close()
+            return nil, err
+        }
+    }
+
+    return ln, nil
+}
+
+// ListenMany opens n listeners on addr. When n is greater than one the
+// listeners are created with SO_REUSEPORT so each can run its own accept loop.
+func ListenMany(ctx context.Context, addr string, n int, cfg Config) ([]net.Listener, error) {
+    if n > 1 {
+        cfg.ReusePort = true
+    }
+
+    listeners := make([]net.Listener, 0, n)
+    for i := 0; i < n; i++ {
+        ln, err := Listen(ctx, addr, cfg)
+        if err != nil {
+            for _, opened := range listeners {
+                opened.Close()
+            }
+            return nil, err
+        }
+        listeners = append(listeners, ln)
+    }
+
+    return listeners, nil
+}
@@ -0,0 +1,25 @@
+package listener
+
+import (
+	"context"
+	"net"
+	"testing"
+)
+
+func TestListenBacklog(t *testing.T) {
+    for _, backlog := range []int{0, 1, 128, 4096, 65535} {
+        ln, err := Listen(context.Background(), "127.0.0.1:0", Config{Backlog: backlog})
+        if err != nil {
+            t.Errorf("Listen with backlog %d: %v", backlog, err)
+            continue
+        }
+
+        conn, err := net.Dial("tcp", ln.Addr().String())
+        if err != nil {
+            t.Errorf("dialling listener with backlog %d: %v", backlog, err)
+        } else {
+            conn.Close()
+        }
+        ln.Close()
+    }
+}
@@ -0,0 +1,55 @@
+//go:build linux
+
+// Only Linux spreads connections between SO_REUSEPORT listeners; the BSDs
+// send them all to one.
+
+package listener
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"testing"
+)
+
+func TestListenManyBalancesAccepts(t *testing.T) {
+    // Find a free port for all the listeners to share.
+    probe, err := net.Listen("tcp", "127.0.0.1:0")
+    if err != nil {
+        t.Fatal(err)
+    }
+    addr := probe.Addr().String()
+    probe.Close()
+
+    listeners, err := ListenMany(context.Background(), addr, 2, Config{})
+    if err != nil {
+        t.Fatalf("ListenMany: %v", err)
+    }
+
+    counts := make([]atomic.Int64, len(listeners))
+    for i, ln := range listeners {
+        srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+            counts[i].Add(1)
+        })}
+        go srv.Serve(ln)
+        t.Cleanup(func() { srv.Close() })
+    }
+
+    // A new connection per request, so the kernel picks a listener each time.
+    client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+    const n = 200
+    for i := 0; i < n; i++ {
+        resp, err := client.Get("http://" + addr)
+        if err != nil {
+            t.Fatalf("request %d: %v", i, err)
+        }
+        resp.Body.Close()
+    }
+
+    for i := range counts {
+        if got := counts[i].Load(); got < n/5 {
+            t.Errorf("listener %d accepted %d of %d requests, want a fair share", i, got, n)
+        }
+    }
+}
@@ -0,0 +1,152 @@
+// Package weightsync shares backend weights between balancer instances
+// through an external store.
+package weightsync
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/Adi-ty/go-loadbalancer/internal/balancer"
+	"github.com/redis/go-redis/v9"
+)
+
+// Defaults for RedisWeightSyncConfig.
+const (
+    DefaultRedisWeightsKey   = "lb:weights"
+    DefaultRedisSyncInterval = 10 * time.Second
+)
+
+// WeightPool is the part of a balancer whose weights are synced.
+type WeightPool interface {
+    All() []*balancer.Server
+    UpdateWeight(host string, weight int) error
+}
+
+// RedisWeightSyncConfig configures RedisWeightSync.
+type RedisWeightSyncConfig struct {
+    Addr     string
+    Username string
+    Password string
+    DB       int
+
+    // Key is the Redis hash holding weights, one field per backend host.
+    // Defaults to DefaultRedisWeightsKey.
+    Key string
+
+    // SyncInterval is how often weights are read from Redis. Defaults to
+    // DefaultRedisSyncInterval.
+    SyncInterval time.Duration
+}
+
+// RedisWeightSync keeps backend weights consistent across balancer instances
+// by storing them in a Redis hash that maps each backend host, e.g.
+// 10.0.0.5:8080, to its weight. Every instance applies the hash to its pool
+// each SyncInterval, and weight changes made through UpdateWeight are written
+// to the hash, so all instances converge on them. Backends not in the hash
+// keep their local weight.
+type RedisWeightSync struct {
+    cfg    RedisWeightSyncConfig
+    pool   WeightPool
+    client *redis.Client
+}
+
+func NewRedisWeightSync(cfg RedisWeightSyncConfig, pool WeightPool) *RedisWeightSync {
+    if cfg.Key == "" {
+        cfg.Key = DefaultRedisWeightsKey
+    }
+    if cfg.SyncInterval <= 0 {
+        cfg.SyncInterval = DefaultRedisSyncInterval
+    }
+
+    return &RedisWeightSync{
+        cfg:  cfg,
+        pool: pool,
+        client: redis.NewClient(&redis.Options{
+            Addr:     cfg.Addr,
+            Username: cfg.Username,
+            Password: cfg.Password,
+            DB:       cfg.DB,
+        }),
+    }
+}
+
+// Run applies the weights in Redis to the pool every SyncInterval until ctx
+// is done. Failed syncs are logged and retried on the next tick.
+func (s *RedisWeightSync) Run(ctx context.Context) {
+    defer s.client.Close()
+
+    ticker := time.NewTicker(s.cfg.SyncInterval)
+    defer ticker.Stop()
+
+    for {
+        if err := s.Sync(ctx); err != nil && ctx.Err() == nil {
+            log.Printf("[SYNC] Redis: %v", err)
+        }
+
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+        }
+    }
+}
+
+// Sync reads the weights hash once and applies every weight that differs
+// from the pool's. Invalid entries and unknown hosts are skipped.
+func (s *RedisWeightSync) Sync(ctx context.Context) error {
+    weights, err := s.client.HGetAll(ctx, s.cfg.Key).Result()
+    if err != nil {
+        return fmt.Errorf("failed to read %s: %w", s.cfg.Key, err)
+    }
+
+    for _, server := range s.pool.All() {
+        value, ok := weights[server.URL.Host]
+        if !ok {
+            continue
+        }
+        weight, err := strconv.Atoi(value)
+        if err != nil {
+            log.Printf("[SYNC] Ignoring weight %q for %s in %s: not an integer", value, server.URL.Host, s.cfg.Key)
+            continue
+        }
+        if weight == server.Weight {
+            continue
+        }
+        if err := s.pool.UpdateWeight(server.URL.Host, weight); err != nil {
+            log.Printf("[SYNC] Ignoring weight for %s in %s: %v", server.URL.Host, s.cfg.Key, err)
+        }
+    }
+    return nil
+}
+
+// UpdateWeight changes the weight of the backend identified by host in Redis
+// and then in the local pool, so the pool never holds a weight the other
+// instances will not pick up on their next sync. The write is a single
+// HSET, which Redis applies atomically, so concurrent updates need no
+// transaction: the last one wins everywhere.
+func (s *RedisWeightSync) UpdateWeight(ctx context.Context, host string, weight int) error {
+    if !s.inPool(host) {
+        return &balancer.ErrServerNotFound{URL: host}
+    }
+    if weight < 1 || weight > balancer.MaxWeight {
+        return &balancer.ErrInvalidWeight{URL: host, Weight: weight}
+    }
+
+    if err := s.client.HSet(ctx, s.cfg.Key, host, weight).Err(); err != nil {
+        return fmt.Errorf("failed to write weight of %s to %s: %w", host, s.cfg.Key, err)
+    }
+    return s.pool.UpdateWeight(host, weight)
+}
+
+// inPool reports whether the pool has a backend identified by host.
+func (s *RedisWeightSync) inPool(host string) bool {
+    for _, server := range s.pool.All() {
+        if server.URL.Host == host {
+            return true
+        }
+    }
+    return false
+}
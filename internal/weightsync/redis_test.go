@@ -0,0 +1,103 @@
+package weightsync
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+
+	"github.com/Adi-ty/go-loadbalancer/internal/balancer"
+)
+
+var testHosts = []string{"10.0.0.1:8080", "10.0.0.2:8080"}
+
+// newInstance returns a balancer with the test backends at weight 1, synced
+// through the Redis at addr.
+func newInstance(t *testing.T, addr string) (*balancer.WeightedLeastConnection, *RedisWeightSync) {
+    t.Helper()
+    var servers []*balancer.Server
+    for _, host := range testHosts {
+        server, err := balancer.NewServer("http://"+host, 1)
+        if err != nil {
+            t.Fatal(err)
+        }
+        servers = append(servers, server)
+    }
+    wlc := balancer.NewWeightedLeastConnection(servers)
+    sync := NewRedisWeightSync(RedisWeightSyncConfig{Addr: addr}, wlc)
+    t.Cleanup(func() { sync.client.Close() })
+    return wlc, sync
+}
+
+func weightOf(wlc *balancer.WeightedLeastConnection, host string) int {
+    for _, server := range wlc.All() {
+        if server.URL.Host == host {
+            return server.Weight
+        }
+    }
+    return 0
+}
+
+func TestRedisWeightSyncConverges(t *testing.T) {
+    mr := miniredis.RunT(t)
+    ctx := context.Background()
+    first, firstSync := newInstance(t, mr.Addr())
+    second, secondSync := newInstance(t, mr.Addr())
+
+    if err := firstSync.UpdateWeight(ctx, testHosts[0], 7); err != nil {
+        t.Fatalf("UpdateWeight: %v", err)
+    }
+    if err := secondSync.UpdateWeight(ctx, testHosts[1], 3); err != nil {
+        t.Fatalf("UpdateWeight: %v", err)
+    }
+    for _, sync := range []*RedisWeightSync{firstSync, secondSync} {
+        if err := sync.Sync(ctx); err != nil {
+            t.Fatalf("Sync: %v", err)
+        }
+    }
+
+    want := map[string]int{testHosts[0]: 7, testHosts[1]: 3}
+    for host, weight := range want {
+        if got := mr.HGet(DefaultRedisWeightsKey, host); got != strconv.Itoa(weight) {
+            t.Errorf("Redis weight of %s = %q, want %d", host, got, weight)
+        }
+        for name, wlc := range map[string]*balancer.WeightedLeastConnection{"first": first, "second": second} {
+            if got := weightOf(wlc, host); got != weight {
+                t.Errorf("%s instance weight of %s = %d, want %d", name, host, got, weight)
+            }
+        }
+    }
+}
+
+func TestRedisWeightSyncWritesRedisFirst(t *testing.T) {
+    mr := miniredis.RunT(t)
+    wlc, sync := newInstance(t, mr.Addr())
+    mr.Close()
+
+    if err := sync.UpdateWeight(context.Background(), testHosts[0], 5); err == nil {
+        t.Fatal("UpdateWeight succeeded with Redis down")
+    }
+    if got := weightOf(wlc, testHosts[0]); got != 1 {
+        t.Errorf("local weight = %d after a failed Redis write, want it unchanged at 1", got)
+    }
+}
+
+func TestRedisWeightSyncRejectsBeforeWriting(t *testing.T) {
+    mr := miniredis.RunT(t)
+    _, sync := newInstance(t, mr.Addr())
+    ctx := context.Background()
+
+    var notFound *balancer.ErrServerNotFound
+    if err := sync.UpdateWeight(ctx, "10.0.0.9:8080", 5); !errors.As(err, &notFound) {
+        t.Errorf("UpdateWeight of an unknown host = %v, want ErrServerNotFound", err)
+    }
+    var invalid *balancer.ErrInvalidWeight
+    if err := sync.UpdateWeight(ctx, testHosts[0], balancer.MaxWeight+1); !errors.As(err, &invalid) {
+        t.Errorf("UpdateWeight with weight %d = %v, want ErrInvalidWeight", balancer.MaxWeight+1, err)
+    }
+    if mr.Exists(DefaultRedisWeightsKey) {
+        t.Errorf("rejected weights were written to Redis")
+    }
+}
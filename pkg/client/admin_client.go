@@ -0,0 +1,120 @@
+// Package client is a Go client for the load balancer's admin API.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// AdminClient talks to the admin API served on --admin-addr.
+type AdminClient struct {
+    // BaseURL is the admin API root, e.g. http://127.0.0.1:9090.
+    BaseURL string
+    // Token, when set, is sent as a bearer token.
+    Token string
+    // HTTPClient is used for requests. nil means http.DefaultClient.
+    HTTPClient *http.Client
+}
+
+// APIError is returned when the admin API answers with a non-2xx status.
+type APIError struct {
+    StatusCode int
+    Message    string
+}
+
+func (e *APIError) Error() string {
+    return fmt.Sprintf("admin API returned %d: %s", e.StatusCode, e.Message)
+}
+
+// ListBackends returns every backend in the pool.
+func (c *AdminClient) ListBackends(ctx context.Context) ([]BackendInfo, error) {
+    var backends []BackendInfo
+    if err := c.do(ctx, http.MethodGet, "/admin/backends", nil, &backends); err != nil {
+        return nil, err
+    }
+    return backends, nil
+}
+
+// AddBackend adds a backend to the pool.
+func (c *AdminClient) AddBackend(ctx context.Context, spec BackendSpec) error {
+    return c.do(ctx, http.MethodPost, "/admin/backends", spec, nil)
+}
+
+// RemoveBackend takes the backend with the given host out of the pool.
+func (c *AdminClient) RemoveBackend(ctx context.Context, host string) error {
+    return c.do(ctx, http.MethodDelete, "/admin/backends/"+url.PathEscape(host), nil, nil)
+}
+
+// UpdateWeight changes the weight of the backend with the given host.
+func (c *AdminClient) UpdateWeight(ctx context.Context, host string, weight int) error {
+    body := struct {
+        Weight int `json:"weight"`
+    }{weight}
+    return c.do(ctx, http.MethodPut, "/admin/backends/"+url.PathEscape(host)+"/weight", body, nil)
+}
+
+// GetMetrics returns the pool's performance report.
+func (c *AdminClient) GetMetrics(ctx context.Context) (MetricsSnapshot, error) {
+    var snapshot MetricsSnapshot
+    err := c.do(ctx, http.MethodGet, "/admin/metrics", nil, &snapshot)
+    return snapshot, err
+}
+
+// do sends a request with in encoded as the JSON body, if non-nil, and
+// decodes a successful response into out, if non-nil.
+func (c *AdminClient) do(ctx context.Context, method, path string, in, out any) error {
+    var body io.Reader
+    if in != nil {
+        data, err := json.Marshal(in)
+        if err != nil {
+            return fmt.Errorf("failed to encode request: %w", err)
+        }
+        body = bytes.NewReader(data)
+    }
+
+    req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(c.BaseURL, "/")+path, body)
+    if err != nil {
+        return err
+    }
+    if in != nil {
+        req.Header.Set("Content-Type", "application/json")
+    }
+    if c.Token != "" {
+        req.Header.Set("Authorization", "Bearer "+c.Token)
+    }
+
+    httpClient := c.HTTPClient
+    if httpClient == nil {
+        httpClient = http.DefaultClient
+    }
+
+    resp, err := httpClient.Do(req)
+    if err != nil {
+        return err
+    }
+    defer resp.Body.Close()
+
+    if resp.StatusCode < 200 || resp.StatusCode > 299 {
+        var apiErr struct {
+            Error string `json:"error"`
+        }
+        if err := json.NewDecoder(resp.Body).Decode(&apiErr); err != nil || apiErr.Error == "" {
+            apiErr.Error = http.StatusText(resp.StatusCode)
+        }
+        return &APIError{StatusCode: resp.StatusCode, Message: apiErr.Error}
+    }
+
+    if out == nil {
+        return nil
+    }
+    if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+        return fmt.Errorf("failed to decode response: %w", err)
+    }
+    return nil
+}
@@ -0,0 +1,132 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// mockAdmin is a fake admin API that records the last request it received.
+type mockAdmin struct {
+    method, path, auth, body string
+}
+
+func newMockAdmin(t *testing.T) (*mockAdmin, *AdminClient) {
+    t.Helper()
+
+    m := &mockAdmin{}
+    mux := http.NewServeMux()
+    mux.HandleFunc("GET /admin/backends", func(w http.ResponseWriter, r *http.Request) {
+        json.NewEncoder(w).Encode([]BackendInfo{
+            {URL: "http://10.0.0.1:8080", Host: "10.0.0.1:8080", Weight: 2, Status: "healthy", TotalRequests: 7},
+        })
+    })
+    mux.HandleFunc("POST /admin/backends", func(w http.ResponseWriter, r *http.Request) {
+        w.WriteHeader(http.StatusCreated)
+    })
+    mux.HandleFunc("DELETE /admin/backends/{host}", func(w http.ResponseWriter, r *http.Request) {
+        if r.PathValue("host") != "10.0.0.1:8080" {
+            w.WriteHeader(http.StatusNotFound)
+            w.Write([]byte(`{"error":"backend not found"}`))
+        }
+    })
+    mux.HandleFunc("PUT /admin/backends/{host}/weight", func(w http.ResponseWriter, r *http.Request) {})
+    mux.HandleFunc("GET /admin/metrics", func(w http.ResponseWriter, r *http.Request) {
+        json.NewEncoder(w).Encode(MetricsSnapshot{TotalRequests: 100, HealthyBackends: 1, TotalBackends: 2})
+    })
+
+    srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        body, _ := io.ReadAll(r.Body)
+        m.method, m.path, m.auth, m.body = r.Method, r.URL.EscapedPath(), r.Header.Get("Authorization"), string(body)
+        mux.ServeHTTP(w, r)
+    }))
+    t.Cleanup(srv.Close)
+
+    return m, &AdminClient{BaseURL: srv.URL + "/", Token: "secret"}
+}
+
+func TestAdminClientListBackends(t *testing.T) {
+    m, c := newMockAdmin(t)
+
+    backends, err := c.ListBackends(context.Background())
+    if err != nil {
+        t.Fatal(err)
+    }
+    want := []BackendInfo{{URL: "http://10.0.0.1:8080", Host: "10.0.0.1:8080", Weight: 2, Status: "healthy", TotalRequests: 7}}
+    if !reflect.DeepEqual(backends, want) {
+        t.Errorf("ListBackends() = %+v, want %+v", backends, want)
+    }
+    if m.auth != "Bearer secret" {
+        t.Errorf("Authorization = %q, want the bearer token", m.auth)
+    }
+}
+
+func TestAdminClientRequests(t *testing.T) {
+    ctx := context.Background()
+    tests := []struct {
+        name               string
+        call               func(c *AdminClient) error
+        method, path, body string
+    }{
+        {
+            name:   "AddBackend",
+            call:   func(c *AdminClient) error { return c.AddBackend(ctx, BackendSpec{URL: "http://10.0.0.2:8080", Weight: 3}) },
+            method: http.MethodPost, path: "/admin/backends", body: `{"url":"http://10.0.0.2:8080","weight":3}`,
+        },
+        {
+            name:   "RemoveBackend",
+            call:   func(c *AdminClient) error { return c.RemoveBackend(ctx, "10.0.0.1:8080") },
+            method: http.MethodDelete, path: "/admin/backends/10.0.0.1:8080",
+        },
+        {
+            name:   "UpdateWeight",
+            call:   func(c *AdminClient) error { return c.UpdateWeight(ctx, "10.0.0.1:8080", 5) },
+            method: http.MethodPut, path: "/admin/backends/10.0.0.1:8080/weight", body: `{"weight":5}`,
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            m, c := newMockAdmin(t)
+            if err := tt.call(c); err != nil {
+                t.Fatal(err)
+            }
+            if m.method != tt.method || m.path != tt.path {
+                t.Errorf("sent %s %s, want %s %s", m.method, m.path, tt.method, tt.path)
+            }
+            if m.body != tt.body {
+                t.Errorf("sent body %q, want %q", m.body, tt.body)
+            }
+        })
+    }
+}
+
+func TestAdminClientGetMetrics(t *testing.T) {
+    _, c := newMockAdmin(t)
+
+    snapshot, err := c.GetMetrics(context.Background())
+    if err != nil {
+        t.Fatal(err)
+    }
+    if snapshot.TotalRequests != 100 || snapshot.HealthyBackends != 1 || snapshot.TotalBackends != 2 {
+        t.Errorf("GetMetrics() = %+v, want 100 requests and 1 of 2 backends healthy", snapshot)
+    }
+}
+
+func TestAdminClientAPIError(t *testing.T) {
+    _, c := newMockAdmin(t)
+
+    err := c.RemoveBackend(context.Background(), "10.0.0.9:8080")
+    var apiErr *APIError
+    if !errors.As(err, &apiErr) {
+        t.Fatalf("RemoveBackend of an unknown host = %v, want an *APIError", err)
+    }
+    if apiErr.StatusCode != http.StatusNotFound || apiErr.Message != "backend not found" {
+        t.Errorf("APIError = %+v, want 404 with the server's message", apiErr)
+    }
+}
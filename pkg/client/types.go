@@ -0,0 +1,44 @@
+package client
+
+// BackendInfo describes a backend as reported by GET /admin/backends.
+type BackendInfo struct {
+    URL               string `json:"url"`
+    Host              string `json:"host"`
+    Weight            int    `json:"weight"`
+    Status            string `json:"status"`
+    ActiveConnections int32  `json:"active_connections"`
+    PeakConnections   int32  `json:"peak_connections"`
+    TotalRequests     uint64 `json:"total_requests"`
+    FailureCount      uint32 `json:"failure_count"`
+}
+
+// BackendSpec describes a backend to add to the pool. A zero Weight means 1.
+type BackendSpec struct {
+    URL    string `json:"url"`
+    Weight int    `json:"weight,omitempty"`
+}
+
+// MetricsSnapshot is the pool performance report returned by
+// GET /admin/metrics.
+type MetricsSnapshot struct {
+    UptimeSeconds     float64          `json:"uptime_seconds"`
+    TotalRequests     uint64           `json:"total_requests"`
+    RequestsPerSecond float64          `json:"requests_per_second"`
+    P50LatencyMs      float64          `json:"p50_latency_ms"`
+    P99LatencyMs      float64          `json:"p99_latency_ms"`
+    HealthyBackends   int              `json:"healthy_backends"`
+    TotalBackends     int              `json:"total_backends"`
+    Backends          []BackendMetrics `json:"backends"`
+}
+
+// BackendMetrics summarises a single backend in a MetricsSnapshot.
+type BackendMetrics struct {
+    URL               string  `json:"url"`
+    Status            string  `json:"status"`
+    Weight            int     `json:"weight"`
+    ActiveConnections int32   `json:"active_connections"`
+    TotalRequests     uint64  `json:"total_requests"`
+    ErrorRate         float64 `json:"error_rate"`
+    P50LatencyMs      float64 `json:"p50_latency_ms"`
+    P99LatencyMs      float64 `json:"p99_latency_ms"`
+}
@@ -0,0 +1,239 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Access log formats accepted by AccessLogConfig.Format.
+const (
+    AccessLogFormatJSON     = "json"
+    AccessLogFormatCLF      = "clf"
+    AccessLogFormatCombined = "combined"
+)
+
+// clfTimeFormat is the %t timestamp layout used by Apache.
+const clfTimeFormat = "02/Jan/2006:15:04:05 -0700"
+
+// AccessLogConfig configures the access log middleware.
+type AccessLogConfig struct {
+    // Output receives one line per request.
+    Output io.Writer
+
+    // Format is one of "json" (the default), "clf" for Apache Common Log
+    // Format, or "combined" which appends the referer and user agent.
+    Format string
+
+    // BodyPreviewBytes, when positive, records up to that many bytes of the
+    // request body as base64 in the JSON request_body_preview field.
+    BodyPreviewBytes int
+
+    // SampleRate is the fraction, 0-1, of 2xx and 3xx responses that are
+    // logged, chosen at random. 4xx and 5xx responses are always logged.
+    // Zero logs no 2xx and 3xx responses; set it to 1 to log every request.
+    SampleRate float64
+}
+
+// AccessLogMiddleware writes an access log line for every request served by
+// the wrapped handler.
+type AccessLogMiddleware struct {
+    next http.Handler
+    cfg  AccessLogConfig
+    mu   sync.Mutex // Serialises writes to cfg.Output
+}
+
+// AccessLog returns NewAccessLogMiddleware as a
+// func(http.Handler) http.Handler.
+func AccessLog(cfg AccessLogConfig) func(http.Handler) http.Handler {
+    return func(next http.Handler) http.Handler {
+        return NewAccessLogMiddleware(next, cfg)
+    }
+}
+
+func NewAccessLogMiddleware(next http.Handler, cfg AccessLogConfig) *AccessLogMiddleware {
+    return &AccessLogMiddleware{
+        next: next,
+        cfg:  cfg,
+    }
+}
+
+type accessLogEntry struct {
+    Timestamp  string  `json:"timestamp"`
+    ClientIP   string  `json:"client_ip"`
+    Method     string  `json:"method"`
+    Path       string  `json:"path"`
+    StatusCode int     `json:"status_code"`
+    BytesSent  int64   `json:"bytes_sent"`
+    DurationMs float64 `json:"duration_ms"`
+    Backend    string  `json:"backend"`
+    RequestID  string  `json:"request_id"`
+
+    RequestBodyPreview string `json:"request_body_preview,omitempty"`
+}
+
+func (m *AccessLogMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+    start := time.Now()
+    rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+
+    if m.cfg.BodyPreviewBytes > 0 && r.Body != nil && r.Body != http.NoBody {
+        rec.bodyPreview = previewBody(r, m.cfg.BodyPreviewBytes)
+    }
+
+    m.next.ServeHTTP(rec, r)
+
+    if !m.sampled(rec.status) {
+        return
+    }
+
+    var line []byte
+    switch m.cfg.Format {
+    case AccessLogFormatCLF:
+        line = formatCLF(r, rec, start, false)
+    case AccessLogFormatCombined:
+        line = formatCLF(r, rec, start, true)
+    default:
+        var err error
+        line, err = formatJSON(r, rec, start)
+        if err != nil {
+            log.Printf("[ACCESS] Failed to encode access log entry: %v", err)
+            return
+        }
+    }
+    line = append(line, '\n')
+
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    if _, err := m.cfg.Output.Write(line); err != nil {
+        log.Printf("[ACCESS] Failed to write access log: %v", err)
+    }
+}
+
+// sampled reports whether the line for a response with status should be
+// written.
+func (m *AccessLogMiddleware) sampled(status int) bool {
+    rate := m.cfg.SampleRate
+    if rate >= 1 || status >= http.StatusBadRequest {
+        return true
+    }
+    return rand.Float64() < rate
+}
+
+func formatJSON(r *http.Request, rec *responseRecorder, start time.Time) ([]byte, error) {
+    entry := accessLogEntry{
+        Timestamp:  start.UTC().Format(time.RFC3339Nano),
+        ClientIP:   clientIP(r),
+        Method:     r.Method,
+        Path:       r.URL.Path,
+        StatusCode: rec.status,
+        BytesSent:  rec.bytes,
+        DurationMs: float64(time.Since(start).Microseconds()) / 1000,
+        Backend:    rec.backend,
+        RequestID:  r.Header.Get("X-Request-ID"),
+    }
+    if len(rec.bodyPreview) > 0 {
+        entry.RequestBodyPreview = base64.StdEncoding.EncodeToString(rec.bodyPreview)
+    }
+    return json.Marshal(entry)
+}
+
+// previewBody reads up to n bytes from the request body and re-attaches them
+// in front of the unread remainder so the full body is still forwarded.
+func previewBody(r *http.Request, n int) []byte {
+    preview := make([]byte, n)
+    read, err := io.ReadFull(r.Body, preview)
+    preview = preview[:read]
+    if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+        log.Printf("[ACCESS] Failed to read request body preview: %v", err)
+    }
+
+    r.Body = struct {
+        io.Reader
+        io.Closer
+    }{io.MultiReader(bytes.NewReader(preview), r.Body), r.Body}
+
+    return preview
+}
+
+// formatCLF renders `%h %l %u %t "%r" %>s %b`, followed by
+// `"%{Referer}i" "%{User-Agent}i"` when combined is set.
+func formatCLF(r *http.Request, rec *responseRecorder, start time.Time, combined bool) []byte {
+    user := "-"
+    if username, _, ok := r.BasicAuth(); ok && username != "" {
+        user = username
+    }
+
+    size := "-"
+    if rec.bytes > 0 {
+        size = strconv.FormatInt(rec.bytes, 10)
+    }
+
+    line := fmt.Sprintf("%s - %s [%s] \"%s %s %s\" %d %s",
+        clientIP(r),
+        user,
+        start.Format(clfTimeFormat),
+        r.Method,
+        r.RequestURI,
+        r.Proto,
+        rec.status,
+        size)
+
+    if combined {
+        line += fmt.Sprintf(" %q %q", r.Referer(), r.UserAgent())
+    }
+    return []byte(line)
+}
+
+// responseRecorder captures the status code and body size written by the
+// wrapped handler, along with the backend reported by the balancer.
+type responseRecorder struct {
+    http.ResponseWriter
+    status      int
+    bytes       int64
+    backend     string
+    bodyPreview []byte
+    wroteHeader bool
+}
+
+func (rec *responseRecorder) WriteHeader(statusCode int) {
+    if !rec.wroteHeader {
+        rec.status = statusCode
+        rec.wroteHeader = true
+    }
+    rec.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+    rec.wroteHeader = true
+    n, err := rec.ResponseWriter.Write(b)
+    rec.bytes += int64(n)
+    return n, err
+}
+
+// RecordBackend implements balancer.BackendRecorder.
+func (rec *responseRecorder) RecordBackend(host string) {
+    rec.backend = host
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer for
+// flushing and hijacking.
+func (rec *responseRecorder) Unwrap() http.ResponseWriter {
+    return rec.ResponseWriter
+}
+
+func clientIP(r *http.Request) string {
+    host, _, err := net.SplitHostPort(r.RemoteAddr)
+    if err != nil {
+        return r.RemoteAddr
+    }
+    return host
+}
@@ -0,0 +1,129 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAccessLogSampling(t *testing.T) {
+    tests := []struct {
+        rate         float64
+        minOK, maxOK int
+    }{
+        {rate: 0.1, minOK: 900, maxOK: 1100},
+        {rate: 0, minOK: 0, maxOK: 0},
+        {rate: 1, minOK: 10000, maxOK: 10000},
+    }
+
+    for _, tt := range tests {
+        var out bytes.Buffer
+        h := AccessLog(AccessLogConfig{Output: &out, SampleRate: tt.rate})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+            if r.URL.Path == "/fail" {
+                w.WriteHeader(http.StatusInternalServerError)
+            }
+        }))
+
+        for i := 0; i < 10000; i++ {
+            serve(h, httptest.NewRequest(http.MethodGet, "/ok", nil))
+        }
+        for i := 0; i < 100; i++ {
+            serve(h, httptest.NewRequest(http.MethodGet, "/fail", nil))
+        }
+
+        ok := strings.Count(out.String(), `"status_code":200`)
+        failed := strings.Count(out.String(), `"status_code":500`)
+        if ok < tt.minOK || ok > tt.maxOK {
+            t.Errorf("rate %v: logged %d of 10000 2xx responses, want %d-%d", tt.rate, ok, tt.minOK, tt.maxOK)
+        }
+        if failed != 100 {
+            t.Errorf("rate %v: logged %d of 100 500 responses, want all", tt.rate, failed)
+        }
+    }
+}
+
+// clfLine matches a Common Log Format line, optionally followed by the
+// referer and user agent of the combined format.
+var clfLine = regexp.MustCompile(`^(\S+) - (\S+) \[([^\]]+)\] "(\S+) (\S+) (\S+)" (\d{3}) (\S+)(?: "([^"]*)" "([^"]*)")?$`)
+
+func TestAccessLogCLF(t *testing.T) {
+    for _, format := range []string{AccessLogFormatCLF, AccessLogFormatCombined} {
+        var out bytes.Buffer
+        h := AccessLog(AccessLogConfig{Output: &out, Format: format, SampleRate: 1})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+            if r.URL.Path == "/missing" {
+                http.NotFound(w, r)
+                return
+            }
+            w.Write([]byte("hello"))
+        }))
+
+        req := httptest.NewRequest(http.MethodGet, "/index.html?lang=en", nil)
+        req.RemoteAddr = "192.0.2.1:1234"
+        req.SetBasicAuth("alice", "secret")
+        req.Header.Set("Referer", "https://example.com/")
+        req.Header.Set("User-Agent", "test-agent/1.0")
+        serve(h, req)
+
+        req = httptest.NewRequest(http.MethodPost, "/missing", nil)
+        req.RemoteAddr = "192.0.2.2:1234"
+        serve(h, req)
+
+        want := [][]string{
+            {"192.0.2.1", "alice", "GET", "/index.html?lang=en", "HTTP/1.1", "200", "5", "https://example.com/", "test-agent/1.0"},
+            {"192.0.2.2", "-", "POST", "/missing", "HTTP/1.1", "404", "19", "", ""},
+        }
+        lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+        if len(lines) != len(want) {
+            t.Fatalf("%s: logged %d lines, want %d", format, len(lines), len(want))
+        }
+        for i, line := range lines {
+            m := clfLine.FindStringSubmatch(line)
+            if m == nil {
+                t.Errorf("%s: line %q is not in Common Log Format", format, line)
+                continue
+            }
+            if _, err := time.Parse(clfTimeFormat, m[3]); err != nil {
+                t.Errorf("%s: timestamp %q: %v", format, m[3], err)
+            }
+            got := append(m[1:3:3], m[4:]...)
+            if format == AccessLogFormatCLF {
+                want[i][7], want[i][8] = "", ""
+            }
+            if strings.Join(got, " ") != strings.Join(want[i], " ") {
+                t.Errorf("%s: line %d fields = %q, want %q", format, i+1, got, want[i])
+            }
+        }
+    }
+}
+
+func TestAccessLogBodyPreview(t *testing.T) {
+    body := make([]byte, 500)
+    for i := range body {
+        body[i] = byte(i)
+    }
+
+    var out bytes.Buffer
+    var forwarded []byte
+    h := AccessLog(AccessLogConfig{Output: &out, BodyPreviewBytes: 100, SampleRate: 1})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        forwarded, _ = io.ReadAll(r.Body)
+    }))
+    serve(h, httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body)))
+
+    var entry accessLogEntry
+    if err := json.Unmarshal(out.Bytes(), &entry); err != nil {
+        t.Fatalf("access log line %q: %v", out.String(), err)
+    }
+    if want := base64.StdEncoding.EncodeToString(body[:100]); entry.RequestBodyPreview != want {
+        t.Errorf("request_body_preview = %q, want the first 100 bytes %q", entry.RequestBodyPreview, want)
+    }
+    if !bytes.Equal(forwarded, body) {
+        t.Errorf("handler read %d bytes, want the whole %d byte body", len(forwarded), len(body))
+    }
+}
@@ -0,0 +1,17 @@
+// Package middleware provides HTTP middleware used by the load balancer. Each
+// one is a plain func(http.Handler) http.Handler, so they can wrap any
+// handler, not just a balancer.
+package middleware
+
+import "net/http"
+
+// Chain composes middlewares into one. The first middleware is the outermost,
+// so Chain(a, b)(h) serves requests through a, then b, then h.
+func Chain(middlewares ...func(http.Handler) http.Handler) func(http.Handler) http.Handler {
+    return func(next http.Handler) http.Handler {
+        for i := len(middlewares) - 1; i >= 0; i-- {
+            next = middlewares[i](next)
+        }
+        return next
+    }
+}
@@ -0,0 +1,227 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/sync/singleflight"
+)
+
+// DefaultCollapseVaryHeaders are the request headers that, besides the method
+// and URL, must match for two requests to share a response. They include the
+// credentials so one client is never sent another's response.
+var DefaultCollapseVaryHeaders = []string{"Accept", "Accept-Encoding", "Accept-Language", "Authorization", "Cookie"}
+
+// DefaultCollapseMaxBodyBytes is the largest response body buffered for
+// sharing among collapsed requests.
+const DefaultCollapseMaxBodyBytes = 1 << 20
+
+// errCollapseTooLarge stops the capture of a response too large to share.
+var errCollapseTooLarge = errors.New("response too large to collapse")
+
+// RequestCollapserConfig configures RequestCollapser.
+type RequestCollapserConfig struct {
+    // VaryHeaders are the request headers included in the collapsing key.
+    // Defaults to DefaultCollapseVaryHeaders.
+    VaryHeaders []string
+
+    // MaxBodyBytes is the largest response body shared among collapsed
+    // requests. Each request waiting on a larger response is forwarded on
+    // its own and streamed. Defaults to DefaultCollapseMaxBodyBytes.
+    MaxBodyBytes int64
+
+    // PoolName, when set, labels lb_collapsed_requests_total with
+    // pool_name, as balancer.WithPoolName does for the pool's metrics.
+    PoolName string
+}
+
+// RequestCollapser coalesces concurrent identical GET and HEAD requests: while
+// one is in flight, requests with the same method, URL and VaryHeaders wait
+// for it and are sent a copy of its response rather than reaching the
+// backend themselves. Collapsed responses are buffered in full before being
+// written, so they are not streamed, and only the request that reached the
+// backend is sent its Set-Cookie headers. Protocol upgrades are never
+// collapsed.
+type RequestCollapser struct {
+    next http.Handler
+    cfg  RequestCollapserConfig
+
+    group     singleflight.Group
+    collapsed atomic.Uint64
+
+    collapsedDesc *prometheus.Desc
+}
+
+func NewRequestCollapser(next http.Handler, cfg RequestCollapserConfig) *RequestCollapser {
+    if cfg.VaryHeaders == nil {
+        cfg.VaryHeaders = DefaultCollapseVaryHeaders
+    }
+    if cfg.MaxBodyBytes <= 0 {
+        cfg.MaxBodyBytes = DefaultCollapseMaxBodyBytes
+    }
+
+    var labels prometheus.Labels
+    if cfg.PoolName != "" {
+        labels = prometheus.Labels{"pool_name": cfg.PoolName}
+    }
+
+    return &RequestCollapser{
+        next: next,
+        cfg:  cfg,
+
+        collapsedDesc: prometheus.NewDesc("lb_collapsed_requests_total",
+            "GET and HEAD requests answered with the response of an identical request already in flight.", nil, labels),
+    }
+}
+
+// collapsedResponse is a response captured for sharing among collapsed
+// requests.
+type collapsedResponse struct {
+    status int
+    header http.Header
+    body   []byte
+}
+
+func (rc *RequestCollapser) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+    if r.Method != http.MethodGet && r.Method != http.MethodHead || r.Header.Get("Upgrade") != "" {
+        rc.next.ServeHTTP(w, r)
+        return
+    }
+
+    // The leader's request is made on behalf of every waiting client, so it
+    // must not be abandoned if the leader's own client goes away.
+    leader := false
+    ch := rc.group.DoChan(rc.key(r), func() (any, error) {
+        leader = true
+        return rc.capture(r.WithContext(context.WithoutCancel(r.Context())))
+    })
+
+    var result singleflight.Result
+    select {
+    case result = <-ch:
+    case <-r.Context().Done():
+        return
+    }
+    if !leader {
+        rc.collapsed.Add(1)
+    }
+
+    switch {
+    case errors.Is(result.Err, errCollapseTooLarge):
+        rc.next.ServeHTTP(w, r)
+        return
+    case result.Err != nil:
+        http.Error(w, "Bad Gateway", http.StatusBadGateway)
+        return
+    }
+
+    resp := result.Val.(*collapsedResponse)
+    for name, values := range resp.header {
+        if name == "Set-Cookie" && !leader {
+            continue
+        }
+        w.Header()[name] = append([]string(nil), values...)
+    }
+    w.WriteHeader(resp.status)
+    w.Write(resp.body)
+}
+
+// capture serves r into memory. It fails with errCollapseTooLarge if the
+// response body passes MaxBodyBytes, and recovers panics, since they would
+// otherwise crash the process from the singleflight goroutine.
+func (rc *RequestCollapser) capture(r *http.Request) (resp any, err error) {
+    capture := &captureWriter{header: make(http.Header), limit: rc.cfg.MaxBodyBytes}
+    defer func() {
+        if p := recover(); p != nil {
+            resp = nil
+            err = fmt.Errorf("collapsed request aborted: %v", p)
+            if capture.tooLarge {
+                err = errCollapseTooLarge
+            }
+        }
+    }()
+
+    rc.next.ServeHTTP(capture, r)
+    if capture.tooLarge {
+        return nil, errCollapseTooLarge
+    }
+    if capture.status == 0 {
+        capture.status = http.StatusOK
+    }
+    return &collapsedResponse{
+        status: capture.status,
+        header: capture.header,
+        body:   capture.body.Bytes(),
+    }, nil
+}
+
+// key identifies requests that may share a response.
+func (rc *RequestCollapser) key(r *http.Request) string {
+    h := sha256.New()
+    h.Write([]byte(r.Method + " " + r.Host + r.URL.RequestURI()))
+    for _, name := range rc.cfg.VaryHeaders {
+        h.Write([]byte{0})
+        h.Write([]byte(name))
+        for _, value := range r.Header.Values(name) {
+            h.Write([]byte{0})
+            h.Write([]byte(value))
+        }
+    }
+    return hex.EncodeToString(h.Sum(nil))
+}
+
+// Collapsed returns how many requests were answered with another request's
+// response.
+func (rc *RequestCollapser) Collapsed() uint64 {
+    return rc.collapsed.Load()
+}
+
+func (rc *RequestCollapser) Describe(ch chan<- *prometheus.Desc) {
+    ch <- rc.collapsedDesc
+}
+
+func (rc *RequestCollapser) Collect(ch chan<- prometheus.Metric) {
+    ch <- prometheus.MustNewConstMetric(rc.collapsedDesc, prometheus.CounterValue, float64(rc.collapsed.Load()))
+}
+
+// captureWriter records a response in memory, failing once the body would
+// pass limit.
+type captureWriter struct {
+    header   http.Header
+    status   int
+    body     bytes.Buffer
+    limit    int64
+    tooLarge bool
+}
+
+func (cw *captureWriter) Header() http.Header {
+    return cw.header
+}
+
+func (cw *captureWriter) WriteHeader(status int) {
+    if cw.status == 0 && status >= http.StatusOK {
+        cw.status = status
+        if n, err := strconv.ParseInt(cw.header.Get("Content-Length"), 10, 64); err == nil && n > cw.limit {
+            cw.tooLarge = true
+        }
+    }
+}
+
+func (cw *captureWriter) Write(p []byte) (int, error) {
+    if cw.status == 0 {
+        cw.WriteHeader(http.StatusOK)
+    }
+    if cw.tooLarge || int64(cw.body.Len()+len(p)) > cw.limit {
+        cw.tooLarge = true
+        return 0, errCollapseTooLarge
+    }
+    return cw.body.Write(p)
+}
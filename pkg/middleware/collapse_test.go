@@ -0,0 +1,131 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// collapseBackend answers every request it is released for and counts them.
+type collapseBackend struct {
+    calls   atomic.Int32
+    started chan struct{}
+    release chan struct{}
+    body    string
+}
+
+func newCollapseBackend(body string) *collapseBackend {
+    return &collapseBackend{started: make(chan struct{}, 100), release: make(chan struct{}), body: body}
+}
+
+func (b *collapseBackend) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+    b.calls.Add(1)
+    b.started <- struct{}{}
+    <-b.release
+    http.SetCookie(w, &http.Cookie{Name: "session", Value: "leader"})
+    w.Write([]byte(b.body))
+}
+
+// serveConcurrently sends n identical requests through h once the first has
+// reached the backend, and returns their responses.
+func serveConcurrently(h http.Handler, backend *collapseBackend, n int, header http.Header) []*httptest.ResponseRecorder {
+    recs := make([]*httptest.ResponseRecorder, n)
+    var wg sync.WaitGroup
+    serve := func(i int) {
+        defer wg.Done()
+        req := httptest.NewRequest(http.MethodGet, "/resource", nil)
+        for name, values := range header {
+            req.Header[name] = values
+        }
+        recs[i] = httptest.NewRecorder()
+        h.ServeHTTP(recs[i], req)
+    }
+
+    wg.Add(n)
+    go serve(0)
+    <-backend.started
+    for i := 1; i < n; i++ {
+        go serve(i)
+    }
+    time.Sleep(50 * time.Millisecond)
+    close(backend.release)
+    wg.Wait()
+    return recs
+}
+
+func TestRequestCollapserCollapsesConcurrentGETs(t *testing.T) {
+    backend := newCollapseBackend("shared")
+    rc := NewRequestCollapser(backend, RequestCollapserConfig{})
+
+    recs := serveConcurrently(rc, backend, 50, nil)
+
+    if got := backend.calls.Load(); got > 2 {
+        t.Errorf("backend received %d requests, want about 1 for 50 identical GETs", got)
+    }
+    if got := rc.Collapsed(); got < 48 {
+        t.Errorf("Collapsed() = %d, want at least 48", got)
+    }
+    cookies := 0
+    for _, rec := range recs {
+        if rec.Code != http.StatusOK || rec.Body.String() != "shared" {
+            t.Fatalf("response = %d %q, want 200 %q", rec.Code, rec.Body.String(), "shared")
+        }
+        if rec.Header().Get("Set-Cookie") != "" {
+            cookies++
+        }
+    }
+    if cookies != int(backend.calls.Load()) {
+        t.Errorf("%d clients were sent Set-Cookie, want only the %d whose request reached the backend", cookies, backend.calls.Load())
+    }
+}
+
+func TestRequestCollapserSkipsUpgrades(t *testing.T) {
+    backend := newCollapseBackend("upgraded")
+    rc := NewRequestCollapser(backend, RequestCollapserConfig{})
+
+    serveConcurrently(rc, backend, 3, http.Header{"Upgrade": {"websocket"}, "Connection": {"Upgrade"}})
+    if got := backend.calls.Load(); got != 3 {
+        t.Errorf("backend received %d requests, want each of the 3 upgrades", got)
+    }
+}
+
+func TestRequestCollapserStreamsLargeResponses(t *testing.T) {
+    body := strings.Repeat("x", 100)
+    backend := newCollapseBackend(body)
+    rc := NewRequestCollapser(backend, RequestCollapserConfig{MaxBodyBytes: 10})
+
+    recs := serveConcurrently(rc, backend, 3, nil)
+    for _, rec := range recs {
+        if rec.Code != http.StatusOK || rec.Body.String() != body {
+            t.Errorf("response = %d with %d bytes, want 200 with the whole %d byte body", rec.Code, rec.Body.Len(), len(body))
+        }
+    }
+}
+
+func TestRequestCollapserWaiterCancels(t *testing.T) {
+    backend := newCollapseBackend("slow")
+    defer close(backend.release)
+    rc := NewRequestCollapser(backend, RequestCollapserConfig{})
+
+    go rc.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/resource", nil))
+    <-backend.started
+
+    ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+    defer cancel()
+    done := make(chan struct{})
+    go func() {
+        rc.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/resource", nil).WithContext(ctx))
+        close(done)
+    }()
+
+    select {
+    case <-done:
+    case <-time.After(time.Second):
+        t.Fatal("waiting request did not return when its context was cancelled")
+    }
+}
@@ -0,0 +1,101 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// compressPool reuses gzip writers across responses.
+var compressPool = sync.Pool{
+    New: func() any { return gzip.NewWriter(nil) },
+}
+
+// Compression gzips responses for clients that accept it. Responses that are
+// already encoded, or that have no body, are sent unchanged.
+func Compression() func(http.Handler) http.Handler {
+    return func(next http.Handler) http.Handler {
+        return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+            w.Header().Add("Vary", "Accept-Encoding")
+            if r.Method == http.MethodHead || !acceptsGzip(r) {
+                next.ServeHTTP(w, r)
+                return
+            }
+
+            cw := &compressWriter{ResponseWriter: w}
+            defer cw.Close()
+            next.ServeHTTP(cw, r)
+        })
+    }
+}
+
+func acceptsGzip(r *http.Request) bool {
+    for _, enc := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+        enc, params, _ := strings.Cut(strings.TrimSpace(enc), ";")
+        if strings.EqualFold(strings.TrimSpace(enc), "gzip") && strings.ReplaceAll(params, " ", "") != "q=0" {
+            return true
+        }
+    }
+    return false
+}
+
+// compressWriter decides whether to compress when the response headers are
+// written.
+type compressWriter struct {
+    http.ResponseWriter
+    gz          *gzip.Writer
+    wroteHeader bool
+}
+
+func (cw *compressWriter) WriteHeader(status int) {
+    if status < http.StatusOK {
+        cw.ResponseWriter.WriteHeader(status)
+        return
+    }
+    if cw.wroteHeader {
+        return
+    }
+    cw.wroteHeader = true
+
+    h := cw.Header()
+    if h.Get("Content-Encoding") == "" && status != http.StatusNoContent && status != http.StatusNotModified {
+        h.Set("Content-Encoding", "gzip")
+        h.Del("Content-Length")
+        cw.gz = compressPool.Get().(*gzip.Writer)
+        cw.gz.Reset(cw.ResponseWriter)
+    }
+    cw.ResponseWriter.WriteHeader(status)
+}
+
+func (cw *compressWriter) Write(p []byte) (int, error) {
+    if !cw.wroteHeader {
+        cw.WriteHeader(http.StatusOK)
+    }
+    if cw.gz == nil {
+        return cw.ResponseWriter.Write(p)
+    }
+    return cw.gz.Write(p)
+}
+
+// Flush sends any buffered compressed data so streamed responses keep
+// streaming.
+func (cw *compressWriter) Flush() {
+    if cw.gz != nil {
+        cw.gz.Flush()
+    }
+    http.NewResponseController(cw.ResponseWriter).Flush()
+}
+
+func (cw *compressWriter) Close() {
+    if cw.gz == nil {
+        return
+    }
+    cw.gz.Close()
+    compressPool.Put(cw.gz)
+    cw.gz = nil
+}
+
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+    return cw.ResponseWriter
+}
@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// CORSConfig configures the CORS middleware.
+type CORSConfig struct {
+    // AllowedOrigins lists the origins allowed to make cross-origin
+    // requests. "*" allows any origin.
+    AllowedOrigins []string
+
+    AllowedMethods   []string // Defaults to GET, HEAD and POST
+    AllowedHeaders   []string
+    AllowCredentials bool
+    MaxAge           int // Seconds a preflight response may be cached
+}
+
+// CORS adds Cross-Origin Resource Sharing headers for allowed origins and
+// answers preflight requests itself with 204. Requests from other origins are
+// passed through without CORS headers, leaving the browser to block them.
+func CORS(cfg CORSConfig) func(http.Handler) http.Handler {
+    methods := cfg.AllowedMethods
+    if len(methods) == 0 {
+        methods = []string{http.MethodGet, http.MethodHead, http.MethodPost}
+    }
+    allowMethods := strings.Join(methods, ", ")
+    allowHeaders := strings.Join(cfg.AllowedHeaders, ", ")
+    anyOrigin := slices.Contains(cfg.AllowedOrigins, "*")
+
+    return func(next http.Handler) http.Handler {
+        return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+            origin := r.Header.Get("Origin")
+            if origin == "" || !(anyOrigin || slices.Contains(cfg.AllowedOrigins, origin)) {
+                next.ServeHTTP(w, r)
+                return
+            }
+
+            h := w.Header()
+            h.Add("Vary", "Origin")
+            if anyOrigin && !cfg.AllowCredentials {
+                h.Set("Access-Control-Allow-Origin", "*")
+            } else {
+                h.Set("Access-Control-Allow-Origin", origin)
+            }
+            if cfg.AllowCredentials {
+                h.Set("Access-Control-Allow-Credentials", "true")
+            }
+
+            if r.Method != http.MethodOptions || r.Header.Get("Access-Control-Request-Method") == "" {
+                next.ServeHTTP(w, r)
+                return
+            }
+
+            h.Set("Access-Control-Allow-Methods", allowMethods)
+            if allowHeaders != "" {
+                h.Set("Access-Control-Allow-Headers", allowHeaders)
+            }
+            if cfg.MaxAge > 0 {
+                h.Set("Access-Control-Max-Age", strconv.Itoa(cfg.MaxAge))
+            }
+            w.WriteHeader(http.StatusNoContent)
+        })
+    }
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"os"
+	"sync"
+)
+
+// LogFile is an append-only file that can be reopened in place, so external
+// tools such as logrotate can move it aside and signal the process.
+type LogFile struct {
+    path string
+    mu   sync.Mutex
+    f    *os.File
+}
+
+func OpenLogFile(path string) (*LogFile, error) {
+    f, err := openAppend(path)
+    if err != nil {
+        return nil, err
+    }
+    return &LogFile{path: path, f: f}, nil
+}
+
+func (lf *LogFile) Write(p []byte) (int, error) {
+    lf.mu.Lock()
+    defer lf.mu.Unlock()
+    return lf.f.Write(p)
+}
+
+// Reopen closes the current file and opens path again, creating it if it was
+// rotated away.
+func (lf *LogFile) Reopen() error {
+    f, err := openAppend(lf.path)
+    if err != nil {
+        return err
+    }
+
+    lf.mu.Lock()
+    old := lf.f
+    lf.f = f
+    lf.mu.Unlock()
+
+    return old.Close()
+}
+
+func (lf *LogFile) Close() error {
+    lf.mu.Lock()
+    defer lf.mu.Unlock()
+    return lf.f.Close()
+}
+
+func openAppend(path string) (*os.File, error) {
+    return os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+}
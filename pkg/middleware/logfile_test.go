@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAccessLogFile(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "access.log")
+    logFile, err := OpenLogFile(path)
+    if err != nil {
+        t.Fatalf("OpenLogFile: %v", err)
+    }
+    defer logFile.Close()
+
+    h := AccessLog(AccessLogConfig{Output: logFile, SampleRate: 1})(okHandler("hello"))
+    for i := 0; i < 5; i++ {
+        serve(h, httptest.NewRequest(http.MethodGet, "/", nil))
+    }
+
+    data, err := os.ReadFile(path)
+    if err != nil {
+        t.Fatal(err)
+    }
+    lines := bytes.Split(bytes.TrimSpace(data), []byte("\n"))
+    if len(lines) != 5 {
+        t.Fatalf("access log has %d lines, want 5", len(lines))
+    }
+    fields := []string{"timestamp", "client_ip", "method", "path", "status_code", "bytes_sent", "duration_ms", "backend", "request_id"}
+    for i, line := range lines {
+        var entry map[string]any
+        if err := json.Unmarshal(line, &entry); err != nil {
+            t.Fatalf("line %d %q: %v", i+1, line, err)
+        }
+        for _, field := range fields {
+            if _, ok := entry[field]; !ok {
+                t.Errorf("line %d has no %s field", i+1, field)
+            }
+        }
+    }
+}
+
+func TestLogFileReopen(t *testing.T) {
+    dir := t.TempDir()
+    path := filepath.Join(dir, "access.log")
+    logFile, err := OpenLogFile(path)
+    if err != nil {
+        t.Fatalf("OpenLogFile: %v", err)
+    }
+    defer logFile.Close()
+
+    logFile.Write([]byte("before\n"))
+    rotated := filepath.Join(dir, "access.log.1")
+    if err := os.Rename(path, rotated); err != nil {
+        t.Fatal(err)
+    }
+    if err := logFile.Reopen(); err != nil {
+        t.Fatalf("Reopen: %v", err)
+    }
+    logFile.Write([]byte("after\n"))
+
+    for file, want := range map[string]string{rotated: "before\n", path: "after\n"} {
+        if got, _ := os.ReadFile(file); string(got) != want {
+            t.Errorf("%s = %q, want %q", filepath.Base(file), got, want)
+        }
+    }
+}
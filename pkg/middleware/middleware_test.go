@@ -0,0 +1,214 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/tls"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// okHandler answers every request with 200 and body.
+func okHandler(body string) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        w.Write([]byte(body))
+    })
+}
+
+func serve(h http.Handler, r *http.Request) *httptest.ResponseRecorder {
+    rec := httptest.NewRecorder()
+    h.ServeHTTP(rec, r)
+    return rec
+}
+
+func TestChainOrder(t *testing.T) {
+    var order []string
+    mark := func(name string) func(http.Handler) http.Handler {
+        return func(next http.Handler) http.Handler {
+            return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+                order = append(order, name)
+                next.ServeHTTP(w, r)
+            })
+        }
+    }
+
+    h := Chain(mark("a"), mark("b"), mark("c"))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        order = append(order, "handler")
+    }))
+    serve(h, httptest.NewRequest(http.MethodGet, "/", nil))
+
+    if got := strings.Join(order, ","); got != "a,b,c,handler" {
+        t.Errorf("served in order %s, want a,b,c,handler", got)
+    }
+    if h := Chain()(okHandler("ok")); serve(h, httptest.NewRequest(http.MethodGet, "/", nil)).Body.String() != "ok" {
+        t.Errorf("an empty chain does not pass requests through")
+    }
+}
+
+func TestRequestID(t *testing.T) {
+    var seen string
+    h := RequestID()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        seen = r.Header.Get(RequestIDHeader)
+    }))
+
+    rec := serve(h, httptest.NewRequest(http.MethodGet, "/", nil))
+    if len(seen) != 32 {
+        t.Errorf("generated request ID %q, want 32 hex characters", seen)
+    }
+    if got := rec.Header().Get(RequestIDHeader); got != seen {
+        t.Errorf("response %s = %q, want the request's %q", RequestIDHeader, got, seen)
+    }
+
+    req := httptest.NewRequest(http.MethodGet, "/", nil)
+    req.Header.Set(RequestIDHeader, "client-id")
+    rec = serve(h, req)
+    if seen != "client-id" || rec.Header().Get(RequestIDHeader) != "client-id" {
+        t.Errorf("client's request ID was replaced by %q", seen)
+    }
+}
+
+func TestCORS(t *testing.T) {
+    h := CORS(CORSConfig{
+        AllowedOrigins: []string{"https://app.example"},
+        AllowedHeaders: []string{"Authorization"},
+        MaxAge:         600,
+    })(okHandler("ok"))
+
+    t.Run("allowed origin", func(t *testing.T) {
+        req := httptest.NewRequest(http.MethodGet, "/", nil)
+        req.Header.Set("Origin", "https://app.example")
+        rec := serve(h, req)
+        if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "https://app.example" {
+            t.Errorf("Access-Control-Allow-Origin = %q", got)
+        }
+        if rec.Body.String() != "ok" {
+            t.Errorf("request was not passed to the handler")
+        }
+    })
+
+    t.Run("other origin", func(t *testing.T) {
+        req := httptest.NewRequest(http.MethodGet, "/", nil)
+        req.Header.Set("Origin", "https://evil.example")
+        if got := serve(h, req).Header().Get("Access-Control-Allow-Origin"); got != "" {
+            t.Errorf("disallowed origin got Access-Control-Allow-Origin %q", got)
+        }
+    })
+
+    t.Run("preflight", func(t *testing.T) {
+        req := httptest.NewRequest(http.MethodOptions, "/", nil)
+        req.Header.Set("Origin", "https://app.example")
+        req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+        rec := serve(h, req)
+        if rec.Code != http.StatusNoContent || rec.Body.Len() != 0 {
+            t.Errorf("preflight = %d %q, want an empty 204", rec.Code, rec.Body)
+        }
+        for name, want := range map[string]string{
+            "Access-Control-Allow-Methods": "GET, HEAD, POST",
+            "Access-Control-Allow-Headers": "Authorization",
+            "Access-Control-Max-Age":       "600",
+        } {
+            if got := rec.Header().Get(name); got != want {
+                t.Errorf("%s = %q, want %q", name, got, want)
+            }
+        }
+    })
+}
+
+func TestRateLimit(t *testing.T) {
+    h := RateLimit(1, 2)(okHandler("ok"))
+
+    request := func(addr string) int {
+        req := httptest.NewRequest(http.MethodGet, "/", nil)
+        req.RemoteAddr = addr
+        return serve(h, req).Code
+    }
+
+    for i := 0; i < 2; i++ {
+        if code := request("192.0.2.1:1000"); code != http.StatusOK {
+            t.Fatalf("request %d within the burst = %d, want %d", i, code, http.StatusOK)
+        }
+    }
+    if code := request("192.0.2.1:1001"); code != http.StatusTooManyRequests {
+        t.Errorf("request over the burst = %d, want %d", code, http.StatusTooManyRequests)
+    }
+    if code := request("192.0.2.2:1000"); code != http.StatusOK {
+        t.Errorf("another client's request = %d, want its own limit", code)
+    }
+}
+
+func TestCompression(t *testing.T) {
+    body := strings.Repeat("compress me ", 100)
+    h := Compression()(okHandler(body))
+
+    req := httptest.NewRequest(http.MethodGet, "/", nil)
+    req.Header.Set("Accept-Encoding", "br, gzip")
+    rec := serve(h, req)
+    if got := rec.Header().Get("Content-Encoding"); got != "gzip" {
+        t.Fatalf("Content-Encoding = %q, want gzip", got)
+    }
+    zr, err := gzip.NewReader(rec.Body)
+    if err != nil {
+        t.Fatalf("gzip.NewReader: %v", err)
+    }
+    if got, _ := io.ReadAll(zr); string(got) != body {
+        t.Errorf("decompressed body differs from the handler's")
+    }
+
+    rec = serve(h, httptest.NewRequest(http.MethodGet, "/", nil))
+    if rec.Header().Get("Content-Encoding") != "" || rec.Body.String() != body {
+        t.Errorf("response to a client without gzip support was compressed")
+    }
+}
+
+func TestHSTS(t *testing.T) {
+    h := HSTS(HSTSConfig{MaxAge: 3600, IncludeSubdomains: true})(okHandler("ok"))
+
+    req := httptest.NewRequest(http.MethodGet, "https://example.com/", nil)
+    req.TLS = &tls.ConnectionState{}
+    if got := serve(h, req).Header().Get("Strict-Transport-Security"); got != "max-age=3600; includeSubDomains" {
+        t.Errorf("Strict-Transport-Security = %q", got)
+    }
+    if got := serve(h, httptest.NewRequest(http.MethodGet, "/", nil)).Header().Get("Strict-Transport-Security"); got != "" {
+        t.Errorf("plain HTTP response got Strict-Transport-Security %q", got)
+    }
+}
+
+func TestHSTSOverTLS(t *testing.T) {
+    h := HSTS(HSTSConfig{MaxAge: 31536000, IncludeSubdomains: true, Preload: true})(okHandler("ok"))
+    srv := httptest.NewTLSServer(h)
+    t.Cleanup(srv.Close)
+
+    resp, err := srv.Client().Get(srv.URL)
+    if err != nil {
+        t.Fatal(err)
+    }
+    resp.Body.Close()
+    if got, want := resp.Header.Get("Strict-Transport-Security"), "max-age=31536000; includeSubDomains; preload"; got != want {
+        t.Errorf("Strict-Transport-Security = %q, want %q", got, want)
+    }
+}
+
+func TestAccessLog(t *testing.T) {
+    var out bytes.Buffer
+    h := Chain(RequestID(), AccessLog(AccessLogConfig{Output: &out, SampleRate: 1}))(okHandler("hello"))
+
+    req := httptest.NewRequest(http.MethodGet, "/path", nil)
+    req.RemoteAddr = "192.0.2.1:1234"
+    serve(h, req)
+
+    var entry accessLogEntry
+    if err := json.Unmarshal(out.Bytes(), &entry); err != nil {
+        t.Fatalf("access log line %q: %v", out.String(), err)
+    }
+    if entry.Method != http.MethodGet || entry.Path != "/path" || entry.StatusCode != http.StatusOK ||
+        entry.BytesSent != 5 || entry.ClientIP != "192.0.2.1" {
+        t.Errorf("access log entry = %+v", entry)
+    }
+    if entry.RequestID == "" {
+        t.Errorf("access log entry has no request ID")
+    }
+}
@@ -0,0 +1,173 @@
+package middleware
+
+import (
+	"container/list"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultMaxTrackedPaths is how many distinct paths PathStatsMiddleware
+// tracks unless configured otherwise.
+const DefaultMaxTrackedPaths = 1000
+
+// DefaultPathIDPattern matches path segments that are UUIDs or integers.
+var DefaultPathIDPattern = regexp.MustCompile(`^(?i:[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}|[0-9]+)$`)
+
+// pathLatencyBucketsMs are the upper bounds of the per-path latency
+// histogram buckets. A final overflow bucket catches everything slower.
+var pathLatencyBucketsMs = [...]float64{1, 2, 5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000}
+
+// PathStatsConfig configures PathStatsMiddleware.
+type PathStatsConfig struct {
+    // IDPattern matches path segments that identify a resource, which are
+    // replaced by {id} so /users/1 and /users/2 share stats. Defaults to
+    // DefaultPathIDPattern.
+    IDPattern *regexp.Regexp
+
+    // MaxPaths caps how many paths are tracked; the least recently seen is
+    // evicted to make room. Defaults to DefaultMaxTrackedPaths.
+    MaxPaths int
+}
+
+// PathStatsMiddleware records a latency histogram per normalised request
+// path.
+type PathStatsMiddleware struct {
+    next http.Handler
+    cfg  PathStatsConfig
+
+    mu    sync.Mutex
+    paths map[string]*list.Element // Values are *pathStats
+    lru   *list.List               // Most recently seen at the front
+}
+
+func NewPathStatsMiddleware(next http.Handler, cfg PathStatsConfig) *PathStatsMiddleware {
+    if cfg.IDPattern == nil {
+        cfg.IDPattern = DefaultPathIDPattern
+    }
+    if cfg.MaxPaths <= 0 {
+        cfg.MaxPaths = DefaultMaxTrackedPaths
+    }
+
+    return &PathStatsMiddleware{
+        next:  next,
+        cfg:   cfg,
+        paths: make(map[string]*list.Element),
+        lru:   list.New(),
+    }
+}
+
+// pathStats accumulates the latencies of one path.
+type pathStats struct {
+    path    string
+    count   atomic.Uint64
+    sumUs   atomic.Uint64
+    buckets [len(pathLatencyBucketsMs) + 1]atomic.Uint64 // Last bucket is overflow
+}
+
+func (ps *pathStats) observe(d time.Duration) {
+    ps.count.Add(1)
+    ps.sumUs.Add(uint64(d.Microseconds()))
+
+    ms := float64(d.Microseconds()) / 1000
+    for i, bound := range pathLatencyBucketsMs {
+        if ms <= bound {
+            ps.buckets[i].Add(1)
+            return
+        }
+    }
+    ps.buckets[len(pathLatencyBucketsMs)].Add(1)
+}
+
+func (m *PathStatsMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+    start := time.Now()
+    m.next.ServeHTTP(w, r)
+    m.stats(m.NormalizePath(r.URL.Path)).observe(time.Since(start))
+}
+
+// NormalizePath replaces every path segment matching the ID pattern with
+// {id}.
+func (m *PathStatsMiddleware) NormalizePath(path string) string {
+    segments := strings.Split(path, "/")
+    for i, segment := range segments {
+        if segment != "" && m.cfg.IDPattern.MatchString(segment) {
+            segments[i] = "{id}"
+        }
+    }
+    return strings.Join(segments, "/")
+}
+
+// stats returns the entry for path, creating it and evicting the least
+// recently seen path if needed.
+func (m *PathStatsMiddleware) stats(path string) *pathStats {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+
+    if elem, ok := m.paths[path]; ok {
+        m.lru.MoveToFront(elem)
+        return elem.Value.(*pathStats)
+    }
+
+    if m.lru.Len() >= m.cfg.MaxPaths {
+        oldest := m.lru.Back()
+        m.lru.Remove(oldest)
+        delete(m.paths, oldest.Value.(*pathStats).path)
+    }
+
+    ps := &pathStats{path: path}
+    m.paths[path] = m.lru.PushFront(ps)
+    return ps
+}
+
+// PathStat is a snapshot of one path's latencies.
+type PathStat struct {
+    Path    string              `json:"path"`
+    Count   uint64              `json:"count"`
+    SumMs   float64             `json:"sum_ms"`
+    MeanMs  float64             `json:"mean_ms"`
+    Buckets []PathLatencyBucket `json:"buckets"`
+}
+
+// PathLatencyBucket counts requests that took at most LeMs milliseconds and
+// more than the previous bucket's bound. The overflow bucket has LeMs 0.
+type PathLatencyBucket struct {
+    LeMs  float64 `json:"le_ms,omitempty"`
+    Count uint64  `json:"count"`
+}
+
+// Snapshot returns the stats of every tracked path, sorted by path.
+func (m *PathStatsMiddleware) Snapshot() []PathStat {
+    m.mu.Lock()
+    tracked := make([]*pathStats, 0, m.lru.Len())
+    for elem := m.lru.Front(); elem != nil; elem = elem.Next() {
+        tracked = append(tracked, elem.Value.(*pathStats))
+    }
+    m.mu.Unlock()
+
+    stats := make([]PathStat, 0, len(tracked))
+    for _, ps := range tracked {
+        stat := PathStat{
+            Path:    ps.path,
+            Count:   ps.count.Load(),
+            SumMs:   float64(ps.sumUs.Load()) / 1000,
+            Buckets: make([]PathLatencyBucket, len(ps.buckets)),
+        }
+        if stat.Count > 0 {
+            stat.MeanMs = stat.SumMs / float64(stat.Count)
+        }
+        for i := range ps.buckets {
+            stat.Buckets[i].Count = ps.buckets[i].Load()
+            if i < len(pathLatencyBucketsMs) {
+                stat.Buckets[i].LeMs = pathLatencyBucketsMs[i]
+            }
+        }
+        stats = append(stats, stat)
+    }
+
+    sort.Slice(stats, func(i, j int) bool { return stats[i].Path < stats[j].Path })
+    return stats
+}
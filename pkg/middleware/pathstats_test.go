@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+)
+
+func TestPathStatsNormalizePath(t *testing.T) {
+    m := NewPathStatsMiddleware(http.NotFoundHandler(), PathStatsConfig{})
+    tests := map[string]string{
+        "/users/42":                                        "/users/{id}",
+        "/users/42/orders/7":                               "/users/{id}/orders/{id}",
+        "/items/3F2504E0-4F89-11D3-9A0C-0305E82C3301/tags": "/items/{id}/tags",
+        "/v2/users":                                        "/v2/users",
+        "/":                                                "/",
+    }
+    for path, want := range tests {
+        if got := m.NormalizePath(path); got != want {
+            t.Errorf("NormalizePath(%q) = %q, want %q", path, got, want)
+        }
+    }
+
+    custom := NewPathStatsMiddleware(http.NotFoundHandler(), PathStatsConfig{IDPattern: regexp.MustCompile(`^user-`)})
+    if got := custom.NormalizePath("/profiles/user-ab12/42"); got != "/profiles/{id}/42" {
+        t.Errorf("NormalizePath with a custom pattern = %q, want /profiles/{id}/42", got)
+    }
+}
+
+func TestPathStatsEvictsLeastRecentlySeen(t *testing.T) {
+    m := NewPathStatsMiddleware(http.NotFoundHandler(), PathStatsConfig{MaxPaths: 2})
+    for _, path := range []string{"/a", "/b", "/a?page=2", "/c"} {
+        m.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+    }
+
+    stats := m.Snapshot()
+    if len(stats) != 2 || stats[0].Path != "/a" || stats[1].Path != "/c" {
+        t.Fatalf("tracked paths = %+v, want /a and /c after /b was evicted", stats)
+    }
+    if stats[0].Count != 2 {
+        t.Errorf("/a count = %d, want 2 with the query string ignored", stats[0].Count)
+    }
+
+    var bucketed uint64
+    for _, bucket := range stats[0].Buckets {
+        bucketed += bucket.Count
+    }
+    if bucketed != stats[0].Count {
+        t.Errorf("buckets hold %d requests, want %d", bucketed, stats[0].Count)
+    }
+}
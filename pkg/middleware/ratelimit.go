@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// rateLimitIdleTTL is how long a client's limiter is kept after its last
+// request.
+const rateLimitIdleTTL = 10 * time.Minute
+
+// RateLimit allows each client IP rps requests per second with bursts of up
+// to burst, answering 429 with Retry-After once a client exceeds it.
+func RateLimit(rps float64, burst int) func(http.Handler) http.Handler {
+    limiters := &clientLimiters{
+        rps:      rate.Limit(rps),
+        burst:    burst,
+        limiters: make(map[string]*clientLimiter),
+    }
+
+    return func(next http.Handler) http.Handler {
+        return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+            if !limiters.get(clientIP(r)).Allow() {
+                w.Header().Set("Retry-After", "1")
+                http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+                return
+            }
+            next.ServeHTTP(w, r)
+        })
+    }
+}
+
+type clientLimiter struct {
+    *rate.Limiter
+    lastSeen time.Time
+}
+
+// clientLimiters hands out one limiter per client, forgetting clients that
+// have been idle for rateLimitIdleTTL.
+type clientLimiters struct {
+    rps   rate.Limit
+    burst int
+
+    mu        sync.Mutex
+    limiters  map[string]*clientLimiter
+    lastSweep time.Time
+}
+
+func (c *clientLimiters) get(ip string) *rate.Limiter {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+
+    now := time.Now()
+    if now.Sub(c.lastSweep) > rateLimitIdleTTL {
+        for key, l := range c.limiters {
+            if now.Sub(l.lastSeen) > rateLimitIdleTTL {
+                delete(c.limiters, key)
+            }
+        }
+        c.lastSweep = now
+    }
+
+    l, ok := c.limiters[ip]
+    if !ok {
+        l = &clientLimiter{Limiter: rate.NewLimiter(c.rps, c.burst)}
+        c.limiters[ip] = l
+    }
+    l.lastSeen = now
+    return l.Limiter
+}
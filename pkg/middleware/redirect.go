@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// HTTPRedirectHandler answers every request with a 301 to the same path and
+// query over HTTPS. It is meant to be served on a plain HTTP listener next to
+// the TLS one.
+type HTTPRedirectHandler struct {
+    httpsHost string
+}
+
+// NewHTTPRedirectHandler redirects to httpsHost, a host[:port]. If httpsHost
+// is only a port, e.g. ":8443", it is joined with the request's hostname; if
+// it is empty the request's host is used without its port.
+func NewHTTPRedirectHandler(httpsHost string) *HTTPRedirectHandler {
+    return &HTTPRedirectHandler{httpsHost: httpsHost}
+}
+
+func (h *HTTPRedirectHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+    host := h.httpsHost
+    if host == "" || strings.HasPrefix(host, ":") {
+        hostname := r.Host
+        if name, _, err := net.SplitHostPort(r.Host); err == nil {
+            hostname = name
+        }
+        if strings.Contains(hostname, ":") {
+            hostname = "[" + hostname + "]" // IPv6
+        }
+        host = hostname + host
+    }
+
+    target := "https://" + host + r.URL.RequestURI()
+    http.Redirect(w, r, target, http.StatusMovedPermanently)
+}
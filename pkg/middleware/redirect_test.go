@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHTTPRedirectHandler(t *testing.T) {
+    tests := []struct {
+        httpsHost string
+        reqHost   string
+        want      string
+    }{
+        {"", "example.com:8080", "https://example.com/orders?id=7&page=2"},
+        {":8443", "example.com:8080", "https://example.com:8443/orders?id=7&page=2"},
+        {":8443", "[::1]:8080", "https://[::1]:8443/orders?id=7&page=2"},
+        {"lb.example.com", "example.com", "https://lb.example.com/orders?id=7&page=2"},
+    }
+
+    for _, tt := range tests {
+        srv := httptest.NewServer(NewHTTPRedirectHandler(tt.httpsHost))
+        client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+            return http.ErrUseLastResponse
+        }}
+
+        req, _ := http.NewRequest(http.MethodGet, srv.URL+"/orders?id=7&page=2", nil)
+        req.Host = tt.reqHost
+        resp, err := client.Do(req)
+        if err != nil {
+            srv.Close()
+            t.Fatal(err)
+        }
+        resp.Body.Close()
+        srv.Close()
+
+        if resp.StatusCode != http.StatusMovedPermanently {
+            t.Errorf("httpsHost %q: status = %d, want 301", tt.httpsHost, resp.StatusCode)
+        }
+        if got := resp.Header.Get("Location"); got != tt.want {
+            t.Errorf("httpsHost %q, Host %q: Location = %q, want %q", tt.httpsHost, tt.reqHost, got, tt.want)
+        }
+    }
+}
@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+// RequestIDHeader carries the ID assigned to each request.
+const RequestIDHeader = "X-Request-ID"
+
+// RequestID gives every request an X-Request-ID, keeping one the client
+// already sent, and echoes it on the response so both sides can correlate
+// logs.
+func RequestID() func(http.Handler) http.Handler {
+    return func(next http.Handler) http.Handler {
+        return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+            id := r.Header.Get(RequestIDHeader)
+            if id == "" {
+                id = newRequestID()
+                r.Header.Set(RequestIDHeader, id)
+            }
+            w.Header().Set(RequestIDHeader, id)
+            next.ServeHTTP(w, r)
+        })
+    }
+}
+
+func newRequestID() string {
+    var b [16]byte
+    rand.Read(b[:])
+    return hex.EncodeToString(b[:])
+}
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func serveSecurityHeaders(cfg SecurityHeadersConfig, backend http.HandlerFunc) http.Header {
+    rec := httptest.NewRecorder()
+    NewSecurityHeadersMiddleware(backend, cfg).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+    return rec.Header()
+}
+
+func TestSecurityHeadersDefaults(t *testing.T) {
+    header := serveSecurityHeaders(DefaultSecurityHeadersConfig(), func(w http.ResponseWriter, r *http.Request) {
+        w.Write([]byte("ok"))
+    })
+
+    for name, want := range map[string]string{
+        "Content-Security-Policy": "default-src 'self'",
+        "X-Content-Type-Options":  "nosniff",
+        "X-Frame-Options":         "DENY",
+        "Permissions-Policy":      "camera=(), microphone=(), geolocation=()",
+        "Referrer-Policy":         "strict-origin-when-cross-origin",
+    } {
+        if got := header.Get(name); got != want {
+            t.Errorf("%s = %q, want %q", name, got, want)
+        }
+    }
+}
+
+func TestSecurityHeadersOverride(t *testing.T) {
+    cfg := DefaultSecurityHeadersConfig()
+    cfg.XFrameOptions = "SAMEORIGIN"
+    cfg.PermissionsPolicy = ""
+
+    header := serveSecurityHeaders(cfg, func(w http.ResponseWriter, r *http.Request) {
+        w.WriteHeader(http.StatusNoContent)
+    })
+    if got := header.Get("X-Frame-Options"); got != "SAMEORIGIN" {
+        t.Errorf("X-Frame-Options = %q, want the configured %q", got, "SAMEORIGIN")
+    }
+    if got := header.Get("Permissions-Policy"); got != "" {
+        t.Errorf("disabled Permissions-Policy was sent as %q", got)
+    }
+}
+
+func TestSecurityHeadersKeepBackendValues(t *testing.T) {
+    header := serveSecurityHeaders(DefaultSecurityHeadersConfig(), func(w http.ResponseWriter, r *http.Request) {
+        w.Header().Set("Content-Security-Policy", "default-src https:")
+        w.WriteHeader(http.StatusOK)
+    })
+    if got := header.Get("Content-Security-Policy"); got != "default-src https:" {
+        t.Errorf("Content-Security-Policy = %q, want the backend's value", got)
+    }
+}
@@ -0,0 +1,125 @@
+// Package testing provides helpers for testing code that runs behind the
+// load balancer.
+package testing
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// FakeBackend is an httptest.Server that records the requests it receives
+// and answers with a configurable status, body and delay. Requests to
+// /health report whether the backend is healthy and are not counted.
+type FakeBackend struct {
+    *httptest.Server
+
+    mu          sync.Mutex
+    calls       int
+    lastRequest *http.Request
+    statusCode  int
+    body        string
+    delay       time.Duration
+    healthy     bool
+}
+
+// NewFakeBackend starts a healthy FakeBackend answering 200 OK. It is closed
+// when the test finishes.
+func NewFakeBackend(t testing.TB) *FakeBackend {
+    t.Helper()
+
+    fb := &FakeBackend{
+        statusCode: http.StatusOK,
+        body:       "OK",
+        healthy:    true,
+    }
+    fb.Server = httptest.NewServer(http.HandlerFunc(fb.serveHTTP))
+    t.Cleanup(fb.Close)
+    return fb
+}
+
+func (fb *FakeBackend) serveHTTP(w http.ResponseWriter, r *http.Request) {
+    if r.URL.Path == "/health" {
+        fb.mu.Lock()
+        healthy := fb.healthy
+        fb.mu.Unlock()
+
+        if !healthy {
+            w.WriteHeader(http.StatusServiceUnavailable)
+            return
+        }
+        w.WriteHeader(http.StatusOK)
+        return
+    }
+
+    // Keep the body so LastRequest can be inspected after the handler
+    // returns.
+    body, _ := io.ReadAll(r.Body)
+    recorded := r.Clone(context.Background())
+    recorded.Body = io.NopCloser(bytes.NewReader(body))
+
+    fb.mu.Lock()
+    fb.calls++
+    fb.lastRequest = recorded
+    status, respBody, delay := fb.statusCode, fb.body, fb.delay
+    fb.mu.Unlock()
+
+    if delay > 0 {
+        select {
+        case <-time.After(delay):
+        case <-r.Context().Done():
+            return
+        }
+    }
+
+    w.WriteHeader(status)
+    io.WriteString(w, respBody)
+}
+
+// CallCount returns the number of non-health-check requests received.
+func (fb *FakeBackend) CallCount() int {
+    fb.mu.Lock()
+    defer fb.mu.Unlock()
+    return fb.calls
+}
+
+// LastRequest returns the most recent non-health-check request, or nil if
+// there has been none. Its body can be read after the fact.
+func (fb *FakeBackend) LastRequest() *http.Request {
+    fb.mu.Lock()
+    defer fb.mu.Unlock()
+    return fb.lastRequest
+}
+
+// SetStatusCode sets the status returned for non-health-check requests.
+func (fb *FakeBackend) SetStatusCode(code int) {
+    fb.mu.Lock()
+    defer fb.mu.Unlock()
+    fb.statusCode = code
+}
+
+// SetBody sets the response body returned for non-health-check requests.
+func (fb *FakeBackend) SetBody(body string) {
+    fb.mu.Lock()
+    defer fb.mu.Unlock()
+    fb.body = body
+}
+
+// SetDelay makes the backend wait d before responding.
+func (fb *FakeBackend) SetDelay(d time.Duration) {
+    fb.mu.Lock()
+    defer fb.mu.Unlock()
+    fb.delay = d
+}
+
+// SetHealthy controls whether /health answers 200 or 503.
+func (fb *FakeBackend) SetHealthy(healthy bool) {
+    fb.mu.Lock()
+    defer fb.mu.Unlock()
+    fb.healthy = healthy
+}
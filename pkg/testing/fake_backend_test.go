@@ -0,0 +1,77 @@
+package testing
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFakeBackend(t *testing.T) {
+    fb := NewFakeBackend(t)
+
+    resp, err := http.Post(fb.URL+"/orders?id=7", "text/plain", strings.NewReader("payload"))
+    if err != nil {
+        t.Fatal(err)
+    }
+    body, _ := io.ReadAll(resp.Body)
+    resp.Body.Close()
+    if resp.StatusCode != http.StatusOK || string(body) != "OK" {
+        t.Errorf("default response = %d %q, want 200 OK", resp.StatusCode, body)
+    }
+
+    last := fb.LastRequest()
+    if last == nil || last.Method != http.MethodPost || last.URL.RequestURI() != "/orders?id=7" {
+        t.Fatalf("LastRequest() = %+v, want POST /orders?id=7", last)
+    }
+    if got, _ := io.ReadAll(last.Body); string(got) != "payload" {
+        t.Errorf("LastRequest() body = %q, want %q", got, "payload")
+    }
+
+    fb.SetStatusCode(http.StatusTeapot)
+    fb.SetBody("short and stout")
+    fb.SetDelay(50 * time.Millisecond)
+    start := time.Now()
+    resp, err = http.Get(fb.URL)
+    if err != nil {
+        t.Fatal(err)
+    }
+    body, _ = io.ReadAll(resp.Body)
+    resp.Body.Close()
+    if resp.StatusCode != http.StatusTeapot || string(body) != "short and stout" {
+        t.Errorf("configured response = %d %q, want 418 %q", resp.StatusCode, body, "short and stout")
+    }
+    if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+        t.Errorf("response took %v, want at least the 50ms delay", elapsed)
+    }
+    if got := fb.CallCount(); got != 2 {
+        t.Errorf("CallCount() = %d, want 2", got)
+    }
+}
+
+func TestFakeBackendHealth(t *testing.T) {
+    fb := NewFakeBackend(t)
+
+    for _, healthy := range []bool{true, false, true} {
+        fb.SetHealthy(healthy)
+        resp, err := http.Get(fb.URL + "/health")
+        if err != nil {
+            t.Fatal(err)
+        }
+        resp.Body.Close()
+        want := http.StatusOK
+        if !healthy {
+            want = http.StatusServiceUnavailable
+        }
+        if resp.StatusCode != want {
+            t.Errorf("healthy %v: /health = %d, want %d", healthy, resp.StatusCode, want)
+        }
+    }
+    if got := fb.CallCount(); got != 0 {
+        t.Errorf("CallCount() = %d after only health checks, want 0", got)
+    }
+    if fb.LastRequest() != nil {
+        t.Errorf("LastRequest() recorded a health check")
+    }
+}